@@ -3,22 +3,114 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Configurations struct {
-	UserServiceName      string
-	UserServiceDBUrl     string
-	UserServicePort      string
-	UserServiceSecretKey string
-	ProxyBaseUrl         string
+	UserServiceName  string
+	UserServiceDBUrl string
+	// UserServiceStorage picks where users-service keeps users: "postgres",
+	// "sqlite" or "memory". Unless USER_SERVICE_STORAGE sets it, it follows
+	// the scheme of UserServiceDBUrl. Idempotency keys are kept by the same
+	// backend, except on SQLite, where they are kept in memory.
+	UserServiceStorage string
+	UserServicePort    string
+	// UserServiceSigningKeyFile is the PEM private key, RSA or Ed25519,
+	// users-service signs access tokens with. When empty, it generates a key
+	// that is lost on restart, along with the tokens it signed.
+	UserServiceSigningKeyFile string
+	// UserServicePreviousSigningKeyFiles are the PEM keys that signed tokens
+	// before a rotation. Their public keys stay published, and the tokens
+	// they signed valid, until the files are removed from the list.
+	UserServicePreviousSigningKeyFiles []string
+	// UserServiceAccessTokenLifetime is how long access tokens stay valid,
+	// 1h by default.
+	UserServiceAccessTokenLifetime time.Duration
+	// UserServiceRefreshTokenLifetime is how long a refresh token can be
+	// traded for new tokens, 720h (30 days) by default. The token it is
+	// traded for is valid as long again.
+	UserServiceRefreshTokenLifetime time.Duration
+	ProxyBaseUrl                    string
+	// UserServiceGrpcPort is the port users-service serves gRPC on; gRPC is
+	// disabled when it is empty.
+	UserServiceGrpcPort string
+	// UserServiceGrpcAddress is where todo-service reaches the users-service
+	// gRPC API. When empty, todo-service verifies users over HTTP through the
+	// proxy instead.
+	UserServiceGrpcAddress string
+	// UserServiceJWKSUrl is where todo-service reads the public keys of
+	// users-service, to verify access tokens itself. When empty, it asks
+	// users-service to verify every token.
+	UserServiceJWKSUrl string
+	// UserServiceJWKSRefreshInterval is how often todo-service reads the
+	// keys again, 5m by default.
+	UserServiceJWKSRefreshInterval time.Duration
 
 	TodoServiceName               string
 	TodoServiceDBConnectionString string
 	TodoServicePort               string
+	TodoServiceGrpcPort           string
+	// TodoServiceStorage picks where todo-service keeps todos and webhooks:
+	// "mongo", the default, "postgres" at TodoServiceDBUrl, or "memory".
+	// Idempotency keys are kept by the same backend.
+	TodoServiceStorage string
+	TodoServiceDBUrl   string
+	// TodoServiceMongo tunes the mongo client of todo-service; zero values
+	// leave the defaults of the connection string and the driver.
+	TodoServiceMongo MongoConfigurations
+	// TodoServiceEventsRedisAddress is the Redis server todo-service also
+	// relays todo events to, on the TodoServiceEventsStream stream. Events
+	// are not sent to a broker when it is empty.
+	TodoServiceEventsRedisAddress string
+	TodoServiceEventsStream       string
+	// TodoServiceOutboxRelay runs the outbox relay, unless
+	// TODO_SERVICE_OUTBOX_RELAY is "false". Only one instance may run it.
+	TodoServiceOutboxRelay bool
 
 	TracingCollectorEndpoint string
+
+	// UserServiceRateLimits and TodoServiceRateLimits are the rate limit
+	// policies of each service, in the format of ratelimit.ParsePolicy.
+	// Requests are not limited when they are empty.
+	UserServiceRateLimits string
+	TodoServiceRateLimits string
+	// RateLimitRedisAddress is the Redis server both services keep their rate
+	// limit buckets in. When empty, each instance keeps its own in memory.
+	RateLimitRedisAddress string
+	// RateLimitTrustProxy makes the rate limiters take the client IP from the
+	// X-Real-IP header set by the proxy.
+	RateLimitTrustProxy bool
+
+	// OpenAPIValidateResponses makes both services check every response
+	// against their OpenAPI spec. It buffers responses and is meant for
+	// tests only.
+	OpenAPIValidateResponses bool
+}
+
+type MongoConfigurations struct {
+	// DatabaseName defaults to the name of the service.
+	DatabaseName string
+	// CollectionPrefix is prepended to the name of every collection, to share
+	// a database.
+	CollectionPrefix string
+	// Timeout bounds every repository call, 5s by default.
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	MaxPoolSize    uint64
+	MinPoolSize    uint64
+	// ReadConcern is a level such as "local" or "majority", and WriteConcern
+	// "majority" or a number of nodes.
+	ReadConcern  string
+	WriteConcern string
+	TLS          bool
+	TLSCAFile    string
+	// TLSCertificateKeyFile holds the client certificate and its key.
+	TLSCertificateKeyFile string
+	TLSInsecure           bool
 }
 
 func GetConfig(filepath string) *Configurations {
@@ -31,16 +123,102 @@ func GetConfig(filepath string) *Configurations {
 		ProxyBaseUrl: os.Getenv("PROXY_BASE_URL"),
 
 		TracingCollectorEndpoint: os.Getenv("TRACING_COLLECTOR_ENDPOINT"),
+		OpenAPIValidateResponses: os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true",
+
+		UserServiceRateLimits: os.Getenv("USER_SERVICE_RATE_LIMITS"),
+		TodoServiceRateLimits: os.Getenv("TODO_SERVICE_RATE_LIMITS"),
+		RateLimitRedisAddress: os.Getenv("RATE_LIMIT_REDIS_ADDRESS"),
+		RateLimitTrustProxy:   os.Getenv("RATE_LIMIT_TRUST_PROXY") == "true",
+
+		UserServiceName:                    "users-service",
+		UserServiceDBUrl:                   os.Getenv("USER_SERVICE_DATABASE_URL"),
+		UserServiceStorage:                 userServiceStorage(os.Getenv("USER_SERVICE_STORAGE"), os.Getenv("USER_SERVICE_DATABASE_URL")),
+		UserServicePort:                    os.Getenv("USER_SERVICE_PORT"),
+		UserServiceSigningKeyFile:          os.Getenv("USER_SERVICE_SIGNING_KEY_FILE"),
+		UserServicePreviousSigningKeyFiles: listEnv("USER_SERVICE_PREVIOUS_SIGNING_KEY_FILES"),
+		UserServiceAccessTokenLifetime:     durationEnv("USER_SERVICE_ACCESS_TOKEN_LIFETIME"),
+		UserServiceRefreshTokenLifetime:    durationEnv("USER_SERVICE_REFRESH_TOKEN_LIFETIME"),
 
-		UserServiceName:      "users-service",
-		UserServiceDBUrl:     os.Getenv("USER_SERVICE_DATABASE_URL"),
-		UserServicePort:      os.Getenv("USER_SERVICE_PORT"),
-		UserServiceSecretKey: os.Getenv("USER_SERVICE_SECRET"),
+		UserServiceGrpcPort:            os.Getenv("USER_SERVICE_GRPC_PORT"),
+		UserServiceGrpcAddress:         os.Getenv("USER_SERVICE_GRPC_ADDRESS"),
+		UserServiceJWKSUrl:             os.Getenv("USER_SERVICE_JWKS_URL"),
+		UserServiceJWKSRefreshInterval: durationEnv("USER_SERVICE_JWKS_REFRESH_INTERVAL"),
 
 		TodoServiceName:               "todos-service",
 		TodoServiceDBConnectionString: os.Getenv("TODO_SERVICE_CONNECTION_STRING"),
 		TodoServicePort:               os.Getenv("TODO_SERVICE_PORT"),
+		TodoServiceGrpcPort:           os.Getenv("TODO_SERVICE_GRPC_PORT"),
+		TodoServiceStorage:            os.Getenv("TODO_SERVICE_STORAGE"),
+		TodoServiceDBUrl:              os.Getenv("TODO_SERVICE_DATABASE_URL"),
+		TodoServiceMongo: MongoConfigurations{
+			DatabaseName:          os.Getenv("TODO_SERVICE_MONGO_DATABASE"),
+			CollectionPrefix:      os.Getenv("TODO_SERVICE_MONGO_COLLECTION_PREFIX"),
+			Timeout:               durationEnv("TODO_SERVICE_MONGO_TIMEOUT"),
+			ConnectTimeout:        durationEnv("TODO_SERVICE_MONGO_CONNECT_TIMEOUT"),
+			MaxPoolSize:           uintEnv("TODO_SERVICE_MONGO_MAX_POOL_SIZE"),
+			MinPoolSize:           uintEnv("TODO_SERVICE_MONGO_MIN_POOL_SIZE"),
+			ReadConcern:           os.Getenv("TODO_SERVICE_MONGO_READ_CONCERN"),
+			WriteConcern:          os.Getenv("TODO_SERVICE_MONGO_WRITE_CONCERN"),
+			TLS:                   os.Getenv("TODO_SERVICE_MONGO_TLS") == "true",
+			TLSCAFile:             os.Getenv("TODO_SERVICE_MONGO_TLS_CA_FILE"),
+			TLSCertificateKeyFile: os.Getenv("TODO_SERVICE_MONGO_TLS_CERTIFICATE_KEY_FILE"),
+			TLSInsecure:           os.Getenv("TODO_SERVICE_MONGO_TLS_INSECURE") == "true",
+		},
+		TodoServiceEventsRedisAddress: os.Getenv("TODO_SERVICE_EVENTS_REDIS_ADDRESS"),
+		TodoServiceEventsStream:       os.Getenv("TODO_SERVICE_EVENTS_STREAM"),
+		TodoServiceOutboxRelay:        os.Getenv("TODO_SERVICE_OUTBOX_RELAY") != "false",
 	}
 
 	return &configurations
 }
+
+// userServiceStorage picks the users-service backend from the scheme of
+// databaseUrl, unless storage names one. It defaults to postgres.
+func userServiceStorage(storage, databaseUrl string) string {
+	if storage != "" {
+		return storage
+	}
+	scheme, _, _ := strings.Cut(databaseUrl, ":")
+	switch strings.ToLower(scheme) {
+	case "sqlite", "file":
+		return "sqlite"
+	default:
+		return "postgres"
+	}
+}
+
+// durationEnv reads a duration such as "5s" from key, zero when it is unset.
+func durationEnv(key string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return duration
+}
+
+func uintEnv(key string) uint64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return number
+}
+
+// listEnv reads a comma separated list from key, skipping empty items.
+func listEnv(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,8 +1,146 @@
 package errors
 
-const (
-	ErrSomethingWentWrong = "something went wrong"
-	ErrUnauthorized       = "unauthorized"
-	ErrInvalidJson        = "Invalid JSON"
-	ErrMissingBody        = "missing body request"
+import (
+	stderrors "errors"
+	"net/http"
 )
+
+// Error is an error both services can answer a request with: Code
+// identifies it to clients, Status is the HTTP status it maps to and
+// Message is safe to show them. The error it wraps, if any, is only logged.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Err     error
+
+	// kind is the error this one was derived from by Wrap or WithMessage,
+	// which errors.Is matches it against.
+	kind *Error
+}
+
+var (
+	ErrSomethingWentWrong = Internal("internal_error", "something went wrong")
+	ErrUnauthorized       = Unauthorized("unauthorized", "unauthorized")
+	ErrInvalidJson        = BadRequest("invalid_json", "Invalid JSON")
+	ErrMissingBody        = BadRequest("missing_body", "missing body request")
+)
+
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return New(code, http.StatusBadRequest, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(code, http.StatusUnauthorized, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(code, http.StatusForbidden, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(code, http.StatusNotFound, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(code, http.StatusConflict, message)
+}
+
+func Unprocessable(code, message string) *Error {
+	return New(code, http.StatusUnprocessableEntity, message)
+}
+
+func TooManyRequests(code, message string) *Error {
+	return New(code, http.StatusTooManyRequests, message)
+}
+
+func Internal(code, message string) *Error {
+	return New(code, http.StatusInternalServerError, message)
+}
+
+func Unavailable(code, message string) *Error {
+	return New(code, http.StatusServiceUnavailable, message)
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the error e was derived from, so that
+// errors.Is(err, ErrTodoNotFound) holds for ErrTodoNotFound.Wrap(cause).
+func (e *Error) Is(target error) bool {
+	for kind := e.kind; kind != nil; kind = kind.kind {
+		if kind == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap returns e with cause attached, e.g. the repository error behind it.
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := e.derive()
+	wrapped.Err = cause
+	return wrapped
+}
+
+// WithMessage returns e with a different message, e.g. one naming the
+// operation of a batch that failed.
+func (e *Error) WithMessage(message string) *Error {
+	derived := e.derive()
+	derived.Message = message
+	return derived
+}
+
+// WithStatus returns e answered with a different status, for the routes
+// that have always answered it differently.
+func (e *Error) WithStatus(status int) *Error {
+	derived := e.derive()
+	derived.Status = status
+	return derived
+}
+
+func (e *Error) derive() *Error {
+	return &Error{Code: e.Code, Status: e.Status, Message: e.Message, Err: e.Err, kind: e}
+}
+
+// Converter is implemented by error types with fields of their own, e.g.
+// the quota a request exceeded, to tell how they are answered.
+type Converter interface {
+	AppError() *Error
+}
+
+// As returns the first *Error, or error implementing Converter, in the
+// chain of err.
+func As(err error) (*Error, bool) {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		switch typed := err.(type) {
+		case *Error:
+			return typed, true
+		case Converter:
+			return typed.AppError(), true
+		}
+	}
+	return nil, false
+}
+
+// From returns the first *Error in the chain of err, or
+// ErrSomethingWentWrong wrapping err when there is none, so that
+// unexpected errors never reach clients.
+func From(err error) *Error {
+	if appErr, ok := As(err); ok {
+		return appErr
+	}
+	return ErrSomethingWentWrong.Wrap(err)
+}
@@ -0,0 +1,72 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps idempotency records in the memory of a single instance,
+// for tests and local development.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	ttl     time.Duration
+	lease   time.Duration
+}
+
+// NewMemoryStore keeps records for ttl, and lets a request hold its key for
+// lease before it completes.
+func NewMemoryStore(ttl, lease time.Duration) *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}, ttl: ttl, lease: lease}
+}
+
+func (m *MemoryStore) Reserve(ctx context.Context, key, requestHash string) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	// NOTE: expired records are dropped as keys are reserved, in place of
+	// the TTL index of MongoStore.
+	for existingKey, record := range m.records {
+		if now.Sub(record.CreatedAt) > m.ttl {
+			delete(m.records, existingKey)
+		}
+	}
+	if existing, ok := m.records[key]; ok && !isAbandoned(existing, now, m.lease) {
+		return cloneRecord(existing), false, nil
+	}
+	record := Record{Key: key, RequestHash: requestHash, CreatedAt: now}
+	m.records[key] = record
+	return record, true, nil
+}
+
+func (m *MemoryStore) Complete(ctx context.Context, record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.records[record.Key]
+	if !ok {
+		return nil
+	}
+	existing.Completed = true
+	existing.StatusCode = record.StatusCode
+	existing.Header = record.Header.Clone()
+	existing.Body = append([]byte(nil), record.Body...)
+	m.records[record.Key] = existing
+	return nil
+}
+
+func (m *MemoryStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
+
+func cloneRecord(record Record) Record {
+	record.Header = record.Header.Clone()
+	record.Body = append([]byte(nil), record.Body...)
+	return record
+}
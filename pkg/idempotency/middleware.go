@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+var (
+	ErrKeyTooLong      = appErrors.BadRequest("idempotency_key_too_long", "Idempotency-Key must be at most 255 characters")
+	ErrKeyReused       = appErrors.Unprocessable("idempotency_key_reused", "Idempotency-Key was already used with a different request")
+	ErrRequestInFlight = appErrors.Conflict("idempotency_request_in_flight", "a request with this Idempotency-Key is still in progress")
+)
+
+// Middleware makes POST requests carrying an Idempotency-Key header safe to
+// retry: the first response for a key is stored and replayed for every later
+// request with the same key and body. Keys are scoped to the caller's
+// Authorization header, and responses with a 5xx status are not stored so
+// the request can be retried.
+func Middleware(store Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				response.Error(w, r, ErrKeyTooLong)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				if err != nil {
+					response.Error(w, r, appErrors.BadRequest("unreadable_body", "failed to read request body"))
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			ctx := r.Context()
+			scopedKey := hash([]byte(r.Header.Get("Authorization")), []byte(key))
+			requestHash := hash([]byte(r.Method), []byte(r.URL.Path), body)
+
+			record, reserved, err := store.Reserve(ctx, scopedKey, requestHash)
+			if err != nil {
+				response.Error(w, r, appErrors.ErrSomethingWentWrong.Wrap(fmt.Errorf("idempotency: failed to reserve key: %w", err)))
+				return
+			}
+			if !reserved {
+				replay(w, r, record, requestHash)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			// NOTE: a client that disconnects once the handler committed its
+			// write cancels r.Context(). The outcome is still recorded, or its
+			// retry would run the request again once the lease ran out; the
+			// stores bound each call with a timeout of their own.
+			ctx = context.WithoutCancel(ctx)
+
+			if recorder.statusCode >= http.StatusInternalServerError {
+				if err := store.Release(ctx, scopedKey); err != nil {
+					log.Printf("idempotency: failed to release key: %v", err)
+				}
+				return
+			}
+			record.StatusCode = recorder.statusCode
+			record.Header = w.Header().Clone()
+			record.Body = recorder.body.Bytes()
+			if err := store.Complete(ctx, record); err != nil {
+				log.Printf("idempotency: failed to store response: %v", err)
+			}
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, record Record, requestHash string) {
+	if record.RequestHash != requestHash {
+		response.Error(w, r, ErrKeyReused)
+		return
+	}
+	if !record.Completed {
+		response.Error(w, r, ErrRequestInFlight)
+		return
+	}
+
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(record.StatusCode)
+	if _, err := w.Write(record.Body); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
+
+func hash(parts ...[]byte) string {
+	hasher := sha256.New()
+	for _, part := range parts {
+		hasher.Write(part)
+		hasher.Write([]byte{0})
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
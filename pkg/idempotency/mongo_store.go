@@ -0,0 +1,142 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoStore struct {
+	client  *mongo.Client
+	records *mongo.Collection
+	lease   time.Duration
+}
+
+var contextTimeoutDuration = 5 * time.Second
+
+// NewMongoStore keeps idempotency records in the idempotency_keys collection
+// of databaseName, where a TTL index expires them after ttl. A request holds
+// its key for lease before it completes.
+func NewMongoStore(ctx context.Context, monitor *event.CommandMonitor, connectionString, databaseName string, ttl, lease time.Duration) (*MongoStore, error) {
+	if lease <= 0 {
+		return nil, errors.New("lease must be positive")
+	}
+
+	opts := options.Client()
+	opts.Monitor = monitor
+	client, err := mongo.Connect(ctx, opts.ApplyURI(connectionString))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a mongo client: %w", err)
+	}
+
+	records := client.Database(databaseName).Collection("idempotency_keys")
+	_, err = records.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(ttl.Seconds())),
+	})
+	if err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to create idempotency_keys TTL index: %w", err)
+	}
+
+	return &MongoStore{client: client, records: records, lease: lease}, nil
+}
+
+// Close disconnects the client of the store.
+func (m *MongoStore) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
+
+func (m *MongoStore) Reserve(ctx context.Context, key, requestHash string) (Record, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	now := time.Now()
+	_, err := m.records.InsertOne(ctx, mongoRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+	})
+	if err == nil {
+		return Record{Key: key, RequestHash: requestHash, CreatedAt: now}, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return Record{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	result, err := m.records.UpdateOne(ctx,
+		bson.M{"_id": key, "completed": false, "created_at": bson.M{"$lt": now.Add(-m.lease)}},
+		bson.M{"$set": bson.M{"request_hash": requestHash, "created_at": now}},
+	)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+	if result.ModifiedCount == 1 {
+		return Record{Key: key, RequestHash: requestHash, CreatedAt: now}, true, nil
+	}
+
+	existing := mongoRecord{}
+	err = m.records.FindOne(ctx, bson.M{"_id": key}).Decode(&existing)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to load idempotency record: %w", err)
+	}
+	return toRecord(existing), false, nil
+}
+
+func (m *MongoStore) Complete(ctx context.Context, record Record) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := m.records.UpdateOne(ctx, bson.M{"_id": record.Key}, bson.M{
+		"$set": bson.M{
+			"completed":   true,
+			"status_code": record.StatusCode,
+			"header":      record.Header,
+			"body":        record.Body,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to persist idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoStore) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := m.records.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+type mongoRecord struct {
+	Key         string              `bson:"_id"`
+	RequestHash string              `bson:"request_hash"`
+	Completed   bool                `bson:"completed"`
+	StatusCode  int                 `bson:"status_code"`
+	Header      map[string][]string `bson:"header"`
+	Body        []byte              `bson:"body"`
+	CreatedAt   time.Time           `bson:"created_at"`
+}
+
+func toRecord(m mongoRecord) Record {
+	return Record{
+		Key:         m.Key,
+		RequestHash: m.RequestHash,
+		Completed:   m.Completed,
+		StatusCode:  m.StatusCode,
+		Header:      http.Header(m.Header),
+		Body:        m.Body,
+		CreatedAt:   m.CreatedAt,
+	}
+}
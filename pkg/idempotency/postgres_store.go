@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore keeps idempotency records in the idempotency_keys table,
+// which the migrations of the service using it create:
+//
+//	CREATE TABLE idempotency_keys(
+//	    key TEXT PRIMARY KEY,
+//	    request_hash TEXT NOT NULL,
+//	    completed BOOLEAN NOT NULL DEFAULT FALSE,
+//	    status_code INT NOT NULL DEFAULT 0,
+//	    header JSONB,
+//	    body BYTEA,
+//	    created_at TIMESTAMPTZ NOT NULL
+//	);
+//	CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys(created_at);
+type PostgresStore struct {
+	connection *pgxpool.Pool
+	ttl        time.Duration
+	lease      time.Duration
+}
+
+// NewPostgresStore keeps idempotency records in the Postgres database at
+// databaseUrl, where they expire after ttl. A request holds its key for lease
+// before it completes.
+func NewPostgresStore(ctx context.Context, tracer pgx.QueryTracer, databaseUrl string, ttl, lease time.Duration) (*PostgresStore, error) {
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+	if lease <= 0 {
+		return nil, errors.New("lease must be positive")
+	}
+
+	dbConfig, err := pgxpool.ParseConfig(databaseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Postgres database url: %w", err)
+	}
+	dbConfig.ConnConfig.Tracer = tracer
+	connectionPool, err := pgxpool.NewWithConfig(ctx, dbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a Postgres pool: %w", err)
+	}
+	return &PostgresStore{connection: connectionPool, ttl: ttl, lease: lease}, nil
+}
+
+func (p *PostgresStore) Reserve(ctx context.Context, key, requestHash string) (Record, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	now := time.Now()
+	// NOTE: expired records are dropped as keys are reserved, in place of
+	// the TTL index of MongoStore.
+	_, err := p.connection.Exec(ctx, "DELETE FROM idempotency_keys WHERE created_at < $1", now.Add(-p.ttl))
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to expire idempotency keys: %w", err)
+	}
+
+	tag, err := p.connection.Exec(ctx,
+		`INSERT INTO idempotency_keys(key, request_hash, created_at) VALUES($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, created_at = EXCLUDED.created_at
+		WHERE NOT idempotency_keys.completed AND idempotency_keys.created_at < $4`,
+		key, requestHash, now, now.Add(-p.lease),
+	)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return Record{Key: key, RequestHash: requestHash, CreatedAt: now}, true, nil
+	}
+
+	existing := Record{Key: key}
+	err = p.connection.QueryRow(ctx,
+		"SELECT request_hash, completed, status_code, header, body, created_at FROM idempotency_keys WHERE key = $1", key,
+	).Scan(&existing.RequestHash, &existing.Completed, &existing.StatusCode, &existing.Header, &existing.Body, &existing.CreatedAt)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("failed to load idempotency record: %w", err)
+	}
+	return existing, false, nil
+}
+
+func (p *PostgresStore) Complete(ctx context.Context, record Record) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := p.connection.Exec(ctx,
+		"UPDATE idempotency_keys SET completed = TRUE, status_code = $2, header = $3, body = $4 WHERE key = $1",
+		record.Key, record.StatusCode, map[string][]string(record.Header), record.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStore) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := p.connection.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresStore) Close(ctx context.Context) error {
+	p.connection.Close()
+	return nil
+}
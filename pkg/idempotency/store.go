@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrRecordNotFound   = errors.New("idempotency record not found")
+	ErrRecordInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+type Record struct {
+	Key         string
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// Store keeps idempotency records. A reservation that is not completed within
+// the lease of the store is taken over by the next request with its key.
+//
+// NOTE: the lease keeps a request whose instance crashed before completing
+// or releasing its key from answering 409 until the record expires. It must
+// outlast the slowest request, or a retry may run concurrently with it.
+type Store interface {
+	// Reserve claims key for a request with the given hash. When the key is
+	// already claimed it returns the existing record and false.
+	Reserve(ctx context.Context, key, requestHash string) (Record, bool, error)
+	Complete(ctx context.Context, record Record) error
+	// Release drops a reservation so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// isAbandoned reports whether record is a reservation that outlived lease
+// without being completed.
+func isAbandoned(record Record, now time.Time, lease time.Duration) bool {
+	return !record.Completed && now.Sub(record.CreatedAt) > lease
+}
@@ -0,0 +1,124 @@
+// Package jwks encodes the public keys access tokens are verified with as a
+// JSON Web Key Set (RFC 7517): users-service publishes its keys in one, and
+// todo-service verifies tokens with the keys it reads back.
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// The algorithms of the keys, as named in the alg of a token's header.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// Key is an RSA or an Ed25519 public key.
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// N and E are the modulus and exponent of an RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are the curve and the public key of an OKP key.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// NewKey encodes publicKey, an *rsa.PublicKey or an ed25519.PublicKey, with
+// its RFC 7638 thumbprint as its kid.
+func NewKey(publicKey crypto.PublicKey) (Key, error) {
+	var key Key
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		key = Key{
+			Kty: "RSA",
+			Alg: RS256,
+			N:   encode(publicKey.N.Bytes()),
+			E:   encode(big.NewInt(int64(publicKey.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		key = Key{Kty: "OKP", Alg: EdDSA, Crv: "Ed25519", X: encode(publicKey)}
+	default:
+		return Key{}, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+	key.Use = "sig"
+	key.Kid = key.thumbprint()
+	return key, nil
+}
+
+// PublicKey decodes the *rsa.PublicKey or ed25519.PublicKey of k.
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %w", k.Kid, err)
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %w", k.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() <= 1 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA key %s", k.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key of key %s: %w", k.Kid, err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %s", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s %s of key %s", k.Kty, k.Crv, k.Kid)
+	}
+}
+
+// thumbprint hashes the required members of k in lexicographic order, as
+// RFC 7638 defines it.
+func (k Key) thumbprint() string {
+	var members interface{}
+	switch k.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Crv, k.Kty, k.X}
+	}
+	// NOTE: the members are plain strings, which cannot fail to marshal.
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return encode(sum[:])
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
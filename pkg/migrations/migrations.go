@@ -0,0 +1,254 @@
+// Package migrations versions the schema of the SQL backends of both
+// services. Migrations are numbered SQL files embedded in the binary, and the
+// versions applied to a database are kept in its schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration is one step of the schema, applied by Up and reverted by Down.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Dialect is what a Migrator needs to know of the database it migrates.
+type Dialect struct {
+	// Lock keeps other runners out until Unlock, both on the connection of
+	// the run.
+	Lock, Unlock func(ctx context.Context, conn *sql.Conn) error
+	// Placeholder is the bind variable of the n-th argument, from 1.
+	Placeholder func(n int) string
+}
+
+// lockId keeps concurrently starting instances from migrating together.
+const lockId = 5120447
+
+var Postgres = Dialect{
+	Lock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockId)
+		return err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockId)
+		return err
+	},
+	Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+}
+
+// SQLite has no advisory locks. Each migration claims its version first
+// instead, which takes the write lock of the database, so a concurrent runner
+// waits for it and then finds the version applied.
+var SQLite = Dialect{
+	Lock:        func(ctx context.Context, conn *sql.Conn) error { return nil },
+	Unlock:      func(ctx context.Context, conn *sql.Conn) error { return nil },
+	Placeholder: func(n int) string { return "?" },
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// NewMigrator migrates db with the migrations in the sql directory of files.
+func NewMigrator(db *sql.DB, dialect Dialect, files fs.FS) (*Migrator, error) {
+	if db == nil {
+		return &Migrator{}, errors.New("db cannot be empty")
+	}
+	if dialect.Lock == nil || dialect.Unlock == nil || dialect.Placeholder == nil {
+		return &Migrator{}, errors.New("dialect cannot be empty")
+	}
+	if files == nil {
+		return &Migrator{}, errors.New("files cannot be empty")
+	}
+	migrations, err := Load(files)
+	if err != nil {
+		return &Migrator{}, err
+	}
+
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads the migrations in the sql directory of fsys, oldest first.
+// Every version needs both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+const createSchemaMigrations = `
+  CREATE TABLE IF NOT EXISTS schema_migrations(
+        version BIGINT PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+  `
+
+// Up applies every migration the database is missing, oldest first, and
+// returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.run(ctx, func(conn *sql.Conn, version int64) error {
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			claim := fmt.Sprintf("INSERT INTO schema_migrations(version, name) VALUES(%s, %s) ON CONFLICT (version) DO NOTHING",
+				m.dialect.Placeholder(1), m.dialect.Placeholder(2))
+			ok, err := m.step(ctx, conn, migration.Up, claim, migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if ok {
+				applied = append(applied, migration)
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, errors.New("steps must be positive")
+	}
+
+	var reverted []Migration
+	err := m.run(ctx, func(conn *sql.Conn, version int64) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			release := fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %s", m.dialect.Placeholder(1))
+			ok, err := m.step(ctx, conn, migration.Down, release, migration.Version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if ok {
+				reverted = append(reverted, migration)
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Version is the latest migration applied to the database, 0 for none.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := m.run(ctx, func(conn *sql.Conn, current int64) error {
+		version = current
+		return nil
+	})
+	return version, err
+}
+
+// run holds the lock of the dialect for the whole of fn, which gets the
+// latest version applied.
+func (m *Migrator) run(ctx context.Context, fn func(conn *sql.Conn, version int64) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to migrate: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.dialect.Lock(ctx, conn); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	// NOTE: the lock is released with a fresh context, so that a cancelled
+	// run does not keep it for the lifetime of the connection.
+	defer m.dialect.Unlock(context.Background(), conn)
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrations); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	var version int64
+	err = conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("failed to read the schema version: %w", err)
+	}
+	return fn(conn, version)
+}
+
+// step runs bookkeeping and then script in one transaction. It leaves
+// script out, and reports false, when bookkeeping finds another runner did
+// the step first.
+func (m *Migrator) step(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...interface{}) (bool, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, bookkeeping, args...)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
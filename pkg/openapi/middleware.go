@@ -0,0 +1,286 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const jsonContentType = "application/json"
+
+var ErrUnsupportedContentType = appErrors.New("unsupported_content_type", http.StatusUnsupportedMediaType, "unsupported content type")
+
+var missingPropertyPattern = regexp.MustCompile(`'([^']*)'`)
+
+// Handler serves the raw document, e.g. at /openapi.json.
+func Handler(spec *Spec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(spec.document); err != nil {
+			log.Printf("Error sending response: %v", err)
+		}
+	}
+}
+
+// RequestValidator rejects requests whose parameters or body do not match
+// the operation in spec with a 400 ErrorResponse, before any handler runs.
+// Requests for paths the spec does not describe are passed through, so the
+// router still answers them with 404 or 405.
+func RequestValidator(spec *Spec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, pathValues := spec.find(r.Method, r.URL.Path)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if message, ok := validateParameters(op, r, pathValues); !ok {
+				writeError(w, r, appErrors.BadRequest("invalid_request", message))
+				return
+			}
+
+			if op.requestBody != nil {
+				var body []byte
+				if r.Body != nil {
+					var err error
+					body, err = io.ReadAll(r.Body)
+					if err != nil {
+						writeError(w, r, appErrors.ErrMissingBody)
+						return
+					}
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				if err := validateBody(op.requestBody, r.Header.Get("Content-Type"), body); err != nil {
+					writeError(w, r, err)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ResponseValidator checks every response against spec and replaces the ones
+// that do not match it with a 500 ErrorResponse describing the mismatch. It
+// buffers whole responses, so it is meant for tests that keep the spec from
+// drifting, not for production. Streams and WebSocket upgrades are passed
+// through unchecked.
+func ResponseValidator(spec *Spec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, _ := spec.find(r.Method, r.URL.Path)
+			if op == nil || !op.answersWithJSON() || r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if err := validateResponse(op, recorder.statusCode, w.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+				log.Printf("%s %s: response does not match the OpenAPI spec: %v", r.Method, r.URL.Path, err)
+				writeError(w, r, appErrors.Internal("response_mismatch", fmt.Sprintf("response does not match the OpenAPI spec: %v", err)))
+				return
+			}
+			w.WriteHeader(recorder.statusCode)
+			if _, err := w.Write(recorder.body.Bytes()); err != nil {
+				log.Printf("Error sending response: %v", err)
+			}
+		})
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func validateParameters(op *operation, r *http.Request, pathValues map[string]string) (string, bool) {
+	query := r.URL.Query()
+	for _, param := range op.parameters {
+		var value string
+		var present bool
+		switch param.in {
+		case "path":
+			value, present = pathValues[param.name]
+		case "query":
+			present = query.Has(param.name)
+			value = query.Get(param.name)
+		case "header":
+			value = r.Header.Get(param.name)
+			present = value != ""
+		default:
+			continue
+		}
+		if !present {
+			if param.required {
+				return param.name + " required", false
+			}
+			continue
+		}
+		if param.schema == nil {
+			continue
+		}
+		if err := validateParameterValue(param.schema, value); err != nil {
+			return describe(param.name, err), false
+		}
+	}
+	return "", true
+}
+
+// validateParameterValue accepts value either as a string or, for
+// parameters typed as booleans or numbers, as the JSON literal it spells.
+func validateParameterValue(schema *jsonschema.Schema, value string) error {
+	err := schema.Validate(value)
+	if err == nil {
+		return nil
+	}
+	if literal, literalErr := decodeJSON(strings.NewReader(value)); literalErr == nil {
+		if _, isString := literal.(string); !isString && schema.Validate(literal) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func validateBody(body *requestBody, contentType string, payload []byte) *appErrors.Error {
+	if len(bytes.TrimSpace(payload)) == 0 {
+		if body.required {
+			return appErrors.ErrMissingBody
+		}
+		return nil
+	}
+
+	schema, ok := body.content[mediaType(contentType)]
+	if !ok {
+		return ErrUnsupportedContentType
+	}
+	instance, err := decodeJSON(bytes.NewReader(payload))
+	if err != nil {
+		return appErrors.ErrInvalidJson
+	}
+	if schema == nil {
+		return nil
+	}
+	if err := schema.Validate(instance); err != nil {
+		return appErrors.BadRequest("invalid_request", describe("", err))
+	}
+	return nil
+}
+
+func validateResponse(op *operation, statusCode int, contentType string, payload []byte) error {
+	content, ok := op.responses[strconv.Itoa(statusCode)]
+	if !ok {
+		content, ok = op.responses[strconv.Itoa(statusCode/100)+"XX"]
+	}
+	if !ok {
+		content, ok = op.responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("status %d is not documented", statusCode)
+	}
+	if len(content) == 0 {
+		if len(payload) != 0 {
+			return fmt.Errorf("status %d is documented without a body", statusCode)
+		}
+		return nil
+	}
+
+	// NOTE: a few handlers leave the content type to net/http sniffing; their
+	// bodies are still expected to be JSON.
+	responseType := mediaType(contentType)
+	if responseType == "" {
+		responseType = jsonContentType
+	}
+	schema, ok := content[responseType]
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", responseType, statusCode)
+	}
+	if schema == nil {
+		return nil
+	}
+	instance, err := decodeJSON(bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	if err := schema.Validate(instance); err != nil {
+		return errors.New(describe("", err))
+	}
+	return nil
+}
+
+// describe turns the first leaf of a schema validation error into a short
+// message, e.g. "email required" or "text: length must be >= 1, but got 0".
+func describe(name string, err error) string {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err.Error()
+	}
+	leaf := validationErr
+	for len(leaf.Causes) > 0 {
+		leaf = leaf.Causes[0]
+	}
+
+	location := strings.TrimPrefix(name+leaf.InstanceLocation, "/")
+	if strings.HasSuffix(leaf.KeywordLocation, "/required") {
+		if missing := missingPropertyPattern.FindStringSubmatch(leaf.Message); missing != nil {
+			return strings.TrimPrefix(location+"/"+missing[1], "/") + " required"
+		}
+	}
+	if location == "" {
+		return leaf.Message
+	}
+	return location + ": " + leaf.Message
+}
+
+// decodeJSON decodes a single JSON value with numbers kept as json.Number,
+// which is what the schema validator expects.
+func decodeJSON(r io.Reader) (interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func mediaType(contentType string) string {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	return parsed
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err *appErrors.Error) {
+	w.Header().Set("Content-Type", jsonContentType)
+	response.Error(w, r, err)
+}
@@ -0,0 +1,363 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// documentURL is the name the document is registered under with the schema
+// compiler, so that "#/components/..." references resolve inside it.
+const documentURL = "openapi.json"
+
+var (
+	ErrUnsupportedVersion = errors.New("only OpenAPI 3.1 documents are supported")
+	ErrUnresolvedRef      = errors.New("unresolved $ref")
+)
+
+var methods = []string{
+	http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+	http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace,
+}
+
+// Spec is a loaded OpenAPI 3.1 document with the JSON schemas of every
+// operation compiled up front.
+type Spec struct {
+	document   []byte
+	operations []*operation
+}
+
+type operation struct {
+	method      string
+	path        string
+	segments    []string
+	parameters  []parameter
+	requestBody *requestBody
+	// responses maps a status code ("200", "2XX" or "default") to the schema
+	// of each media type it can be returned as. Media types without a schema
+	// map to nil.
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	schema   *jsonschema.Schema
+}
+
+type requestBody struct {
+	required bool
+	content  map[string]*jsonschema.Schema
+}
+
+type parameterObject struct {
+	Name     string          `json:"name"`
+	In       string          `json:"in"`
+	Required bool            `json:"required"`
+	Schema   json.RawMessage `json:"schema"`
+}
+
+type requestBodyObject struct {
+	Required bool                       `json:"required"`
+	Content  map[string]json.RawMessage `json:"content"`
+}
+
+type responseObject struct {
+	Content map[string]json.RawMessage `json:"content"`
+}
+
+type operationObject struct {
+	Parameters  []json.RawMessage          `json:"parameters"`
+	RequestBody json.RawMessage            `json:"requestBody"`
+	Responses   map[string]json.RawMessage `json:"responses"`
+}
+
+// loader compiles the schemas of one document. Every object is addressed by
+// its JSON pointer so that schemas are compiled in place and their $refs
+// resolve against the whole document.
+type loader struct {
+	compiler *jsonschema.Compiler
+	root     interface{}
+}
+
+// Load parses an OpenAPI 3.1 document and compiles the schemas of its
+// parameters, request bodies and responses.
+func Load(document []byte) (*Spec, error) {
+	var root struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(root.OpenAPI, "3.1") {
+		return nil, ErrUnsupportedVersion
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(documentURL, bytes.NewReader(document)); err != nil {
+		return nil, err
+	}
+	l := &loader{compiler: compiler}
+	if err := json.Unmarshal(document, &l.root); err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{document: document}
+	for path, pathItem := range root.Paths {
+		pathPointer := "#/paths/" + escapePointer(path)
+		var shared []json.RawMessage
+		if raw, ok := pathItem["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		for _, method := range methods {
+			raw, ok := pathItem[strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+			op, err := l.operation(method, path, pathPointer, shared, raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			spec.operations = append(spec.operations, op)
+		}
+	}
+	// NOTE: literal segments win over templated ones, like in chi, so
+	// /todos/bulk is matched before /todos/{id}.
+	sort.SliceStable(spec.operations, func(i, j int) bool {
+		return literalSegments(spec.operations[i].segments) > literalSegments(spec.operations[j].segments)
+	})
+	return spec, nil
+}
+
+// MustLoad is like Load but panics if the document is invalid. It is meant
+// for documents embedded in the binary.
+func MustLoad(document []byte) *Spec {
+	spec, err := Load(document)
+	if err != nil {
+		panic(err)
+	}
+	return spec
+}
+
+// Document returns the raw OpenAPI document.
+func (s *Spec) Document() []byte {
+	return s.document
+}
+
+func (l *loader) operation(method, path, pathPointer string, shared []json.RawMessage, raw json.RawMessage) (*operation, error) {
+	var object operationObject
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, err
+	}
+	pointer := pathPointer + "/" + strings.ToLower(method)
+
+	op := &operation{
+		method:    method,
+		path:      path,
+		segments:  splitPath(path),
+		responses: map[string]map[string]*jsonschema.Schema{},
+	}
+
+	for index, raw := range shared {
+		param, err := l.parameter(raw, fmt.Sprintf("%s/parameters/%d", pathPointer, index))
+		if err != nil {
+			return nil, err
+		}
+		op.parameters = append(op.parameters, param)
+	}
+	for index, raw := range object.Parameters {
+		param, err := l.parameter(raw, fmt.Sprintf("%s/parameters/%d", pointer, index))
+		if err != nil {
+			return nil, err
+		}
+		op.parameters = append(op.parameters, param)
+	}
+
+	if object.RequestBody != nil {
+		var body requestBodyObject
+		bodyPointer, err := l.resolve(object.RequestBody, pointer+"/requestBody", &body)
+		if err != nil {
+			return nil, err
+		}
+		content, err := l.content(body.Content, bodyPointer)
+		if err != nil {
+			return nil, err
+		}
+		op.requestBody = &requestBody{required: body.Required, content: content}
+	}
+
+	for status, raw := range object.Responses {
+		var response responseObject
+		responsePointer, err := l.resolve(raw, pointer+"/responses/"+escapePointer(status), &response)
+		if err != nil {
+			return nil, err
+		}
+		op.responses[status], err = l.content(response.Content, responsePointer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+func (l *loader) parameter(raw json.RawMessage, pointer string) (parameter, error) {
+	var object parameterObject
+	pointer, err := l.resolve(raw, pointer, &object)
+	if err != nil {
+		return parameter{}, err
+	}
+	param := parameter{name: object.Name, in: object.In, required: object.Required}
+	if object.Schema == nil {
+		return param, nil
+	}
+	param.schema, err = l.compiler.Compile(documentURL + pointer + "/schema")
+	if err != nil {
+		return parameter{}, fmt.Errorf("parameter %s: %w", object.Name, err)
+	}
+	return param, nil
+}
+
+func (l *loader) content(content map[string]json.RawMessage, pointer string) (map[string]*jsonschema.Schema, error) {
+	schemas := map[string]*jsonschema.Schema{}
+	for mediaType, raw := range content {
+		var mediaTypeObject struct {
+			Schema json.RawMessage `json:"schema"`
+		}
+		if err := json.Unmarshal(raw, &mediaTypeObject); err != nil {
+			return nil, err
+		}
+		schemas[mediaType] = nil
+		if mediaTypeObject.Schema == nil {
+			continue
+		}
+		schema, err := l.compiler.Compile(documentURL + pointer + "/content/" + escapePointer(mediaType) + "/schema")
+		if err != nil {
+			return nil, err
+		}
+		schemas[mediaType] = schema
+	}
+	return schemas, nil
+}
+
+// resolve decodes the object at pointer into v, following a local $ref if
+// the object is a reference, and returns the pointer of the object decoded.
+func (l *loader) resolve(raw json.RawMessage, pointer string, v interface{}) (string, error) {
+	var reference struct {
+		Ref string `json:"$ref"`
+	}
+	if err := json.Unmarshal(raw, &reference); err != nil {
+		return "", err
+	}
+	if reference.Ref == "" {
+		return pointer, json.Unmarshal(raw, v)
+	}
+
+	target, ok := l.lookup(reference.Ref)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnresolvedRef, reference.Ref)
+	}
+	resolved, err := json.Marshal(target)
+	if err != nil {
+		return "", err
+	}
+	return reference.Ref, json.Unmarshal(resolved, v)
+}
+
+func (l *loader) lookup(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	node := l.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		node, ok = object[strings.NewReplacer("~1", "/", "~0", "~").Replace(token)]
+		if !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// answersWithJSON reports whether the operation succeeds with a JSON body,
+// as opposed to streams and protocol upgrades, which cannot be buffered.
+func (op *operation) answersWithJSON() bool {
+	for status, content := range op.responses {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		if _, ok := content[jsonContentType]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the operation serving method and path, along with the values
+// of its path parameters.
+func (s *Spec) find(method, path string) (*operation, map[string]string) {
+	segments := splitPath(path)
+	for _, op := range s.operations {
+		if op.method != method || len(op.segments) != len(segments) {
+			continue
+		}
+		values, ok := matchSegments(op.segments, segments)
+		if ok {
+			return op, values
+		}
+	}
+	return nil, nil
+}
+
+func matchSegments(template, segments []string) (map[string]string, bool) {
+	values := map[string]string{}
+	for index, segment := range template {
+		if isTemplated(segment) {
+			if segments[index] == "" {
+				return nil, false
+			}
+			values[strings.Trim(segment, "{}")] = segments[index]
+			continue
+		}
+		if segment != segments[index] {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isTemplated(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func literalSegments(segments []string) int {
+	count := 0
+	for _, segment := range segments {
+		if !isTemplated(segment) {
+			count++
+		}
+	}
+	return count
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
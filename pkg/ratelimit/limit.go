@@ -0,0 +1,192 @@
+// Package ratelimit limits how often a client may call a route, with one
+// token bucket per route and identity kept in a pluggable Store.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPolicy = errors.New("invalid rate limit policy")
+
+// Limit refills Requests tokens evenly over Period, holding at most Burst of
+// them. A request takes one token.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// ParseLimit parses a limit written as "<requests>/<period>", e.g. "10/1m",
+// optionally followed by ":<burst>". Burst defaults to requests.
+func ParseLimit(value string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(value, ":")
+	requests, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: limit %q is not <requests>/<period>", ErrInvalidPolicy, value)
+	}
+
+	var limit Limit
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("%w: limit %q needs a positive number of requests", ErrInvalidPolicy, value)
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period < time.Millisecond {
+		return Limit{}, fmt.Errorf("%w: limit %q needs a period of at least 1ms", ErrInvalidPolicy, value)
+	}
+	limit.Burst = limit.Requests
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("%w: limit %q needs a positive burst", ErrInvalidPolicy, value)
+		}
+	}
+	return limit, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s:%d", l.Requests, l.Period, l.Burst)
+}
+
+func (l Limit) enabled() bool {
+	return l.Requests > 0 && l.Period >= time.Millisecond && l.Burst > 0
+}
+
+// perMillisecond is the number of tokens the bucket gains every millisecond.
+func (l Limit) perMillisecond() float64 {
+	return float64(l.Requests) / float64(l.Period.Milliseconds())
+}
+
+// Result is the state of a bucket after a request tried to take a token.
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is how long the bucket takes to fill up again.
+	Reset time.Duration
+	// RetryAfter is how long a denied request has to wait for a token.
+	RetryAfter time.Duration
+}
+
+// refill returns the tokens of a bucket that held tokens at updatedAt, once
+// refilled up to now.
+func refill(limit Limit, tokens float64, updatedAt, now time.Time) float64 {
+	if elapsed := now.Sub(updatedAt).Milliseconds(); elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+float64(elapsed)*limit.perMillisecond())
+	}
+	return tokens
+}
+
+// take refills a bucket holding tokens at updatedAt up to now and takes one
+// token from it. It returns the result and the tokens left; every Store keeps
+// its buckets with the same arithmetic.
+func take(limit Limit, tokens float64, updatedAt, now time.Time) (Result, float64) {
+	tokens = refill(limit, tokens, updatedAt, now)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return newResult(limit, tokens, allowed), tokens
+}
+
+// newResult describes a bucket left with tokens after a request took one,
+// or was denied one.
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.perMillisecond()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(tokens),
+		Reset:     millis((float64(limit.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = millis((1 - tokens) / rate)
+	}
+	return result
+}
+
+func millis(value float64) time.Duration {
+	return time.Duration(math.Ceil(value)) * time.Millisecond
+}
+
+// Rule limits the requests made to one route. Either limit may be left zero
+// to skip it.
+type Rule struct {
+	// Route is "<METHOD> <pattern>" as registered in the router without its
+	// version prefix, e.g. "POST /users/login", or "*" for every route without
+	// a rule of its own.
+	Route string
+	// IP limits every request by client IP, before it is authenticated.
+	IP Limit
+	// User limits the requests of an authenticated user.
+	User Limit
+}
+
+// Policy is the set of rules of one service.
+type Policy []Rule
+
+// ParsePolicy parses rules separated by ";", each a route followed by
+// "ip=<limit>" and/or "user=<limit>", e.g.
+//
+//	POST /users/login ip=5/1m;* ip=600/1m user=300/1m
+func ParsePolicy(value string) (Policy, error) {
+	var policy Policy
+	for _, entry := range strings.Split(value, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		rule := Rule{Route: fields[0]}
+		fields = fields[1:]
+		if rule.Route != "*" {
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("%w: rule %q has no route pattern", ErrInvalidPolicy, entry)
+			}
+			rule.Route = strings.ToUpper(rule.Route) + " " + fields[0]
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: rule %q has no limits", ErrInvalidPolicy, entry)
+		}
+
+		for _, field := range fields {
+			identity, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("%w: %q is not <identity>=<limit>", ErrInvalidPolicy, field)
+			}
+			limit, err := ParseLimit(value)
+			if err != nil {
+				return nil, err
+			}
+			switch identity {
+			case "ip":
+				rule.IP = limit
+			case "user":
+				rule.User = limit
+			default:
+				return nil, fmt.Errorf("%w: unknown identity %q", ErrInvalidPolicy, identity)
+			}
+		}
+		policy = append(policy, rule)
+	}
+	return policy, nil
+}
+
+// rule returns the rule of route, falling back to the "*" rule.
+func (p Policy) rule(route string) (Rule, bool) {
+	var fallback Rule
+	var hasFallback bool
+	for _, rule := range p {
+		if rule.Route == route {
+			return rule, true
+		}
+		if rule.Route == "*" {
+			fallback, hasFallback = rule, true
+		}
+	}
+	return fallback, hasFallback
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in the memory of a single instance; instances
+// behind the same proxy need a shared store such as RedisStore.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// NOTE: full buckets are dropped every sweepInterval, otherwise every
+	// client ever seen would be kept forever.
+	sweepInterval time.Duration
+	lastSweep     time.Time
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     Limit
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:       map[string]*bucket{},
+		sweepInterval: time.Minute,
+		lastSweep:     time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	result, tokens := take(limit, b.tokens, b.updatedAt, now)
+	b.tokens, b.updatedAt, b.limit = tokens, now, limit
+	return result, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if refill(b.limit, b.tokens, b.updatedAt, now) >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
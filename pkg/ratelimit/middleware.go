@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+)
+
+const (
+	LimitHeader      = "RateLimit-Limit"
+	RemainingHeader  = "RateLimit-Remaining"
+	ResetHeader      = "RateLimit-Reset"
+	PolicyHeader     = "RateLimit-Policy"
+	RetryAfterHeader = "Retry-After"
+
+	// RealIPHeader is set by the proxy to the address of the client.
+	RealIPHeader = "X-Real-IP"
+)
+
+var ErrTooManyRequests = appErrors.TooManyRequests("too_many_requests", "too many requests, retry later")
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+// IdentifyUser returns the id of the user making r, or "" when r is not
+// authenticated.
+type IdentifyUser func(r *http.Request) string
+
+type Limiter struct {
+	store        Store
+	policy       Policy
+	identifyUser IdentifyUser
+	// trustProxy makes the limiter take the client IP from RealIPHeader. It
+	// must only be set when every request goes through the proxy, otherwise
+	// clients could pick their own IP.
+	trustProxy bool
+}
+
+// NewLimiter enforces policy with buckets kept in store. identifyUser is only
+// called for routes with a user limit, after the IP limit let the request
+// through.
+func NewLimiter(store Store, policy Policy, identifyUser IdentifyUser, trustProxy bool) (*Limiter, error) {
+	if store == nil {
+		return nil, errors.New("store cannot be empty")
+	}
+	if identifyUser == nil {
+		return nil, errors.New("identifyUser cannot be empty")
+	}
+	return &Limiter{store: store, policy: policy, identifyUser: identifyUser, trustProxy: trustProxy}, nil
+}
+
+// Middleware limits the requests to every route of routes with the rule of
+// that route. Routes are matched without their version prefix, so /v1/todos
+// and its unversioned alias share their buckets. Every limited response
+// carries the RateLimit headers of the bucket closest to running out, and a
+// denied request gets a 429 with Retry-After.
+func (l *Limiter) Middleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := l.route(routes, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rule, ok := l.policy.rule(route)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var results []Result
+			if rule.IP.enabled() {
+				result, ok := l.take(r, rule.Route+"|ip|"+l.clientIP(r), rule.IP)
+				if ok {
+					results = append(results, result)
+				}
+			}
+			if rule.User.enabled() && allowed(results) {
+				if userId := l.identifyUser(r); userId != "" {
+					result, ok := l.take(r, rule.Route+"|user|"+userId, rule.User)
+					if ok {
+						results = append(results, result)
+					}
+				}
+			}
+			if len(results) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result := binding(results)
+			setHeaders(w, result)
+			if !result.Allowed {
+				w.Header().Set(RetryAfterHeader, strconv.Itoa(seconds(result.RetryAfter)))
+				w.Header().Set("Content-Type", "application/json")
+				response.Error(w, r, ErrTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// take reports false when the store failed. The request is let through
+// then: an unavailable store should not take the service down with it.
+func (l *Limiter) take(r *http.Request, key string, limit Limit) (Result, bool) {
+	result, err := l.store.Take(r.Context(), key, limit)
+	if err != nil {
+		log.Printf("ratelimit: %v", err)
+		return Result{}, false
+	}
+	return result, true
+}
+
+func (l *Limiter) route(routes chi.Routes, r *http.Request) (string, bool) {
+	rctx := chi.NewRouteContext()
+	if !routes.Match(rctx, r.Method, r.URL.Path) {
+		return "", false
+	}
+	pattern := versionPrefix.ReplaceAllString(rctx.RoutePattern(), "/")
+	return r.Method + " " + pattern, true
+}
+
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.trustProxy {
+		if ip := strings.TrimSpace(r.Header.Get(RealIPHeader)); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func allowed(results []Result) bool {
+	for _, result := range results {
+		if !result.Allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// binding returns the denied result, if any, or the one with the fewest
+// tokens left.
+func binding(results []Result) Result {
+	closest := results[0]
+	for _, result := range results[1:] {
+		if !result.Allowed || (closest.Allowed && result.Remaining < closest.Remaining) {
+			closest = result
+		}
+	}
+	return closest
+}
+
+func setHeaders(w http.ResponseWriter, result Result) {
+	w.Header().Set(LimitHeader, strconv.Itoa(result.Limit.Burst))
+	w.Header().Set(RemainingHeader, strconv.Itoa(result.Remaining))
+	w.Header().Set(ResetHeader, strconv.Itoa(seconds(result.Reset)))
+	w.Header().Set(PolicyHeader, fmt.Sprintf("%d;w=%d;burst=%d",
+		result.Limit.Requests, seconds(result.Limit.Period), result.Limit.Burst))
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript is take, run atomically next to the bucket. The bucket is a
+// hash of its tokens and the time they were counted, in milliseconds, and
+// expires once it would be full again.
+//
+// KEYS[1]: bucket; ARGV: burst, tokens per millisecond, now in milliseconds.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated_at")
+local tokens = tonumber(bucket[1])
+local updated_at = tonumber(bucket[2])
+if tokens == nil or updated_at == nil then
+	tokens = burst
+	updated_at = now
+end
+if now > updated_at then
+	tokens = math.min(burst, tokens + (now - updated_at) * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated_at", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in Redis, or any server speaking its protocol, so
+// that every instance of a service shares them.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore keeps buckets under keys starting with prefix.
+func NewRedisStore(client redis.Scripter, prefix string) (*RedisStore, error) {
+	if client == nil {
+		return nil, errors.New("redis client cannot be empty")
+	}
+	if prefix == "" {
+		return nil, errors.New("prefix cannot be empty")
+	}
+	return &RedisStore{client: client, prefix: prefix}, nil
+}
+
+// NOTE: buckets are counted against the clock of the instance taking the
+// token, so instances sharing a store need their clocks in sync.
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := takeScript.Run(ctx, s.client, []string{s.prefix + ":" + key},
+		limit.Burst, strconv.FormatFloat(limit.perMillisecond(), 'f', -1, 64), time.Now().UnixMilli(),
+	).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take a token from %s: %w", key, err)
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply taking a token from %s: %v", key, reply)
+	}
+	allowed, _ := reply[0].(int64)
+	tokensReply, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensReply, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected reply taking a token from %s: %w", key, err)
+	}
+
+	return newResult(limit, tokens, allowed == 1), nil
+}
@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Store interface {
+	// Take takes a token from the bucket of key, which holds limit, and
+	// reports whether there was one.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewStore returns a RedisStore keeping buckets under prefix on the server at
+// redisAddress, or a MemoryStore when redisAddress is empty.
+func NewStore(redisAddress, prefix string) (Store, error) {
+	if redisAddress == "" {
+		return NewMemoryStore(), nil
+	}
+	return NewRedisStore(redis.NewClient(&redis.Options{Addr: redisAddress}), prefix)
+}
@@ -12,7 +12,9 @@ import (
 )
 
 func ExecuteRequest(req *http.Request, s *server.Server) *httptest.ResponseRecorder {
-	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	rr := httptest.NewRecorder()
 	s.Router.ServeHTTP(rr, req)
@@ -0,0 +1,26 @@
+package tests
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufconnSize = 1024 * 1024
+
+// NewBufconnClientConn serves grpcServer on an in-memory listener and
+// returns a client connection to it, so gRPC tests need no free port.
+func NewBufconnClientConn(grpcServer *grpc.Server) (*grpc.ClientConn, error) {
+	listener := bufconn.Listen(bufconnSize)
+	go grpcServer.Serve(listener)
+
+	return grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+}
@@ -3,9 +3,15 @@ package utils
 import (
 	"encoding/json"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 )
 
+const ProblemContentType = "application/problem+json"
+
 func SuccessResponse(w http.ResponseWriter, message string, data interface{}) {
 	type SuccessResponse struct {
 		Status  string      `json:"status"`
@@ -32,3 +38,72 @@ func ErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 		log.Printf("Error sending response: %v", err)
 	}
 }
+
+// ErrorResponseWithCode adds code to the error response, for errors clients
+// tell apart by more than their status.
+func ErrorResponseWithCode(w http.ResponseWriter, message, code string, statusCode int) {
+	type ErrorResponse struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	}
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Status: "error", Message: message, Code: code}); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
+
+// Error answers r with err, mapped to its status and safe message by
+// appErrors.From: as an RFC 7807 problem when r accepts
+// application/problem+json, and as an ErrorResponse with its code otherwise.
+// The cause of an internal error is logged rather than sent.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := appErrors.From(err)
+	if appErr.Status >= http.StatusInternalServerError && appErr.Err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, appErr.Err)
+	}
+	w.Header().Add("Vary", "Accept")
+	if !acceptsProblem(r) {
+		ErrorResponseWithCode(w, appErr.Message, appErr.Code, appErr.Status)
+		return
+	}
+
+	type Problem struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+		Code     string `json:"code"`
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(appErr.Status)
+	if err := json.NewEncoder(w).Encode(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(appErr.Status),
+		Status:   appErr.Status,
+		Detail:   appErr.Message,
+		Instance: r.URL.Path,
+		Code:     appErr.Code,
+	}); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
+
+// acceptsProblem reports whether the Accept header of r lists
+// application/problem+json without ruling it out with q=0.
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || mediaType != ProblemContentType {
+				continue
+			}
+			if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+// Package versioning holds the helpers both services use to serve several
+// versions of their HTTP API side by side.
+package versioning
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	DeprecationHeader = "Deprecation"
+	SunsetHeader      = "Sunset"
+	LinkHeader        = "Link"
+)
+
+// Deprecation describes when a set of routes was deprecated, when it stops
+// being served and which versioned prefix replaces it.
+type Deprecation struct {
+	DeprecatedAt time.Time
+	Sunset       time.Time
+	// Successor is the prefix of the routes that replace the deprecated ones,
+	// e.g. "/v1".
+	Successor string
+}
+
+// Deprecated marks every response with the Deprecation (RFC 9745) and Sunset
+// (RFC 8594) headers of deprecation, and links to the successor route.
+func Deprecated(deprecation Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(DeprecationHeader, fmt.Sprintf("@%d", deprecation.DeprecatedAt.Unix()))
+			w.Header().Set(SunsetHeader, deprecation.Sunset.UTC().Format(http.TimeFormat))
+			if deprecation.Successor != "" {
+				w.Header().Add(LinkHeader, fmt.Sprintf(`<%s%s>; rel="successor-version"`, deprecation.Successor, r.URL.Path))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package versioning
+
+import "time"
+
+// Unversioned is the deprecation of the routes both services served before
+// they were versioned; they stay available as aliases of /v1 until Sunset.
+var Unversioned = Deprecation{
+	DeprecatedAt: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+	Sunset:       time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+	Successor:    "/v1",
+}
@@ -39,7 +39,7 @@ func main() {
 }
 
 func createUser() (string, string) {
-	route := proxyBaseURL + "/users"
+	route := proxyBaseURL + "/v1/users"
 	email := faker.New().Internet().Email()
 	first_name := faker.New().Person().FirstName()
 	last_name := faker.New().Person().LastName()
@@ -57,7 +57,7 @@ func createUser() (string, string) {
 }
 
 func logInUser(email, password string) string {
-	route := proxyBaseURL + "/users/login"
+	route := proxyBaseURL + "/v1/users/login"
 	requestBody := []byte(fmt.Sprintf(`{
       "email": "%s",
       "password": "%s"
@@ -78,7 +78,7 @@ func logInUser(email, password string) string {
 }
 
 func createTodo(accessToken, text string) string {
-	route := proxyBaseURL + "/todos"
+	route := proxyBaseURL + "/v1/todos"
 	requestBody := []byte(fmt.Sprintf(`{
       "text": "%s"
       }`, text))
@@ -98,7 +98,7 @@ func createTodo(accessToken, text string) string {
 }
 
 func getTodo(accessToken, todoId string) {
-	route := proxyBaseURL + "/todos" + "/" + todoId
+	route := proxyBaseURL + "/v1/todos" + "/" + todoId
 	req, _ := http.NewRequest(http.MethodGet, route, nil)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	res := newMakeRequest(req)
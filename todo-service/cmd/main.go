@@ -2,25 +2,125 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/exaring/otelpgx"
+	"github.com/google/uuid"
 	"github.com/olad5/productive-pulse/config"
 	"github.com/olad5/productive-pulse/pkg/app/server"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
 	"github.com/olad5/productive-pulse/pkg/utils"
 	"github.com/olad5/productive-pulse/todo-service/internal/app/router"
+	"github.com/olad5/productive-pulse/todo-service/internal/app/rpc"
+	"github.com/olad5/productive-pulse/todo-service/internal/collab"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/olad5/productive-pulse/todo-service/internal/graph"
 	"github.com/olad5/productive-pulse/todo-service/internal/handlers"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra/memory"
 	"github.com/olad5/productive-pulse/todo-service/internal/infra/mongo"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra/postgres"
 
 	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
 	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/webhooks"
+	"github.com/olad5/productive-pulse/todo-service/internal/workers"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/event"
 	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
+const (
+	unsnoozeWorkerInterval = 30 * time.Second
+	outboxRelayInterval    = 500 * time.Millisecond
+	outboxMaxAttempts      = 100
+	eventStreamMaxLength   = 100000
+	defaultEventStream     = "todo-events"
+	webhookWorkerInterval  = time.Second
+	idempotencyKeyTTL      = 24 * time.Hour
+	idempotencyKeyLease    = time.Minute
+	webhookDeliveryTimeout = 10 * time.Second
+	todoEventLogSize       = 1000
+	shutdownTimeout        = 15 * time.Second
+	jwksRefreshInterval    = 5 * time.Minute
+	jwksRequestTimeout     = 5 * time.Second
+)
+
+// todoStore is what a storage backend provides besides webhooks.
+type todoStore interface {
+	infra.TodoRepository
+	infra.OutboxRepository
+	infra.UsageRepository
+	infra.ProjectRepository
+}
+
+func newRepositories(ctx context.Context, configurations *config.Configurations, mongoMonitor *event.CommandMonitor) (todoStore, infra.WebhookRepository, error) {
+	switch configurations.TodoServiceStorage {
+	case "", "mongo":
+		mongoConfig := mongo.NewMongoConfig(configurations)
+		todoRepo, err := mongo.NewMongoRepo(ctx, mongoMonitor, mongoConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		webhookRepo, err := mongo.NewMongoWebhookRepo(ctx, mongoMonitor, mongoConfig)
+		if err != nil {
+			todoRepo.Close(ctx)
+			return nil, nil, err
+		}
+		return todoRepo, webhookRepo, nil
+	case "postgres":
+		postgresTracer := otelpgx.NewTracer()
+		todoRepo, err := postgres.NewPostgresRepo(ctx, postgresTracer, configurations.TodoServiceDBUrl)
+		if err != nil {
+			return nil, nil, err
+		}
+		webhookRepo, err := postgres.NewPostgresWebhookRepo(ctx, postgresTracer, configurations.TodoServiceDBUrl)
+		if err != nil {
+			todoRepo.Close(ctx)
+			return nil, nil, err
+		}
+		return todoRepo, webhookRepo, nil
+	case "memory":
+		todoRepo := memory.NewMemoryRepo()
+		webhookRepo, err := memory.NewMemoryWebhookRepo(todoRepo)
+		if err != nil {
+			return nil, nil, err
+		}
+		return todoRepo, webhookRepo, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown todo service storage %q", configurations.TodoServiceStorage)
+	}
+}
+
 func main() {
 	configurations := config.GetConfig(".env")
-	ctx := context.Background()
+	flag.StringVar(&configurations.TodoServiceStorage, "storage", configurations.TodoServiceStorage,
+		`where to keep todos: "mongo", "postgres" or "memory", which needs no database`)
+	migrateOnStart := flag.Bool("migrate", true,
+		"apply pending migrations at startup; without it, run the migrate subcommand before deploying")
+	flag.Parse()
+	// NOTE: ctx is cancelled on SIGINT or SIGTERM, which stops the workers
+	// and starts the shutdown of the servers.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	tracerProvider, err := utils.NewTracerProvider(configurations.TodoServiceName, configurations.TracingCollectorEndpoint)
 	if err != nil {
@@ -30,9 +130,21 @@ func main() {
 	mongoMonitor := otelmongo.NewMonitor(otelmongo.WithTracerProvider(tracerProvider))
 	tracer := tracerProvider.Tracer(configurations.TodoServiceName)
 
-	todoRepo, err := mongo.NewMongoRepo(ctx, mongoMonitor, configurations.TodoServiceDBConnectionString)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(ctx, configurations, mongoMonitor, flag.Args()[1:]); err != nil {
+			log.Fatal("Failed to migrate: ", err)
+		}
+		return
+	}
+	if *migrateOnStart && configurations.TodoServiceStorage != "memory" {
+		if err := migrate(ctx, configurations, mongoMonitor); err != nil {
+			log.Fatal("Failed to migrate: ", err)
+		}
+	}
+
+	todoRepo, webhookRepo, err := newRepositories(ctx, configurations, mongoMonitor)
 	if err != nil {
-		log.Fatal("Error Initializing todo Repo")
+		log.Fatal("Error Initializing Repos", err)
 	}
 
 	err = todoRepo.Ping(ctx)
@@ -40,28 +152,285 @@ func main() {
 		log.Fatal("Failed to ping todoRepo", err)
 	}
 
-	todoService, err := todos.NewTodoService(todoRepo, configurations)
+	quotaService, err := quotas.NewQuotaService(todoRepo, quotas.DefaultPlans)
+	if err != nil {
+		log.Fatal("Error Initializing QuotaService", err)
+	}
+
+	projectService, err := projects.NewProjectService(todoRepo)
+	if err != nil {
+		log.Fatal("Error Initializing ProjectService")
+	}
+
+	webhookClient := webhooks.NewDeliveryClient(webhookDeliveryTimeout, webhooks.PublicAddresses)
+	webhookClient.Transport = otelhttp.NewTransport(webhookClient.Transport)
+	webhookService, err := webhooks.NewWebhookService(webhookRepo, quotaService, webhookClient, webhooks.PublicAddresses, tracer, webhooks.DefaultRetryPolicy)
+	if err != nil {
+		log.Fatal("Error Initializing WebhookService")
+	}
+
+	eventBus := events.NewBus()
+	eventBus.Subscribe(webhookService.HandleEvent)
+
+	streamHub := events.NewStreamHub(todoEventLogSize)
+	eventBus.Subscribe(streamHub.HandleEvent)
+
+	collabHub, err := collab.NewHub(collab.NewInMemoryPubSub(), func(ctx context.Context, userId uuid.UUID, projectId string) (bool, error) {
+		return projectService.CanAccessProject(ctx, tracer, userId.String(), projectId)
+	})
+	if err != nil {
+		log.Fatal("Error Initializing Collaboration Hub")
+	}
+	eventBus.Subscribe(collabHub.HandleEvent)
+
+	// NOTE: running tracks the workers and servers, which use the
+	// repositories until they return.
+	var running sync.WaitGroup
+
+	sinks := []events.Publisher{eventBus}
+	var eventsClient *redis.Client
+	if eventsRedisAddress := configurations.TodoServiceEventsRedisAddress; eventsRedisAddress != "" {
+		stream := configurations.TodoServiceEventsStream
+		if stream == "" {
+			stream = defaultEventStream
+		}
+		eventsClient = redis.NewClient(&redis.Options{Addr: eventsRedisAddress})
+		broker, err := events.NewRedisStreamBroker(eventsClient, eventStreamMaxLength)
+		if err != nil {
+			log.Fatal("Error Initializing Event Broker", err)
+		}
+		brokerSink, err := events.NewBrokerSink(broker, stream)
+		if err != nil {
+			log.Fatal("Error Initializing Event Broker", err)
+		}
+		sinks = append(sinks, brokerSink)
+	}
+
+	// NOTE: nothing claims the events of the outbox, so a second relay would
+	// send them twice and out of order; only one instance may run it.
+	if configurations.TodoServiceOutboxRelay {
+		outboxRelay, err := workers.NewOutboxRelay(todoRepo, sinks, tracer, outboxRelayInterval, outboxMaxAttempts)
+		if err != nil {
+			log.Fatal("Error Initializing OutboxRelay")
+		}
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			outboxRelay.Run(ctx)
+		}()
+	}
+
+	webhookDeliveryWorker, err := workers.NewWebhookDeliveryWorker(webhookService, webhookWorkerInterval)
+	if err != nil {
+		log.Fatal("Error Initializing WebhookDeliveryWorker")
+	}
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		webhookDeliveryWorker.Run(ctx)
+	}()
+
+	todoService, err := todos.NewTodoService(todoRepo, todoRepo, quotaService, projectService, configurations)
 	if err != nil {
 		log.Fatal("Error Initializing TodoService")
 	}
 
-	userServiceClient := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
-	userService, err := user.NewUserService(userServiceClient, configurations.ProxyBaseUrl)
+	unsnoozeWorker, err := workers.NewUnsnoozeWorker(*todoService, tracer, unsnoozeWorkerInterval)
+	if err != nil {
+		log.Fatal("Error Initializing UnsnoozeWorker")
+	}
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		unsnoozeWorker.Run(ctx)
+	}()
+
+	var userService user.UserServiceAdapter
+	if address := configurations.UserServiceGrpcAddress; address != "" {
+		userServiceConn, err := grpc.Dial(address,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		)
+		if err != nil {
+			log.Fatal("Error connecting to the UserService gRPC API", err)
+		}
+		defer userServiceConn.Close()
+		userService, err = user.NewUserGrpcService(userServiceConn)
+		if err != nil {
+			log.Fatal("Error Initializing UserService")
+		}
+	} else {
+		userServiceClient := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+		userService, err = user.NewUserService(userServiceClient, configurations.ProxyBaseUrl)
+		if err != nil {
+			log.Fatal("Error Initializing UserService")
+		}
+	}
+	resilientUserService, err := user.NewResilientUserService(userService, otel.Meter(configurations.TodoServiceName), user.DefaultResiliencePolicy)
+	if err != nil {
+		log.Fatal("Error Initializing UserService")
+	}
+	userService = resilientUserService
+	if jwksUrl := configurations.UserServiceJWKSUrl; jwksUrl != "" {
+		refreshInterval := configurations.UserServiceJWKSRefreshInterval
+		if refreshInterval <= 0 {
+			refreshInterval = jwksRefreshInterval
+		}
+		jwksClient := &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   jwksRequestTimeout,
+		}
+		localUserService, err := user.NewLocalUserService(jwksClient, jwksUrl, userService, refreshInterval)
+		if err != nil {
+			log.Fatal("Error Initializing UserService")
+		}
+		// NOTE: until the keys are read, every token is verified by
+		// users-service.
+		if err := localUserService.Refresh(ctx); err != nil {
+			log.Print("Failed to read the keys of UserService: ", err)
+		}
+		go localUserService.Run(ctx)
+		userService = localUserService
+	}
+	requestScopedUserService, err := user.NewRequestScopedUserService(userService)
 	if err != nil {
 		log.Fatal("Error Initializing UserService")
 	}
+	userService = requestScopedUserService
 
 	todoHandler, err := handlers.NewTodoHandler(*todoService, userService, tracer)
 	if err != nil {
 		log.Fatal("failed to create the TodoHandler: ", err)
 	}
 
-	appRouter := router.NewHttpRouter(*todoHandler, configurations)
+	webhookHandler, err := handlers.NewWebhookHandler(webhookService, userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the WebhookHandler: ", err)
+	}
+
+	projectHandler, err := handlers.NewProjectHandler(projectService, userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the ProjectHandler: ", err)
+	}
+
+	usageHandler, err := handlers.NewUsageHandler(quotaService, userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the UsageHandler: ", err)
+	}
+
+	todoEventsHandler, err := handlers.NewTodoEventsHandler(streamHub, userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the TodoEventsHandler: ", err)
+	}
+
+	collaborationHandler, err := handlers.NewCollaborationHandler(collabHub, userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the CollaborationHandler: ", err)
+	}
+
+	graphQLServer, err := graph.NewServer(todoService, projectService, streamHub, tracer, graph.DefaultLimits)
+	if err != nil {
+		log.Fatal("Error Initializing GraphQL Server", err)
+	}
+	graphQLHandler, err := handlers.NewGraphQLHandler(graphQLServer, userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the GraphQLHandler: ", err)
+	}
+
+	var idempotencyStore idempotency.Store
+	switch configurations.TodoServiceStorage {
+	case "memory":
+		idempotencyStore = idempotency.NewMemoryStore(idempotencyKeyTTL, idempotencyKeyLease)
+	case "postgres":
+		idempotencyStore, err = idempotency.NewPostgresStore(ctx, otelpgx.NewTracer(), configurations.TodoServiceDBUrl, idempotencyKeyTTL, idempotencyKeyLease)
+	default:
+		idempotencyStore, err = idempotency.NewMongoStore(ctx, mongoMonitor, configurations.TodoServiceDBConnectionString, mongo.NewMongoConfig(configurations).Database(), idempotencyKeyTTL, idempotencyKeyLease)
+	}
+	if err != nil {
+		log.Fatal("Error Initializing Idempotency Store", err)
+	}
+
+	rateLimitPolicy, err := ratelimit.ParsePolicy(configurations.TodoServiceRateLimits)
+	if err != nil {
+		log.Fatal("Invalid rate limit policy: ", err)
+	}
+	rateLimitStore, err := ratelimit.NewStore(configurations.RateLimitRedisAddress, configurations.TodoServiceName)
+	if err != nil {
+		log.Fatal("Error Initializing Rate Limit Store", err)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(rateLimitStore, rateLimitPolicy, router.IdentifyUser(requestScopedUserService, tracer), configurations.RateLimitTrustProxy)
+	if err != nil {
+		log.Fatal("failed to create the rate limiter: ", err)
+	}
+
+	countAPICalls := router.CountAPICalls(quotaService, requestScopedUserService, tracer)
+
+	appRouter := router.NewHttpRouter(*todoHandler, *todoEventsHandler, *collaborationHandler, *graphQLHandler, *webhookHandler, *projectHandler, *usageHandler, idempotencyStore, rateLimiter, countAPICalls, configurations)
 
 	svr := server.CreateNewServer(appRouter)
 
+	var grpcServer *grpc.Server
+	if grpcPort := configurations.TodoServiceGrpcPort; grpcPort != "" {
+		todoServer, err := rpc.NewTodoServer(*todoService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the Todo gRPC server: ", err)
+		}
+		grpcServer, err = rpc.NewGrpcServer(todoServer, userService, quotaService)
+		if err != nil {
+			log.Fatal("failed to create the gRPC server: ", err)
+		}
+		listener, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Fatal("failed to listen for gRPC: ", err)
+		}
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			log.Printf("gRPC Server Listening on port %s", grpcPort)
+			// NOTE: Serve returns nil once GracefulStop is done.
+			if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				log.Fatal(err)
+			}
+		}()
+	}
+
 	port := configurations.TodoServicePort
+	httpServer := &http.Server{Addr: ":" + port, Handler: svr.Router}
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		log.Printf("Server Listening on port %s", port)
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
 
-	log.Printf("Server Listening on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, svr.Router))
+	<-ctx.Done()
+	stop()
+	log.Print("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Print("failed to shut the server down: ", err)
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	running.Wait()
+	if eventsClient != nil {
+		if err := eventsClient.Close(); err != nil {
+			log.Print("failed to close the event broker client: ", err)
+		}
+	}
+	if store, ok := idempotencyStore.(interface{ Close(context.Context) error }); ok {
+		if err := store.Close(shutdownCtx); err != nil {
+			log.Print("failed to close the idempotency store: ", err)
+		}
+	}
+	if err := webhookRepo.Close(shutdownCtx); err != nil {
+		log.Print("failed to close webhookRepo: ", err)
+	}
+	if err := todoRepo.Close(shutdownCtx); err != nil {
+		log.Print("failed to close todoRepo: ", err)
+	}
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/exaring/otelpgx"
+	"github.com/olad5/productive-pulse/config"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra/mongo"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra/postgres"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const migrateUsage = `usage: todo-service migrate`
+
+// migrate brings the schema of the storage up to date: the indexes and data
+// migrations of mongo, or the SQL migrations of postgres.
+func migrate(ctx context.Context, configurations *config.Configurations, mongoMonitor *event.CommandMonitor) error {
+	switch configurations.TodoServiceStorage {
+	case "", "mongo":
+		migrator, err := mongo.NewMigrator(ctx, mongoMonitor, mongo.NewMongoConfig(configurations))
+		if err != nil {
+			return err
+		}
+		defer migrator.Close(ctx)
+		applied, err := migrator.Run(ctx)
+		for _, id := range applied {
+			log.Printf("applied migration %s", id)
+		}
+		return err
+	case "postgres":
+		todoRepo, err := postgres.NewPostgresRepo(ctx, otelpgx.NewTracer(), configurations.TodoServiceDBUrl)
+		if err != nil {
+			return err
+		}
+		defer todoRepo.Close(ctx)
+		applied, err := todoRepo.Migrator().Up(ctx)
+		for _, migration := range applied {
+			log.Printf("applied migration %d_%s", migration.Version, migration.Name)
+		}
+		return err
+	default:
+		return fmt.Errorf("%q storage has no schema to migrate", configurations.TodoServiceStorage)
+	}
+}
+
+// runMigrate runs the migrate subcommand, with the arguments that follow it.
+func runMigrate(ctx context.Context, configurations *config.Configurations, mongoMonitor *event.CommandMonitor, args []string) error {
+	if len(args) > 0 {
+		return errors.New(migrateUsage)
+	}
+	if err := migrate(ctx, configurations, mongoMonitor); err != nil {
+		return err
+	}
+	log.Print("schema is up to date")
+	return nil
+}
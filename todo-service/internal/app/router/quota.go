@@ -0,0 +1,46 @@
+package router
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CountAPICalls counts every authenticated request against the API calls of
+// the user's plan, and refuses it with a 403 once they are used up. Requests
+// without a valid token are left to the handler, which refuses them.
+func CountAPICalls(quotaService *quotas.QuotaService, userService *user.RequestScopedUserService, tracer trace.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userId, err := userService.VerifyAndShare(r.Context(), tracer, authHeader)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err = quotaService.RecordAPICall(r.Context(), tracer, userId)
+			var quotaErr *quotas.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				w.Header().Set("Content-Type", "application/json")
+				response.Error(w, r, quotaErr)
+				return
+			}
+			// NOTE: as with rate limiting, a call that could not be counted is
+			// let through rather than failing the request.
+			if err != nil {
+				log.Printf("quotas: %v", err)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
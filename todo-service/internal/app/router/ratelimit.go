@@ -0,0 +1,26 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdentifyUser identifies the callers of the rate limited routes through
+// users-service. The verification is shared with the handler serving the
+// request, which does not verify the token again.
+func IdentifyUser(userService *user.RequestScopedUserService, tracer trace.Tracer) ratelimit.IdentifyUser {
+	return func(r *http.Request) string {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			return ""
+		}
+		userId, err := userService.VerifyAndShare(r.Context(), tracer, authHeader)
+		if err != nil {
+			return ""
+		}
+		return userId
+	}
+}
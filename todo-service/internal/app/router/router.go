@@ -8,20 +8,93 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/olad5/productive-pulse/config"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	"github.com/olad5/productive-pulse/pkg/openapi"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	"github.com/olad5/productive-pulse/pkg/versioning"
+	"github.com/olad5/productive-pulse/todo-service/api"
 	"github.com/olad5/productive-pulse/todo-service/internal/handlers"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
 )
 
-func NewHttpRouter(todoHandler handlers.TodoHandler, configurations *config.Configurations) http.Handler {
+func NewHttpRouter(todoHandler handlers.TodoHandler, todoEventsHandler handlers.TodoEventsHandler, collaborationHandler handlers.CollaborationHandler, graphQLHandler handlers.GraphQLHandler, webhookHandler handlers.WebhookHandler, projectHandler handlers.ProjectHandler, usageHandler handlers.UsageHandler, idempotencyStore idempotency.Store, rateLimiter *ratelimit.Limiter, countAPICalls func(http.Handler) http.Handler, configurations *config.Configurations) http.Handler {
 	router := chi.NewRouter()
-	router.Use(
-		middleware.AllowContentType("application/json"),
-		middleware.SetHeader("Content-Type", "application/json"),
-	)
+	router.Use(middleware.AllowContentType("application/json", utils.MergePatchContentType, utils.JSONPatchContentType))
 	router.Use(otelchi.Middleware(configurations.TodoServiceName, otelchi.WithChiRoutes(router)))
+	if configurations.OpenAPIValidateResponses {
+		router.Use(openapi.ResponseValidator(api.Spec))
+	}
+	router.Use(user.ShareVerifications)
+	router.Use(rateLimiter.Middleware(router))
+	router.Use(openapi.RequestValidator(api.Spec))
 
+	router.Get("/openapi.json", openapi.Handler(api.Spec))
+	router.Route("/v1", func(router chi.Router) {
+		registerV1Routes(router, todoHandler, todoEventsHandler, collaborationHandler, graphQLHandler, webhookHandler, projectHandler, usageHandler, idempotencyStore, countAPICalls)
+	})
+	router.Route("/v2", func(router chi.Router) {
+		registerV2Routes(router, todoHandler.V2(), idempotencyStore, countAPICalls)
+	})
+	router.Group(func(router chi.Router) {
+		router.Use(versioning.Deprecated(versioning.Unversioned))
+		registerV1Routes(router, todoHandler, todoEventsHandler, collaborationHandler, graphQLHandler, webhookHandler, projectHandler, usageHandler, idempotencyStore, countAPICalls)
+	})
+	return router
+}
+
+func registerV1Routes(router chi.Router, todoHandler handlers.TodoHandler, todoEventsHandler handlers.TodoEventsHandler, collaborationHandler handlers.CollaborationHandler, graphQLHandler handlers.GraphQLHandler, webhookHandler handlers.WebhookHandler, projectHandler handlers.ProjectHandler, usageHandler handlers.UsageHandler, idempotencyStore idempotency.Store, countAPICalls func(http.Handler) http.Handler) {
+	router.Group(func(router chi.Router) {
+		router.Use(countAPICalls)
+
+		// NOTE: the event stream, the WebSocket and GraphQL subscriptions set
+		// their own content types, so they are registered outside the group
+		// that defaults responses to JSON.
+		router.Get("/todos/events", todoEventsHandler.StreamTodoEvents)
+		router.Get("/todos/ws", collaborationHandler.Collaborate)
+		router.Post("/graphql", graphQLHandler.ServeGraphQL)
+
+		router.Group(func(router chi.Router) {
+			router.Use(middleware.SetHeader("Content-Type", "application/json"))
+			router.Use(idempotency.Middleware(idempotencyStore))
+			registerJSONRoutes(router, todoHandler, webhookHandler, projectHandler)
+		})
+	})
+
+	// NOTE: usage is not counted as an API call, so it keeps answering once
+	// the user ran out of them.
+	router.With(middleware.SetHeader("Content-Type", "application/json")).Get("/usage", usageHandler.GetUsage)
+}
+
+// registerV2Routes serves the todo routes whose response shape changed in
+// /v2; everything else is only served by /v1 for now.
+func registerV2Routes(router chi.Router, todoHandler handlers.TodoHandler, idempotencyStore idempotency.Store, countAPICalls func(http.Handler) http.Handler) {
+	router.Use(countAPICalls)
+	router.Use(middleware.SetHeader("Content-Type", "application/json"))
+	router.Use(idempotency.Middleware(idempotencyStore))
+	router.Get("/todos/{id}", todoHandler.GetTodo)
+	router.Get("/todos", todoHandler.GetTodos)
+	router.Post("/todos/{id}/snooze", todoHandler.SnoozeTodo)
+	router.Post("/todos", todoHandler.CreateTodo)
+}
+
+func registerJSONRoutes(router chi.Router, todoHandler handlers.TodoHandler, webhookHandler handlers.WebhookHandler, projectHandler handlers.ProjectHandler) {
 	router.Get("/todos/{id}", todoHandler.GetTodo)
 	router.Get("/todos", todoHandler.GetTodos)
 	router.Patch("/todos/{id}", todoHandler.UpdateTodo)
+	router.Post("/todos/bulk", todoHandler.BulkTodos)
+	router.Post("/todos/{id}/snooze", todoHandler.SnoozeTodo)
 	router.Post("/todos", todoHandler.CreateTodo)
-	return router
+	router.Post("/sync", todoHandler.SyncTodos)
+
+	router.Get("/webhooks", webhookHandler.GetWebhooks)
+	router.Post("/webhooks", webhookHandler.CreateWebhook)
+	router.Delete("/webhooks/{id}", webhookHandler.DeleteWebhook)
+	router.Post("/webhooks/{id}/enable", webhookHandler.EnableWebhook)
+	router.Get("/webhooks/{id}/deliveries", webhookHandler.GetDeliveries)
+	router.Post("/webhooks/{id}/deliveries/{deliveryId}/replay", webhookHandler.ReplayDelivery)
+
+	router.Get("/projects", projectHandler.GetProjects)
+	router.Post("/projects", projectHandler.CreateProject)
+	router.Post("/projects/{id}/members", projectHandler.AddProjectMember)
 }
@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey int
+
+const userIdKey contextKey = iota
+
+// authorizationMetadataKey carries the same "Bearer <token>" value the HTTP
+// API expects in the Authorization header.
+const authorizationMetadataKey = "authorization"
+
+func withUserId(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, userIdKey, userId)
+}
+
+func userIdFrom(ctx context.Context) string {
+	userId, _ := ctx.Value(userIdKey).(string)
+	return userId
+}
+
+// authInterceptor verifies the caller's bearer token through userService
+// before any todo RPC runs, and passes the user id on in the context.
+func authInterceptor(userService user.UserServiceAdapter, tracer trace.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(authorizationMetadataKey)
+		if len(values) == 0 || values[0] == "" {
+			return nil, status.Error(codes.Unauthenticated, appErrors.ErrUnauthorized.Message)
+		}
+
+		userId, err := userService.VerifyUser(ctx, tracer, values[0])
+		if err != nil {
+			appErr := appErrors.From(err)
+			switch appErr.Status {
+			case http.StatusUnauthorized:
+				return nil, status.Error(codes.Unauthenticated, appErr.Message)
+			case http.StatusServiceUnavailable:
+				return nil, status.Error(codes.Unavailable, appErr.Message)
+			default:
+				return nil, status.Error(codes.Internal, appErr.Message)
+			}
+		}
+
+		return handler(withUserId(ctx, userId), req)
+	}
+}
+
+// quotaInterceptor counts every authenticated RPC against the API calls of
+// the caller's plan, the way the HTTP API counts requests.
+func quotaInterceptor(quotaService *quotas.QuotaService, tracer trace.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := quotaService.RecordAPICall(ctx, tracer, userIdFrom(ctx)); err != nil {
+			return nil, toStatus(err)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	todosv1 "github.com/olad5/productive-pulse/pkg/proto/todos/v1"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TodoServer serves todos.v1.TodoService on top of the same TodoService used
+// by the HTTP handlers.
+type TodoServer struct {
+	todosv1.UnimplementedTodoServiceServer
+	todoService todos.TodoService
+	tracer      trace.Tracer
+}
+
+func NewTodoServer(todoService todos.TodoService, tracer trace.Tracer) (*TodoServer, error) {
+	if todoService == (todos.TodoService{}) {
+		return nil, errors.New("TodoService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &TodoServer{todoService: todoService, tracer: tracer}, nil
+}
+
+// NewGrpcServer returns a gRPC server with the TodoServer registered behind
+// the otel, authentication and quota interceptors.
+func NewGrpcServer(todoServer *TodoServer, userService user.UserServiceAdapter, quotaService *quotas.QuotaService) (*grpc.Server, error) {
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if quotaService == nil {
+		return nil, errors.New("QuotaService cannot be empty")
+	}
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(),
+			authInterceptor(userService, todoServer.tracer),
+			quotaInterceptor(quotaService, todoServer.tracer),
+		),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+	todosv1.RegisterTodoServiceServer(grpcServer, todoServer)
+	return grpcServer, nil
+}
+
+func (t *TodoServer) CreateTodo(ctx context.Context, request *todosv1.CreateTodoRequest) (*todosv1.Todo, error) {
+	ctx, span := t.tracer.Start(ctx, "CreateTodo-rpc")
+	defer span.End()
+
+	newTodo, err := t.todoService.CreateTodo(ctx, t.tracer, userIdFrom(ctx), request.GetText())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTodoMessage(newTodo), nil
+}
+
+func (t *TodoServer) GetTodo(ctx context.Context, request *todosv1.GetTodoRequest) (*todosv1.Todo, error) {
+	ctx, span := t.tracer.Start(ctx, "GetTodo-rpc")
+	defer span.End()
+
+	todo, err := t.todoService.GetTodo(ctx, t.tracer, userIdFrom(ctx), request.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTodoMessage(todo), nil
+}
+
+func (t *TodoServer) ListTodos(ctx context.Context, request *todosv1.ListTodosRequest) (*todosv1.ListTodosResponse, error) {
+	ctx, span := t.tracer.Start(ctx, "ListTodos-rpc")
+	defer span.End()
+
+	userTodos, err := t.todoService.GetTodos(ctx, t.tracer, userIdFrom(ctx), request.GetIncludeSnoozed())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &todosv1.ListTodosResponse{Todos: make([]*todosv1.Todo, 0, len(userTodos))}
+	for _, todo := range userTodos {
+		response.Todos = append(response.Todos, toTodoMessage(todo))
+	}
+	return response, nil
+}
+
+func (t *TodoServer) UpdateTodo(ctx context.Context, request *todosv1.UpdateTodoRequest) (*todosv1.Todo, error) {
+	ctx, span := t.tracer.Start(ctx, "UpdateTodo-rpc")
+	defer span.End()
+
+	updatedTodo, err := t.todoService.UpdateTodo(ctx, t.tracer, userIdFrom(ctx), request.GetId(), request.GetText())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTodoMessage(updatedTodo), nil
+}
+
+func (t *TodoServer) DeleteTodo(ctx context.Context, request *todosv1.DeleteTodoRequest) (*todosv1.Todo, error) {
+	ctx, span := t.tracer.Start(ctx, "DeleteTodo-rpc")
+	defer span.End()
+
+	deletedTodo, err := t.todoService.DeleteTodo(ctx, t.tracer, userIdFrom(ctx), request.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTodoMessage(deletedTodo), nil
+}
+
+func toStatus(err error) error {
+	var quotaErr *quotas.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return status.Error(codes.ResourceExhausted, quotaErr.Error())
+	}
+	switch {
+	case errors.Is(err, todos.ErrInvalidTodoId), errors.Is(err, todos.ErrInvalidUserId), errors.Is(err, todos.ErrEmptyTodoText):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, todos.ErrTodoNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, todos.ErrNotOwnerOfTodo):
+		return status.Error(codes.PermissionDenied, appErrors.ErrUnauthorized.Message)
+	default:
+		return status.Error(codes.Internal, appErrors.ErrSomethingWentWrong.Message)
+	}
+}
+
+func toTodoMessage(todo domain.Todo) *todosv1.Todo {
+	return &todosv1.Todo{
+		Id:           todo.ID.String(),
+		Text:         todo.Text,
+		SnoozedUntil: toTimestamp(todo.SnoozedUntil),
+		CompletedAt:  toTimestamp(todo.CompletedAt),
+		CreatedAt:    timestamppb.New(todo.CreatedAt),
+		UpdatedAt:    timestamppb.New(todo.UpdatedAt),
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,113 @@
+package collab
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const clientBufferSize = 64
+
+// Client is one live connection as seen by the Hub. The transport drains
+// Outbound and stops once Done is closed.
+type Client struct {
+	ID     string
+	UserId uuid.UUID
+
+	outbound  chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.Mutex
+	projects map[string]*Message
+}
+
+func NewClient(userId uuid.UUID) *Client {
+	return &Client{
+		ID:       uuid.NewString(),
+		UserId:   userId,
+		outbound: make(chan []byte, clientBufferSize),
+		done:     make(chan struct{}),
+		projects: map[string]*Message{},
+	}
+}
+
+func (c *Client) Outbound() <-chan []byte {
+	return c.outbound
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Drop signals the transport to close the connection.
+func (c *Client) Drop() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// Deliver queues payload without blocking; a client that cannot keep up is
+// dropped so it cannot stall delivery to everyone else.
+func (c *Client) Deliver(payload []byte) {
+	select {
+	case <-c.done:
+	case c.outbound <- payload:
+	default:
+		c.Drop()
+	}
+}
+
+func (c *Client) Send(message Message) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("collab client failed to encode %s message: %v", message.Type, err)
+		return
+	}
+	c.Deliver(payload)
+}
+
+func (c *Client) joined(projectId string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.projects[projectId]
+	return ok
+}
+
+func (c *Client) join(projectId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.projects[projectId]; !ok {
+		c.projects[projectId] = nil
+	}
+}
+
+func (c *Client) leave(projectId string) (wasJoined bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.projects[projectId]
+	delete(c.projects, projectId)
+	return ok
+}
+
+func (c *Client) setPresence(projectId string, presence Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.projects[projectId] = &presence
+}
+
+func (c *Client) presence(projectId string) *Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.projects[projectId]
+}
+
+func (c *Client) joinedProjects() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	projectIds := make([]string, 0, len(c.projects))
+	for projectId := range c.projects {
+		projectIds = append(projectIds, projectId)
+	}
+	return projectIds
+}
@@ -0,0 +1,210 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+)
+
+var (
+	ErrProjectAccessDenied  = errors.New("you do not have access to this project")
+	ErrNotSubscribed        = errors.New("subscribe to the project first")
+	ErrInvalidPresenceState = errors.New("presence state must be one of viewing, editing or idle")
+	ErrMissingProjectId     = errors.New("project_id is required")
+	ErrAccessCheckFailed    = errors.New("could not check access to this project, try again")
+)
+
+// Hub fans collaboration messages out to the clients connected to this
+// instance. Every message goes through the PubSub, so clients of other
+// instances sharing the same backend see it too.
+type Hub struct {
+	pubsub    PubSub
+	canAccess AccessCheck
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+type room struct {
+	clients     map[*Client]struct{}
+	unsubscribe func()
+}
+
+// AccessCheck reports whether userId may join the room of projectId.
+type AccessCheck func(ctx context.Context, userId uuid.UUID, projectId string) (bool, error)
+
+func NewHub(pubsub PubSub, canAccess AccessCheck) (*Hub, error) {
+	if pubsub == nil {
+		return nil, errors.New("pubsub cannot be empty")
+	}
+	if canAccess == nil {
+		return nil, errors.New("canAccess cannot be empty")
+	}
+	return &Hub{pubsub: pubsub, canAccess: canAccess, rooms: map[string]*room{}}, nil
+}
+
+func projectTopic(projectId string) string {
+	return "collab.project." + projectId
+}
+
+func (h *Hub) Join(ctx context.Context, client *Client, projectId string) error {
+	if projectId == "" {
+		return ErrMissingProjectId
+	}
+	allowed, err := h.canAccess(ctx, client.UserId, projectId)
+	if err != nil {
+		// NOTE: errors are sent to the client, so the cause is only logged.
+		log.Printf("collab hub failed to check access to project %s: %v", projectId, err)
+		return ErrAccessCheckFailed
+	}
+	if !allowed {
+		return ErrProjectAccessDenied
+	}
+
+	h.mu.Lock()
+	currentRoom, ok := h.rooms[projectId]
+	if !ok {
+		// NOTE: the room outlives the request that created it, so deliveries
+		// run under their own context.
+		unsubscribe, err := h.pubsub.Subscribe(projectTopic(projectId), func(payload []byte) {
+			h.deliver(context.Background(), projectId, payload)
+		})
+		if err != nil {
+			h.mu.Unlock()
+			return err
+		}
+		currentRoom = &room{clients: map[*Client]struct{}{}, unsubscribe: unsubscribe}
+		h.rooms[projectId] = currentRoom
+	}
+	currentRoom.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	client.join(projectId)
+	client.Send(Message{Type: MessageSubscribed, ProjectId: projectId, ClientId: client.ID})
+	return h.publish(ctx, projectId, Message{Type: messagePresenceQuery, ProjectId: projectId})
+}
+
+func (h *Hub) Leave(ctx context.Context, client *Client, projectId string) error {
+	if !client.leave(projectId) {
+		return nil
+	}
+
+	h.mu.Lock()
+	if currentRoom, ok := h.rooms[projectId]; ok {
+		delete(currentRoom.clients, client)
+		if len(currentRoom.clients) == 0 {
+			currentRoom.unsubscribe()
+			delete(h.rooms, projectId)
+		}
+	}
+	h.mu.Unlock()
+
+	return h.publish(ctx, projectId, Message{
+		Type:      MessagePresence,
+		ProjectId: projectId,
+		ClientId:  client.ID,
+		UserId:    client.UserId.String(),
+		State:     PresenceLeft,
+	})
+}
+
+// Disconnect leaves every project the client joined.
+func (h *Hub) Disconnect(ctx context.Context, client *Client) {
+	for _, projectId := range client.joinedProjects() {
+		if err := h.Leave(ctx, client, projectId); err != nil {
+			log.Printf("collab hub failed to leave project %s: %v", projectId, err)
+		}
+	}
+	client.Drop()
+}
+
+func (h *Hub) SetPresence(ctx context.Context, client *Client, projectId, todoId string, state PresenceState) error {
+	if !client.joined(projectId) {
+		return ErrNotSubscribed
+	}
+	if !IsClientPresenceState(state) {
+		return ErrInvalidPresenceState
+	}
+	presence := Message{
+		Type:      MessagePresence,
+		ProjectId: projectId,
+		ClientId:  client.ID,
+		UserId:    client.UserId.String(),
+		TodoId:    todoId,
+		State:     state,
+	}
+	client.setPresence(projectId, presence)
+	return h.publish(ctx, projectId, presence)
+}
+
+// HandleEvent is an events.Handler that broadcasts todo changes to the
+// project the todo belongs to.
+func (h *Hub) HandleEvent(ctx context.Context, event domain.TodoEvent) error {
+	projectId := event.Todo.ProjectId.String()
+	if event.Todo.ProjectId == uuid.Nil {
+		// NOTE: events recorded before todos had projects carry none, and
+		// belong to the personal project.
+		projectId = domain.PersonalProject(event.UserId).ID.String()
+	}
+	err := h.publish(ctx, projectId, Message{
+		Type:      MessageChange,
+		ProjectId: projectId,
+		Event: &ChangeEvent{
+			ID:   event.ID.String(),
+			Type: event.Type,
+			Todo: utils.ToTodoDTO(event.Todo),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("collab hub failed to publish %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+func (h *Hub) publish(ctx context.Context, projectId string, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return h.pubsub.Publish(ctx, projectTopic(projectId), payload)
+}
+
+func (h *Hub) deliver(ctx context.Context, projectId string, payload []byte) {
+	h.mu.Lock()
+	var clients []*Client
+	if currentRoom, ok := h.rooms[projectId]; ok {
+		for client := range currentRoom.clients {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.Unlock()
+
+	var message Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		log.Printf("collab hub received an invalid message on project %s: %v", projectId, err)
+		return
+	}
+	if message.Type != messagePresenceQuery {
+		for _, client := range clients {
+			client.Deliver(payload)
+		}
+		return
+	}
+
+	// Answer on behalf of local clients so a newcomer on any instance sees
+	// everyone already present.
+	for _, client := range clients {
+		if presence := client.presence(projectId); presence != nil {
+			if err := h.publish(ctx, projectId, *presence); err != nil {
+				log.Printf("collab hub failed to announce presence on project %s: %v", projectId, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package collab
+
+import "github.com/olad5/productive-pulse/todo-service/internal/domain"
+
+type MessageType string
+
+const (
+	// Sent by clients.
+	MessageSubscribe   MessageType = "subscribe"
+	MessageUnsubscribe MessageType = "unsubscribe"
+	MessageAuth        MessageType = "auth"
+
+	// Sent by clients to announce themselves, and by the server to relay it.
+	MessagePresence MessageType = "presence"
+
+	// Sent by the server.
+	MessageSubscribed MessageType = "subscribed"
+	MessageChange     MessageType = "change"
+	MessageError      MessageType = "error"
+
+	// Exchanged between Hubs so a new subscriber learns who is already there.
+	messagePresenceQuery MessageType = "presence_query"
+)
+
+type PresenceState string
+
+const (
+	PresenceViewing PresenceState = "viewing"
+	PresenceEditing PresenceState = "editing"
+	PresenceIdle    PresenceState = "idle"
+	PresenceLeft    PresenceState = "left"
+)
+
+func IsClientPresenceState(state PresenceState) bool {
+	return state == PresenceViewing || state == PresenceEditing || state == PresenceIdle
+}
+
+type Message struct {
+	Type      MessageType   `json:"type"`
+	ProjectId string        `json:"project_id,omitempty"`
+	ClientId  string        `json:"client_id,omitempty"`
+	UserId    string        `json:"user_id,omitempty"`
+	TodoId    string        `json:"todo_id,omitempty"`
+	State     PresenceState `json:"state,omitempty"`
+	Token     string        `json:"token,omitempty"`
+	Event     *ChangeEvent  `json:"event,omitempty"`
+	Message   string        `json:"message,omitempty"`
+}
+
+type ChangeEvent struct {
+	ID   string                 `json:"id"`
+	Type domain.TodoEventType   `json:"type"`
+	Todo map[string]interface{} `json:"todo"`
+}
@@ -0,0 +1,60 @@
+package collab
+
+import (
+	"context"
+	"sync"
+)
+
+// PubSub carries collaboration messages between Hubs. The in-memory
+// implementation serves a single instance; running several instances behind
+// the proxy needs a shared backend (Redis, NATS, ...) behind this interface.
+type PubSub interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	Subscribe(topic string, handler func(payload []byte)) (unsubscribe func(), err error)
+}
+
+type InMemoryPubSub struct {
+	mu          sync.RWMutex
+	nextId      int
+	subscribers map[string]map[int]func(payload []byte)
+}
+
+func NewInMemoryPubSub() *InMemoryPubSub {
+	return &InMemoryPubSub{subscribers: map[string]map[int]func(payload []byte){}}
+}
+
+func (p *InMemoryPubSub) Publish(ctx context.Context, topic string, payload []byte) error {
+	p.mu.RLock()
+	handlers := make([]func(payload []byte), 0, len(p.subscribers[topic]))
+	for _, handler := range p.subscribers[topic] {
+		handlers = append(handlers, handler)
+	}
+	p.mu.RUnlock()
+
+	// NOTE: handlers run outside the lock so they may publish in turn.
+	for _, handler := range handlers {
+		handler(payload)
+	}
+	return nil
+}
+
+func (p *InMemoryPubSub) Subscribe(topic string, handler func(payload []byte)) (func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextId
+	p.nextId++
+	if p.subscribers[topic] == nil {
+		p.subscribers[topic] = map[int]func(payload []byte){}
+	}
+	p.subscribers[topic][id] = handler
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subscribers[topic], id)
+		if len(p.subscribers[topic]) == 0 {
+			delete(p.subscribers, topic)
+		}
+	}, nil
+}
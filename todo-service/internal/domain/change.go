@@ -0,0 +1,16 @@
+package domain
+
+import "github.com/google/uuid"
+
+// TodoChange is the latest recorded state of a todo in its owner's change
+// log. Sequence increases with every write to any of the owner's todos, so
+// reading the log after a sequence yields everything that changed since.
+type TodoChange struct {
+	TodoId   uuid.UUID
+	UserId   uuid.UUID
+	Sequence int64
+	Todo     Todo
+	// Deleted marks a tombstone; Todo then holds the state at deletion.
+	Deleted   bool
+	DeletedAt HLC
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TodoEventType string
+
+const (
+	TodoCreated   TodoEventType = "todo.created"
+	TodoUpdated   TodoEventType = "todo.updated"
+	TodoCompleted TodoEventType = "todo.completed"
+	TodoDeleted   TodoEventType = "todo.deleted"
+	TodoSnoozed   TodoEventType = "todo.snoozed"
+	TodoUnsnoozed TodoEventType = "todo.unsnoozed"
+)
+
+var TodoEventTypes = []TodoEventType{
+	TodoCreated,
+	TodoUpdated,
+	TodoCompleted,
+	TodoDeleted,
+	TodoSnoozed,
+	TodoUnsnoozed,
+}
+
+func IsTodoEventType(eventType TodoEventType) bool {
+	for _, known := range TodoEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type TodoEvent struct {
+	ID         uuid.UUID
+	Type       TodoEventType
+	TodoId     uuid.UUID
+	UserId     uuid.UUID
+	Todo       Todo
+	OccurredAt time.Time
+}
+
+func NewTodoEvent(eventType TodoEventType, todo Todo) TodoEvent {
+	return TodoEvent{
+		ID:         uuid.New(),
+		Type:       eventType,
+		TodoId:     todo.ID,
+		UserId:     todo.UserId,
+		Todo:       todo,
+		OccurredAt: time.Now(),
+	}
+}
+
+// OutboxEntry is a TodoEvent waiting in the outbox to be relayed. Sequence
+// orders the events of a single todo.
+type OutboxEntry struct {
+	Event     TodoEvent
+	Sequence  int64
+	Attempts  int
+	LastError string
+}
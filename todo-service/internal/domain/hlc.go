@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxClockDrift bounds how far ahead of this server's clock a remote HLC may
+// be; anything further is rejected so one bad device clock cannot win every
+// future conflict.
+const MaxClockDrift = time.Minute
+
+var (
+	ErrInvalidHLC = errors.New("invalid hybrid logical clock timestamp")
+	ErrClockDrift = errors.New("hybrid logical clock timestamp is too far in the future")
+)
+
+// HLC is a hybrid logical clock timestamp: wall time in milliseconds, a
+// logical counter that orders events within the same millisecond, and the
+// id of the node that issued it as the final tie-breaker.
+type HLC struct {
+	WallTime int64
+	Logical  uint32
+	NodeId   string
+}
+
+func (h HLC) IsZero() bool {
+	return h.WallTime == 0 && h.Logical == 0 && h.NodeId == ""
+}
+
+// Compare returns -1, 0 or 1 as h orders before, equal to or after other.
+func (h HLC) Compare(other HLC) int {
+	switch {
+	case h.WallTime != other.WallTime:
+		return compareInt64(h.WallTime, other.WallTime)
+	case h.Logical != other.Logical:
+		return compareInt64(int64(h.Logical), int64(other.Logical))
+	default:
+		return strings.Compare(h.NodeId, other.NodeId)
+	}
+}
+
+func (h HLC) After(other HLC) bool {
+	return h.Compare(other) > 0
+}
+
+// String encodes h with fixed-width numbers so encoded timestamps sort in
+// clock order.
+func (h HLC) String() string {
+	if h.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%015d:%010d:%s", h.WallTime, h.Logical, h.NodeId)
+}
+
+func ParseHLC(s string) (HLC, error) {
+	if s == "" {
+		return HLC{}, nil
+	}
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[2] == "" {
+		return HLC{}, ErrInvalidHLC
+	}
+	wallTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || wallTime <= 0 {
+		return HLC{}, ErrInvalidHLC
+	}
+	logical, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return HLC{}, ErrInvalidHLC
+	}
+	return HLC{WallTime: wallTime, Logical: uint32(logical), NodeId: parts[2]}, nil
+}
+
+func (h HLC) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *HLC) UnmarshalText(text []byte) error {
+	parsed, err := ParseHLC(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// Clock issues HLC timestamps for one node.
+type Clock struct {
+	mu     sync.Mutex
+	nodeId string
+	last   HLC
+	now    func() time.Time
+}
+
+func NewClock(nodeId string) *Clock {
+	return &Clock{nodeId: nodeId, now: time.Now}
+}
+
+// Now returns a timestamp for a local event, after every timestamp the
+// clock has issued or observed.
+func (c *Clock) Now() HLC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	physical := c.now().UnixMilli()
+	if physical > c.last.WallTime {
+		c.last = HLC{WallTime: physical}
+	} else {
+		c.last.Logical++
+	}
+	c.last.NodeId = c.nodeId
+	return c.last
+}
+
+// Observe merges a remote timestamp into the clock so that later local
+// timestamps order after it.
+func (c *Clock) Observe(remote HLC) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	physical := c.now().UnixMilli()
+	if remote.WallTime > physical+MaxClockDrift.Milliseconds() {
+		return ErrClockDrift
+	}
+
+	wallTime := physical
+	if c.last.WallTime > wallTime {
+		wallTime = c.last.WallTime
+	}
+	if remote.WallTime > wallTime {
+		wallTime = remote.WallTime
+	}
+
+	var logical uint32
+	switch {
+	case wallTime == c.last.WallTime && wallTime == remote.WallTime:
+		logical = c.last.Logical
+		if remote.Logical > logical {
+			logical = remote.Logical
+		}
+		logical++
+	case wallTime == c.last.WallTime:
+		logical = c.last.Logical + 1
+	case wallTime == remote.WallTime:
+		logical = remote.Logical + 1
+	}
+	c.last = HLC{WallTime: wallTime, Logical: logical, NodeId: c.nodeId}
+	return nil
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const PersonalProjectName = "Personal"
+
+// Project groups todos. Every user also has a personal project, which holds
+// the todos they did not move anywhere else.
+type Project struct {
+	ID        uuid.UUID
+	UserId    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PersonalProject is not stored: it shares the id of its owner, so that the
+// todos written before projects existed belong to it.
+func PersonalProject(userId uuid.UUID) Project {
+	return Project{ID: userId, Name: PersonalProjectName, UserId: userId}
+}
+
+// ProjectMember lets a user other than the owner collaborate on a project.
+type ProjectMember struct {
+	ProjectId uuid.UUID
+	UserId    uuid.UUID
+	AddedAt   time.Time
+}
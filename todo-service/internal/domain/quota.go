@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QuotaResource string
+
+const (
+	QuotaTodos QuotaResource = "todos"
+	// NOTE: QuotaAttachmentBytes is reserved in the plans, and reported with
+	// nothing used, until todos carry attachments; nothing enforces it yet.
+	QuotaAttachmentBytes QuotaResource = "attachment_bytes"
+	QuotaWebhooks        QuotaResource = "webhooks"
+	// QuotaAPICalls counts the authenticated requests of the current period.
+	QuotaAPICalls QuotaResource = "api_calls"
+)
+
+var QuotaResources = []QuotaResource{QuotaTodos, QuotaAttachmentBytes, QuotaWebhooks, QuotaAPICalls}
+
+// Plan caps what a user may hold or do. A resource without a limit is
+// unlimited.
+type Plan struct {
+	Name   string
+	Limits map[QuotaResource]int64
+}
+
+func (p Plan) Limit(resource QuotaResource) (int64, bool) {
+	limit, ok := p.Limits[resource]
+	return limit, ok
+}
+
+// Usage is what a user holds, and the API calls they made during Period.
+type Usage struct {
+	UserId uuid.UUID
+	// Plan is empty for users who were never moved off the default plan.
+	Plan   string
+	Period time.Time
+	Used   map[QuotaResource]int64
+}
+
+// UsagePeriod returns the start of the calendar month, in UTC, that API
+// calls made at t count towards.
+func UsagePeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
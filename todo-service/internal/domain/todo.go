@@ -7,9 +7,97 @@ import (
 )
 
 type Todo struct {
-	ID        uuid.UUID
-	UserId    uuid.UUID
-	Text      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID     uuid.UUID
+	UserId uuid.UUID
+	Text   string
+	// ProjectId is the project the todo is filed under, the personal project
+	// of its owner unless it was moved.
+	ProjectId    uuid.UUID
+	Labels       []string
+	SnoozedUntil *time.Time
+	CompletedAt  *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	// Clocks holds the HLC of the last write to each mutable field, which
+	// decides conflicts between concurrent offline edits.
+	Clocks map[TodoField]HLC
+}
+
+func (t Todo) IsSnoozed(now time.Time) bool {
+	return t.SnoozedUntil != nil && t.SnoozedUntil.After(now)
+}
+
+type TodoField string
+
+const (
+	TodoFieldText         TodoField = "text"
+	TodoFieldProjectId    TodoField = "project_id"
+	TodoFieldLabels       TodoField = "labels"
+	TodoFieldSnoozedUntil TodoField = "snoozed_until"
+	TodoFieldCompletedAt  TodoField = "completed_at"
+)
+
+var TodoFields = []TodoField{TodoFieldText, TodoFieldProjectId, TodoFieldLabels, TodoFieldSnoozedUntil, TodoFieldCompletedAt}
+
+func IsTodoField(field TodoField) bool {
+	for _, todoField := range TodoFields {
+		if todoField == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Stamp returns a copy of t recording at as the last write to fields.
+func (t Todo) Stamp(at HLC, fields ...TodoField) Todo {
+	clocks := make(map[TodoField]HLC, len(t.Clocks)+len(fields))
+	for field, clock := range t.Clocks {
+		clocks[field] = clock
+	}
+	for _, field := range fields {
+		clocks[field] = at
+	}
+	t.Clocks = clocks
+	return t
+}
+
+// ChangedFields lists the mutable fields whose value differs between t and
+// updated.
+func (t Todo) ChangedFields(updated Todo) []TodoField {
+	var fields []TodoField
+	if t.Text != updated.Text {
+		fields = append(fields, TodoFieldText)
+	}
+	if t.ProjectId != updated.ProjectId {
+		fields = append(fields, TodoFieldProjectId)
+	}
+	if !equalLabels(t.Labels, updated.Labels) {
+		fields = append(fields, TodoFieldLabels)
+	}
+	if !equalTimes(t.SnoozedUntil, updated.SnoozedUntil) {
+		fields = append(fields, TodoFieldSnoozedUntil)
+	}
+	if !equalTimes(t.CompletedAt, updated.CompletedAt) {
+		fields = append(fields, TodoFieldCompletedAt)
+	}
+	return fields
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func equalLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
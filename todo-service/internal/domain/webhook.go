@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Webhook struct {
+	ID                  uuid.UUID
+	UserId              uuid.UUID
+	URL                 string
+	Secret              string
+	EventTypes          []TodoEventType
+	Disabled            bool
+	ConsecutiveFailures int
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func (w Webhook) Subscribes(eventType TodoEventType) bool {
+	for _, subscribed := range w.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	ID          uuid.UUID
+	WebhookId   uuid.UUID
+	EventId     uuid.UUID
+	EventType   TodoEventType
+	Payload     []byte
+	Attempts    int
+	StatusCode  int
+	Error       string
+	Succeeded   bool
+	CreatedAt   time.Time
+	DeliveredAt *time.Time
+	// NextAttemptAt is when the delivery is due to be attempted again. It is
+	// nil once the delivery succeeded or ran out of attempts.
+	NextAttemptAt *time.Time
+}
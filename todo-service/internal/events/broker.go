@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+// MessageBroker is the narrow slice of a broker client BrokerSink needs, so
+// any broker (Kafka, NATS, RabbitMQ, ...) can be plugged in with an adapter.
+type MessageBroker interface {
+	Publish(ctx context.Context, topic, key string, payload []byte) error
+}
+
+// BrokerSink is a Publisher that forwards events to a message broker. Events
+// are keyed by todo id so partitioned brokers keep the per-todo order.
+type BrokerSink struct {
+	broker MessageBroker
+	topic  string
+}
+
+func NewBrokerSink(broker MessageBroker, topic string) (*BrokerSink, error) {
+	if broker == nil {
+		return nil, errors.New("broker cannot be empty")
+	}
+	if topic == "" {
+		return nil, errors.New("topic cannot be empty")
+	}
+	return &BrokerSink{broker, topic}, nil
+}
+
+func (b *BrokerSink) Publish(ctx context.Context, event domain.TodoEvent) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":          event.ID,
+		"type":        event.Type,
+		"todo_id":     event.TodoId,
+		"user_id":     event.UserId,
+		"occurred_at": event.OccurredAt,
+	})
+	if err != nil {
+		return err
+	}
+	return b.broker.Publish(ctx, b.topic, event.TodoId.String(), payload)
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, event domain.TodoEvent) error
+}
+
+// Handler takes in an event, and returns an error when it should be sent
+// again. It may see an event more than once, and must tolerate duplicates by
+// event id.
+type Handler func(ctx context.Context, event domain.TodoEvent) error
+
+// Bus is an in-process Publisher that fans events out to its subscribers
+// synchronously, in the order they subscribed. Publish fails when any of them
+// fails, after every one of them saw the event.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, event domain.TodoEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var errs []error
+	for _, handler := range b.subscribers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamBroker is a MessageBroker that appends messages to Redis
+// streams, one per topic. Each entry carries its key and payload as fields.
+type RedisStreamBroker struct {
+	client redis.Cmdable
+	// maxLength caps each stream, which Redis trims approximately.
+	maxLength int64
+}
+
+func NewRedisStreamBroker(client redis.Cmdable, maxLength int64) (*RedisStreamBroker, error) {
+	if client == nil {
+		return nil, errors.New("redis client cannot be empty")
+	}
+	if maxLength <= 0 {
+		return nil, errors.New("maxLength must be positive")
+	}
+	return &RedisStreamBroker{client, maxLength}, nil
+}
+
+func (r *RedisStreamBroker) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: r.maxLength,
+		Approx: true,
+		Values: map[string]interface{}{"key": key, "payload": payload},
+	}).Err()
+}
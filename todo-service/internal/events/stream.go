@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+const subscriptionBufferSize = 64
+
+// StreamHub fans events out to live per-user subscriptions and keeps the
+// last logSize events so a reconnecting client can resume after the last
+// event it saw.
+type StreamHub struct {
+	mu            sync.Mutex
+	log           []domain.TodoEvent
+	logSize       int
+	subscriptions map[uuid.UUID]map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	userId uuid.UUID
+	// Events is closed when the subscription is dropped, either by
+	// Unsubscribe or because the subscriber fell too far behind.
+	Events chan domain.TodoEvent
+}
+
+func NewStreamHub(logSize int) *StreamHub {
+	return &StreamHub{
+		logSize:       logSize,
+		subscriptions: map[uuid.UUID]map[*Subscription]struct{}{},
+	}
+}
+
+// HandleEvent is an events.Handler that records event and forwards it to the
+// subscriptions of the todo's owner. An event that is still in the log was
+// delivered again by the relay, and is dropped.
+func (s *StreamHub) HandleEvent(ctx context.Context, event domain.TodoEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, logged := range s.log {
+		if logged.ID == event.ID {
+			return nil
+		}
+	}
+	s.log = append(s.log, event)
+	if len(s.log) > s.logSize {
+		s.log = s.log[len(s.log)-s.logSize:]
+	}
+
+	for subscription := range s.subscriptions[event.UserId] {
+		select {
+		case subscription.Events <- event:
+		default:
+			// NOTE: a subscriber that cannot keep up is dropped rather than
+			// blocking the relay; it resumes from its Last-Event-ID.
+			s.remove(subscription)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscription for userId. When lastEventId is set it
+// also returns the user's logged events after it; resumed is false when that
+// event is no longer in the log, in which case the client has missed events.
+func (s *StreamHub) Subscribe(userId uuid.UUID, lastEventId string) (subscription *Subscription, backlog []domain.TodoEvent, resumed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription = &Subscription{
+		userId: userId,
+		Events: make(chan domain.TodoEvent, subscriptionBufferSize),
+	}
+	if s.subscriptions[userId] == nil {
+		s.subscriptions[userId] = map[*Subscription]struct{}{}
+	}
+	s.subscriptions[userId][subscription] = struct{}{}
+
+	if lastEventId == "" {
+		return subscription, nil, true
+	}
+	position := -1
+	for index, event := range s.log {
+		if event.ID.String() == lastEventId {
+			position = index
+			break
+		}
+	}
+	if position == -1 {
+		return subscription, nil, false
+	}
+	for _, event := range s.log[position+1:] {
+		if event.UserId == userId {
+			backlog = append(backlog, event)
+		}
+	}
+	return subscription, backlog, true
+}
+
+func (s *StreamHub) Unsubscribe(subscription *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(subscription)
+}
+
+func (s *StreamHub) remove(subscription *Subscription) {
+	userSubscriptions, ok := s.subscriptions[subscription.userId]
+	if !ok {
+		return
+	}
+	if _, ok := userSubscriptions[subscription]; !ok {
+		return
+	}
+	delete(userSubscriptions, subscription)
+	if len(userSubscriptions) == 0 {
+		delete(s.subscriptions, subscription.userId)
+	}
+	close(subscription.Events)
+}
@@ -0,0 +1,40 @@
+package graph
+
+import "context"
+
+type contextKey int
+
+const (
+	userIdKey contextKey = iota
+	todoLoaderKey
+	projectLoaderKey
+)
+
+// WithUserId marks ctx as acting for the authenticated userId; every
+// resolver reads it from there.
+func WithUserId(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, userIdKey, userId)
+}
+
+func userIdFrom(ctx context.Context) string {
+	userId, _ := ctx.Value(userIdKey).(string)
+	return userId
+}
+
+func withTodoLoader(ctx context.Context, loader *todoLoader) context.Context {
+	return context.WithValue(ctx, todoLoaderKey, loader)
+}
+
+func todoLoaderFrom(ctx context.Context) *todoLoader {
+	loader, _ := ctx.Value(todoLoaderKey).(*todoLoader)
+	return loader
+}
+
+func withProjectLoader(ctx context.Context, loader *projectLoader) context.Context {
+	return context.WithValue(ctx, projectLoaderKey, loader)
+}
+
+func projectLoaderFrom(ctx context.Context) *projectLoader {
+	loader, _ := ctx.Value(projectLoaderKey).(*projectLoader)
+	return loader
+}
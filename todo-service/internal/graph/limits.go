@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listCostMultiplier is the number of items a list field is assumed to
+// return when estimating the cost of the selections below it.
+const listCostMultiplier = 10
+
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+var DefaultLimits = Limits{MaxDepth: 8, MaxComplexity: 500}
+
+// checkLimits rejects operations nested deeper than MaxDepth or estimated
+// to cost more than MaxComplexity. Every field costs one, and everything
+// selected below a list field is weighted by listCostMultiplier.
+// Introspection fields are not counted.
+func checkLimits(schema graphql.Schema, operation *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, limits Limits) error {
+	var root graphql.Type
+	switch operation.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+
+	depth, complexity := measure(schema, root, operation.SelectionSet, fragments, 1)
+	if depth > limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth)
+	}
+	if complexity > limits.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)
+	}
+	return nil
+}
+
+func measure(schema graphql.Schema, parent graphql.Type, selectionSet *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, depth int) (maxDepth, complexity int) {
+	if selectionSet == nil {
+		return depth - 1, 0
+	}
+	maxDepth = depth
+	for _, selection := range selectionSet.Selections {
+		var selectionDepth, selectionComplexity int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			selectionDepth, selectionComplexity = depth, 1
+			if selection.SelectionSet != nil {
+				fieldType, isList := fieldType(parent, selection.Name.Value)
+				childDepth, childComplexity := measure(schema, fieldType, selection.SelectionSet, fragments, depth+1)
+				if isList {
+					childComplexity *= listCostMultiplier
+				}
+				selectionDepth = childDepth
+				selectionComplexity += childComplexity
+			}
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				fragmentType = schema.Type(selection.TypeCondition.Name.Value)
+			}
+			selectionDepth, selectionComplexity = measure(schema, fragmentType, selection.SelectionSet, fragments, depth)
+		case *ast.FragmentSpread:
+			// NOTE: validation has already rejected unknown and cyclic
+			// fragments.
+			fragment, ok := fragments[selection.Name.Value]
+			if !ok {
+				continue
+			}
+			selectionDepth, selectionComplexity = measure(schema, schema.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet, fragments, depth)
+		}
+		if selectionDepth > maxDepth {
+			maxDepth = selectionDepth
+		}
+		complexity += selectionComplexity
+	}
+	return maxDepth, complexity
+}
+
+func fieldType(parent graphql.Type, name string) (graphql.Type, bool) {
+	object, ok := parent.(*graphql.Object)
+	if !ok {
+		return nil, false
+	}
+	field, ok := object.Fields()[name]
+	if !ok {
+		return nil, false
+	}
+	var isList bool
+	fieldType := field.Type
+	for {
+		switch wrapped := fieldType.(type) {
+		case *graphql.NonNull:
+			fieldType = wrapped.OfType
+		case *graphql.List:
+			isList = true
+			fieldType = wrapped.OfType
+		default:
+			return fieldType, isList
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type todoResult struct {
+	todo  domain.Todo
+	found bool
+	err   error
+}
+
+// todoLoader batches the todo lookups of one request. Load only queues the
+// id and returns a thunk; the executor resolves every field of a level
+// before calling any thunk, so the first thunk called fetches every id
+// queued by then in a single repository read.
+type todoLoader struct {
+	todoService *todos.TodoService
+	tracer      trace.Tracer
+	userId      string
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]todoResult
+}
+
+func newTodoLoader(todoService *todos.TodoService, tracer trace.Tracer, userId string) *todoLoader {
+	return &todoLoader{
+		todoService: todoService,
+		tracer:      tracer,
+		userId:      userId,
+		results:     map[string]todoResult{},
+	}
+}
+
+func (l *todoLoader) Load(ctx context.Context, todoId string) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.results[todoId]; !ok {
+		l.pending = append(l.pending, todoId)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.dispatch(ctx)
+
+		l.mu.Lock()
+		result := l.results[todoId]
+		l.mu.Unlock()
+		if result.err != nil {
+			return nil, result.err
+		}
+		if !result.found {
+			return nil, todos.ErrTodoNotFound
+		}
+		return result.todo, nil
+	}
+}
+
+func (l *todoLoader) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var batch []string
+	seen := map[string]bool{}
+	for _, todoId := range l.pending {
+		if _, ok := l.results[todoId]; ok || seen[todoId] {
+			continue
+		}
+		seen[todoId] = true
+		batch = append(batch, todoId)
+	}
+	l.pending = nil
+	if len(batch) == 0 {
+		return
+	}
+
+	loaded, err := l.todoService.GetTodosByIds(ctx, l.tracer, l.userId, batch)
+	if err != nil {
+		for _, todoId := range batch {
+			l.results[todoId] = todoResult{err: err}
+		}
+		return
+	}
+	for _, todoId := range batch {
+		l.results[todoId] = todoResult{}
+	}
+	for _, todo := range loaded {
+		l.results[todo.ID.String()] = todoResult{todo: todo, found: true}
+	}
+}
+
+// projectLoader reads the projects of the user of one request the first time
+// a todo's project is resolved, however many todos ask for it.
+type projectLoader struct {
+	projectService *projects.ProjectService
+	tracer         trace.Tracer
+	userId         string
+
+	once     sync.Once
+	projects map[uuid.UUID]domain.Project
+	err      error
+}
+
+func newProjectLoader(projectService *projects.ProjectService, tracer trace.Tracer, userId string) *projectLoader {
+	return &projectLoader{projectService: projectService, tracer: tracer, userId: userId}
+}
+
+func (l *projectLoader) Load(ctx context.Context, projectId uuid.UUID) (domain.Project, error) {
+	l.once.Do(func() {
+		var userProjects []domain.Project
+		userProjects, l.err = l.projectService.GetProjects(ctx, l.tracer, l.userId)
+		l.projects = make(map[uuid.UUID]domain.Project, len(userProjects))
+		for _, project := range userProjects {
+			l.projects[project.ID] = project
+		}
+	})
+	if l.err != nil {
+		return domain.Project{}, l.err
+	}
+	if project, ok := l.projects[projectId]; ok {
+		return project, nil
+	}
+	// NOTE: a subscription keeps its loader while projects are created, so
+	// the projects missing from the first read are looked up one by one.
+	return l.projectService.GetProject(ctx, l.tracer, l.userId, projectId.String())
+}
@@ -0,0 +1,470 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type resolvers struct {
+	todoService    *todos.TodoService
+	projectService *projects.ProjectService
+	hub            *events.StreamHub
+	tracer         trace.Tracer
+}
+
+func newSchema(r resolvers) (graphql.Schema, error) {
+	projectType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Project",
+		Fields: graphql.Fields{},
+	})
+	labelType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Label",
+		Fields: graphql.Fields{},
+	})
+
+	todoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Todo",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.ID),
+				Resolve: todoField(func(todo domain.Todo) interface{} { return todo.ID.String() }),
+			},
+			"text": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: todoField(func(todo domain.Todo) interface{} { return todo.Text }),
+			},
+			"snoozedUntil": &graphql.Field{
+				Type:    graphql.DateTime,
+				Resolve: todoField(func(todo domain.Todo) interface{} { return todo.SnoozedUntil }),
+			},
+			"completedAt": &graphql.Field{
+				Type:    graphql.DateTime,
+				Resolve: todoField(func(todo domain.Todo) interface{} { return todo.CompletedAt }),
+			},
+			"createdAt": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.DateTime),
+				Resolve: todoField(func(todo domain.Todo) interface{} { return todo.CreatedAt }),
+			},
+			"updatedAt": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.DateTime),
+				Resolve: todoField(func(todo domain.Todo) interface{} { return todo.UpdatedAt }),
+			},
+			"project": &graphql.Field{
+				Type: graphql.NewNonNull(projectType),
+				Resolve: r.traced("Todo.project", func(p graphql.ResolveParams) (interface{}, error) {
+					return projectLoaderFrom(p.Context).Load(p.Context, p.Source.(domain.Todo).ProjectId)
+				}),
+			},
+			"labels": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(labelType))),
+				Resolve: todoField(func(todo domain.Todo) interface{} {
+					if todo.Labels == nil {
+						return []string{}
+					}
+					return todo.Labels
+				}),
+			},
+		},
+	})
+
+	includeSnoozedArgs := graphql.FieldConfigArgument{
+		"includeSnoozed": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+	}
+	todoListType := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType)))
+	projectListType := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(projectType)))
+
+	projectType.AddFieldConfig("id", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.ID),
+		Resolve: projectField(func(project domain.Project) interface{} { return project.ID.String() }),
+	})
+	projectType.AddFieldConfig("name", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.String),
+		Resolve: projectField(func(project domain.Project) interface{} { return project.Name }),
+	})
+	projectType.AddFieldConfig("todos", &graphql.Field{
+		Type: todoListType,
+		Args: includeSnoozedArgs,
+		Resolve: r.traced("Project.todos", func(p graphql.ResolveParams) (interface{}, error) {
+			projectId := p.Source.(domain.Project).ID
+			return r.filterTodos(p, func(todo domain.Todo) bool { return todo.ProjectId == projectId })
+		}),
+	})
+
+	labelType.AddFieldConfig("name", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(string), nil
+		},
+	})
+	labelType.AddFieldConfig("todos", &graphql.Field{
+		Type: todoListType,
+		Args: includeSnoozedArgs,
+		Resolve: r.traced("Label.todos", func(p graphql.ResolveParams) (interface{}, error) {
+			label := p.Source.(string)
+			return r.filterTodos(p, func(todo domain.Todo) bool { return hasLabel(todo, label) })
+		}),
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(string), nil
+				},
+			},
+			"projects": &graphql.Field{
+				Type:    projectListType,
+				Resolve: r.traced("User.projects", r.resolveProjects),
+			},
+			"todos": &graphql.Field{
+				Type: todoListType,
+				Args: includeSnoozedArgs,
+				Resolve: r.traced("User.todos", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.todoService.GetTodos(p.Context, r.tracer, p.Source.(string), p.Args["includeSnoozed"].(bool))
+				}),
+			},
+		},
+	})
+
+	todoEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoEvent",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.ID),
+				Resolve: eventField(func(event domain.TodoEvent) interface{} { return event.ID.String() }),
+			},
+			"type": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: eventField(func(event domain.TodoEvent) interface{} { return string(event.Type) }),
+			},
+			"occurredAt": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.DateTime),
+				Resolve: eventField(func(event domain.TodoEvent) interface{} { return event.OccurredAt }),
+			},
+			"todo": &graphql.Field{
+				Type:    graphql.NewNonNull(todoType),
+				Resolve: eventField(func(event domain.TodoEvent) interface{} { return event.Todo }),
+			},
+		},
+	})
+
+	idArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return userIdFrom(p.Context), nil
+				},
+			},
+			"todo": &graphql.Field{
+				Type:    todoType,
+				Args:    idArgs,
+				Resolve: r.traced("Query.todo", r.resolveTodo),
+			},
+			"todos": &graphql.Field{
+				Type: todoListType,
+				Args: includeSnoozedArgs,
+				Resolve: r.traced("Query.todos", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.todoService.GetTodos(p.Context, r.tracer, userIdFrom(p.Context), p.Args["includeSnoozed"].(bool))
+				}),
+			},
+			"projects": &graphql.Field{
+				Type:    projectListType,
+				Resolve: r.traced("Query.projects", r.resolveProjects),
+			},
+			"project": &graphql.Field{
+				Type: projectType,
+				Args: idArgs,
+				Resolve: r.traced("Query.project", func(p graphql.ResolveParams) (interface{}, error) {
+					project, err := r.projectService.GetProject(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string))
+					if errors.Is(err, projects.ErrProjectNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return project, nil
+				}),
+			},
+			"labels": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(labelType))),
+				Resolve: r.traced("Query.labels", r.resolveLabels),
+			},
+		},
+	})
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"text": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.traced("Mutation.createTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.todoService.CreateTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["text"].(string))
+				}),
+			},
+			"updateTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"text": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.traced("Mutation.updateTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					text := p.Args["text"].(string)
+					return r.todoService.PatchTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string),
+						func(todo domain.Todo) (domain.Todo, error) {
+							todo.Text = text
+							return todo, nil
+						})
+				}),
+			},
+			"completeTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: idArgs,
+				Resolve: r.traced("Mutation.completeTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.todoService.CompleteTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string))
+				}),
+			},
+			"snoozeTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"until": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+				},
+				Resolve: r.traced("Mutation.snoozeTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					until, ok := p.Args["until"].(time.Time)
+					if !ok {
+						return nil, errors.New("until must be an RFC 3339 date-time")
+					}
+					return r.todoService.SnoozeTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string), until)
+				}),
+			},
+			"moveTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"projectId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: r.traced("Mutation.moveTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.todoService.MoveTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string), p.Args["projectId"].(string))
+				}),
+			},
+			"labelTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"labels": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+				},
+				Resolve: r.traced("Mutation.labelTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					labels := []string{}
+					for _, label := range p.Args["labels"].([]interface{}) {
+						labels = append(labels, label.(string))
+					}
+					return r.todoService.LabelTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string), labels)
+				}),
+			},
+			"createProject": &graphql.Field{
+				Type: graphql.NewNonNull(projectType),
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.traced("Mutation.createProject", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.projectService.CreateProject(p.Context, r.tracer, userIdFrom(p.Context), p.Args["name"].(string))
+				}),
+			},
+			"deleteTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: idArgs,
+				Resolve: r.traced("Mutation.deleteTodo", func(p graphql.ResolveParams) (interface{}, error) {
+					return r.todoService.DeleteTodo(p.Context, r.tracer, userIdFrom(p.Context), p.Args["id"].(string))
+				}),
+			},
+		},
+	})
+
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"todoEvents": &graphql.Field{
+				Type:      graphql.NewNonNull(todoEventType),
+				Subscribe: r.traced("Subscription.todoEvents", r.subscribeTodoEvents),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        queryType,
+		Mutation:     mutationType,
+		Subscription: subscriptionType,
+	})
+}
+
+func (r resolvers) resolveTodo(p graphql.ResolveParams) (interface{}, error) {
+	todoId, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, todos.ErrInvalidTodoId
+	}
+	return todoLoaderFrom(p.Context).Load(p.Context, todoId.String()), nil
+}
+
+func (r resolvers) resolveProjects(p graphql.ResolveParams) (interface{}, error) {
+	return r.projectService.GetProjects(p.Context, r.tracer, userIdFrom(p.Context))
+}
+
+// resolveLabels lists every label on the user's todos, snoozed ones
+// included, in the order they were first used on a todo.
+func (r resolvers) resolveLabels(p graphql.ResolveParams) (interface{}, error) {
+	userTodos, err := r.todoService.GetTodos(p.Context, r.tracer, userIdFrom(p.Context), true)
+	if err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	seen := map[string]bool{}
+	for _, todo := range userTodos {
+		for _, label := range todo.Labels {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// filterTodos lists the user's todos that keep passes.
+//
+// NOTE: todos are only listed per user, so the ones of a project or a label
+// are picked out of them here.
+func (r resolvers) filterTodos(p graphql.ResolveParams, keep func(domain.Todo) bool) (interface{}, error) {
+	userTodos, err := r.todoService.GetTodos(p.Context, r.tracer, userIdFrom(p.Context), p.Args["includeSnoozed"].(bool))
+	if err != nil {
+		return nil, err
+	}
+	kept := []domain.Todo{}
+	for _, todo := range userTodos {
+		if keep(todo) {
+			kept = append(kept, todo)
+		}
+	}
+	return kept, nil
+}
+
+func hasLabel(todo domain.Todo, label string) bool {
+	for _, todoLabel := range todo.Labels {
+		if todoLabel == label {
+			return true
+		}
+	}
+	return false
+}
+
+func (r resolvers) subscribeTodoEvents(p graphql.ResolveParams) (interface{}, error) {
+	userId, err := uuid.Parse(userIdFrom(p.Context))
+	if err != nil {
+		return nil, todos.ErrInvalidUserId
+	}
+	subscription, _, _ := r.hub.Subscribe(userId, "")
+
+	source := make(chan interface{})
+	go func() {
+		defer close(source)
+		defer r.hub.Unsubscribe(subscription)
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, open := <-subscription.Events:
+				if !open {
+					return
+				}
+				select {
+				case source <- event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return source, nil
+}
+
+// traced wraps a resolver that does I/O in a span named after the field. A
+// thunk returned for batching ends the span when it resolves.
+func (r resolvers) traced(name string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx, span := r.tracer.Start(p.Context, name+"-resolver")
+		p.Context = ctx
+
+		result, err := resolve(p)
+		if thunk, ok := result.(func() (interface{}, error)); ok && err == nil {
+			return func() (interface{}, error) {
+				defer span.End()
+				result, err := thunk()
+				return result, safeError(err)
+			}, nil
+		}
+		span.End()
+		return result, safeError(err)
+	}
+}
+
+// safeError hands clients the message and code of the service's own
+// errors, and hides everything else, which may carry infrastructure details.
+func safeError(err error) error {
+	if err == nil || err == context.Canceled {
+		return err
+	}
+	appErr := appErrors.From(err)
+	return codedError{errors.New(appErr.Message), appErr.Code}
+}
+
+// codedError hands its code to clients in the extensions of the GraphQL
+// error, the way the HTTP API sends it in the error response.
+type codedError struct {
+	error
+	code string
+}
+
+func (c codedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": c.code}
+}
+
+func todoField(get func(domain.Todo) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(domain.Todo)), nil
+	}
+}
+
+func projectField(get func(domain.Project) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(domain.Project)), nil
+	}
+}
+
+func eventField(get func(domain.TodoEvent) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(domain.TodoEvent)), nil
+	}
+}
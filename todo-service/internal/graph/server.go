@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// PreparedRequest is a request that parsed, validated and fits the limits.
+type PreparedRequest struct {
+	request  Request
+	document *ast.Document
+	// IsSubscription tells the transport to stream results.
+	IsSubscription bool
+}
+
+type Server struct {
+	schema         graphql.Schema
+	limits         Limits
+	todoService    *todos.TodoService
+	projectService *projects.ProjectService
+	tracer         trace.Tracer
+}
+
+func NewServer(todoService *todos.TodoService, projectService *projects.ProjectService, hub *events.StreamHub, tracer trace.Tracer, limits Limits) (*Server, error) {
+	if todoService == nil {
+		return nil, errors.New("TodoService cannot be empty")
+	}
+	if projectService == nil {
+		return nil, errors.New("ProjectService cannot be empty")
+	}
+	if hub == nil {
+		return nil, errors.New("StreamHub cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	schema, err := newSchema(resolvers{todoService, projectService, hub, tracer})
+	if err != nil {
+		return nil, err
+	}
+	return &Server{schema, limits, todoService, projectService, tracer}, nil
+}
+
+// Prepare parses and validates request. On failure it returns the result to
+// send back instead.
+func (s *Server) Prepare(request Request) (*PreparedRequest, *graphql.Result) {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return nil, &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	validation := graphql.ValidateDocument(&s.schema, document, nil)
+	if !validation.IsValid {
+		return nil, &graphql.Result{Errors: validation.Errors}
+	}
+
+	var operation *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if request.OperationName == "" || (definition.Name != nil && definition.Name.Value == request.OperationName) {
+				if operation != nil && request.OperationName == "" {
+					return nil, errorResult(errors.New("operationName is required when the document has several operations"))
+				}
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		}
+	}
+	if operation == nil {
+		return nil, errorResult(errors.New("unknown operation"))
+	}
+	if err := checkLimits(s.schema, operation, fragments, s.limits); err != nil {
+		return nil, errorResult(err)
+	}
+
+	return &PreparedRequest{
+		request:        request,
+		document:       document,
+		IsSubscription: operation.Operation == ast.OperationTypeSubscription,
+	}, nil
+}
+
+// Execute runs a query or mutation for the user in ctx (see WithUserId).
+func (s *Server) Execute(ctx context.Context, prepared *PreparedRequest) *graphql.Result {
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           prepared.document,
+		OperationName: prepared.request.OperationName,
+		Args:          prepared.request.Variables,
+		Context:       s.requestContext(ctx),
+	})
+}
+
+// Subscribe runs a subscription until ctx is done.
+func (s *Server) Subscribe(ctx context.Context, prepared *PreparedRequest) chan *graphql.Result {
+	return graphql.ExecuteSubscription(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           prepared.document,
+		OperationName: prepared.request.OperationName,
+		Args:          prepared.request.Variables,
+		Context:       s.requestContext(ctx),
+	})
+}
+
+func (s *Server) requestContext(ctx context.Context) context.Context {
+	ctx = withTodoLoader(ctx, newTodoLoader(s.todoService, s.tracer, userIdFrom(ctx)))
+	return withProjectLoader(ctx, newProjectLoader(s.projectService, s.tracer, userIdFrom(ctx)))
+}
+
+func errorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+)
+
+func (t TodoHandler) BulkTodos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := t.tracer.Start(ctx, "BulkTodos-handler")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type operationDTO struct {
+		Op        string   `json:"op"`
+		Id        string   `json:"id"`
+		Text      string   `json:"text"`
+		ProjectId string   `json:"project_id"`
+		Labels    []string `json:"labels"`
+	}
+	type requestDTO struct {
+		Atomic     bool           `json:"atomic"`
+		Operations []operationDTO `json:"operations"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	operations := make([]todos.BulkOperation, 0, len(request.Operations))
+	for _, operation := range request.Operations {
+		operations = append(operations, todos.BulkOperation{
+			Type:      todos.BulkOperationType(operation.Op),
+			TodoId:    operation.Id,
+			Text:      operation.Text,
+			ProjectId: operation.ProjectId,
+			Labels:    operation.Labels,
+		})
+	}
+
+	results, err := t.todoService.BulkTodos(ctx, t.tracer, userId, operations, request.Atomic)
+	var operationErr *todos.BulkOperationError
+	if errors.As(err, &operationErr) {
+		response.Error(w, r, bulkOperationError(operationErr))
+		return
+	}
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	resultsData := make([]map[string]interface{}, 0, len(results))
+	for index, result := range results {
+		resultData := map[string]interface{}{
+			"index": index,
+			"op":    result.Type,
+		}
+		if result.Err != nil {
+			appErr := appErrors.From(result.Err)
+			resultData["status"] = "error"
+			resultData["message"] = appErr.Message
+			resultData["code"] = appErr.Code
+		} else {
+			resultData["status"] = "ok"
+			resultData["data"] = utils.ToTodoDTO(result.Todo)
+		}
+		resultsData = append(resultsData, resultData)
+	}
+
+	response.SuccessResponse(w, "bulk operations applied", resultsData)
+	return
+}
+
+// bulkOperationError answers an atomic bulk request rolled back by one of
+// its operations with a 400 naming the operation, unless the operation went
+// over a quota or failed unexpectedly.
+func bulkOperationError(operationErr *todos.BulkOperationError) *appErrors.Error {
+	cause := appErrors.From(operationErr.Err)
+	statusCode := http.StatusBadRequest
+	if cause.Status == http.StatusForbidden || cause.Status >= http.StatusInternalServerError {
+		statusCode = cause.Status
+	}
+	return &appErrors.Error{
+		Code:    cause.Code,
+		Status:  statusCode,
+		Message: fmt.Sprintf("operation %d failed: %s", operationErr.Index, cause.Message),
+		Err:     operationErr,
+	}
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/collab"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	collabMaxMessageSize = 4096
+	collabWriteWait      = 10 * time.Second
+	collabPongWait       = 60 * time.Second
+	collabPingInterval   = 50 * time.Second
+	// Tokens without an exp claim are re-validated on this interval instead.
+	collabRevalidateInterval = 5 * time.Minute
+)
+
+type CollaborationHandler struct {
+	hub         *collab.Hub
+	userService user.UserServiceAdapter
+	tracer      trace.Tracer
+	upgrader    websocket.Upgrader
+}
+
+func NewCollaborationHandler(hub *collab.Hub, userService user.UserServiceAdapter, tracer trace.Tracer) (*CollaborationHandler, error) {
+	if hub == nil {
+		return nil, errors.New("Hub cannot be empty")
+	}
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	upgrader := websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+	return &CollaborationHandler{hub, userService, tracer, upgrader}, nil
+}
+
+// Collaborate upgrades to a WebSocket on which the client subscribes to
+// projects, receives their changes and exchanges presence. The bearer token
+// is re-validated when it expires; clients keep the connection alive across
+// token refreshes by sending an auth message with the new token.
+func (c CollaborationHandler) Collaborate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := c.tracer.Start(ctx, "Collaborate-handler")
+	defer span.End()
+
+	// NOTE: browsers cannot set headers on a WebSocket handshake, so the
+	// token may also be passed as the access_token query parameter.
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" && r.URL.Query().Get("access_token") != "" {
+		authHeader = "Bearer " + r.URL.Query().Get("access_token")
+	}
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := c.userService.VerifyUser(ctx, c.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+
+	conn, err := c.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error status.
+		return
+	}
+
+	session := &collaborationSession{
+		handler:    c,
+		conn:       conn,
+		client:     collab.NewClient(userIdInUUID),
+		authHeader: authHeader,
+		expiresAt:  tokenExpiry(authHeader),
+		reauth:     make(chan struct{}, 1),
+		readerDone: make(chan struct{}),
+	}
+	defer c.hub.Disconnect(ctx, session.client)
+
+	go session.writeLoop(ctx)
+	session.readLoop(ctx)
+}
+
+type collaborationSession struct {
+	handler CollaborationHandler
+	conn    *websocket.Conn
+	client  *collab.Client
+
+	mu         sync.Mutex
+	authHeader string
+	expiresAt  time.Time
+
+	reauth     chan struct{}
+	readerDone chan struct{}
+}
+
+func (s *collaborationSession) readLoop(ctx context.Context) {
+	defer close(s.readerDone)
+
+	s.conn.SetReadLimit(collabMaxMessageSize)
+	_ = s.conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	})
+
+	for {
+		_, payload, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var message collab.Message
+		if err := json.Unmarshal(payload, &message); err != nil {
+			s.sendError(appErrors.ErrInvalidJson.Message)
+			continue
+		}
+		if err := s.handleMessage(ctx, message); err != nil {
+			s.sendError(err.Error())
+		}
+	}
+}
+
+func (s *collaborationSession) handleMessage(ctx context.Context, message collab.Message) error {
+	hub := s.handler.hub
+	switch message.Type {
+	case collab.MessageSubscribe:
+		return hub.Join(ctx, s.client, message.ProjectId)
+	case collab.MessageUnsubscribe:
+		return hub.Leave(ctx, s.client, message.ProjectId)
+	case collab.MessagePresence:
+		return hub.SetPresence(ctx, s.client, message.ProjectId, message.TodoId, message.State)
+	case collab.MessageAuth:
+		return s.refreshToken(ctx, message.Token)
+	default:
+		return errors.New("unknown message type")
+	}
+}
+
+// refreshToken swaps in a new token for the same user so the connection
+// survives the expiry of the one it was opened with.
+func (s *collaborationSession) refreshToken(ctx context.Context, token string) error {
+	authHeader := "Bearer " + token
+	userId, err := s.handler.userService.VerifyUser(ctx, s.handler.tracer, authHeader)
+	if err != nil {
+		// NOTE: the error is sent to the client, so without its cause.
+		return errors.New(appErrors.From(err).Message)
+	}
+	if userId != s.client.UserId.String() {
+		return appErrors.ErrUnauthorized
+	}
+
+	s.mu.Lock()
+	s.authHeader = authHeader
+	s.expiresAt = tokenExpiry(authHeader)
+	s.mu.Unlock()
+
+	select {
+	case s.reauth <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// revalidate reports whether the current token is still accepted, and when
+// it should be checked next.
+func (s *collaborationSession) revalidate(ctx context.Context) (time.Time, bool) {
+	s.mu.Lock()
+	authHeader := s.authHeader
+	s.mu.Unlock()
+
+	userId, err := s.handler.userService.VerifyUser(ctx, s.handler.tracer, authHeader)
+	if err != nil || userId != s.client.UserId.String() {
+		return time.Time{}, false
+	}
+	expiresAt := tokenExpiry(authHeader)
+	s.mu.Lock()
+	s.expiresAt = expiresAt
+	s.mu.Unlock()
+	return expiresAt, true
+}
+
+func (s *collaborationSession) writeLoop(ctx context.Context) {
+	defer s.conn.Close()
+
+	ping := time.NewTicker(collabPingInterval)
+	defer ping.Stop()
+
+	s.mu.Lock()
+	expiry := time.NewTimer(time.Until(s.expiresAt))
+	s.mu.Unlock()
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-s.readerDone:
+			return
+		case <-s.client.Done():
+			s.close(websocket.CloseTryAgainLater, "connection could not keep up")
+			return
+		case payload := <-s.client.Outbound():
+			_ = s.conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if err := s.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ping.C:
+			_ = s.conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-s.reauth:
+			s.mu.Lock()
+			resetTimer(expiry, time.Until(s.expiresAt))
+			s.mu.Unlock()
+		case <-expiry.C:
+			expiresAt, ok := s.revalidate(ctx)
+			if !ok {
+				s.close(websocket.ClosePolicyViolation, "token expired")
+				return
+			}
+			expiry.Reset(time.Until(expiresAt))
+		}
+	}
+}
+
+func (s *collaborationSession) sendError(message string) {
+	s.client.Send(collab.Message{Type: collab.MessageError, Message: message})
+}
+
+func (s *collaborationSession) close(code int, reason string) {
+	closeMessage := websocket.FormatCloseMessage(code, reason)
+	_ = s.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(collabWriteWait))
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// tokenExpiry reads the exp claim without verifying the token; it only
+// schedules re-validation, which goes through the UserServiceAdapter.
+func tokenExpiry(authHeader string) time.Time {
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err == nil {
+		if expiresAt, err := token.Claims.GetExpirationTime(); err == nil && expiresAt != nil {
+			return expiresAt.Time
+		}
+	}
+	return time.Now().Add(collabRevalidateInterval)
+}
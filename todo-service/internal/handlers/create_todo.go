@@ -6,8 +6,6 @@ import (
 
 	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 	response "github.com/olad5/productive-pulse/pkg/utils"
-	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
-	"github.com/olad5/productive-pulse/todo-service/internal/utils"
 )
 
 func (t TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
@@ -15,7 +13,7 @@ func (t TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	ctx, span := t.tracer.Start(ctx, "CreateTodo-handler")
 	defer span.End()
 	if r.Body == nil {
-		response.ErrorResponse(w, appErrors.ErrMissingBody, http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrMissingBody)
 		return
 	}
 
@@ -26,36 +24,28 @@ func (t TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	var request requestDTO
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrInvalidJson, http.StatusBadRequest)
-		return
-	}
-	if request.Text == "" {
-		response.ErrorResponse(w, "Text required", http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrInvalidJson)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, appErrors.ErrUnauthorized)
 		return
 	}
 
 	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, err)
 		return
 	}
 	newTodo, err := t.todoService.CreateTodo(ctx, t.tracer, userId, request.Text)
 	if err != nil {
-		if err == todos.ErrInvalidUserId {
-			response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
-			return
-		}
-		response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
 	response.SuccessResponse(w, "todo created",
-		utils.ToTodoDTO(newTodo))
+		t.presenter.Todo(newTodo))
 	return
 }
@@ -7,7 +7,6 @@ import (
 	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 	response "github.com/olad5/productive-pulse/pkg/utils"
 	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
-	"github.com/olad5/productive-pulse/todo-service/internal/utils"
 )
 
 func (t TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
@@ -18,42 +17,29 @@ func (t TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 	todoId := chi.URLParam(r, "id")
 
 	if todoId == "" {
-		response.ErrorResponse(w, "todo id required", http.StatusBadRequest)
+		response.Error(w, r, todos.ErrInvalidTodoId.WithMessage("todo id required"))
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, appErrors.ErrUnauthorized)
 		return
 	}
 
 	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, err)
 		return
 	}
 
 	todo, err := t.todoService.GetTodo(ctx, t.tracer, userId, todoId)
 	if err != nil {
-		if err == todos.ErrInvalidTodoId {
-			response.ErrorResponse(w, "invalid todoId", http.StatusBadRequest)
-			return
-		}
-		if err == todos.ErrTodoNotFound {
-			response.ErrorResponse(w, err.Error(), http.StatusNotFound)
-			return
-		}
-
-		if err == todos.ErrNotOwnerOfTodo {
-			response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
-			return
-		}
-		response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
 	response.SuccessResponse(w, "todo retreived",
-		utils.ToTodoDTO(todo))
+		t.presenter.Todo(todo))
 	return
 }
@@ -5,8 +5,6 @@ import (
 
 	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 	response "github.com/olad5/productive-pulse/pkg/utils"
-	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
-	"github.com/olad5/productive-pulse/todo-service/internal/utils"
 )
 
 func (t TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
@@ -16,30 +14,22 @@ func (t TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, appErrors.ErrUnauthorized)
 		return
 	}
 	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, err)
 		return
 	}
 
-	foundTodos, err := t.todoService.GetTodos(ctx, t.tracer, userId)
-	if err != nil && err == todos.ErrInvalidUserId {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
-		return
-	}
+	includeSnoozed := r.URL.Query().Get("include_snoozed") == "true"
+
+	foundTodos, err := t.todoService.GetTodos(ctx, t.tracer, userId, includeSnoozed)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	var todosData []map[string]interface{}
-	for _, todo := range foundTodos {
-		todoData := utils.ToTodoDTO(todo)
-		todosData = append(todosData, todoData)
-	}
-
-	response.SuccessResponse(w, "todos retreived", todosData)
+	response.SuccessResponse(w, "todos retreived", t.presenter.Todos(foundTodos))
 }
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/graph"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type GraphQLHandler struct {
+	server      *graph.Server
+	userService user.UserServiceAdapter
+	tracer      trace.Tracer
+}
+
+func NewGraphQLHandler(server *graph.Server, userService user.UserServiceAdapter, tracer trace.Tracer) (*GraphQLHandler, error) {
+	if server == nil {
+		return nil, errors.New("GraphQL server cannot be empty")
+	}
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &GraphQLHandler{server, userService, tracer}, nil
+}
+
+// ServeGraphQL answers queries and mutations with a GraphQL JSON result.
+// Subscriptions are streamed as Server-Sent Events, one next event per
+// result, and need an Accept: text/event-stream request.
+func (g GraphQLHandler) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := g.tracer.Start(ctx, "GraphQL-handler")
+	defer span.End()
+
+	if r.Body == nil {
+		writeJSONError(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	var request graph.Request
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeJSONError(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		writeJSONError(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := g.userService.VerifyUser(ctx, g.tracer, authHeader)
+	if err != nil {
+		writeJSONError(w, r, err)
+		return
+	}
+	ctx = graph.WithUserId(ctx, userId)
+
+	prepared, failure := g.server.Prepare(request)
+	if failure != nil {
+		writeGraphQLResult(w, failure)
+		return
+	}
+	if !prepared.IsSubscription {
+		writeGraphQLResult(w, g.server.Execute(ctx, prepared))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeJSONError(w, r, appErrors.New("event_stream_required", http.StatusNotAcceptable, "subscriptions require Accept: text/event-stream"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	results := g.server.Subscribe(ctx, prepared)
+	// NOTE: the executor may still be sending when the client goes away.
+	defer func() {
+		go func() {
+			for range results {
+			}
+		}()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result, open := <-results:
+			if !open {
+				fmt.Fprint(w, "event: complete\ndata: \n\n")
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				log.Printf("GraphQL failed to encode subscription result: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeGraphQLResult(w http.ResponseWriter, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	response.Error(w, r, err)
+}
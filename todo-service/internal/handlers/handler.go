@@ -12,6 +12,7 @@ type TodoHandler struct {
 	todoService todos.TodoService
 	userService user.UserServiceAdapter
 	tracer      trace.Tracer
+	presenter   todoPresenter
 }
 
 func NewTodoHandler(todoService todos.TodoService, userService user.UserServiceAdapter, tracer trace.Tracer) (*TodoHandler, error) {
@@ -24,5 +25,11 @@ func NewTodoHandler(todoService todos.TodoService, userService user.UserServiceA
 	if tracer == nil {
 		return nil, errors.New("tracer cannot be empty")
 	}
-	return &TodoHandler{todoService, userService, tracer}, nil
+	return &TodoHandler{todoService, userService, tracer, v1TodoPresenter{}}, nil
+}
+
+// V2 returns a copy of the handler that answers with the /v2 todo shape.
+func (t TodoHandler) V2() TodoHandler {
+	t.presenter = v2TodoPresenter{}
+	return t
 }
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+)
+
+// todoPresenter renders todos in the response shape of one API version, so
+// the same handlers can serve every version.
+type todoPresenter interface {
+	Todo(todo domain.Todo) interface{}
+	Todos(todos []domain.Todo) interface{}
+}
+
+type v1TodoPresenter struct{}
+
+func (v1TodoPresenter) Todo(todo domain.Todo) interface{} {
+	return utils.ToTodoDTO(todo)
+}
+
+// NOTE: /v1 has always answered an empty listing with null, and clients
+// depend on it.
+func (v1TodoPresenter) Todos(todos []domain.Todo) interface{} {
+	var todosData []map[string]interface{}
+	for _, todo := range todos {
+		todosData = append(todosData, utils.ToTodoDTO(todo))
+	}
+	return todosData
+}
+
+type v2TodoPresenter struct{}
+
+func (v2TodoPresenter) Todo(todo domain.Todo) interface{} {
+	return utils.ToTodoV2DTO(todo)
+}
+
+func (v2TodoPresenter) Todos(todos []domain.Todo) interface{} {
+	todosData := make([]utils.TodoV2DTO, 0, len(todos))
+	for _, todo := range todos {
+		todosData = append(todosData, utils.ToTodoV2DTO(todo))
+	}
+	return todosData
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ProjectHandler struct {
+	projectService *projects.ProjectService
+	userService    user.UserServiceAdapter
+	tracer         trace.Tracer
+}
+
+func NewProjectHandler(projectService *projects.ProjectService, userService user.UserServiceAdapter, tracer trace.Tracer) (*ProjectHandler, error) {
+	if projectService == nil {
+		return nil, errors.New("ProjectService cannot be empty")
+	}
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &ProjectHandler{projectService, userService, tracer}, nil
+}
+
+func (p ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := p.tracer.Start(ctx, "CreateProject-handler")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type requestDTO struct {
+		Name string `json:"name"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	userId, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	newProject, err := p.projectService.CreateProject(ctx, p.tracer, userId, request.Name)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.SuccessResponse(w, "project created", utils.ToProjectDTO(newProject))
+}
+
+func (p ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := p.tracer.Start(ctx, "GetProjects-handler")
+	defer span.End()
+
+	userId, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	foundProjects, err := p.projectService.GetProjects(ctx, p.tracer, userId)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	projectsData := []map[string]interface{}{}
+	for _, project := range foundProjects {
+		projectsData = append(projectsData, utils.ToProjectDTO(project))
+	}
+	response.SuccessResponse(w, "projects retrieved", projectsData)
+}
+
+func (p ProjectHandler) AddProjectMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := p.tracer.Start(ctx, "AddProjectMember-handler")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type requestDTO struct {
+		UserId string `json:"user_id"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	userId, ok := p.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	member, err := p.projectService.AddProjectMember(ctx, p.tracer, userId, chi.URLParam(r, "id"), request.UserId)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.SuccessResponse(w, "project member added", utils.ToProjectMemberDTO(member))
+}
+
+func (p ProjectHandler) authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return "", false
+	}
+	userId, err := p.userService.VerifyUser(r.Context(), p.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return "", false
+	}
+	return userId, true
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+)
+
+func (t TodoHandler) SnoozeTodo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := t.tracer.Start(ctx, "SnoozeTodo-handler")
+	defer span.End()
+
+	todoId := chi.URLParam(r, "id")
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type requestDTO struct {
+		Until time.Time `json:"until"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	snoozedTodo, err := t.todoService.SnoozeTodo(ctx, t.tracer, userId, todoId, request.Until)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.SuccessResponse(w, "todo snoozed",
+		t.presenter.Todo(snoozedTodo))
+	return
+}
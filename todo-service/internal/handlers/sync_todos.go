@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+)
+
+type syncFieldDTO struct {
+	Value json.RawMessage `json:"value"`
+	HLC   string          `json:"hlc"`
+}
+
+type syncChangeDTO struct {
+	Id        string                  `json:"id"`
+	Deleted   bool                    `json:"deleted"`
+	DeletedAt string                  `json:"deleted_at"`
+	Fields    map[string]syncFieldDTO `json:"fields"`
+}
+
+func (t TodoHandler) SyncTodos(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := t.tracer.Start(ctx, "SyncTodos-handler")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type requestDTO struct {
+		SyncToken string          `json:"sync_token"`
+		Changes   []syncChangeDTO `json:"changes"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	changes := make([]todos.SyncChange, 0, len(request.Changes))
+	for index, changeDTO := range request.Changes {
+		change, err := toSyncChange(changeDTO)
+		if err != nil {
+			response.Error(w, r, appErrors.BadRequest("invalid_change", fmt.Sprintf("change %d: %s", index, err.Error())))
+			return
+		}
+		changes = append(changes, change)
+	}
+
+	result, err := t.todoService.Sync(ctx, t.tracer, userId, request.SyncToken, changes)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	changesData := make([]map[string]interface{}, 0, len(result.Changes))
+	for _, change := range result.Changes {
+		changesData = append(changesData, toSyncChangeDTO(change))
+	}
+	rejectedData := make([]map[string]interface{}, 0, len(result.Rejected))
+	for _, rejection := range result.Rejected {
+		rejectedData = append(rejectedData, map[string]interface{}{
+			"id":      rejection.TodoId,
+			"message": rejection.Err.Error(),
+		})
+	}
+
+	response.SuccessResponse(w, "todos synced", map[string]interface{}{
+		"sync_token": result.Token,
+		"has_more":   result.HasMore,
+		"changes":    changesData,
+		"rejected":   rejectedData,
+	})
+	return
+}
+
+func toSyncChange(changeDTO syncChangeDTO) (todos.SyncChange, error) {
+	todoId, err := uuid.Parse(changeDTO.Id)
+	if err != nil {
+		return todos.SyncChange{}, errors.New("invalid todoId")
+	}
+	change := todos.SyncChange{TodoId: todoId, Deleted: changeDTO.Deleted}
+	if changeDTO.Deleted {
+		change.DeletedAt, err = domain.ParseHLC(changeDTO.DeletedAt)
+		if err != nil {
+			return todos.SyncChange{}, err
+		}
+		return change, nil
+	}
+
+	change.Todo.Clocks = map[domain.TodoField]domain.HLC{}
+	for name, fieldDTO := range changeDTO.Fields {
+		field := domain.TodoField(name)
+		if !domain.IsTodoField(field) {
+			return todos.SyncChange{}, fmt.Errorf("unknown field %q", name)
+		}
+		clock, err := domain.ParseHLC(fieldDTO.HLC)
+		if err != nil {
+			return todos.SyncChange{}, err
+		}
+		if clock.IsZero() {
+			return todos.SyncChange{}, todos.ErrMissingClock
+		}
+		change.Todo.Clocks[field] = clock
+
+		switch field {
+		case domain.TodoFieldText:
+			err = json.Unmarshal(fieldDTO.Value, &change.Todo.Text)
+		case domain.TodoFieldProjectId:
+			err = json.Unmarshal(fieldDTO.Value, &change.Todo.ProjectId)
+		case domain.TodoFieldLabels:
+			err = json.Unmarshal(fieldDTO.Value, &change.Todo.Labels)
+		case domain.TodoFieldSnoozedUntil:
+			change.Todo.SnoozedUntil, err = unmarshalSyncTime(fieldDTO.Value)
+		case domain.TodoFieldCompletedAt:
+			change.Todo.CompletedAt, err = unmarshalSyncTime(fieldDTO.Value)
+		}
+		if err != nil {
+			return todos.SyncChange{}, fmt.Errorf("invalid value for %q", name)
+		}
+	}
+	if len(change.Todo.Clocks) == 0 {
+		return todos.SyncChange{}, errors.New("a change must set at least one field")
+	}
+	return change, nil
+}
+
+func unmarshalSyncTime(value json.RawMessage) (*time.Time, error) {
+	var parsed *time.Time
+	if err := json.Unmarshal(value, &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+func toSyncChangeDTO(change domain.TodoChange) map[string]interface{} {
+	clocks := map[string]string{}
+	for field, clock := range change.Todo.Clocks {
+		clocks[string(field)] = clock.String()
+	}
+	changeData := map[string]interface{}{
+		"id":      change.TodoId,
+		"deleted": change.Deleted,
+		"clocks":  clocks,
+	}
+	if change.Deleted {
+		changeData["deleted_at"] = change.DeletedAt.String()
+	} else {
+		changeData["todo"] = utils.ToTodoDTO(change.Todo)
+	}
+	return changeData
+}
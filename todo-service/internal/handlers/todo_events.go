@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+type TodoEventsHandler struct {
+	hub         *events.StreamHub
+	userService user.UserServiceAdapter
+	tracer      trace.Tracer
+}
+
+func NewTodoEventsHandler(hub *events.StreamHub, userService user.UserServiceAdapter, tracer trace.Tracer) (*TodoEventsHandler, error) {
+	if hub == nil {
+		return nil, errors.New("StreamHub cannot be empty")
+	}
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &TodoEventsHandler{hub, userService, tracer}, nil
+}
+
+// StreamTodoEvents streams the authenticated user's todo events as
+// Server-Sent Events until the client disconnects.
+func (t TodoEventsHandler) StreamTodoEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := t.tracer.Start(ctx, "StreamTodoEvents-handler")
+	defer span.End()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Error(w, r, appErrors.Internal("streaming_unsupported", "streaming unsupported"))
+		return
+	}
+
+	subscription, backlog, resumed := t.hub.Subscribe(userIdInUUID, r.Header.Get("Last-Event-ID"))
+	defer t.hub.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumed {
+		// NOTE: the client's last event fell out of the log, so it must
+		// refetch its todos instead of relying on the stream to catch up.
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range backlog {
+		if err := writeTodoEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, open := <-subscription.Events:
+			if !open {
+				return
+			}
+			if err := writeTodoEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeTodoEvent(w http.ResponseWriter, event domain.TodoEvent) error {
+	data, err := json.Marshal(utils.ToTodoDTO(event.Todo))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -2,15 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
 	"github.com/olad5/productive-pulse/todo-service/internal/utils"
 )
 
 func (t TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, utils.MergePatchContentType) || strings.HasPrefix(contentType, utils.JSONPatchContentType) {
+		t.PatchTodo(w, r)
+		return
+	}
+
 	ctx := r.Context()
 	ctx, span := t.tracer.Start(ctx, "CreateTodo-handler")
 	defer span.End()
@@ -18,7 +29,7 @@ func (t TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	existingTodoId := chi.URLParam(r, "id")
 
 	if r.Body == nil {
-		response.ErrorResponse(w, appErrors.ErrMissingBody, http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrMissingBody)
 		return
 	}
 	type requestDTO struct {
@@ -27,31 +38,78 @@ func (t TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	var request requestDTO
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrInvalidJson, http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrInvalidJson)
 		return
 	}
-	if request.Text == "" {
-		response.ErrorResponse(w, "Text required", http.StatusBadRequest)
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	updatedTodo, err := t.todoService.UpdateTodo(ctx, t.tracer, userId, existingTodoId, request.Text)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.SuccessResponse(w, "todo updated",
+		t.presenter.Todo(updatedTodo))
+	return
+}
+
+// PatchTodo applies an application/merge-patch+json or
+// application/json-patch+json document to the todo's DTO representation.
+func (t TodoHandler) PatchTodo(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := t.tracer.Start(ctx, "PatchTodo-handler")
+	defer span.End()
+
+	existingTodoId := chi.URLParam(r, "id")
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil || len(patch) == 0 {
+		response.Error(w, r, appErrors.ErrMissingBody)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, appErrors.ErrUnauthorized)
 		return
 	}
 	userId, err := t.userService.VerifyUser(ctx, t.tracer, authHeader)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, err)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	patchedTodo, err := t.todoService.PatchTodo(ctx, t.tracer, userId, existingTodoId,
+		func(todo domain.Todo) (domain.Todo, error) {
+			return utils.ApplyTodoPatch(todo, contentType, patch)
+		})
+	// NOTE: a patch that leaves the todo invalid is unprocessable, like a
+	// patch that cannot be applied at all.
+	if errors.Is(err, todos.ErrSnoozeInPast) || errors.Is(err, todos.ErrEmptyTodoText) {
+		response.Error(w, r, appErrors.From(err).WithStatus(http.StatusUnprocessableEntity))
 		return
 	}
-	updatedTodo, err := t.todoService.UpdateTodo(ctx, t.tracer, userId, existingTodoId, request.Text)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
 	response.SuccessResponse(w, "todo updated",
-		utils.ToTodoDTO(updatedTodo))
+		t.presenter.Todo(patchedTodo))
 	return
 }
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type UsageHandler struct {
+	quotaService *quotas.QuotaService
+	userService  user.UserServiceAdapter
+	tracer       trace.Tracer
+}
+
+func NewUsageHandler(quotaService *quotas.QuotaService, userService user.UserServiceAdapter, tracer trace.Tracer) (*UsageHandler, error) {
+	if quotaService == nil {
+		return nil, errors.New("QuotaService cannot be empty")
+	}
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &UsageHandler{quotaService, userService, tracer}, nil
+}
+
+func (u UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := u.tracer.Start(ctx, "GetUsage-handler")
+	defer span.End()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return
+	}
+	userId, err := u.userService.VerifyUser(ctx, u.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	usage, plan, err := u.quotaService.GetUsage(ctx, u.tracer, userId)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrSomethingWentWrong)
+		return
+	}
+
+	response.SuccessResponse(w, "usage retrieved", utils.ToUsageDTO(usage, plan))
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/webhooks"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebhookHandler struct {
+	webhookService *webhooks.WebhookService
+	userService    user.UserServiceAdapter
+	tracer         trace.Tracer
+}
+
+func NewWebhookHandler(webhookService *webhooks.WebhookService, userService user.UserServiceAdapter, tracer trace.Tracer) (*WebhookHandler, error) {
+	if webhookService == nil {
+		return nil, errors.New("WebhookService cannot be empty")
+	}
+	if userService == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &WebhookHandler{webhookService, userService, tracer}, nil
+}
+
+func (wh WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := wh.tracer.Start(ctx, "CreateWebhook-handler")
+	defer span.End()
+
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type requestDTO struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret"`
+		EventTypes []string `json:"event_types"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	userId, ok := wh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	newWebhook, err := wh.webhookService.CreateWebhook(ctx, wh.tracer, userId, request.URL, request.Secret, request.EventTypes)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	webhookData := utils.ToWebhookDTO(newWebhook)
+	// NOTE: the secret is only ever returned when the webhook is created.
+	webhookData["secret"] = newWebhook.Secret
+	response.SuccessResponse(w, "webhook created", webhookData)
+}
+
+func (wh WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := wh.tracer.Start(ctx, "GetWebhooks-handler")
+	defer span.End()
+
+	userId, ok := wh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	foundWebhooks, err := wh.webhookService.GetWebhooks(ctx, wh.tracer, userId)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	webhooksData := []map[string]interface{}{}
+	for _, webhook := range foundWebhooks {
+		webhooksData = append(webhooksData, utils.ToWebhookDTO(webhook))
+	}
+	response.SuccessResponse(w, "webhooks retreived", webhooksData)
+}
+
+func (wh WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := wh.tracer.Start(ctx, "DeleteWebhook-handler")
+	defer span.End()
+
+	userId, ok := wh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	err := wh.webhookService.DeleteWebhook(ctx, wh.tracer, userId, chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.SuccessResponse(w, "webhook deleted", nil)
+}
+
+func (wh WebhookHandler) EnableWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := wh.tracer.Start(ctx, "EnableWebhook-handler")
+	defer span.End()
+
+	userId, ok := wh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := wh.webhookService.EnableWebhook(ctx, wh.tracer, userId, chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.SuccessResponse(w, "webhook enabled", utils.ToWebhookDTO(webhook))
+}
+
+func (wh WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := wh.tracer.Start(ctx, "GetDeliveries-handler")
+	defer span.End()
+
+	userId, ok := wh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := wh.webhookService.GetDeliveries(ctx, wh.tracer, userId, chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	deliveriesData := []map[string]interface{}{}
+	for _, delivery := range deliveries {
+		deliveriesData = append(deliveriesData, utils.ToWebhookDeliveryDTO(delivery))
+	}
+	response.SuccessResponse(w, "webhook deliveries retreived", deliveriesData)
+}
+
+func (wh WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := wh.tracer.Start(ctx, "ReplayDelivery-handler")
+	defer span.End()
+
+	userId, ok := wh.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := wh.webhookService.ReplayDelivery(ctx, wh.tracer, userId, chi.URLParam(r, "id"), chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+	response.SuccessResponse(w, "webhook delivery replayed", utils.ToWebhookDeliveryDTO(delivery))
+}
+
+func (wh WebhookHandler) authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		response.Error(w, r, appErrors.ErrUnauthorized)
+		return "", false
+	}
+	userId, err := wh.userService.VerifyUser(r.Context(), wh.tracer, authHeader)
+	if err != nil {
+		response.Error(w, r, err)
+		return "", false
+	}
+	return userId, true
+}
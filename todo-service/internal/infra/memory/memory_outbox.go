@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+// dispatchedEventRetention is how long relayed events stay in the outbox
+// before they are pruned.
+var dispatchedEventRetention = 7 * 24 * time.Hour
+
+type outboxRecord struct {
+	entry        domain.OutboxEntry
+	dispatched   bool
+	dispatchedAt time.Time
+	parked       bool
+}
+
+func (m *MemoryRepository) AppendEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	return m.store.write(ctx, func(d *data) error {
+		for _, event := range events {
+			d.outboxSequences[event.TodoId]++
+			event.Todo = cloneTodo(event.Todo)
+			d.outbox = append(d.outbox, outboxRecord{entry: domain.OutboxEntry{
+				Event:    event,
+				Sequence: d.outboxSequences[event.TodoId],
+			}})
+		}
+		return nil
+	})
+}
+
+func (m *MemoryRepository) GetPendingEvents(ctx context.Context, limit int) ([]domain.OutboxEntry, error) {
+	var entries []domain.OutboxEntry
+	err := m.store.write(ctx, func(d *data) error {
+		// NOTE: the relay prunes what was dispatched long enough ago each
+		// time it polls.
+		cutoff := time.Now().Add(-dispatchedEventRetention)
+		kept := d.outbox[:0]
+		for _, record := range d.outbox {
+			if record.dispatched && record.dispatchedAt.Before(cutoff) {
+				continue
+			}
+			kept = append(kept, record)
+			if !record.dispatched && !record.parked {
+				entries = append(entries, record.entry)
+			}
+		}
+		d.outbox = kept
+		return nil
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Event.OccurredAt.Before(entries[j].Event.OccurredAt)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, err
+}
+
+func (m *MemoryRepository) MarkEventDispatched(ctx context.Context, eventId uuid.UUID) error {
+	return m.updateEvent(ctx, eventId, func(record *outboxRecord) {
+		record.dispatched = true
+		record.dispatchedAt = time.Now()
+		record.entry.Attempts++
+	})
+}
+
+func (m *MemoryRepository) MarkEventFailed(ctx context.Context, eventId uuid.UUID, cause error) error {
+	return m.updateEvent(ctx, eventId, func(record *outboxRecord) {
+		record.entry.LastError = cause.Error()
+		record.entry.Attempts++
+	})
+}
+
+func (m *MemoryRepository) MarkEventParked(ctx context.Context, eventId uuid.UUID, cause error) error {
+	return m.updateEvent(ctx, eventId, func(record *outboxRecord) {
+		record.parked = true
+		record.entry.LastError = cause.Error()
+		record.entry.Attempts++
+	})
+}
+
+func (m *MemoryRepository) updateEvent(ctx context.Context, eventId uuid.UUID, update func(record *outboxRecord)) error {
+	return m.store.write(ctx, func(d *data) error {
+		for i := range d.outbox {
+			if d.outbox[i].entry.Event.ID == eventId {
+				update(&d.outbox[i])
+				return nil
+			}
+		}
+		return nil
+	})
+}
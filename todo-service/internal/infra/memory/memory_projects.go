@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+func (m *MemoryRepository) CreateProject(ctx context.Context, project domain.Project) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.projects[project.ID]; ok {
+			return fmt.Errorf("failed to persist project: duplicate id %s", project.ID)
+		}
+		d.projects[project.ID] = project
+		return nil
+	})
+}
+
+func (m *MemoryRepository) GetProject(ctx context.Context, projectId uuid.UUID) (domain.Project, error) {
+	var (
+		project domain.Project
+		ok      bool
+	)
+	m.store.read(ctx, func(d *data) {
+		project, ok = d.projects[projectId]
+	})
+	if !ok {
+		return domain.Project{}, infra.ErrRecordNotFound
+	}
+	return project, nil
+}
+
+func (m *MemoryRepository) GetProjects(ctx context.Context, userId uuid.UUID) ([]domain.Project, error) {
+	projects := []domain.Project{}
+	m.store.read(ctx, func(d *data) {
+		for _, project := range d.projects {
+			if project.UserId == userId {
+				projects = append(projects, project)
+			}
+		}
+	})
+	sort.SliceStable(projects, func(i, j int) bool {
+		return projects[i].CreatedAt.Before(projects[j].CreatedAt)
+	})
+	return projects, nil
+}
+
+type projectMemberKey struct {
+	projectId uuid.UUID
+	userId    uuid.UUID
+}
+
+func (m *MemoryRepository) AddProjectMember(ctx context.Context, member domain.ProjectMember) error {
+	return m.store.write(ctx, func(d *data) error {
+		key := projectMemberKey{member.ProjectId, member.UserId}
+		if _, ok := d.projectMembers[key]; !ok {
+			d.projectMembers[key] = member
+		}
+		return nil
+	})
+}
+
+func (m *MemoryRepository) IsProjectMember(ctx context.Context, projectId, userId uuid.UUID) (bool, error) {
+	var ok bool
+	m.store.read(ctx, func(d *data) {
+		_, ok = d.projectMembers[projectMemberKey{projectId, userId}]
+	})
+	return ok, nil
+}
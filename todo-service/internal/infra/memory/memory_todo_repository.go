@@ -0,0 +1,368 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+// MemoryRepository keeps todos in the memory of a single instance, for tests
+// and local development. Nothing survives a restart.
+type MemoryRepository struct {
+	store *store
+}
+
+// store is shared by a MemoryRepository and the MemoryWebhookRepository built
+// on it, as the collections of one database are.
+type store struct {
+	mu   sync.Mutex
+	data data
+}
+
+type data struct {
+	todos           map[uuid.UUID]domain.Todo
+	projects        map[uuid.UUID]domain.Project
+	projectMembers  map[projectMemberKey]domain.ProjectMember
+	changes         map[uuid.UUID]domain.TodoChange
+	changeSequences map[uuid.UUID]int64
+	outbox          []outboxRecord
+	outboxSequences map[uuid.UUID]int64
+	usage           map[uuid.UUID]usageRecord
+	apiCalls        map[apiCallsKey]int64
+	webhooks        map[uuid.UUID]domain.Webhook
+	deliveries      map[uuid.UUID]domain.WebhookDelivery
+}
+
+func NewMemoryRepo() *MemoryRepository {
+	return &MemoryRepository{store: &store{data: data{
+		todos:           map[uuid.UUID]domain.Todo{},
+		projects:        map[uuid.UUID]domain.Project{},
+		projectMembers:  map[projectMemberKey]domain.ProjectMember{},
+		changes:         map[uuid.UUID]domain.TodoChange{},
+		changeSequences: map[uuid.UUID]int64{},
+		outboxSequences: map[uuid.UUID]int64{},
+		usage:           map[uuid.UUID]usageRecord{},
+		apiCalls:        map[apiCallsKey]int64{},
+		webhooks:        map[uuid.UUID]domain.Webhook{},
+		deliveries:      map[uuid.UUID]domain.WebhookDelivery{},
+	}}}
+}
+
+// clone copies what a transaction may change. Stored values are replaced
+// rather than changed in place, so copying the containers is enough.
+func (d data) clone() data {
+	outbox := make([]outboxRecord, len(d.outbox))
+	copy(outbox, d.outbox)
+	return data{
+		todos:           cloneMap(d.todos),
+		projects:        cloneMap(d.projects),
+		projectMembers:  cloneMap(d.projectMembers),
+		changes:         cloneMap(d.changes),
+		changeSequences: cloneMap(d.changeSequences),
+		outbox:          outbox,
+		outboxSequences: cloneMap(d.outboxSequences),
+		usage:           cloneMap(d.usage),
+		apiCalls:        cloneMap(d.apiCalls),
+		webhooks:        cloneMap(d.webhooks),
+		deliveries:      cloneMap(d.deliveries),
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	clone := make(map[K]V, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+	return clone
+}
+
+type txKey struct{}
+
+func (s *store) inTransaction(ctx context.Context) bool {
+	owner, _ := ctx.Value(txKey{}).(*store)
+	return owner == s
+}
+
+// read and write run fn under the lock, which a running transaction
+// already holds. A write must fail before it changes d, so that it needs no
+// transaction of its own.
+func (s *store) read(ctx context.Context, fn func(d *data)) {
+	_ = s.write(ctx, func(d *data) error {
+		fn(d)
+		return nil
+	})
+}
+
+func (s *store) write(ctx context.Context, fn func(d *data) error) error {
+	if s.inTransaction(ctx) {
+		return fn(&s.data)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&s.data)
+}
+
+// transaction holds the lock for the whole of fn, so transactions run one at
+// a time, and restores what fn found if it fails.
+// NOTE: a transaction copies the whole state to roll back to, which is fine
+// for the amounts of data of tests and local development.
+func (s *store) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTransaction(ctx) {
+		return fn(ctx)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.data.clone()
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		s.data = snapshot
+		return err
+	}
+	return nil
+}
+
+func (m *MemoryRepository) CreateTodo(ctx context.Context, todo domain.Todo) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.todos[todo.ID]; ok {
+			return fmt.Errorf("failed to persist todo: duplicate id %s", todo.ID)
+		}
+		d.todos[todo.ID] = cloneTodo(todo)
+		d.adjustUsage(todo.UserId, domain.QuotaTodos, 1)
+		d.recordChange(todo, false, domain.HLC{})
+		return nil
+	})
+}
+
+func (m *MemoryRepository) GetTodo(ctx context.Context, userId, todoId uuid.UUID) (domain.Todo, error) {
+	var (
+		todo domain.Todo
+		ok   bool
+	)
+	m.store.read(ctx, func(d *data) {
+		todo, ok = d.todos[todoId]
+	})
+	if !ok {
+		return domain.Todo{}, infra.ErrRecordNotFound
+	}
+	if todo.UserId != userId {
+		return domain.Todo{}, infra.ErrNotOwnerOfTodo
+	}
+	return cloneTodo(todo), nil
+}
+
+func (m *MemoryRepository) GetTodos(ctx context.Context, userId uuid.UUID, includeSnoozed bool) ([]domain.Todo, error) {
+	now := time.Now()
+	return m.findTodos(ctx, func(todo domain.Todo) bool {
+		return todo.UserId == userId && (includeSnoozed || !todo.IsSnoozed(now))
+	}), nil
+}
+
+func (m *MemoryRepository) GetTodosByIds(ctx context.Context, userId uuid.UUID, todoIds []uuid.UUID) ([]domain.Todo, error) {
+	wanted := make(map[uuid.UUID]bool, len(todoIds))
+	for _, todoId := range todoIds {
+		wanted[todoId] = true
+	}
+	todos := m.findTodos(ctx, func(todo domain.Todo) bool {
+		return wanted[todo.ID] && todo.UserId == userId
+	})
+	if todos == nil {
+		return []domain.Todo{}, nil
+	}
+	return todos, nil
+}
+
+func (m *MemoryRepository) UpdateTodo(ctx context.Context, todo domain.Todo, fields ...domain.TodoField) error {
+	for _, field := range fields {
+		if !domain.IsTodoField(field) {
+			return fmt.Errorf("unknown todo field: %s", field)
+		}
+	}
+	return m.store.write(ctx, func(d *data) error {
+		stored, ok := d.todos[todo.ID]
+		if !ok {
+			return infra.ErrRecordNotFound
+		}
+		stored = cloneTodo(stored)
+		stored.UpdatedAt = todo.UpdatedAt
+		for _, field := range fields {
+			switch field {
+			case domain.TodoFieldText:
+				stored.Text = todo.Text
+			case domain.TodoFieldProjectId:
+				stored.ProjectId = todo.ProjectId
+			case domain.TodoFieldLabels:
+				stored.Labels = cloneLabels(todo.Labels)
+			case domain.TodoFieldSnoozedUntil:
+				stored.SnoozedUntil = cloneTime(todo.SnoozedUntil)
+			case domain.TodoFieldCompletedAt:
+				stored.CompletedAt = cloneTime(todo.CompletedAt)
+			}
+			if clock, ok := todo.Clocks[field]; ok {
+				if stored.Clocks == nil {
+					stored.Clocks = map[domain.TodoField]domain.HLC{}
+				}
+				stored.Clocks[field] = clock
+			}
+		}
+		d.todos[todo.ID] = stored
+		d.recordChange(stored, false, domain.HLC{})
+		return nil
+	})
+}
+
+func (m *MemoryRepository) DeleteTodo(ctx context.Context, todo domain.Todo, deletedAt domain.HLC) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.todos[todo.ID]; !ok {
+			return infra.ErrRecordNotFound
+		}
+		delete(d.todos, todo.ID)
+		d.adjustUsage(todo.UserId, domain.QuotaTodos, -1)
+		d.recordChange(todo, true, deletedAt)
+		return nil
+	})
+}
+
+func (m *MemoryRepository) GetDueSnoozedTodos(ctx context.Context, now time.Time) ([]domain.Todo, error) {
+	return m.findTodos(ctx, func(todo domain.Todo) bool {
+		return todo.SnoozedUntil != nil && !todo.SnoozedUntil.After(now)
+	}), nil
+}
+
+func (m *MemoryRepository) UnsnoozeTodo(ctx context.Context, todo domain.Todo, now time.Time, at domain.HLC) (bool, error) {
+	var unsnoozed bool
+	err := m.store.write(ctx, func(d *data) error {
+		// NOTE: the snoozed_until match guards against a todo that was
+		// re-snoozed after it was read.
+		stored, ok := d.todos[todo.ID]
+		if !ok || !equalTimes(stored.SnoozedUntil, todo.SnoozedUntil) {
+			unsnoozed = false
+			return nil
+		}
+		stored = cloneTodo(stored)
+		stored.SnoozedUntil = nil
+		stored.UpdatedAt = now
+		if stored.Clocks == nil {
+			stored.Clocks = map[domain.TodoField]domain.HLC{}
+		}
+		stored.Clocks[domain.TodoFieldSnoozedUntil] = at
+		d.todos[todo.ID] = stored
+		d.recordChange(stored, false, domain.HLC{})
+		unsnoozed = true
+		return nil
+	})
+	return unsnoozed, err
+}
+
+// findTodos returns the todos matching match, oldest first.
+func (m *MemoryRepository) findTodos(ctx context.Context, match func(todo domain.Todo) bool) []domain.Todo {
+	var todos []domain.Todo
+	m.store.read(ctx, func(d *data) {
+		for _, todo := range d.todos {
+			if match(todo) {
+				todos = append(todos, cloneTodo(todo))
+			}
+		}
+	})
+	sort.SliceStable(todos, func(i, j int) bool {
+		return todos[i].CreatedAt.Before(todos[j].CreatedAt)
+	})
+	return todos
+}
+
+func (m *MemoryRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.store.transaction(ctx, fn)
+}
+
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+// recordChange must run inside the transaction of the write it records.
+func (d *data) recordChange(todo domain.Todo, deleted bool, deletedAt domain.HLC) {
+	d.changeSequences[todo.UserId]++
+	d.changes[todo.ID] = domain.TodoChange{
+		TodoId:    todo.ID,
+		UserId:    todo.UserId,
+		Sequence:  d.changeSequences[todo.UserId],
+		Todo:      cloneTodo(todo),
+		Deleted:   deleted,
+		DeletedAt: deletedAt,
+	}
+}
+
+func (m *MemoryRepository) GetChangesSince(ctx context.Context, userId uuid.UUID, sequence int64, limit int) ([]domain.TodoChange, error) {
+	changes := []domain.TodoChange{}
+	m.store.read(ctx, func(d *data) {
+		for _, change := range d.changes {
+			if change.UserId == userId && change.Sequence > sequence {
+				changes = append(changes, change)
+			}
+		}
+	})
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Sequence < changes[j].Sequence
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	for i := range changes {
+		changes[i].Todo = cloneTodo(changes[i].Todo)
+	}
+	return changes, nil
+}
+
+func (m *MemoryRepository) GetChange(ctx context.Context, todoId uuid.UUID) (domain.TodoChange, error) {
+	var (
+		change domain.TodoChange
+		ok     bool
+	)
+	m.store.read(ctx, func(d *data) {
+		change, ok = d.changes[todoId]
+	})
+	if !ok {
+		return domain.TodoChange{}, infra.ErrRecordNotFound
+	}
+	change.Todo = cloneTodo(change.Todo)
+	return change, nil
+}
+
+// cloneTodo keeps callers from changing a stored todo through its pointers
+// and clocks.
+func cloneTodo(todo domain.Todo) domain.Todo {
+	todo.Labels = cloneLabels(todo.Labels)
+	todo.SnoozedUntil = cloneTime(todo.SnoozedUntil)
+	todo.CompletedAt = cloneTime(todo.CompletedAt)
+	if todo.Clocks != nil {
+		todo.Clocks = cloneMap(todo.Clocks)
+	}
+	return todo
+}
+
+func cloneLabels(labels []string) []string {
+	return append([]string{}, labels...)
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+type usageRecord struct {
+	plan string
+	used map[domain.QuotaResource]int64
+}
+
+type apiCallsKey struct {
+	userId uuid.UUID
+	period time.Time
+}
+
+// adjustUsage must run inside the transaction of the write it counts, so the
+// counter never drifts from what the user holds.
+func (d *data) adjustUsage(userId uuid.UUID, resource domain.QuotaResource, delta int64) {
+	usage := d.usage[userId]
+	used := make(map[domain.QuotaResource]int64, len(usage.used)+1)
+	for counted, amount := range usage.used {
+		used[counted] = amount
+	}
+	used[resource] += delta
+	usage.used = used
+	d.usage[userId] = usage
+}
+
+func (m *MemoryRepository) GetUsage(ctx context.Context, userId uuid.UUID, period time.Time) (domain.Usage, error) {
+	usage := domain.Usage{UserId: userId, Period: period, Used: map[domain.QuotaResource]int64{}}
+	m.store.read(ctx, func(d *data) {
+		record := d.usage[userId]
+		usage.Plan = record.plan
+		for _, resource := range domain.QuotaResources {
+			usage.Used[resource] = record.used[resource]
+		}
+		usage.Used[domain.QuotaAPICalls] = d.apiCalls[apiCallsKey{userId, period.UTC()}]
+	})
+	return usage, nil
+}
+
+func (m *MemoryRepository) RecordAPICall(ctx context.Context, userId uuid.UUID, period time.Time, limit int64) (bool, error) {
+	var recorded bool
+	err := m.store.write(ctx, func(d *data) error {
+		key := apiCallsKey{userId, period.UTC()}
+		if limit >= 0 && d.apiCalls[key] >= limit {
+			return nil
+		}
+		d.apiCalls[key]++
+		recorded = true
+		return nil
+	})
+	return recorded, err
+}
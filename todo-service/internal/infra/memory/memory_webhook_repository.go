@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+const maxDeliveriesListed = 100
+
+type MemoryWebhookRepository struct {
+	store *store
+}
+
+// NewMemoryWebhookRepo keeps webhooks next to the todos of todoRepo, which
+// counts them towards the quotas of their owners.
+func NewMemoryWebhookRepo(todoRepo *MemoryRepository) (*MemoryWebhookRepository, error) {
+	if todoRepo == nil {
+		return &MemoryWebhookRepository{}, errors.New("todoRepo cannot be empty")
+	}
+	return &MemoryWebhookRepository{store: todoRepo.store}, nil
+}
+
+func (m *MemoryWebhookRepository) Close(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryWebhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.webhooks[webhook.ID]; ok {
+			return fmt.Errorf("failed to persist webhook: duplicate id %s", webhook.ID)
+		}
+		d.webhooks[webhook.ID] = cloneWebhook(webhook)
+		d.adjustUsage(webhook.UserId, domain.QuotaWebhooks, 1)
+		return nil
+	})
+}
+
+func (m *MemoryWebhookRepository) UpdateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.webhooks[webhook.ID]; ok {
+			d.webhooks[webhook.ID] = cloneWebhook(webhook)
+		}
+		return nil
+	})
+}
+
+func (m *MemoryWebhookRepository) DeleteWebhook(ctx context.Context, webhookId uuid.UUID) error {
+	return m.store.write(ctx, func(d *data) error {
+		deleted, ok := d.webhooks[webhookId]
+		if !ok {
+			return infra.ErrRecordNotFound
+		}
+		delete(d.webhooks, webhookId)
+		for id, delivery := range d.deliveries {
+			if delivery.WebhookId == webhookId {
+				delete(d.deliveries, id)
+			}
+		}
+		d.adjustUsage(deleted.UserId, domain.QuotaWebhooks, -1)
+		return nil
+	})
+}
+
+func (m *MemoryWebhookRepository) GetWebhook(ctx context.Context, userId, webhookId uuid.UUID) (domain.Webhook, error) {
+	var (
+		webhook domain.Webhook
+		ok      bool
+	)
+	m.store.read(ctx, func(d *data) {
+		webhook, ok = d.webhooks[webhookId]
+	})
+	if !ok {
+		return domain.Webhook{}, infra.ErrRecordNotFound
+	}
+	if webhook.UserId != userId {
+		return domain.Webhook{}, infra.ErrNotOwnerOfWebhook
+	}
+	return cloneWebhook(webhook), nil
+}
+
+func (m *MemoryWebhookRepository) GetWebhooks(ctx context.Context, userId uuid.UUID) ([]domain.Webhook, error) {
+	return m.findWebhooks(ctx, func(webhook domain.Webhook) bool {
+		return webhook.UserId == userId
+	}), nil
+}
+
+func (m *MemoryWebhookRepository) GetWebhooksForEvent(ctx context.Context, userId uuid.UUID, eventType domain.TodoEventType) ([]domain.Webhook, error) {
+	return m.findWebhooks(ctx, func(webhook domain.Webhook) bool {
+		return webhook.UserId == userId && !webhook.Disabled && webhook.Subscribes(eventType)
+	}), nil
+}
+
+func (m *MemoryWebhookRepository) GetWebhookForDelivery(ctx context.Context, webhookId uuid.UUID) (domain.Webhook, error) {
+	var (
+		webhook domain.Webhook
+		ok      bool
+	)
+	m.store.read(ctx, func(d *data) {
+		webhook, ok = d.webhooks[webhookId]
+	})
+	if !ok {
+		return domain.Webhook{}, infra.ErrRecordNotFound
+	}
+	return cloneWebhook(webhook), nil
+}
+
+// findWebhooks returns the webhooks matching match, oldest first.
+func (m *MemoryWebhookRepository) findWebhooks(ctx context.Context, match func(webhook domain.Webhook) bool) []domain.Webhook {
+	var webhooks []domain.Webhook
+	m.store.read(ctx, func(d *data) {
+		for _, webhook := range d.webhooks {
+			if match(webhook) {
+				webhooks = append(webhooks, cloneWebhook(webhook))
+			}
+		}
+	})
+	sort.SliceStable(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks
+}
+
+func (m *MemoryWebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.deliveries[delivery.ID]; ok {
+			return fmt.Errorf("failed to persist webhook delivery %s: %w", delivery.ID, infra.ErrDuplicateDelivery)
+		}
+		d.deliveries[delivery.ID] = cloneDelivery(delivery)
+		return nil
+	})
+}
+
+func (m *MemoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	return m.store.write(ctx, func(d *data) error {
+		if _, ok := d.deliveries[delivery.ID]; ok {
+			d.deliveries[delivery.ID] = cloneDelivery(delivery)
+		}
+		return nil
+	})
+}
+
+func (m *MemoryWebhookRepository) GetDelivery(ctx context.Context, webhookId, deliveryId uuid.UUID) (domain.WebhookDelivery, error) {
+	var (
+		delivery domain.WebhookDelivery
+		ok       bool
+	)
+	m.store.read(ctx, func(d *data) {
+		delivery, ok = d.deliveries[deliveryId]
+	})
+	if !ok || delivery.WebhookId != webhookId {
+		return domain.WebhookDelivery{}, infra.ErrRecordNotFound
+	}
+	return cloneDelivery(delivery), nil
+}
+
+func (m *MemoryWebhookRepository) GetDeliveries(ctx context.Context, webhookId uuid.UUID) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	m.store.read(ctx, func(d *data) {
+		for _, delivery := range d.deliveries {
+			if delivery.WebhookId == webhookId {
+				deliveries = append(deliveries, cloneDelivery(delivery))
+			}
+		}
+	})
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if len(deliveries) > maxDeliveriesListed {
+		deliveries = deliveries[:maxDeliveriesListed]
+	}
+	return deliveries, nil
+}
+
+func (m *MemoryWebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	m.store.read(ctx, func(d *data) {
+		for _, delivery := range d.deliveries {
+			if delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+				deliveries = append(deliveries, cloneDelivery(delivery))
+			}
+		}
+	})
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(*deliveries[j].NextAttemptAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func cloneWebhook(webhook domain.Webhook) domain.Webhook {
+	webhook.EventTypes = append([]domain.TodoEventType(nil), webhook.EventTypes...)
+	return webhook
+}
+
+func cloneDelivery(delivery domain.WebhookDelivery) domain.WebhookDelivery {
+	delivery.Payload = append([]byte(nil), delivery.Payload...)
+	delivery.DeliveredAt = cloneTime(delivery.DeliveredAt)
+	delivery.NextAttemptAt = cloneTime(delivery.NextAttemptAt)
+	return delivery
+}
@@ -0,0 +1,104 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recordChange must run inside the transaction of the write it records.
+func (m *MongoRepository) recordChange(ctx context.Context, todo domain.Todo, deleted bool, deletedAt domain.HLC) error {
+	// NOTE: as with the outbox, bumping a per-user counter makes concurrent
+	// writes of one user conflict, so a sequence is never committed behind
+	// one a client has already synced past.
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+	err := m.changeSequences.FindOneAndUpdate(ctx,
+		bson.M{"_id": todo.UserId},
+		bson.M{"$inc": bson.M{"sequence": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return fmt.Errorf("failed to sequence todo change: %w", err)
+	}
+
+	_, err = m.changes.ReplaceOne(ctx,
+		bson.M{"_id": todo.ID},
+		mongoTodoChange{
+			TodoId:    todo.ID,
+			UserId:    todo.UserId,
+			Sequence:  counter.Sequence,
+			Todo:      toMongoTodo(todo),
+			Deleted:   deleted,
+			DeletedAt: deletedAt.String(),
+		},
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist todo change: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoRepository) GetChangesSince(ctx context.Context, userId uuid.UUID, sequence int64, limit int) ([]domain.TodoChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "sequence", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := m.changes.Find(ctx, bson.M{"user_id": userId, "sequence": bson.M{"$gt": sequence}}, opts)
+	if err != nil {
+		return []domain.TodoChange{}, errors.New("errors getting todo changes")
+	}
+	defer cursor.Close(ctx)
+	var mongoChanges []mongoTodoChange
+	if err = cursor.All(ctx, &mongoChanges); err != nil {
+		return []domain.TodoChange{}, errors.New("errors getting todo changes")
+	}
+	changes := []domain.TodoChange{}
+	for _, mongoChange := range mongoChanges {
+		changes = append(changes, toTodoChange(mongoChange))
+	}
+	return changes, nil
+}
+
+func (m *MongoRepository) GetChange(ctx context.Context, todoId uuid.UUID) (domain.TodoChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var change mongoTodoChange
+	err := m.changes.FindOne(ctx, bson.M{"_id": todoId}).Decode(&change)
+	if err != nil {
+		return domain.TodoChange{}, infra.ErrRecordNotFound
+	}
+	return toTodoChange(change), nil
+}
+
+type mongoTodoChange struct {
+	TodoId    uuid.UUID `bson:"_id"`
+	UserId    uuid.UUID `bson:"user_id"`
+	Sequence  int64     `bson:"sequence"`
+	Todo      mongoTodo `bson:"todo"`
+	Deleted   bool      `bson:"deleted"`
+	DeletedAt string    `bson:"deleted_at,omitempty"`
+}
+
+func toTodoChange(m mongoTodoChange) domain.TodoChange {
+	deletedAt, _ := domain.ParseHLC(m.DeletedAt)
+	return domain.TodoChange{
+		TodoId:    m.TodoId,
+		UserId:    m.UserId,
+		Sequence:  m.Sequence,
+		Todo:      toTodo(m.Todo),
+		Deleted:   m.Deleted,
+		DeletedAt: deletedAt,
+	}
+}
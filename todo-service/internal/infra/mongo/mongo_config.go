@@ -0,0 +1,134 @@
+package mongo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olad5/productive-pulse/config"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+const (
+	defaultDatabaseName = "todo-service"
+	defaultTimeout      = 5 * time.Second
+)
+
+// MongoConfig is how the repositories reach their database.
+type MongoConfig struct {
+	ConnectionString string
+	config.MongoConfigurations
+}
+
+func NewMongoConfig(configurations *config.Configurations) MongoConfig {
+	return MongoConfig{
+		ConnectionString:    configurations.TodoServiceDBConnectionString,
+		MongoConfigurations: configurations.TodoServiceMongo,
+	}
+}
+
+// Database is the name of the database of the repositories.
+func (c MongoConfig) Database() string {
+	if c.DatabaseName == "" {
+		return defaultDatabaseName
+	}
+	return c.DatabaseName
+}
+
+func (c MongoConfig) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return c.Timeout
+}
+
+func (c MongoConfig) collection(database *mongo.Database, name string) *mongo.Collection {
+	return database.Collection(c.CollectionPrefix + name)
+}
+
+// connect returns the database of c on a client of its own, which the caller
+// disconnects once done.
+func connect(ctx context.Context, monitor *event.CommandMonitor, c MongoConfig) (*mongo.Database, error) {
+	if c.ConnectionString == "" {
+		return nil, errors.New("connectionString cannot be empty")
+	}
+
+	opts := options.Client().ApplyURI(c.ConnectionString).SetRegistry(mongoRegistry)
+	opts.Monitor = monitor
+	if c.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(c.ConnectTimeout)
+	}
+	if c.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	if c.MinPoolSize > 0 {
+		opts.SetMinPoolSize(c.MinPoolSize)
+	}
+	if c.ReadConcern != "" {
+		opts.SetReadConcern(readconcern.New(readconcern.Level(c.ReadConcern)))
+	}
+	if c.WriteConcern != "" {
+		writeConcern, err := parseWriteConcern(c.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetWriteConcern(writeConcern)
+	}
+	if c.TLS || c.TLSCAFile != "" || c.TLSCertificateKeyFile != "" {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a mongo client: %w", err)
+	}
+	return client.Database(c.Database()), nil
+}
+
+func parseWriteConcern(value string) (*writeconcern.WriteConcern, error) {
+	if value == "majority" {
+		return writeconcern.New(writeconcern.WMajority()), nil
+	}
+	nodes, err := strconv.Atoi(value)
+	if err != nil || nodes < 0 {
+		return nil, fmt.Errorf("invalid write concern %q, want \"majority\" or a number of nodes", value)
+	}
+	return writeconcern.New(writeconcern.W(nodes)), nil
+}
+
+func (c MongoConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.TLSInsecure}
+	if c.TLSCAFile != "" {
+		ca, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the mongo CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("the mongo CA file holds no PEM certificate")
+		}
+	}
+	if c.TLSCertificateKeyFile != "" {
+		// NOTE: like tlsCertificateKeyFile in a connection string, the file
+		// holds both the certificate and its key.
+		certificate, err := tls.LoadX509KeyPair(c.TLSCertificateKeyFile, c.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the mongo certificate key file: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,224 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexSpec declares an index a repository relies on. EnsureIndexes creates
+// the indexes that are missing and rebuilds those whose options changed.
+type indexSpec struct {
+	collection string
+	keys       bson.D
+	// ttl expires documents that long after the time in the single key, when
+	// set.
+	ttl time.Duration
+}
+
+var indexes = []indexSpec{
+	// NOTE: backs the default GetTodos listing, which filters on user_id and
+	// skips todos whose snoozed_until is still in the future.
+	{collection: "todos", keys: bson.D{{Key: "user_id", Value: 1}, {Key: "snoozed_until", Value: 1}}},
+	// Lists the todos of a user in the order they were created.
+	{collection: "todos", keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	{collection: "todos", keys: bson.D{{Key: "text", Value: "text"}}},
+	// Lists the projects of a user in the order they were created.
+	{collection: "projects", keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	{collection: "todo_changes", keys: bson.D{{Key: "user_id", Value: 1}, {Key: "sequence", Value: 1}}},
+	{collection: "outbox", keys: bson.D{{Key: "dispatched", Value: 1}, {Key: "occurred_at", Value: 1}}},
+	{
+		collection: "outbox",
+		keys:       bson.D{{Key: "dispatched_at", Value: 1}},
+		ttl:        dispatchedEventRetention,
+	},
+	{
+		collection: "api_calls",
+		keys:       bson.D{{Key: "period", Value: 1}},
+		ttl:        apiCallRetention,
+	},
+	{collection: "webhooks", keys: bson.D{{Key: "user_id", Value: 1}, {Key: "event_types", Value: 1}}},
+	{collection: "webhook_deliveries", keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{collection: "webhook_deliveries", keys: bson.D{{Key: "next_attempt_at", Value: 1}}},
+}
+
+// dataMigration changes documents that were written before a change of their
+// shape. It must be safe to run again, as runners that start together may
+// all apply it.
+type dataMigration struct {
+	id  string
+	run func(ctx context.Context, m *Migrator) error
+}
+
+// dataMigrations run in order, once each, after the indexes are ensured.
+// Append to them; never edit or reorder those that shipped.
+var dataMigrations = []dataMigration{
+	// Todos written before snoozing and completion have neither field.
+	backfill("0001_backfill_todos_snoozed_until", "todos", "snoozed_until", nil),
+	backfill("0002_backfill_todos_completed_at", "todos", "completed_at", nil),
+	// Todos written before projects belong to the personal project of their
+	// owner, which shares the owner's id, and carry no labels.
+	{
+		id: "0003_backfill_todos_project_id",
+		run: func(ctx context.Context, m *Migrator) error {
+			_, err := m.collection("todos").UpdateMany(ctx,
+				bson.M{"project_id": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"project_id": "$user_id"}}}},
+			)
+			return err
+		},
+	},
+	backfill("0004_backfill_todos_labels", "todos", "labels", bson.A{}),
+}
+
+// backfill sets field to value on the documents of collection that lack it,
+// for a field added to the type they decode into.
+func backfill(id, collection, field string, value interface{}) dataMigration {
+	return dataMigration{
+		id: id,
+		run: func(ctx context.Context, m *Migrator) error {
+			_, err := m.collection(collection).UpdateMany(ctx,
+				bson.M{field: bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{field: value}},
+			)
+			return err
+		},
+	}
+}
+
+// Migrator ensures the indexes of the todo-service database and applies its
+// data migrations, keeping track of them in the schema_migrations
+// collection.
+type Migrator struct {
+	database    *mongo.Database
+	mongoConfig MongoConfig
+	indexes     []indexSpec
+	migrations  []dataMigration
+}
+
+func NewMigrator(ctx context.Context, monitor *event.CommandMonitor, mongoConfig MongoConfig) (*Migrator, error) {
+	database, err := connect(ctx, monitor, mongoConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		database:    database,
+		mongoConfig: mongoConfig,
+		indexes:     indexes,
+		migrations:  dataMigrations,
+	}, nil
+}
+
+// Close disconnects the client of the migrator.
+func (m *Migrator) Close(ctx context.Context) error {
+	return m.database.Client().Disconnect(ctx)
+}
+
+func (m *Migrator) collection(name string) *mongo.Collection {
+	return m.mongoConfig.collection(m.database, name)
+}
+
+// Run ensures the indexes and then applies the pending data migrations,
+// returning their ids.
+func (m *Migrator) Run(ctx context.Context) ([]string, error) {
+	if err := m.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return m.Migrate(ctx)
+}
+
+func (m *Migrator) EnsureIndexes(ctx context.Context) error {
+	for _, index := range m.indexes {
+		if err := ensureIndex(ctx, m.collection(index.collection), index); err != nil {
+			return fmt.Errorf("failed to create %s index %s: %w", index.collection, indexName(index.keys), err)
+		}
+	}
+	return nil
+}
+
+func ensureIndex(ctx context.Context, collection *mongo.Collection, index indexSpec) error {
+	opts := options.Index().SetName(indexName(index.keys))
+	if index.ttl > 0 {
+		opts.SetExpireAfterSeconds(int32(index.ttl.Seconds()))
+	}
+	model := mongo.IndexModel{Keys: index.keys, Options: opts}
+
+	_, err := collection.Indexes().CreateOne(ctx, model)
+	if !isIndexConflict(err) {
+		return err
+	}
+	// NOTE: an index with the same keys but other options, such as a TTL
+	// that changed, has to be dropped to be rebuilt.
+	if _, err := collection.Indexes().DropOne(ctx, indexName(index.keys)); err != nil {
+		return err
+	}
+	_, err = collection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// indexName is the name mongo gives an index of keys by default, so that the
+// indexes created before they were declared here are recognised.
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// 85 is IndexOptionsConflict and 86 IndexKeySpecsConflict.
+func isIndexConflict(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && (commandErr.Code == 85 || commandErr.Code == 86)
+}
+
+type mongoMigration struct {
+	ID        string    `bson:"_id"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Migrate applies the data migrations that were not applied yet, in order,
+// and returns their ids.
+func (m *Migrator) Migrate(ctx context.Context) ([]string, error) {
+	records := m.collection("schema_migrations")
+	cursor, err := records.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	var applied []mongoMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	done := make(map[string]bool, len(applied))
+	for _, migration := range applied {
+		done[migration.ID] = true
+	}
+
+	var ran []string
+	for _, migration := range m.migrations {
+		if done[migration.id] {
+			continue
+		}
+		if err := migration.run(ctx, m); err != nil {
+			return ran, fmt.Errorf("failed to apply migration %s: %w", migration.id, err)
+		}
+		_, err := records.UpdateOne(ctx,
+			bson.M{"_id": migration.id},
+			bson.M{"$setOnInsert": bson.M{"applied_at": time.Now()}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return ran, fmt.Errorf("failed to record migration %s: %w", migration.id, err)
+		}
+		ran = append(ran, migration.id)
+	}
+	return ran, nil
+}
@@ -0,0 +1,151 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dispatchedEventRetention is how long relayed events stay in the outbox
+// before the TTL index removes them.
+var dispatchedEventRetention = 7 * 24 * time.Hour
+
+func (m *MongoRepository) AppendEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	for _, event := range events {
+		// NOTE: bumping a per-todo counter makes concurrent transactions on the
+		// same todo conflict, so sequence order matches commit order.
+		var counter struct {
+			Sequence int64 `bson:"sequence"`
+		}
+		err := m.outboxSequences.FindOneAndUpdate(ctx,
+			bson.M{"_id": event.TodoId},
+			bson.M{"$inc": bson.M{"sequence": 1}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&counter)
+		if err != nil {
+			return fmt.Errorf("failed to sequence outbox event: %w", err)
+		}
+
+		_, err = m.outbox.InsertOne(ctx, mongoOutboxEntry{
+			ID:         event.ID,
+			Type:       string(event.Type),
+			TodoId:     event.TodoId,
+			UserId:     event.UserId,
+			Todo:       toMongoTodo(event.Todo),
+			OccurredAt: event.OccurredAt,
+			Sequence:   counter.Sequence,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to persist outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *MongoRepository) GetPendingEvents(ctx context.Context, limit int) ([]domain.OutboxEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "occurred_at", Value: 1}}).
+		SetLimit(int64(limit))
+	// NOTE: events written before parking existed lack the field, which
+	// $ne matches.
+	cursor, err := m.outbox.Find(ctx, bson.M{"dispatched": false, "parked": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return []domain.OutboxEntry{}, errors.New("errors getting outbox events")
+	}
+	defer cursor.Close(ctx)
+	var mongoEntries []mongoOutboxEntry
+	if err = cursor.All(ctx, &mongoEntries); err != nil {
+		return []domain.OutboxEntry{}, errors.New("errors getting outbox events")
+	}
+	var entries []domain.OutboxEntry
+	for _, mongoEntry := range mongoEntries {
+		entries = append(entries, toOutboxEntry(mongoEntry))
+	}
+	return entries, nil
+}
+
+func (m *MongoRepository) MarkEventDispatched(ctx context.Context, eventId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.outbox.UpdateOne(ctx, bson.M{"_id": eventId}, bson.M{
+		"$set": bson.M{"dispatched": true, "dispatched_at": time.Now()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event dispatched: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoRepository) MarkEventFailed(ctx context.Context, eventId uuid.UUID, cause error) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.outbox.UpdateOne(ctx, bson.M{"_id": eventId}, bson.M{
+		"$set": bson.M{"last_error": cause.Error()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoRepository) MarkEventParked(ctx context.Context, eventId uuid.UUID, cause error) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.outbox.UpdateOne(ctx, bson.M{"_id": eventId}, bson.M{
+		"$set": bson.M{"last_error": cause.Error(), "parked": true, "parked_at": time.Now()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to park outbox event: %w", err)
+	}
+	return nil
+}
+
+type mongoOutboxEntry struct {
+	ID           uuid.UUID  `bson:"_id"`
+	Type         string     `bson:"type"`
+	TodoId       uuid.UUID  `bson:"todo_id"`
+	UserId       uuid.UUID  `bson:"user_id"`
+	Todo         mongoTodo  `bson:"todo"`
+	OccurredAt   time.Time  `bson:"occurred_at"`
+	Sequence     int64      `bson:"sequence"`
+	Attempts     int        `bson:"attempts"`
+	LastError    string     `bson:"last_error"`
+	Dispatched   bool       `bson:"dispatched"`
+	DispatchedAt *time.Time `bson:"dispatched_at"`
+	Parked       bool       `bson:"parked"`
+	ParkedAt     *time.Time `bson:"parked_at"`
+}
+
+func toOutboxEntry(m mongoOutboxEntry) domain.OutboxEntry {
+	return domain.OutboxEntry{
+		Event: domain.TodoEvent{
+			ID:         m.ID,
+			Type:       domain.TodoEventType(m.Type),
+			TodoId:     m.TodoId,
+			UserId:     m.UserId,
+			Todo:       toTodo(m.Todo),
+			OccurredAt: m.OccurredAt,
+		},
+		Sequence:  m.Sequence,
+		Attempts:  m.Attempts,
+		LastError: m.LastError,
+	}
+}
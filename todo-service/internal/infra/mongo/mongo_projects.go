@@ -0,0 +1,122 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (m *MongoRepository) CreateProject(ctx context.Context, project domain.Project) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.projects.InsertOne(ctx, toMongoProject(project))
+	if err != nil {
+		return fmt.Errorf("failed to persist project: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoRepository) GetProject(ctx context.Context, projectId uuid.UUID) (domain.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	project := mongoProject{}
+	err := m.projects.FindOne(ctx, bson.M{"_id": projectId}).Decode(&project)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Project{}, infra.ErrRecordNotFound
+	}
+	if err != nil {
+		return domain.Project{}, fmt.Errorf("failed to get project: %w", err)
+	}
+	return toProject(project), nil
+}
+
+func (m *MongoRepository) GetProjects(ctx context.Context, userId uuid.UUID) ([]domain.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	cursor, err := m.projects.Find(ctx, bson.M{"user_id": userId}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return []domain.Project{}, errors.New("errors getting projects")
+	}
+	defer cursor.Close(ctx)
+	var mongoProjects []mongoProject
+	if err = cursor.All(ctx, &mongoProjects); err != nil {
+		return []domain.Project{}, errors.New("errors getting projects")
+	}
+	projects := []domain.Project{}
+	for _, mongoProject := range mongoProjects {
+		projects = append(projects, toProject(mongoProject))
+	}
+	return projects, nil
+}
+
+type mongoProject struct {
+	ID        uuid.UUID `bson:"_id"`
+	UserId    uuid.UUID `bson:"user_id"`
+	Name      string    `bson:"name"`
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func toMongoProject(project domain.Project) mongoProject {
+	return mongoProject{
+		ID:        project.ID,
+		UserId:    project.UserId,
+		Name:      project.Name,
+		CreatedAt: project.CreatedAt,
+		UpdatedAt: project.UpdatedAt,
+	}
+}
+
+func toProject(m mongoProject) domain.Project {
+	return domain.Project{
+		ID:        m.ID,
+		UserId:    m.UserId,
+		Name:      m.Name,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+// NOTE: members are keyed by project and user, which keeps a user from being
+// added to a project twice without an index of its own.
+type mongoProjectMemberId struct {
+	ProjectId uuid.UUID `bson:"project_id"`
+	UserId    uuid.UUID `bson:"user_id"`
+}
+
+func (m *MongoRepository) AddProjectMember(ctx context.Context, member domain.ProjectMember) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.projectMembers.UpdateOne(ctx,
+		bson.M{"_id": mongoProjectMemberId{member.ProjectId, member.UserId}},
+		bson.M{"$setOnInsert": bson.M{"added_at": member.AddedAt}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist project member: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoRepository) IsProjectMember(ctx context.Context, projectId, userId uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	count, err := m.projectMembers.CountDocuments(ctx, bson.M{"_id": mongoProjectMemberId{projectId, userId}})
+	if err != nil {
+		return false, fmt.Errorf("failed to get project member: %w", err)
+	}
+	return count > 0, nil
+}
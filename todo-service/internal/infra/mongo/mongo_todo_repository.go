@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 	"go.mongodb.org/mongo-driver/bson/bsonrw"
@@ -19,11 +20,20 @@ import (
 )
 
 type MongoRepository struct {
-	todos *mongo.Collection
+	todos           *mongo.Collection
+	projects        *mongo.Collection
+	projectMembers  *mongo.Collection
+	outbox          *mongo.Collection
+	outboxSequences *mongo.Collection
+	changes         *mongo.Collection
+	changeSequences *mongo.Collection
+	usage           *mongo.Collection
+	apiCalls        *mongo.Collection
+	// webhooks is only read, to count the webhooks of a user.
+	webhooks *mongo.Collection
+	timeout  time.Duration
 }
 
-var contextTimeoutDuration = 5 * time.Second
-
 var (
 	tUUID       = reflect.TypeOf(uuid.UUID{})
 	uuidSubtype = byte(0x04)
@@ -34,57 +44,74 @@ var (
 			Build()
 )
 
-func NewMongoRepo(ctx context.Context, monitor *event.CommandMonitor, connectionString string) (*MongoRepository, error) {
-	opts := options.Client()
-	opts.Monitor = monitor
-	client, err := mongo.Connect(ctx, opts.ApplyURI(connectionString).SetRegistry(mongoRegistry))
+func NewMongoRepo(ctx context.Context, monitor *event.CommandMonitor, mongoConfig MongoConfig) (*MongoRepository, error) {
+	database, err := connect(ctx, monitor, mongoConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create a mongo client: %w", err)
+		return nil, err
 	}
 
-	todoCollection := client.Database("todo-service").Collection("todos")
-
 	return &MongoRepository{
-		todos: todoCollection,
+		todos:           mongoConfig.collection(database, "todos"),
+		projects:        mongoConfig.collection(database, "projects"),
+		projectMembers:  mongoConfig.collection(database, "project_members"),
+		outbox:          mongoConfig.collection(database, "outbox"),
+		outboxSequences: mongoConfig.collection(database, "outbox_sequences"),
+		changes:         mongoConfig.collection(database, "todo_changes"),
+		changeSequences: mongoConfig.collection(database, "todo_change_sequences"),
+		usage:           mongoConfig.collection(database, "usage"),
+		apiCalls:        mongoConfig.collection(database, "api_calls"),
+		webhooks:        mongoConfig.collection(database, "webhooks"),
+		timeout:         mongoConfig.timeout(),
 	}, nil
 }
 
 func (m *MongoRepository) CreateTodo(ctx context.Context, todo domain.Todo) error {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
-	mongoTodo := toMongoTodo(todo)
-	_, err := m.todos.InsertOne(ctx, mongoTodo)
-	if err != nil {
-		return fmt.Errorf("failed to persist todo: %w", err)
-	}
-	return nil
+	return m.RunInTransaction(ctx, func(ctx context.Context) error {
+		mongoTodo := toMongoTodo(todo)
+		_, err := m.todos.InsertOne(ctx, mongoTodo)
+		if err != nil {
+			return fmt.Errorf("failed to persist todo: %w", err)
+		}
+		if err := adjustUsage(ctx, m.usage, todo.UserId, domain.QuotaTodos, 1); err != nil {
+			return err
+		}
+		return m.recordChange(ctx, todo, false, domain.HLC{})
+	})
 }
 
 func (m *MongoRepository) GetTodo(ctx context.Context, userId, todoId uuid.UUID) (domain.Todo, error) {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
 	todo := mongoTodo{}
 	err := m.todos.FindOne(ctx, bson.M{"_id": todoId}).Decode(&todo)
 	if err != nil {
-		return domain.Todo{}, errors.New("record not found")
+		return domain.Todo{}, infra.ErrRecordNotFound
 	}
 	domainTodo := toTodo(todo)
 	if domainTodo.UserId != userId {
-		return domain.Todo{}, errors.New("current user is not owner of this todo")
+		return domain.Todo{}, infra.ErrNotOwnerOfTodo
 	}
 
 	return domainTodo, nil
 }
 
-func (m *MongoRepository) GetTodos(ctx context.Context, userId uuid.UUID) ([]domain.Todo, error) {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+func (m *MongoRepository) GetTodos(ctx context.Context, userId uuid.UUID, includeSnoozed bool) ([]domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
 	filter := bson.M{"user_id": userId}
+	if !includeSnoozed {
+		filter["$or"] = bson.A{
+			bson.M{"snoozed_until": nil},
+			bson.M{"snoozed_until": bson.M{"$lte": time.Now()}},
+		}
+	}
 
-	cursor, err := m.todos.Find(ctx, filter)
+	cursor, err := m.todos.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
 	if err != nil {
 		return []domain.Todo{}, errors.New("errors getting todos")
 	}
@@ -101,20 +128,167 @@ func (m *MongoRepository) GetTodos(ctx context.Context, userId uuid.UUID) ([]dom
 	return domainTodos, nil
 }
 
-func (m *MongoRepository) UpdateTodo(ctx context.Context, todo domain.Todo) error {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+func (m *MongoRepository) GetTodosByIds(ctx context.Context, userId uuid.UUID, todoIds []uuid.UUID) ([]domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
-	mongoTodo := toMongoTodo(todo)
+	cursor, err := m.todos.Find(ctx, bson.M{"_id": bson.M{"$in": todoIds}, "user_id": userId})
+	if err != nil {
+		return []domain.Todo{}, errors.New("errors getting todos")
+	}
+	defer cursor.Close(ctx)
+	var mongoTodos []mongoTodo
+	if err = cursor.All(ctx, &mongoTodos); err != nil {
+		return []domain.Todo{}, errors.New("errors getting todos")
+	}
+	domainTodos := []domain.Todo{}
+	for _, mongoTodo := range mongoTodos {
+		domainTodos = append(domainTodos, toTodo(mongoTodo))
+	}
+
+	return domainTodos, nil
+}
+
+func (m *MongoRepository) UpdateTodo(ctx context.Context, todo domain.Todo, fields ...domain.TodoField) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	updatedTodo := toMongoTodo(todo)
+	changes := bson.M{"updated_at": updatedTodo.UpdatedAt}
+	for _, field := range fields {
+		switch field {
+		case domain.TodoFieldText:
+			changes["text"] = updatedTodo.Text
+		case domain.TodoFieldProjectId:
+			changes["project_id"] = updatedTodo.ProjectId
+		case domain.TodoFieldLabels:
+			changes["labels"] = updatedTodo.Labels
+		case domain.TodoFieldSnoozedUntil:
+			changes["snoozed_until"] = updatedTodo.SnoozedUntil
+		case domain.TodoFieldCompletedAt:
+			changes["completed_at"] = updatedTodo.CompletedAt
+		default:
+			return fmt.Errorf("unknown todo field: %s", field)
+		}
+		if clock, ok := updatedTodo.Clocks[string(field)]; ok {
+			changes["clocks."+string(field)] = clock
+		}
+	}
 	filter := bson.M{"_id": todo.ID}
 	updatedDoc := bson.M{
-		"$set": mongoTodo,
+		"$set": changes,
 	}
-	_, err := m.todos.UpdateOne(ctx, filter, updatedDoc)
+	return m.RunInTransaction(ctx, func(ctx context.Context) error {
+		var stored mongoTodo
+		err := m.todos.FindOneAndUpdate(ctx, filter, updatedDoc,
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&stored)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return infra.ErrRecordNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to persist todo: %w", err)
+		}
+		return m.recordChange(ctx, toTodo(stored), false, domain.HLC{})
+	})
+}
+
+func (m *MongoRepository) DeleteTodo(ctx context.Context, todo domain.Todo, deletedAt domain.HLC) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	return m.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err := m.todos.DeleteOne(ctx, bson.M{"_id": todo.ID})
+		if err != nil {
+			return fmt.Errorf("failed to delete todo: %w", err)
+		}
+		if result.DeletedCount == 0 {
+			return infra.ErrRecordNotFound
+		}
+		if err := adjustUsage(ctx, m.usage, todo.UserId, domain.QuotaTodos, -1); err != nil {
+			return err
+		}
+		return m.recordChange(ctx, todo, true, deletedAt)
+	})
+}
+
+func (m *MongoRepository) GetDueSnoozedTodos(ctx context.Context, now time.Time) ([]domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	filter := bson.M{"snoozed_until": bson.M{"$ne": nil, "$lte": now}}
+	cursor, err := m.todos.Find(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to persist todo: %w", err)
+		return []domain.Todo{}, errors.New("errors getting snoozed todos")
 	}
-	return nil
+	defer cursor.Close(ctx)
+	var mongoTodos []mongoTodo
+	if err = cursor.All(ctx, &mongoTodos); err != nil {
+		return []domain.Todo{}, errors.New("errors getting snoozed todos")
+	}
+	var domainTodos []domain.Todo
+	for _, mongoTodo := range mongoTodos {
+		domainTodos = append(domainTodos, toTodo(mongoTodo))
+	}
+
+	return domainTodos, nil
+}
+
+func (m *MongoRepository) UnsnoozeTodo(ctx context.Context, todo domain.Todo, now time.Time, at domain.HLC) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var unsnoozed bool
+	err := m.RunInTransaction(ctx, func(ctx context.Context) error {
+		// NOTE: the snoozed_until match guards against a todo that was
+		// re-snoozed after it was read.
+		var updated mongoTodo
+		err := m.todos.FindOneAndUpdate(ctx,
+			bson.M{"_id": todo.ID, "snoozed_until": todo.SnoozedUntil},
+			bson.M{"$set": bson.M{
+				"snoozed_until": nil,
+				"updated_at":    now,
+				"clocks." + string(domain.TodoFieldSnoozedUntil): at.String(),
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			unsnoozed = false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unsnooze todo: %w", err)
+		}
+		unsnoozed = true
+		return m.recordChange(ctx, toTodo(updated), false, domain.HLC{})
+	})
+	return unsnoozed, err
+}
+
+func (m *MongoRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runInTransaction(ctx, m.todos.Database().Client(), fn)
+}
+
+func runInTransaction(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start mongo session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
+
+// Close disconnects the client of the repository.
+func (m *MongoRepository) Close(ctx context.Context) error {
+	return m.todos.Database().Client().Disconnect(ctx)
 }
 
 func (m *MongoRepository) Ping(ctx context.Context) error {
@@ -125,30 +299,69 @@ func (m *MongoRepository) Ping(ctx context.Context) error {
 }
 
 type mongoTodo struct {
-	ID        uuid.UUID `bson:"_id"`
-	UserId    uuid.UUID `bson:"user_id"`
-	Text      string    `bson:"text"`
-	CreatedAt time.Time `bson:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at"`
+	ID           uuid.UUID  `bson:"_id"`
+	UserId       uuid.UUID  `bson:"user_id"`
+	Text         string     `bson:"text"`
+	ProjectId    uuid.UUID  `bson:"project_id"`
+	Labels       []string   `bson:"labels"`
+	SnoozedUntil *time.Time `bson:"snoozed_until"`
+	CompletedAt  *time.Time `bson:"completed_at"`
+	CreatedAt    time.Time  `bson:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at"`
+	// Clocks maps field names to encoded HLCs; absent for todos written
+	// before change tracking.
+	Clocks map[string]string `bson:"clocks,omitempty"`
 }
 
 func toMongoTodo(todo domain.Todo) mongoTodo {
+	var clocks map[string]string
+	if len(todo.Clocks) > 0 {
+		clocks = make(map[string]string, len(todo.Clocks))
+		for field, clock := range todo.Clocks {
+			clocks[string(field)] = clock.String()
+		}
+	}
 	return mongoTodo{
-		ID:        todo.ID,
-		UserId:    todo.UserId,
-		Text:      todo.Text,
-		CreatedAt: todo.CreatedAt,
-		UpdatedAt: todo.UpdatedAt,
+		ID:           todo.ID,
+		UserId:       todo.UserId,
+		Text:         todo.Text,
+		ProjectId:    todo.ProjectId,
+		Labels:       append([]string{}, todo.Labels...),
+		SnoozedUntil: todo.SnoozedUntil,
+		CompletedAt:  todo.CompletedAt,
+		CreatedAt:    todo.CreatedAt,
+		UpdatedAt:    todo.UpdatedAt,
+		Clocks:       clocks,
 	}
 }
 
 func toTodo(m mongoTodo) domain.Todo {
+	var clocks map[domain.TodoField]domain.HLC
+	if len(m.Clocks) > 0 {
+		clocks = make(map[domain.TodoField]domain.HLC, len(m.Clocks))
+		for field, encoded := range m.Clocks {
+			if clock, err := domain.ParseHLC(encoded); err == nil {
+				clocks[domain.TodoField(field)] = clock
+			}
+		}
+	}
+	// NOTE: todos recorded in the change log or the outbox before projects
+	// existed carry no project, and belong to the personal one.
+	projectId := m.ProjectId
+	if projectId == uuid.Nil {
+		projectId = m.UserId
+	}
 	return domain.Todo{
-		ID:        m.ID,
-		UserId:    m.UserId,
-		Text:      m.Text,
-		CreatedAt: m.CreatedAt,
-		UpdatedAt: m.UpdatedAt,
+		ID:           m.ID,
+		UserId:       m.UserId,
+		Text:         m.Text,
+		ProjectId:    projectId,
+		Labels:       append([]string{}, m.Labels...),
+		SnoozedUntil: m.SnoozedUntil,
+		CompletedAt:  m.CompletedAt,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+		Clocks:       clocks,
 	}
 }
 
@@ -0,0 +1,144 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// apiCallRetention is how long the API call count of a period is kept after
+// the period started, before the TTL index removes it.
+var apiCallRetention = 400 * 24 * time.Hour
+
+// adjustUsage must run inside the transaction of the write it counts, so the
+// counter never drifts from what the user holds.
+func adjustUsage(ctx context.Context, usage *mongo.Collection, userId uuid.UUID, resource domain.QuotaResource, delta int64) error {
+	_, err := usage.UpdateOne(ctx,
+		bson.M{"_id": userId},
+		bson.M{"$inc": bson.M{string(resource): delta}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update %s usage: %w", resource, err)
+	}
+	return nil
+}
+
+func (m *MongoRepository) GetUsage(ctx context.Context, userId uuid.UUID, period time.Time) (domain.Usage, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var usage mongoUsage
+	err := m.RunInTransaction(ctx, func(ctx context.Context) error {
+		usage = mongoUsage{}
+		err := m.usage.FindOne(ctx, bson.M{"_id": userId}).Decode(&usage)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to get usage: %w", err)
+		}
+		if usage.Counted {
+			return nil
+		}
+
+		// NOTE: the counters only follow the writes made since they were
+		// introduced, so what a user held before is counted once, here. The
+		// update conflicts with any write counted meanwhile.
+		usage.Todos, err = m.todos.CountDocuments(ctx, bson.M{"user_id": userId})
+		if err != nil {
+			return fmt.Errorf("failed to count todos: %w", err)
+		}
+		usage.Webhooks, err = m.webhooks.CountDocuments(ctx, bson.M{"user_id": userId})
+		if err != nil {
+			return fmt.Errorf("failed to count webhooks: %w", err)
+		}
+		usage.Counted = true
+		_, err = m.usage.UpdateOne(ctx,
+			bson.M{"_id": userId},
+			bson.M{"$set": bson.M{
+				string(domain.QuotaTodos):    usage.Todos,
+				string(domain.QuotaWebhooks): usage.Webhooks,
+				"counted":                    true,
+			}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to persist usage: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Usage{}, err
+	}
+
+	var calls mongoAPICalls
+	err = m.apiCalls.FindOne(ctx, bson.M{"_id": apiCallsId(userId, period)}).Decode(&calls)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Usage{}, fmt.Errorf("failed to get api calls: %w", err)
+	}
+
+	return domain.Usage{
+		UserId: userId,
+		Plan:   usage.Plan,
+		Period: period,
+		Used: map[domain.QuotaResource]int64{
+			domain.QuotaTodos:    usage.Todos,
+			domain.QuotaWebhooks: usage.Webhooks,
+			domain.QuotaAPICalls: calls.Calls,
+		},
+	}, nil
+}
+
+func (m *MongoRepository) RecordAPICall(ctx context.Context, userId uuid.UUID, period time.Time, limit int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	if limit == 0 {
+		return false, nil
+	}
+	filter := bson.M{"_id": apiCallsId(userId, period)}
+	if limit > 0 {
+		filter["calls"] = bson.M{"$lt": limit}
+	}
+	// NOTE: once the limit is reached the filter stops matching, and the
+	// upsert fails on the _id of the existing count instead of adding one.
+	_, err := m.apiCalls.UpdateOne(ctx, filter,
+		bson.M{
+			"$inc":         bson.M{"calls": 1},
+			"$setOnInsert": bson.M{"period": period},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record api call: %w", err)
+	}
+	return true, nil
+}
+
+type mongoUsage struct {
+	UserId uuid.UUID `bson:"_id"`
+	// Plan is set by hand for users off the default plan.
+	Plan     string `bson:"plan,omitempty"`
+	Todos    int64  `bson:"todos"`
+	Webhooks int64  `bson:"webhooks"`
+	// Counted is set once the todos and webhooks held before the counters
+	// existed were counted.
+	Counted bool `bson:"counted"`
+}
+
+type mongoAPICalls struct {
+	Calls  int64     `bson:"calls"`
+	Period time.Time `bson:"period"`
+}
+
+func apiCallsId(userId uuid.UUID, period time.Time) bson.D {
+	return bson.D{{Key: "user_id", Value: userId}, {Key: "period", Value: period}}
+}
@@ -0,0 +1,329 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxDeliveriesListed = 100
+
+type MongoWebhookRepository struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+	usage      *mongo.Collection
+	timeout    time.Duration
+}
+
+func NewMongoWebhookRepo(ctx context.Context, monitor *event.CommandMonitor, mongoConfig MongoConfig) (*MongoWebhookRepository, error) {
+	database, err := connect(ctx, monitor, mongoConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MongoWebhookRepository{
+		webhooks:   mongoConfig.collection(database, "webhooks"),
+		deliveries: mongoConfig.collection(database, "webhook_deliveries"),
+		usage:      mongoConfig.collection(database, "usage"),
+		timeout:    mongoConfig.timeout(),
+	}, nil
+}
+
+// Close disconnects the client of the repository.
+func (m *MongoWebhookRepository) Close(ctx context.Context) error {
+	return m.webhooks.Database().Client().Disconnect(ctx)
+}
+
+func (m *MongoWebhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	return runInTransaction(ctx, m.webhooks.Database().Client(), func(ctx context.Context) error {
+		_, err := m.webhooks.InsertOne(ctx, toMongoWebhook(webhook))
+		if err != nil {
+			return fmt.Errorf("failed to persist webhook: %w", err)
+		}
+		return adjustUsage(ctx, m.usage, webhook.UserId, domain.QuotaWebhooks, 1)
+	})
+}
+
+func (m *MongoWebhookRepository) UpdateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.webhooks.ReplaceOne(ctx, bson.M{"_id": webhook.ID}, toMongoWebhook(webhook))
+	if err != nil {
+		return fmt.Errorf("failed to persist webhook: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoWebhookRepository) DeleteWebhook(ctx context.Context, webhookId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	return runInTransaction(ctx, m.webhooks.Database().Client(), func(ctx context.Context) error {
+		var deleted mongoWebhook
+		err := m.webhooks.FindOneAndDelete(ctx, bson.M{"_id": webhookId}).Decode(&deleted)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return infra.ErrRecordNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		_, err = m.deliveries.DeleteMany(ctx, bson.M{"webhook_id": webhookId})
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		return adjustUsage(ctx, m.usage, deleted.UserId, domain.QuotaWebhooks, -1)
+	})
+}
+
+func (m *MongoWebhookRepository) GetWebhook(ctx context.Context, userId, webhookId uuid.UUID) (domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	webhook := mongoWebhook{}
+	err := m.webhooks.FindOne(ctx, bson.M{"_id": webhookId}).Decode(&webhook)
+	if err != nil {
+		return domain.Webhook{}, infra.ErrRecordNotFound
+	}
+	if webhook.UserId != userId {
+		return domain.Webhook{}, infra.ErrNotOwnerOfWebhook
+	}
+	return toWebhook(webhook), nil
+}
+
+func (m *MongoWebhookRepository) GetWebhooks(ctx context.Context, userId uuid.UUID) ([]domain.Webhook, error) {
+	return m.findWebhooks(ctx, bson.M{"user_id": userId})
+}
+
+func (m *MongoWebhookRepository) GetWebhooksForEvent(ctx context.Context, userId uuid.UUID, eventType domain.TodoEventType) ([]domain.Webhook, error) {
+	return m.findWebhooks(ctx, bson.M{
+		"user_id":     userId,
+		"event_types": string(eventType),
+		"disabled":    false,
+	})
+}
+
+func (m *MongoWebhookRepository) GetWebhookForDelivery(ctx context.Context, webhookId uuid.UUID) (domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	webhook := mongoWebhook{}
+	err := m.webhooks.FindOne(ctx, bson.M{"_id": webhookId}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Webhook{}, infra.ErrRecordNotFound
+	}
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return toWebhook(webhook), nil
+}
+
+func (m *MongoWebhookRepository) findWebhooks(ctx context.Context, filter bson.M) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	cursor, err := m.webhooks.Find(ctx, filter)
+	if err != nil {
+		return []domain.Webhook{}, errors.New("errors getting webhooks")
+	}
+	defer cursor.Close(ctx)
+	var mongoWebhooks []mongoWebhook
+	if err = cursor.All(ctx, &mongoWebhooks); err != nil {
+		return []domain.Webhook{}, errors.New("errors getting webhooks")
+	}
+	var webhooks []domain.Webhook
+	for _, mongoWebhook := range mongoWebhooks {
+		webhooks = append(webhooks, toWebhook(mongoWebhook))
+	}
+	return webhooks, nil
+}
+
+func (m *MongoWebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.deliveries.InsertOne(ctx, toMongoDelivery(delivery))
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to persist webhook delivery %s: %w", delivery.ID, infra.ErrDuplicateDelivery)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to persist webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoWebhookRepository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	_, err := m.deliveries.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, toMongoDelivery(delivery))
+	if err != nil {
+		return fmt.Errorf("failed to persist webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (m *MongoWebhookRepository) GetDelivery(ctx context.Context, webhookId, deliveryId uuid.UUID) (domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	delivery := mongoDelivery{}
+	err := m.deliveries.FindOne(ctx, bson.M{"_id": deliveryId, "webhook_id": webhookId}).Decode(&delivery)
+	if err != nil {
+		return domain.WebhookDelivery{}, infra.ErrRecordNotFound
+	}
+	return toDelivery(delivery), nil
+}
+
+func (m *MongoWebhookRepository) GetDeliveries(ctx context.Context, webhookId uuid.UUID) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(maxDeliveriesListed)
+	cursor, err := m.deliveries.Find(ctx, bson.M{"webhook_id": webhookId}, opts)
+	if err != nil {
+		return []domain.WebhookDelivery{}, errors.New("errors getting webhook deliveries")
+	}
+	defer cursor.Close(ctx)
+	var mongoDeliveries []mongoDelivery
+	if err = cursor.All(ctx, &mongoDeliveries); err != nil {
+		return []domain.WebhookDelivery{}, errors.New("errors getting webhook deliveries")
+	}
+	var deliveries []domain.WebhookDelivery
+	for _, mongoDelivery := range mongoDeliveries {
+		deliveries = append(deliveries, toDelivery(mongoDelivery))
+	}
+	return deliveries, nil
+}
+
+func (m *MongoWebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := m.deliveries.Find(ctx, bson.M{"next_attempt_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return []domain.WebhookDelivery{}, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var mongoDeliveries []mongoDelivery
+	if err = cursor.All(ctx, &mongoDeliveries); err != nil {
+		return []domain.WebhookDelivery{}, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	var deliveries []domain.WebhookDelivery
+	for _, mongoDelivery := range mongoDeliveries {
+		deliveries = append(deliveries, toDelivery(mongoDelivery))
+	}
+	return deliveries, nil
+}
+
+type mongoWebhook struct {
+	ID                  uuid.UUID `bson:"_id"`
+	UserId              uuid.UUID `bson:"user_id"`
+	URL                 string    `bson:"url"`
+	Secret              string    `bson:"secret"`
+	EventTypes          []string  `bson:"event_types"`
+	Disabled            bool      `bson:"disabled"`
+	ConsecutiveFailures int       `bson:"consecutive_failures"`
+	CreatedAt           time.Time `bson:"created_at"`
+	UpdatedAt           time.Time `bson:"updated_at"`
+}
+
+func toMongoWebhook(webhook domain.Webhook) mongoWebhook {
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, eventType := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	return mongoWebhook{
+		ID:                  webhook.ID,
+		UserId:              webhook.UserId,
+		URL:                 webhook.URL,
+		Secret:              webhook.Secret,
+		EventTypes:          eventTypes,
+		Disabled:            webhook.Disabled,
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+		CreatedAt:           webhook.CreatedAt,
+		UpdatedAt:           webhook.UpdatedAt,
+	}
+}
+
+func toWebhook(m mongoWebhook) domain.Webhook {
+	eventTypes := make([]domain.TodoEventType, 0, len(m.EventTypes))
+	for _, eventType := range m.EventTypes {
+		eventTypes = append(eventTypes, domain.TodoEventType(eventType))
+	}
+	return domain.Webhook{
+		ID:                  m.ID,
+		UserId:              m.UserId,
+		URL:                 m.URL,
+		Secret:              m.Secret,
+		EventTypes:          eventTypes,
+		Disabled:            m.Disabled,
+		ConsecutiveFailures: m.ConsecutiveFailures,
+		CreatedAt:           m.CreatedAt,
+		UpdatedAt:           m.UpdatedAt,
+	}
+}
+
+type mongoDelivery struct {
+	ID          uuid.UUID  `bson:"_id"`
+	WebhookId   uuid.UUID  `bson:"webhook_id"`
+	EventId     uuid.UUID  `bson:"event_id"`
+	EventType   string     `bson:"event_type"`
+	Payload     []byte     `bson:"payload"`
+	Attempts    int        `bson:"attempts"`
+	StatusCode  int        `bson:"status_code"`
+	Error       string     `bson:"error"`
+	Succeeded   bool       `bson:"succeeded"`
+	CreatedAt   time.Time  `bson:"created_at"`
+	DeliveredAt *time.Time `bson:"delivered_at"`
+	// NextAttemptAt is null once the delivery is finished, which no due
+	// time matches.
+	NextAttemptAt *time.Time `bson:"next_attempt_at"`
+}
+
+func toMongoDelivery(delivery domain.WebhookDelivery) mongoDelivery {
+	return mongoDelivery{
+		ID:            delivery.ID,
+		WebhookId:     delivery.WebhookId,
+		EventId:       delivery.EventId,
+		EventType:     string(delivery.EventType),
+		Payload:       delivery.Payload,
+		Attempts:      delivery.Attempts,
+		StatusCode:    delivery.StatusCode,
+		Error:         delivery.Error,
+		Succeeded:     delivery.Succeeded,
+		CreatedAt:     delivery.CreatedAt,
+		DeliveredAt:   delivery.DeliveredAt,
+		NextAttemptAt: delivery.NextAttemptAt,
+	}
+}
+
+func toDelivery(m mongoDelivery) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:            m.ID,
+		WebhookId:     m.WebhookId,
+		EventId:       m.EventId,
+		EventType:     domain.TodoEventType(m.EventType),
+		Payload:       m.Payload,
+		Attempts:      m.Attempts,
+		StatusCode:    m.StatusCode,
+		Error:         m.Error,
+		Succeeded:     m.Succeeded,
+		CreatedAt:     m.CreatedAt,
+		DeliveredAt:   m.DeliveredAt,
+		NextAttemptAt: m.NextAttemptAt,
+	}
+}
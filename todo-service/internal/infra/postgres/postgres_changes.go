@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+// recordChange must run inside the transaction of the write it records.
+func (p *PostgresRepository) recordChange(ctx context.Context, todo domain.Todo, deleted bool, deletedAt domain.HLC) error {
+	db := conn(ctx, p.connection)
+
+	// NOTE: the counter row stays locked until the transaction commits, so
+	// concurrent writes of one user take their sequences in commit order and
+	// none is committed behind one a client has already synced past.
+	var sequence int64
+	err := db.QueryRow(ctx,
+		`INSERT INTO todo_change_sequences(user_id, sequence) VALUES($1, 1)
+     ON CONFLICT (user_id) DO UPDATE SET sequence = todo_change_sequences.sequence + 1
+     RETURNING sequence`,
+		todo.UserId,
+	).Scan(&sequence)
+	if err != nil {
+		return fmt.Errorf("failed to sequence todo change: %w", err)
+	}
+
+	_, err = db.Exec(ctx,
+		`INSERT INTO todo_changes(todo_id, user_id, sequence, todo, deleted, deleted_at) VALUES($1, $2, $3, $4, $5, $6)
+     ON CONFLICT (todo_id) DO UPDATE SET user_id = EXCLUDED.user_id, sequence = EXCLUDED.sequence,
+       todo = EXCLUDED.todo, deleted = EXCLUDED.deleted, deleted_at = EXCLUDED.deleted_at`,
+		todo.ID, todo.UserId, sequence, toPostgresTodo(todo), deleted, deletedAt.String(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist todo change: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetChangesSince(ctx context.Context, userId uuid.UUID, sequence int64, limit int) ([]domain.TodoChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	rows, err := conn(ctx, p.connection).Query(ctx,
+		`SELECT `+changeColumns+` FROM todo_changes
+     WHERE user_id = $1 AND sequence > $2 ORDER BY sequence LIMIT $3`,
+		userId, sequence, limit,
+	)
+	if err != nil {
+		return []domain.TodoChange{}, errors.New("errors getting todo changes")
+	}
+	defer rows.Close()
+	changes := []domain.TodoChange{}
+	for rows.Next() {
+		change, err := scanTodoChange(rows)
+		if err != nil {
+			return []domain.TodoChange{}, errors.New("errors getting todo changes")
+		}
+		changes = append(changes, toTodoChange(change))
+	}
+	if rows.Err() != nil {
+		return []domain.TodoChange{}, errors.New("errors getting todo changes")
+	}
+	return changes, nil
+}
+
+func (p *PostgresRepository) GetChange(ctx context.Context, todoId uuid.UUID) (domain.TodoChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	change, err := scanTodoChange(conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT "+changeColumns+" FROM todo_changes WHERE todo_id = $1", todoId,
+	))
+	if err != nil {
+		return domain.TodoChange{}, infra.ErrRecordNotFound
+	}
+	return toTodoChange(change), nil
+}
+
+const changeColumns = "todo_id, user_id, sequence, todo, deleted, deleted_at"
+
+type postgresTodoChange struct {
+	TodoId    uuid.UUID
+	UserId    uuid.UUID
+	Sequence  int64
+	Todo      postgresTodo
+	Deleted   bool
+	DeletedAt string
+}
+
+func scanTodoChange(row pgx.Row) (postgresTodoChange, error) {
+	var change postgresTodoChange
+	err := row.Scan(&change.TodoId, &change.UserId, &change.Sequence, &change.Todo, &change.Deleted, &change.DeletedAt)
+	return change, err
+}
+
+func toTodoChange(p postgresTodoChange) domain.TodoChange {
+	deletedAt, _ := domain.ParseHLC(p.DeletedAt)
+	return domain.TodoChange{
+		TodoId:    p.TodoId,
+		UserId:    p.UserId,
+		Sequence:  p.Sequence,
+		Todo:      toTodo(p.Todo),
+		Deleted:   p.Deleted,
+		DeletedAt: deletedAt,
+	}
+}
@@ -0,0 +1,8 @@
+package postgres
+
+import "embed"
+
+// migrationFiles versions the schema of the todo-service database.
+//
+//go:embed sql/*.sql
+var migrationFiles embed.FS
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+// dispatchedEventRetention is how long relayed events stay in the outbox
+// before they are pruned.
+var dispatchedEventRetention = 7 * 24 * time.Hour
+
+func (p *PostgresRepository) AppendEvents(ctx context.Context, events ...domain.TodoEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	db := conn(ctx, p.connection)
+	for _, event := range events {
+		// NOTE: the counter row of the todo stays locked until the
+		// transaction commits, so sequence order matches commit order.
+		var sequence int64
+		err := db.QueryRow(ctx,
+			`INSERT INTO outbox_sequences(todo_id, sequence) VALUES($1, 1)
+       ON CONFLICT (todo_id) DO UPDATE SET sequence = outbox_sequences.sequence + 1
+       RETURNING sequence`,
+			event.TodoId,
+		).Scan(&sequence)
+		if err != nil {
+			return fmt.Errorf("failed to sequence outbox event: %w", err)
+		}
+
+		_, err = db.Exec(ctx,
+			`INSERT INTO outbox(id, type, todo_id, user_id, todo, occurred_at, sequence)
+       VALUES($1, $2, $3, $4, $5, $6, $7)`,
+			event.ID, string(event.Type), event.TodoId, event.UserId, toPostgresTodo(event.Todo), event.OccurredAt, sequence,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to persist outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetPendingEvents(ctx context.Context, limit int) ([]domain.OutboxEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	// NOTE: postgres has no TTL index, so the relay prunes what was
+	// dispatched long enough ago each time it polls.
+	_, err := p.connection.Exec(ctx,
+		"DELETE FROM outbox WHERE dispatched AND dispatched_at < $1",
+		time.Now().Add(-dispatchedEventRetention),
+	)
+	if err != nil {
+		return []domain.OutboxEntry{}, fmt.Errorf("failed to prune outbox events: %w", err)
+	}
+
+	rows, err := p.connection.Query(ctx,
+		`SELECT id, type, todo_id, user_id, todo, occurred_at, sequence, attempts, last_error
+     FROM outbox WHERE NOT dispatched AND NOT parked ORDER BY occurred_at LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return []domain.OutboxEntry{}, errors.New("errors getting outbox events")
+	}
+	defer rows.Close()
+	var entries []domain.OutboxEntry
+	for rows.Next() {
+		entry, err := scanOutboxEntry(rows)
+		if err != nil {
+			return []domain.OutboxEntry{}, errors.New("errors getting outbox events")
+		}
+		entries = append(entries, entry)
+	}
+	if rows.Err() != nil {
+		return []domain.OutboxEntry{}, errors.New("errors getting outbox events")
+	}
+	return entries, nil
+}
+
+func (p *PostgresRepository) MarkEventDispatched(ctx context.Context, eventId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := p.connection.Exec(ctx,
+		"UPDATE outbox SET dispatched = TRUE, dispatched_at = $2, attempts = attempts + 1 WHERE id = $1",
+		eventId, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event dispatched: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) MarkEventFailed(ctx context.Context, eventId uuid.UUID, cause error) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := p.connection.Exec(ctx,
+		"UPDATE outbox SET last_error = $2, attempts = attempts + 1 WHERE id = $1",
+		eventId, cause.Error(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) MarkEventParked(ctx context.Context, eventId uuid.UUID, cause error) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := p.connection.Exec(ctx,
+		"UPDATE outbox SET last_error = $2, attempts = attempts + 1, parked = TRUE, parked_at = $3 WHERE id = $1",
+		eventId, cause.Error(), time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to park outbox event: %w", err)
+	}
+	return nil
+}
+
+func scanOutboxEntry(row pgx.Row) (domain.OutboxEntry, error) {
+	var (
+		entry     domain.OutboxEntry
+		eventType string
+		todo      postgresTodo
+	)
+	err := row.Scan(
+		&entry.Event.ID, &eventType, &entry.Event.TodoId, &entry.Event.UserId, &todo,
+		&entry.Event.OccurredAt, &entry.Sequence, &entry.Attempts, &entry.LastError,
+	)
+	entry.Event.Type = domain.TodoEventType(eventType)
+	entry.Event.Todo = toTodo(todo)
+	return entry, err
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+const projectColumns = "id, user_id, name, created_at, updated_at"
+
+func (p *PostgresRepository) CreateProject(ctx context.Context, project domain.Project) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := conn(ctx, p.connection).Exec(ctx,
+		"INSERT INTO projects("+projectColumns+") VALUES($1, $2, $3, $4, $5)",
+		project.ID, project.UserId, project.Name, project.CreatedAt, project.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist project: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetProject(ctx context.Context, projectId uuid.UUID) (domain.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	project, err := scanProject(conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE id = $1", projectId,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Project{}, infra.ErrRecordNotFound
+	}
+	if err != nil {
+		return domain.Project{}, fmt.Errorf("failed to get project: %w", err)
+	}
+	return project, nil
+}
+
+func (p *PostgresRepository) GetProjects(ctx context.Context, userId uuid.UUID) ([]domain.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	rows, err := conn(ctx, p.connection).Query(ctx,
+		"SELECT "+projectColumns+" FROM projects WHERE user_id = $1 ORDER BY created_at", userId,
+	)
+	if err != nil {
+		return []domain.Project{}, errors.New("errors getting projects")
+	}
+	defer rows.Close()
+	projects := []domain.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return []domain.Project{}, errors.New("errors getting projects")
+		}
+		projects = append(projects, project)
+	}
+	if rows.Err() != nil {
+		return []domain.Project{}, errors.New("errors getting projects")
+	}
+	return projects, nil
+}
+
+func scanProject(row pgx.Row) (domain.Project, error) {
+	var project domain.Project
+	err := row.Scan(&project.ID, &project.UserId, &project.Name, &project.CreatedAt, &project.UpdatedAt)
+	return project, err
+}
+
+func (p *PostgresRepository) AddProjectMember(ctx context.Context, member domain.ProjectMember) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := conn(ctx, p.connection).Exec(ctx,
+		"INSERT INTO project_members(project_id, user_id, added_at) VALUES($1, $2, $3) ON CONFLICT (project_id, user_id) DO NOTHING",
+		member.ProjectId, member.UserId, member.AddedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist project member: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) IsProjectMember(ctx context.Context, projectId, userId uuid.UUID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	var isMember bool
+	err := conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2)", projectId, userId,
+	).Scan(&isMember)
+	if err != nil {
+		return false, fmt.Errorf("failed to get project member: %w", err)
+	}
+	return isMember, nil
+}
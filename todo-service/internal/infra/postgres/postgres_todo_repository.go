@@ -0,0 +1,406 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/olad5/productive-pulse/pkg/migrations"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+type PostgresRepository struct {
+	connection *pgxpool.Pool
+	migrator   *migrations.Migrator
+}
+
+var contextTimeoutDuration = 5 * time.Second
+
+// maxTransactionAttempts bounds how often a transaction that lost a deadlock
+// or a serialization conflict is run again, as the mongo repository does for
+// transient transaction errors.
+const maxTransactionAttempts = 3
+
+const todoColumns = "id, user_id, text, project_id, labels, snoozed_until, completed_at, created_at, updated_at, clocks"
+
+func NewPostgresRepo(ctx context.Context, tracer pgx.QueryTracer, databaseUrl string) (*PostgresRepository, error) {
+	connectionPool, err := newPool(ctx, tracer, databaseUrl)
+	if err != nil {
+		return &PostgresRepository{}, err
+	}
+
+	// NOTE: migrations go through database/sql, on connections of their own
+	// that are closed once a run is over.
+	migrationDB := stdlib.OpenDB(*connectionPool.Config().ConnConfig.Copy())
+	migrationDB.SetMaxIdleConns(0)
+	migrator, err := migrations.NewMigrator(migrationDB, migrations.Postgres, migrationFiles)
+	if err != nil {
+		connectionPool.Close()
+		return &PostgresRepository{}, err
+	}
+
+	return &PostgresRepository{connection: connectionPool, migrator: migrator}, nil
+}
+
+// Migrator migrates the schema of the database, which holds the tables of
+// both the todo and the webhook repository. Both expect it to be up to date.
+func (p *PostgresRepository) Migrator() *migrations.Migrator {
+	return p.migrator
+}
+
+func newPool(ctx context.Context, tracer pgx.QueryTracer, databaseUrl string) (*pgxpool.Pool, error) {
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+
+	dbConfig, err := pgxpool.ParseConfig(databaseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse Postgres database url:  %w", err)
+	}
+	dbConfig.ConnConfig.Tracer = tracer
+	connectionPool, err := pgxpool.NewWithConfig(ctx, dbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create PostgresRepository:  %w", err)
+	}
+	return connectionPool, nil
+}
+
+func (p *PostgresRepository) CreateTodo(ctx context.Context, todo domain.Todo) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	return p.RunInTransaction(ctx, func(ctx context.Context) error {
+		postgresTodo := toPostgresTodo(todo)
+		_, err := conn(ctx, p.connection).Exec(ctx,
+			"INSERT INTO todos("+todoColumns+") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			postgresTodo.ID, postgresTodo.UserId, postgresTodo.Text, postgresTodo.ProjectId, postgresTodo.Labels,
+			postgresTodo.SnoozedUntil, postgresTodo.CompletedAt, postgresTodo.CreatedAt, postgresTodo.UpdatedAt, postgresTodo.Clocks,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to persist todo: %w", err)
+		}
+		if err := adjustUsage(ctx, p.connection, todo.UserId, domain.QuotaTodos, 1); err != nil {
+			return err
+		}
+		return p.recordChange(ctx, todo, false, domain.HLC{})
+	})
+}
+
+func (p *PostgresRepository) GetTodo(ctx context.Context, userId, todoId uuid.UUID) (domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	row := conn(ctx, p.connection).QueryRow(ctx, "SELECT "+todoColumns+" FROM todos WHERE id = $1", todoId)
+	todo, err := scanTodo(row)
+	if err != nil {
+		return domain.Todo{}, infra.ErrRecordNotFound
+	}
+	domainTodo := toTodo(todo)
+	if domainTodo.UserId != userId {
+		return domain.Todo{}, infra.ErrNotOwnerOfTodo
+	}
+
+	return domainTodo, nil
+}
+
+func (p *PostgresRepository) GetTodos(ctx context.Context, userId uuid.UUID, includeSnoozed bool) ([]domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	query := "SELECT " + todoColumns + " FROM todos WHERE user_id = $1"
+	args := []interface{}{userId}
+	if !includeSnoozed {
+		query += " AND (snoozed_until IS NULL OR snoozed_until <= $2)"
+		args = append(args, time.Now())
+	}
+	query += " ORDER BY created_at"
+
+	todos, err := p.queryTodos(ctx, query, args...)
+	if err != nil {
+		return []domain.Todo{}, errors.New("errors getting todos")
+	}
+	return todos, nil
+}
+
+func (p *PostgresRepository) GetTodosByIds(ctx context.Context, userId uuid.UUID, todoIds []uuid.UUID) ([]domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	todos, err := p.queryTodos(ctx,
+		"SELECT "+todoColumns+" FROM todos WHERE id = ANY($1::uuid[]) AND user_id = $2",
+		todoIds, userId,
+	)
+	if err != nil {
+		return []domain.Todo{}, errors.New("errors getting todos")
+	}
+	if todos == nil {
+		return []domain.Todo{}, nil
+	}
+	return todos, nil
+}
+
+func (p *PostgresRepository) UpdateTodo(ctx context.Context, todo domain.Todo, fields ...domain.TodoField) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	updatedTodo := toPostgresTodo(todo)
+	assignments := []string{"updated_at = $2"}
+	args := []interface{}{todo.ID, updatedTodo.UpdatedAt}
+	clocks := map[string]string{}
+	for _, field := range fields {
+		var value interface{}
+		switch field {
+		case domain.TodoFieldText:
+			value = updatedTodo.Text
+		case domain.TodoFieldProjectId:
+			value = updatedTodo.ProjectId
+		case domain.TodoFieldLabels:
+			value = updatedTodo.Labels
+		case domain.TodoFieldSnoozedUntil:
+			value = updatedTodo.SnoozedUntil
+		case domain.TodoFieldCompletedAt:
+			value = updatedTodo.CompletedAt
+		default:
+			return fmt.Errorf("unknown todo field: %s", field)
+		}
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", field, len(args)))
+		if clock, ok := updatedTodo.Clocks[string(field)]; ok {
+			clocks[string(field)] = clock
+		}
+	}
+	args = append(args, clocks)
+	assignments = append(assignments, fmt.Sprintf("clocks = clocks || $%d::jsonb", len(args)))
+
+	query := "UPDATE todos SET " + strings.Join(assignments, ", ") + " WHERE id = $1 RETURNING " + todoColumns
+	return p.RunInTransaction(ctx, func(ctx context.Context) error {
+		stored, err := scanTodo(conn(ctx, p.connection).QueryRow(ctx, query, args...))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return infra.ErrRecordNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to persist todo: %w", err)
+		}
+		return p.recordChange(ctx, toTodo(stored), false, domain.HLC{})
+	})
+}
+
+func (p *PostgresRepository) DeleteTodo(ctx context.Context, todo domain.Todo, deletedAt domain.HLC) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	return p.RunInTransaction(ctx, func(ctx context.Context) error {
+		result, err := conn(ctx, p.connection).Exec(ctx, "DELETE FROM todos WHERE id = $1", todo.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete todo: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return infra.ErrRecordNotFound
+		}
+		if err := adjustUsage(ctx, p.connection, todo.UserId, domain.QuotaTodos, -1); err != nil {
+			return err
+		}
+		return p.recordChange(ctx, todo, true, deletedAt)
+	})
+}
+
+func (p *PostgresRepository) GetDueSnoozedTodos(ctx context.Context, now time.Time) ([]domain.Todo, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	todos, err := p.queryTodos(ctx,
+		"SELECT "+todoColumns+" FROM todos WHERE snoozed_until IS NOT NULL AND snoozed_until <= $1",
+		now,
+	)
+	if err != nil {
+		return []domain.Todo{}, errors.New("errors getting snoozed todos")
+	}
+	return todos, nil
+}
+
+func (p *PostgresRepository) UnsnoozeTodo(ctx context.Context, todo domain.Todo, now time.Time, at domain.HLC) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	var unsnoozed bool
+	err := p.RunInTransaction(ctx, func(ctx context.Context) error {
+		// NOTE: the snoozed_until match guards against a todo that was
+		// re-snoozed after it was read.
+		updated, err := scanTodo(conn(ctx, p.connection).QueryRow(ctx,
+			`UPDATE todos SET snoozed_until = NULL, updated_at = $3, clocks = clocks || $4::jsonb
+       WHERE id = $1 AND snoozed_until IS NOT DISTINCT FROM $2
+       RETURNING `+todoColumns,
+			todo.ID, todo.SnoozedUntil, now,
+			map[string]string{string(domain.TodoFieldSnoozedUntil): at.String()},
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			unsnoozed = false
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unsnooze todo: %w", err)
+		}
+		unsnoozed = true
+		return p.recordChange(ctx, toTodo(updated), false, domain.HLC{})
+	})
+	return unsnoozed, err
+}
+
+func (p *PostgresRepository) queryTodos(ctx context.Context, query string, args ...interface{}) ([]domain.Todo, error) {
+	rows, err := conn(ctx, p.connection).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var domainTodos []domain.Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		domainTodos = append(domainTodos, toTodo(todo))
+	}
+	return domainTodos, rows.Err()
+}
+
+func (p *PostgresRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runInTransaction(ctx, p.connection, fn)
+}
+
+type txKey struct{}
+
+// querier is implemented by both the pool and a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// conn returns the transaction running in ctx, if any, or else connection.
+func conn(ctx context.Context, connection *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return connection
+}
+
+func runInTransaction(ctx context.Context, connection *pgxpool.Pool, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	var err error
+	for attempt := 0; attempt < maxTransactionAttempts; attempt++ {
+		err = pgx.BeginFunc(ctx, connection, func(tx pgx.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		if !isTransientError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isTransientError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// serialization_failure and deadlock_detected
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+func (p *PostgresRepository) Close(ctx context.Context) error {
+	p.connection.Close()
+	return nil
+}
+
+func (p *PostgresRepository) Ping(ctx context.Context) error {
+	err := p.connection.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to Ping Postgres DB:  %w", err)
+	}
+
+	return nil
+}
+
+// postgresTodo is also stored as JSON, in the change log and the outbox.
+type postgresTodo struct {
+	ID           uuid.UUID  `json:"id"`
+	UserId       uuid.UUID  `json:"user_id"`
+	Text         string     `json:"text"`
+	ProjectId    uuid.UUID  `json:"project_id"`
+	Labels       []string   `json:"labels"`
+	SnoozedUntil *time.Time `json:"snoozed_until"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// Clocks maps field names to encoded HLCs.
+	Clocks map[string]string `json:"clocks"`
+}
+
+func scanTodo(row pgx.Row) (postgresTodo, error) {
+	var todo postgresTodo
+	err := row.Scan(
+		&todo.ID, &todo.UserId, &todo.Text, &todo.ProjectId, &todo.Labels, &todo.SnoozedUntil, &todo.CompletedAt,
+		&todo.CreatedAt, &todo.UpdatedAt, &todo.Clocks,
+	)
+	return todo, err
+}
+
+func toPostgresTodo(todo domain.Todo) postgresTodo {
+	clocks := make(map[string]string, len(todo.Clocks))
+	for field, clock := range todo.Clocks {
+		clocks[string(field)] = clock.String()
+	}
+	return postgresTodo{
+		ID:           todo.ID,
+		UserId:       todo.UserId,
+		Text:         todo.Text,
+		ProjectId:    todo.ProjectId,
+		Labels:       append([]string{}, todo.Labels...),
+		SnoozedUntil: todo.SnoozedUntil,
+		CompletedAt:  todo.CompletedAt,
+		CreatedAt:    todo.CreatedAt,
+		UpdatedAt:    todo.UpdatedAt,
+		Clocks:       clocks,
+	}
+}
+
+func toTodo(p postgresTodo) domain.Todo {
+	var clocks map[domain.TodoField]domain.HLC
+	if len(p.Clocks) > 0 {
+		clocks = make(map[domain.TodoField]domain.HLC, len(p.Clocks))
+		for field, encoded := range p.Clocks {
+			if clock, err := domain.ParseHLC(encoded); err == nil {
+				clocks[domain.TodoField(field)] = clock
+			}
+		}
+	}
+	// NOTE: todos recorded in the change log or the outbox before projects
+	// existed carry no project, and belong to the personal one.
+	projectId := p.ProjectId
+	if projectId == uuid.Nil {
+		projectId = p.UserId
+	}
+	return domain.Todo{
+		ID:           p.ID,
+		UserId:       p.UserId,
+		Text:         p.Text,
+		ProjectId:    projectId,
+		Labels:       append([]string{}, p.Labels...),
+		SnoozedUntil: p.SnoozedUntil,
+		CompletedAt:  p.CompletedAt,
+		CreatedAt:    p.CreatedAt,
+		UpdatedAt:    p.UpdatedAt,
+		Clocks:       clocks,
+	}
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+// apiCallRetention is how long the API call count of a period is kept after
+// the period started.
+var apiCallRetention = 400 * 24 * time.Hour
+
+// usageColumns maps the resources counted in the usage table to their column.
+var usageColumns = map[domain.QuotaResource]string{
+	domain.QuotaTodos:    "todos",
+	domain.QuotaWebhooks: "webhooks",
+}
+
+// adjustUsage must run inside the transaction of the write it counts, so the
+// counter never drifts from what the user holds.
+func adjustUsage(ctx context.Context, connection *pgxpool.Pool, userId uuid.UUID, resource domain.QuotaResource, delta int64) error {
+	column, ok := usageColumns[resource]
+	if !ok {
+		return fmt.Errorf("unknown usage resource: %s", resource)
+	}
+	_, err := conn(ctx, connection).Exec(ctx,
+		fmt.Sprintf(`INSERT INTO usage(user_id, %[1]s) VALUES($1, $2)
+     ON CONFLICT (user_id) DO UPDATE SET %[1]s = usage.%[1]s + EXCLUDED.%[1]s`, column),
+		userId, delta,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update %s usage: %w", resource, err)
+	}
+	return nil
+}
+
+func (p *PostgresRepository) GetUsage(ctx context.Context, userId uuid.UUID, period time.Time) (domain.Usage, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	var usage postgresUsage
+	err := p.RunInTransaction(ctx, func(ctx context.Context) error {
+		db := conn(ctx, p.connection)
+		// NOTE: locking the row makes a check made in this transaction hold
+		// until it commits, as writes counted meanwhile wait for it.
+		selectUsage := `SELECT plan, todos, webhooks, counted FROM usage WHERE user_id = $1 FOR UPDATE`
+		err := scanUsage(db.QueryRow(ctx, selectUsage, userId), &usage)
+		if errors.Is(err, pgx.ErrNoRows) {
+			_, err = db.Exec(ctx, "INSERT INTO usage(user_id) VALUES($1) ON CONFLICT (user_id) DO NOTHING", userId)
+			if err != nil {
+				return fmt.Errorf("failed to persist usage: %w", err)
+			}
+			err = scanUsage(db.QueryRow(ctx, selectUsage, userId), &usage)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get usage: %w", err)
+		}
+		if usage.Counted {
+			return nil
+		}
+
+		// NOTE: the counters only follow the writes made since they were
+		// introduced, so what a user held before is counted once, here.
+		err = db.QueryRow(ctx,
+			`UPDATE usage SET
+         todos = (SELECT count(*) FROM todos WHERE user_id = $1),
+         webhooks = (SELECT count(*) FROM webhooks WHERE user_id = $1),
+         counted = TRUE
+       WHERE user_id = $1
+       RETURNING todos, webhooks`,
+			userId,
+		).Scan(&usage.Todos, &usage.Webhooks)
+		if err != nil {
+			return fmt.Errorf("failed to persist usage: %w", err)
+		}
+		usage.Counted = true
+		return nil
+	})
+	if err != nil {
+		return domain.Usage{}, err
+	}
+
+	var calls int64
+	err = conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT calls FROM api_calls WHERE user_id = $1 AND period = $2", userId, period,
+	).Scan(&calls)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return domain.Usage{}, fmt.Errorf("failed to get api calls: %w", err)
+	}
+
+	return domain.Usage{
+		UserId: userId,
+		Plan:   usage.Plan,
+		Period: period,
+		Used: map[domain.QuotaResource]int64{
+			domain.QuotaTodos:    usage.Todos,
+			domain.QuotaWebhooks: usage.Webhooks,
+			domain.QuotaAPICalls: calls,
+		},
+	}, nil
+}
+
+func (p *PostgresRepository) RecordAPICall(ctx context.Context, userId uuid.UUID, period time.Time, limit int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	if limit == 0 {
+		return false, nil
+	}
+	// NOTE: once the limit is reached the conflict update is skipped, and no
+	// row comes back.
+	var calls int64
+	err := conn(ctx, p.connection).QueryRow(ctx,
+		`INSERT INTO api_calls(user_id, period, calls) VALUES($1, $2, 1)
+     ON CONFLICT (user_id, period) DO UPDATE SET calls = api_calls.calls + 1
+     WHERE $3::bigint < 0 OR api_calls.calls < $3::bigint
+     RETURNING calls`,
+		userId, period, limit,
+	).Scan(&calls)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record api call: %w", err)
+	}
+	if calls == 1 {
+		// NOTE: postgres has no TTL index, so the first call of a user in a
+		// period prunes the counts past retention. The call is counted
+		// either way, and a failed prune is left to the next user's.
+		_, _ = p.connection.Exec(ctx, "DELETE FROM api_calls WHERE period < $1", time.Now().Add(-apiCallRetention))
+	}
+	return true, nil
+}
+
+type postgresUsage struct {
+	// Plan is set by hand for users off the default plan.
+	Plan     string
+	Todos    int64
+	Webhooks int64
+	// Counted is set once the todos and webhooks held before the counters
+	// existed were counted.
+	Counted bool
+}
+
+func scanUsage(row pgx.Row, usage *postgresUsage) error {
+	return row.Scan(&usage.Plan, &usage.Todos, &usage.Webhooks, &usage.Counted)
+}
@@ -0,0 +1,334 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+const maxDeliveriesListed = 100
+
+const (
+	webhookColumns  = "id, user_id, url, secret, event_types, disabled, consecutive_failures, created_at, updated_at"
+	deliveryColumns = "id, webhook_id, event_id, event_type, payload, attempts, status_code, error, succeeded, created_at, delivered_at, next_attempt_at"
+)
+
+type PostgresWebhookRepository struct {
+	connection *pgxpool.Pool
+}
+
+func NewPostgresWebhookRepo(ctx context.Context, tracer pgx.QueryTracer, databaseUrl string) (*PostgresWebhookRepository, error) {
+	connectionPool, err := newPool(ctx, tracer, databaseUrl)
+	if err != nil {
+		return &PostgresWebhookRepository{}, err
+	}
+	return &PostgresWebhookRepository{connection: connectionPool}, nil
+}
+
+func (p *PostgresWebhookRepository) Close(ctx context.Context) error {
+	p.connection.Close()
+	return nil
+}
+
+func (p *PostgresWebhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	return runInTransaction(ctx, p.connection, func(ctx context.Context) error {
+		w := toPostgresWebhook(webhook)
+		_, err := conn(ctx, p.connection).Exec(ctx,
+			"INSERT INTO webhooks("+webhookColumns+") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+			w.ID, w.UserId, w.URL, w.Secret, w.EventTypes, w.Disabled, w.ConsecutiveFailures, w.CreatedAt, w.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to persist webhook: %w", err)
+		}
+		return adjustUsage(ctx, p.connection, webhook.UserId, domain.QuotaWebhooks, 1)
+	})
+}
+
+func (p *PostgresWebhookRepository) UpdateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	w := toPostgresWebhook(webhook)
+	_, err := conn(ctx, p.connection).Exec(ctx,
+		`UPDATE webhooks SET user_id = $2, url = $3, secret = $4, event_types = $5, disabled = $6,
+       consecutive_failures = $7, created_at = $8, updated_at = $9
+     WHERE id = $1`,
+		w.ID, w.UserId, w.URL, w.Secret, w.EventTypes, w.Disabled, w.ConsecutiveFailures, w.CreatedAt, w.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist webhook: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresWebhookRepository) DeleteWebhook(ctx context.Context, webhookId uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	return runInTransaction(ctx, p.connection, func(ctx context.Context) error {
+		// NOTE: its deliveries go with it, through ON DELETE CASCADE.
+		var userId uuid.UUID
+		err := conn(ctx, p.connection).QueryRow(ctx,
+			"DELETE FROM webhooks WHERE id = $1 RETURNING user_id", webhookId,
+		).Scan(&userId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return infra.ErrRecordNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		return adjustUsage(ctx, p.connection, userId, domain.QuotaWebhooks, -1)
+	})
+}
+
+func (p *PostgresWebhookRepository) GetWebhook(ctx context.Context, userId, webhookId uuid.UUID) (domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	webhook, err := scanWebhook(conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", webhookId,
+	))
+	if err != nil {
+		return domain.Webhook{}, infra.ErrRecordNotFound
+	}
+	if webhook.UserId != userId {
+		return domain.Webhook{}, infra.ErrNotOwnerOfWebhook
+	}
+	return toWebhook(webhook), nil
+}
+
+func (p *PostgresWebhookRepository) GetWebhooks(ctx context.Context, userId uuid.UUID) ([]domain.Webhook, error) {
+	return p.findWebhooks(ctx, "user_id = $1", userId)
+}
+
+func (p *PostgresWebhookRepository) GetWebhooksForEvent(ctx context.Context, userId uuid.UUID, eventType domain.TodoEventType) ([]domain.Webhook, error) {
+	return p.findWebhooks(ctx, "user_id = $1 AND $2 = ANY(event_types) AND NOT disabled", userId, string(eventType))
+}
+
+func (p *PostgresWebhookRepository) GetWebhookForDelivery(ctx context.Context, webhookId uuid.UUID) (domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	webhook, err := scanWebhook(conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", webhookId,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Webhook{}, infra.ErrRecordNotFound
+	}
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return toWebhook(webhook), nil
+}
+
+func (p *PostgresWebhookRepository) findWebhooks(ctx context.Context, filter string, args ...interface{}) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	rows, err := conn(ctx, p.connection).Query(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE "+filter+" ORDER BY created_at", args...,
+	)
+	if err != nil {
+		return []domain.Webhook{}, errors.New("errors getting webhooks")
+	}
+	defer rows.Close()
+	var webhooks []domain.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return []domain.Webhook{}, errors.New("errors getting webhooks")
+		}
+		webhooks = append(webhooks, toWebhook(webhook))
+	}
+	if rows.Err() != nil {
+		return []domain.Webhook{}, errors.New("errors getting webhooks")
+	}
+	return webhooks, nil
+}
+
+func (p *PostgresWebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := conn(ctx, p.connection).Exec(ctx,
+		"INSERT INTO webhook_deliveries("+deliveryColumns+") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+		deliveryArgs(delivery)...,
+	)
+	var pgErr *pgconn.PgError
+	// 23505 is unique_violation
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "webhook_deliveries_pkey" {
+		return fmt.Errorf("failed to persist webhook delivery %s: %w", delivery.ID, infra.ErrDuplicateDelivery)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to persist webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresWebhookRepository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	_, err := conn(ctx, p.connection).Exec(ctx,
+		`UPDATE webhook_deliveries SET webhook_id = $2, event_id = $3, event_type = $4, payload = $5, attempts = $6,
+       status_code = $7, error = $8, succeeded = $9, created_at = $10, delivered_at = $11, next_attempt_at = $12
+     WHERE id = $1`,
+		deliveryArgs(delivery)...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to persist webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (p *PostgresWebhookRepository) GetDelivery(ctx context.Context, webhookId, deliveryId uuid.UUID) (domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	delivery, err := scanDelivery(conn(ctx, p.connection).QueryRow(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2", deliveryId, webhookId,
+	))
+	if err != nil {
+		return domain.WebhookDelivery{}, infra.ErrRecordNotFound
+	}
+	return delivery, nil
+}
+
+func (p *PostgresWebhookRepository) GetDeliveries(ctx context.Context, webhookId uuid.UUID) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	rows, err := conn(ctx, p.connection).Query(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2",
+		webhookId, maxDeliveriesListed,
+	)
+	if err != nil {
+		return []domain.WebhookDelivery{}, errors.New("errors getting webhook deliveries")
+	}
+	defer rows.Close()
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return []domain.WebhookDelivery{}, errors.New("errors getting webhook deliveries")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if rows.Err() != nil {
+		return []domain.WebhookDelivery{}, errors.New("errors getting webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+func (p *PostgresWebhookRepository) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextTimeoutDuration)
+	defer cancel()
+
+	rows, err := conn(ctx, p.connection).Query(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE next_attempt_at <= $1 ORDER BY next_attempt_at LIMIT $2",
+		now, limit,
+	)
+	if err != nil {
+		return []domain.WebhookDelivery{}, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var deliveries []domain.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return []domain.WebhookDelivery{}, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return []domain.WebhookDelivery{}, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+type postgresWebhook struct {
+	ID                  uuid.UUID
+	UserId              uuid.UUID
+	URL                 string
+	Secret              string
+	EventTypes          []string
+	Disabled            bool
+	ConsecutiveFailures int
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func scanWebhook(row pgx.Row) (postgresWebhook, error) {
+	var w postgresWebhook
+	err := row.Scan(&w.ID, &w.UserId, &w.URL, &w.Secret, &w.EventTypes, &w.Disabled, &w.ConsecutiveFailures, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+func toPostgresWebhook(webhook domain.Webhook) postgresWebhook {
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, eventType := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	return postgresWebhook{
+		ID:                  webhook.ID,
+		UserId:              webhook.UserId,
+		URL:                 webhook.URL,
+		Secret:              webhook.Secret,
+		EventTypes:          eventTypes,
+		Disabled:            webhook.Disabled,
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+		CreatedAt:           webhook.CreatedAt,
+		UpdatedAt:           webhook.UpdatedAt,
+	}
+}
+
+func toWebhook(p postgresWebhook) domain.Webhook {
+	eventTypes := make([]domain.TodoEventType, 0, len(p.EventTypes))
+	for _, eventType := range p.EventTypes {
+		eventTypes = append(eventTypes, domain.TodoEventType(eventType))
+	}
+	return domain.Webhook{
+		ID:                  p.ID,
+		UserId:              p.UserId,
+		URL:                 p.URL,
+		Secret:              p.Secret,
+		EventTypes:          eventTypes,
+		Disabled:            p.Disabled,
+		ConsecutiveFailures: p.ConsecutiveFailures,
+		CreatedAt:           p.CreatedAt,
+		UpdatedAt:           p.UpdatedAt,
+	}
+}
+
+func deliveryArgs(delivery domain.WebhookDelivery) []interface{} {
+	return []interface{}{
+		delivery.ID, delivery.WebhookId, delivery.EventId, string(delivery.EventType), delivery.Payload,
+		delivery.Attempts, delivery.StatusCode, delivery.Error, delivery.Succeeded, delivery.CreatedAt, delivery.DeliveredAt,
+		delivery.NextAttemptAt,
+	}
+}
+
+func scanDelivery(row pgx.Row) (domain.WebhookDelivery, error) {
+	var (
+		delivery  domain.WebhookDelivery
+		eventType string
+	)
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookId, &delivery.EventId, &eventType, &delivery.Payload, &delivery.Attempts,
+		&delivery.StatusCode, &delivery.Error, &delivery.Succeeded, &delivery.CreatedAt, &delivery.DeliveredAt,
+		&delivery.NextAttemptAt,
+	)
+	delivery.EventType = domain.TodoEventType(eventType)
+	return delivery, err
+}
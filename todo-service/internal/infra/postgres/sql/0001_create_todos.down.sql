@@ -0,0 +1,9 @@
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
+DROP TABLE api_calls;
+DROP TABLE usage;
+DROP TABLE outbox;
+DROP TABLE outbox_sequences;
+DROP TABLE todo_changes;
+DROP TABLE todo_change_sequences;
+DROP TABLE todos;
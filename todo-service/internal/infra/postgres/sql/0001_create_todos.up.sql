@@ -0,0 +1,92 @@
+-- NOTE: the tables are created only if missing, so that databases whose
+-- tables were created at startup, before the schema was versioned, are
+-- taken over as they are.
+CREATE TABLE IF NOT EXISTS todos(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    text TEXT NOT NULL,
+    snoozed_until TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    clocks JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS todos_user_id_snoozed_until_idx ON todos(user_id, snoozed_until);
+CREATE INDEX IF NOT EXISTS todos_snoozed_until_idx ON todos(snoozed_until) WHERE snoozed_until IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS todo_change_sequences(
+    user_id UUID PRIMARY KEY,
+    sequence BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS todo_changes(
+    todo_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    sequence BIGINT NOT NULL,
+    todo JSONB NOT NULL,
+    deleted BOOLEAN NOT NULL,
+    deleted_at TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS todo_changes_user_id_sequence_idx ON todo_changes(user_id, sequence);
+
+CREATE TABLE IF NOT EXISTS outbox_sequences(
+    todo_id UUID PRIMARY KEY,
+    sequence BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS outbox(
+    id UUID PRIMARY KEY,
+    type TEXT NOT NULL,
+    todo_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    todo JSONB NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    sequence BIGINT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    dispatched BOOLEAN NOT NULL DEFAULT FALSE,
+    dispatched_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox(occurred_at) WHERE NOT dispatched;
+CREATE INDEX IF NOT EXISTS outbox_dispatched_at_idx ON outbox(dispatched_at) WHERE dispatched;
+
+CREATE TABLE IF NOT EXISTS usage(
+    user_id UUID PRIMARY KEY,
+    plan TEXT NOT NULL DEFAULT '',
+    todos BIGINT NOT NULL DEFAULT 0,
+    attachment_bytes BIGINT NOT NULL DEFAULT 0,
+    webhooks BIGINT NOT NULL DEFAULT 0,
+    counted BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE TABLE IF NOT EXISTS api_calls(
+    user_id UUID NOT NULL,
+    period TIMESTAMPTZ NOT NULL,
+    calls BIGINT NOT NULL,
+    PRIMARY KEY (user_id, period)
+);
+CREATE INDEX IF NOT EXISTS api_calls_period_idx ON api_calls(period);
+
+CREATE TABLE IF NOT EXISTS webhooks(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    disabled BOOLEAN NOT NULL,
+    consecutive_failures INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks(user_id);
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+    id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload BYTEA,
+    attempts INT NOT NULL,
+    status_code INT NOT NULL,
+    error TEXT NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_created_at_idx ON webhook_deliveries(webhook_id, created_at DESC);
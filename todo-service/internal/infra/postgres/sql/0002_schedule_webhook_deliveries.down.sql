@@ -0,0 +1,2 @@
+DROP INDEX webhook_deliveries_next_attempt_at_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN next_attempt_at;
@@ -0,0 +1,4 @@
+-- NOTE: the column may have been added at startup before the schema was
+-- versioned.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS webhook_deliveries_next_attempt_at_idx ON webhook_deliveries(next_attempt_at) WHERE next_attempt_at IS NOT NULL;
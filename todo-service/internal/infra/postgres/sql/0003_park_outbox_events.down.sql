@@ -0,0 +1,2 @@
+ALTER TABLE outbox DROP COLUMN parked_at;
+ALTER TABLE outbox DROP COLUMN parked;
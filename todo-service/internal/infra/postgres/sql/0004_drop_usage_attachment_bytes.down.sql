@@ -0,0 +1 @@
+ALTER TABLE usage ADD COLUMN attachment_bytes BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE usage DROP COLUMN IF EXISTS attachment_bytes;
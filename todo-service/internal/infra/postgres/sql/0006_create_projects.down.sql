@@ -0,0 +1,3 @@
+ALTER TABLE todos DROP COLUMN labels;
+ALTER TABLE todos DROP COLUMN project_id;
+DROP TABLE projects;
@@ -0,0 +1,15 @@
+CREATE TABLE projects(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX projects_user_id_created_at_idx ON projects(user_id, created_at);
+
+-- Todos start out in the personal project of their owner, which shares the
+-- owner's id.
+ALTER TABLE todos ADD COLUMN project_id UUID;
+UPDATE todos SET project_id = user_id;
+ALTER TABLE todos ALTER COLUMN project_id SET NOT NULL;
+ALTER TABLE todos ADD COLUMN labels TEXT[] NOT NULL DEFAULT '{}';
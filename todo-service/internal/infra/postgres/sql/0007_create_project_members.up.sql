@@ -0,0 +1,6 @@
+CREATE TABLE project_members(
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (project_id, user_id)
+);
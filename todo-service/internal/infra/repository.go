@@ -2,15 +2,119 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/olad5/productive-pulse/todo-service/internal/domain"
 )
 
+var (
+	ErrRecordNotFound    = errors.New("record not found")
+	ErrNotOwnerOfTodo    = errors.New("current user is not owner of this todo")
+	ErrNotOwnerOfWebhook = errors.New("current user is not owner of this webhook")
+	ErrDuplicateDelivery = errors.New("delivery already exists")
+)
+
+// TodoRepository records every write in the owner's change log, in the same
+// transaction as the write itself.
 type TodoRepository interface {
 	Ping(ctx context.Context) error
+	// Close releases the connections of the repository, which must not be
+	// used afterwards.
+	Close(ctx context.Context) error
 	CreateTodo(ctx context.Context, todo domain.Todo) error
-	UpdateTodo(ctx context.Context, todo domain.Todo) error
+	// UpdateTodo writes updated_at and the given fields of todo along with
+	// their clocks, leaving the rest of the stored todo untouched.
+	UpdateTodo(ctx context.Context, todo domain.Todo, fields ...domain.TodoField) error
+	// DeleteTodo removes todo and leaves a tombstone stamped deletedAt in the
+	// change log.
+	DeleteTodo(ctx context.Context, todo domain.Todo, deletedAt domain.HLC) error
 	GetTodo(ctx context.Context, userId, todoId uuid.UUID) (domain.Todo, error)
-	GetTodos(ctx context.Context, userId uuid.UUID) ([]domain.Todo, error)
+	GetTodos(ctx context.Context, userId uuid.UUID, includeSnoozed bool) ([]domain.Todo, error)
+	// GetTodosByIds returns the todos of userId among todoIds in one read;
+	// ids that are missing or owned by someone else are skipped.
+	GetTodosByIds(ctx context.Context, userId uuid.UUID, todoIds []uuid.UUID) ([]domain.Todo, error)
+	GetDueSnoozedTodos(ctx context.Context, now time.Time) ([]domain.Todo, error)
+	// UnsnoozeTodo clears the snooze of todo, stamped at, unless it was
+	// changed since todo was read, and reports whether it did.
+	UnsnoozeTodo(ctx context.Context, todo domain.Todo, now time.Time, at domain.HLC) (bool, error)
+	// GetChangesSince returns up to limit entries of the user's change log
+	// with a sequence above sequence, in sequence order.
+	GetChangesSince(ctx context.Context, userId uuid.UUID, sequence int64, limit int) ([]domain.TodoChange, error)
+	// GetChange returns the change log entry of a todo, which outlives the
+	// todo as a tombstone once it is deleted.
+	GetChange(ctx context.Context, todoId uuid.UUID) (domain.TodoChange, error)
+	// RunInTransaction runs fn so that every repository call made with the
+	// context it receives commits or rolls back together. Called within a
+	// running transaction, fn joins it.
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ProjectRepository keeps the projects users file todos under. The personal
+// project of a user is not stored.
+type ProjectRepository interface {
+	// CreateProject joins the transaction running in ctx, if any.
+	CreateProject(ctx context.Context, project domain.Project) error
+	// GetProject returns the project whoever owns it.
+	GetProject(ctx context.Context, projectId uuid.UUID) (domain.Project, error)
+	// GetProjects returns the projects of userId, oldest first.
+	GetProjects(ctx context.Context, userId uuid.UUID) ([]domain.Project, error)
+	// AddProjectMember leaves a member that was already added as it is.
+	AddProjectMember(ctx context.Context, member domain.ProjectMember) error
+	IsProjectMember(ctx context.Context, projectId, userId uuid.UUID) (bool, error)
+}
+
+// UsageRepository reads the counters quotas are checked against. The todo
+// and webhook repositories keep them up to date in the transactions of their
+// writes.
+type UsageRepository interface {
+	// GetUsage returns what userId holds and the API calls they made during
+	// period. It joins the transaction running in ctx, if any, so a check
+	// made in it holds until it commits.
+	GetUsage(ctx context.Context, userId uuid.UUID, period time.Time) (domain.Usage, error)
+	// RecordAPICall counts a call of userId during period unless limit calls
+	// were counted already, and reports whether it did. A negative limit
+	// counts every call.
+	RecordAPICall(ctx context.Context, userId uuid.UUID, period time.Time, limit int64) (bool, error)
+}
+
+type OutboxRepository interface {
+	// AppendEvents joins the transaction running in ctx, if any.
+	AppendEvents(ctx context.Context, events ...domain.TodoEvent) error
+	// GetPendingEvents returns up to limit events that were neither
+	// dispatched nor parked, oldest first.
+	GetPendingEvents(ctx context.Context, limit int) ([]domain.OutboxEntry, error)
+	MarkEventDispatched(ctx context.Context, eventId uuid.UUID) error
+	MarkEventFailed(ctx context.Context, eventId uuid.UUID, cause error) error
+	// MarkEventParked records the last failure of an event that will not be
+	// relayed again. It stays in the outbox, with its error, to be looked into.
+	MarkEventParked(ctx context.Context, eventId uuid.UUID, cause error) error
+}
+
+type WebhookRepository interface {
+	// Close releases the connections of the repository, which must not be
+	// used afterwards.
+	Close(ctx context.Context) error
+	CreateWebhook(ctx context.Context, webhook domain.Webhook) error
+	UpdateWebhook(ctx context.Context, webhook domain.Webhook) error
+	DeleteWebhook(ctx context.Context, webhookId uuid.UUID) error
+	GetWebhook(ctx context.Context, userId, webhookId uuid.UUID) (domain.Webhook, error)
+	GetWebhooks(ctx context.Context, userId uuid.UUID) ([]domain.Webhook, error)
+	// GetWebhooksForEvent returns the enabled webhooks of userId that
+	// subscribe to eventType.
+	GetWebhooksForEvent(ctx context.Context, userId uuid.UUID, eventType domain.TodoEventType) ([]domain.Webhook, error)
+	// GetWebhookForDelivery returns the webhook whoever owns it, for the
+	// deliveries made to it.
+	GetWebhookForDelivery(ctx context.Context, webhookId uuid.UUID) (domain.Webhook, error)
+
+	// CreateDelivery fails with ErrDuplicateDelivery when a delivery with
+	// the same id exists.
+	CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	GetDelivery(ctx context.Context, webhookId, deliveryId uuid.UUID) (domain.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookId uuid.UUID) ([]domain.WebhookDelivery, error)
+	// GetDueDeliveries returns up to limit deliveries whose next attempt is
+	// due at now, longest due first.
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error)
 }
@@ -0,0 +1,98 @@
+package user
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+// The values are those of the user_service.breaker.state gauge.
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker opens after threshold calls in a row failed, and fails
+// calls fast while it is open. Once openFor passed, it lets a single trial
+// call through: the breaker closes if it succeeds, and opens again if not.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	openFor   time.Duration
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	// trialRunning is set while the trial call of a half-open breaker runs.
+	trialRunning bool
+}
+
+func newCircuitBreaker(threshold int, openFor time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, openFor: openFor}
+}
+
+// allow reports whether a call may be made now. A call that is allowed must
+// be followed by record.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && now.Sub(b.openedAt) >= b.openFor {
+		b.state = breakerHalfOpen
+	}
+	switch b.state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		if b.trialRunning {
+			return false
+		}
+		b.trialRunning = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) record(succeeded bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialRunning = false
+	if succeeded {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+// abandon stands in for record when an allowed call was cancelled before
+// users-service answered, which tells nothing of its health.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialRunning = false
+}
+
+func (b *circuitBreaker) current() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,175 @@
+package user
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/pkg/jwks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// minRefreshInterval spaces out the reads of the keys asked for by tokens
+// that name a key LocalUserService does not know.
+const minRefreshInterval = 10 * time.Second
+
+var (
+	ErrInvalidToken = appErrors.ErrUnauthorized.Wrap(errors.New("invalid token"))
+	errUnknownKey   = errors.New("unknown signing key")
+)
+
+// LocalUserService verifies access tokens itself, with the public keys
+// users-service publishes at jwksUrl, so requests do not wait on
+// users-service. A token signed with a key it does not know, such as one
+// just rotated in, is verified by the adapter it wraps while the keys are
+// read again.
+type LocalUserService struct {
+	client          *http.Client
+	jwksUrl         string
+	remote          UserServiceAdapter
+	refreshInterval time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	refreshedAt time.Time
+	// refresh asks Run to read the keys before the next interval.
+	refresh chan struct{}
+}
+
+func NewLocalUserService(client *http.Client, jwksUrl string, remote UserServiceAdapter, refreshInterval time.Duration) (*LocalUserService, error) {
+	if client == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if jwksUrl == "" {
+		return nil, errors.New("jwksUrl cannot be empty")
+	}
+	if remote == nil {
+		return nil, errors.New("remote cannot be empty")
+	}
+	if refreshInterval <= 0 {
+		return nil, errors.New("refreshInterval must be positive")
+	}
+	return &LocalUserService{
+		client:          client,
+		jwksUrl:         jwksUrl,
+		remote:          remote,
+		refreshInterval: refreshInterval,
+		keys:            map[string]crypto.PublicKey{},
+		refresh:         make(chan struct{}, 1),
+	}, nil
+}
+
+// Run reads the keys every refreshInterval, and sooner when a token names a
+// key that is not among them, until ctx is cancelled.
+func (u *LocalUserService) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-u.refresh:
+			u.mu.RLock()
+			recent := time.Since(u.refreshedAt) < minRefreshInterval
+			u.mu.RUnlock()
+			if recent {
+				continue
+			}
+		}
+		if err := u.Refresh(ctx); err != nil {
+			log.Printf("LocalUserService failed to refresh the keys: %v", err)
+		}
+	}
+}
+
+// Refresh reads the keys from users-service. The keys read before are kept
+// when it fails.
+func (u *LocalUserService) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.jwksUrl, nil)
+	if err != nil {
+		return err
+	}
+	res, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad request to the JWKS of UserService: %d", res.StatusCode)
+	}
+	var set jwks.Set
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("could not decode the JWKS of UserService: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			// NOTE: a key of a type this version cannot use is skipped, so
+			// the others still verify tokens.
+			log.Printf("LocalUserService skipped a key: %v", err)
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	u.mu.Lock()
+	u.keys = keys
+	u.refreshedAt = time.Now()
+	u.mu.Unlock()
+	return nil
+}
+
+func (u *LocalUserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	ctx, span := tracer.Start(ctx, "user-service-local-adapter")
+	defer span.End()
+
+	const Bearer = "Bearer "
+	if !strings.HasPrefix(authHeader, Bearer) {
+		return "", ErrInvalidAuthHeader
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(authHeader, Bearer), &claims, u.keyFunc,
+		jwt.WithValidMethods([]string{jwks.RS256, jwks.EdDSA}),
+	)
+	if errors.Is(err, errUnknownKey) {
+		span.SetAttributes(attribute.Bool("user.remote_verification", true))
+		select {
+		case u.refresh <- struct{}{}:
+		default:
+		}
+		return u.remote.VerifyUser(ctx, tracer, authHeader)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.ExpiresAt == nil || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
+
+func (u *LocalUserService) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	u.mu.RLock()
+	publicKey, ok := u.keys[kid]
+	u.mu.RUnlock()
+	if !ok {
+		return nil, errUnknownKey
+	}
+	return publicKey, nil
+}
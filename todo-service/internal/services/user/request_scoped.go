@@ -0,0 +1,86 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type verificationsKey struct{}
+
+type verification struct {
+	userId string
+	err    error
+}
+
+// verifications holds the outcome of the Authorization headers verified
+// while serving one request, until they are reused.
+type verifications struct {
+	mu       sync.Mutex
+	byHeader map[string]verification
+}
+
+// ShareVerifications lets a verification made with VerifyAndShare while
+// serving a request be reused by the next VerifyUser call for the same
+// Authorization header, so the rate limiter, the API call counter and the
+// handler do not each call users-service.
+func ShareVerifications(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), verificationsKey{}, &verifications{byHeader: map[string]verification{}})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestScopedUserService reuses the verification shared earlier in the
+// same request, and calls the adapter it wraps otherwise.
+type RequestScopedUserService struct {
+	next UserServiceAdapter
+}
+
+func NewRequestScopedUserService(next UserServiceAdapter) (*RequestScopedUserService, error) {
+	if next == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	return &RequestScopedUserService{next}, nil
+}
+
+// VerifyAndShare verifies authHeader and leaves the outcome for the next
+// VerifyUser call made while serving the same request. A verification
+// already shared is reused, and left in place.
+func (u *RequestScopedUserService) VerifyAndShare(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	shared, ok := ctx.Value(verificationsKey{}).(*verifications)
+	if !ok {
+		return u.next.VerifyUser(ctx, tracer, authHeader)
+	}
+	shared.mu.Lock()
+	v, ok := shared.byHeader[authHeader]
+	shared.mu.Unlock()
+	if ok {
+		return v.userId, v.err
+	}
+
+	userId, err := u.next.VerifyUser(ctx, tracer, authHeader)
+	shared.mu.Lock()
+	shared.byHeader[authHeader] = verification{userId, err}
+	shared.mu.Unlock()
+	return userId, err
+}
+
+// NOTE: a shared verification is only reused once. Long-lived requests such
+// as WebSockets verify their token again later, and must be verified afresh
+// to notice it expired.
+func (u *RequestScopedUserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	if shared, ok := ctx.Value(verificationsKey{}).(*verifications); ok {
+		shared.mu.Lock()
+		v, ok := shared.byHeader[authHeader]
+		delete(shared.byHeader, authHeader)
+		shared.mu.Unlock()
+		if ok {
+			return v.userId, v.err
+		}
+	}
+	return u.next.VerifyUser(ctx, tracer, authHeader)
+}
@@ -0,0 +1,276 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrUserServiceUnavailable fails verifications fast while the circuit
+	// breaker is open.
+	ErrUserServiceUnavailable = appErrors.Unavailable("user_service_unavailable", "users-service is unavailable, try again later")
+	// ErrUserServiceFailed is returned when users-service did not answer a
+	// verification after every retry.
+	ErrUserServiceFailed = appErrors.Unavailable("user_service_unavailable", "users-service is unavailable, try again later")
+)
+
+// ResiliencePolicy controls how ResilientUserService caches verifications
+// and calls the adapter it wraps.
+type ResiliencePolicy struct {
+	// CacheSize is how many verified tokens are remembered at most.
+	CacheSize int
+	// Timeout bounds each attempt at a verification.
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// FailureThreshold is how many verifications in a row may fail before
+	// the circuit breaker opens, and OpenDuration how long it stays open.
+	FailureThreshold int
+	OpenDuration     time.Duration
+}
+
+var DefaultResiliencePolicy = ResiliencePolicy{
+	CacheSize:        10000,
+	Timeout:          2 * time.Second,
+	MaxAttempts:      3,
+	InitialBackoff:   50 * time.Millisecond,
+	MaxBackoff:       500 * time.Millisecond,
+	FailureThreshold: 5,
+	OpenDuration:     10 * time.Second,
+}
+
+// ResilientUserService remembers the user of a verified token until the
+// token expires, and shares one call among concurrent verifications of the
+// same token. Calls that fail without an answer from users-service are
+// retried; once verifications keep failing, a circuit breaker fails them
+// fast with ErrUserServiceUnavailable until users-service recovers.
+// A token users-service rejected is neither retried nor remembered.
+type ResilientUserService struct {
+	next    UserServiceAdapter
+	policy  ResiliencePolicy
+	cache   *tokenCache
+	breaker *circuitBreaker
+	lookups *singleflight.Group
+
+	// flights are the contexts of the shared lookups, by token hash, which
+	// are cancelled once every verification waiting for them gave up.
+	flightsMu sync.Mutex
+	flights   map[string]*flight
+
+	cacheLookups metric.Int64Counter
+}
+
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+func NewResilientUserService(next UserServiceAdapter, meter metric.Meter, policy ResiliencePolicy) (*ResilientUserService, error) {
+	if next == nil {
+		return nil, errors.New("UserService cannot be empty")
+	}
+	if meter == nil {
+		return nil, errors.New("meter cannot be empty")
+	}
+	if policy.CacheSize < 1 {
+		return nil, errors.New("CacheSize must be at least 1")
+	}
+	if policy.Timeout <= 0 {
+		return nil, errors.New("Timeout must be positive")
+	}
+	if policy.MaxAttempts < 1 {
+		return nil, errors.New("MaxAttempts must be at least 1")
+	}
+	if policy.FailureThreshold < 1 {
+		return nil, errors.New("FailureThreshold must be at least 1")
+	}
+
+	u := &ResilientUserService{
+		next:    next,
+		policy:  policy,
+		cache:   newTokenCache(policy.CacheSize),
+		breaker: newCircuitBreaker(policy.FailureThreshold, policy.OpenDuration),
+		lookups: &singleflight.Group{},
+		flights: map[string]*flight{},
+	}
+	var err error
+	u.cacheLookups, err = meter.Int64Counter("user_service.cache.lookups",
+		metric.WithDescription("Verifications looked up in the cache, by result"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = meter.Int64ObservableGauge("user_service.breaker.state",
+		metric.WithDescription("State of the circuit breaker in front of UserService: 0 closed, 1 half-open, 2 open"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			observer.Observe(int64(u.breaker.current()))
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *ResilientUserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	ctx, span := tracer.Start(ctx, "user-service-resilient-adapter")
+	defer span.End()
+
+	tokenHash := hashToken(authHeader)
+	userId, hit := u.cache.get(tokenHash, time.Now())
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	u.cacheLookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+	span.SetAttributes(attribute.Bool("user.cache_hit", hit))
+	if hit {
+		return userId, nil
+	}
+
+	// NOTE: the call is shared with the verifications of the same token that
+	// start while it runs, so it is only cancelled once all of them gave up.
+	// It still belongs to the trace of the request that started it.
+	shared := u.join(tokenHash, span)
+	defer u.leave(tokenHash)
+	lookup := u.lookups.DoChan(tokenHash, func() (interface{}, error) {
+		return u.verify(shared, tracer, authHeader, tokenHash)
+	})
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case outcome := <-lookup:
+		span.SetAttributes(
+			attribute.Bool("user.lookup_shared", outcome.Shared),
+			attribute.String("user.breaker_state", u.breaker.current().String()),
+		)
+		if outcome.Err != nil {
+			return "", outcome.Err
+		}
+		return outcome.Val.(string), nil
+	}
+}
+
+func (u *ResilientUserService) verify(ctx context.Context, tracer trace.Tracer, authHeader, tokenHash string) (string, error) {
+	if !u.breaker.allow(time.Now()) {
+		return "", ErrUserServiceUnavailable
+	}
+	userId, err := u.verifyWithRetries(ctx, tracer, authHeader)
+	if err != nil && ctx.Err() != nil {
+		u.breaker.abandon()
+		return "", err
+	}
+	// NOTE: a rejected token is an answer, so it counts as a success of
+	// users-service.
+	rejected := errors.Is(err, appErrors.ErrUnauthorized)
+	u.breaker.record(err == nil || rejected, time.Now())
+	if rejected {
+		return "", err
+	}
+	if err != nil {
+		return "", ErrUserServiceFailed.Wrap(err)
+	}
+	if expiresAt, ok := tokenExpiry(authHeader); ok {
+		u.cache.put(tokenHash, userId, expiresAt)
+	}
+	return userId, nil
+}
+
+func (u *ResilientUserService) verifyWithRetries(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	backoff := u.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, u.policy.Timeout)
+		userId, err := u.next.VerifyUser(attemptCtx, tracer, authHeader)
+		cancel()
+		if err == nil || errors.Is(err, appErrors.ErrUnauthorized) || attempt >= u.policy.MaxAttempts {
+			return userId, err
+		}
+		wait := time.NewTimer(jitter(backoff))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return "", ctx.Err()
+		case <-wait.C:
+		}
+		backoff *= 2
+		if backoff > u.policy.MaxBackoff {
+			backoff = u.policy.MaxBackoff
+		}
+	}
+}
+
+// join counts a verification in the lookup of tokenHash and returns the
+// context of the lookup, which it starts in the trace of span if there is
+// none running.
+func (u *ResilientUserService) join(tokenHash string, span trace.Span) context.Context {
+	u.flightsMu.Lock()
+	defer u.flightsMu.Unlock()
+
+	current, ok := u.flights[tokenHash]
+	if !ok {
+		ctx, cancel := context.WithCancel(trace.ContextWithSpan(context.Background(), span))
+		current = &flight{ctx: ctx, cancel: cancel}
+		u.flights[tokenHash] = current
+	}
+	current.waiters++
+	return current.ctx
+}
+
+// leave undoes join. The last verification to leave cancels the lookup and
+// forgets it, so that the next verification of the token starts afresh
+// rather than waiting for a cancelled one.
+func (u *ResilientUserService) leave(tokenHash string) {
+	u.flightsMu.Lock()
+	defer u.flightsMu.Unlock()
+
+	current := u.flights[tokenHash]
+	current.waiters--
+	if current.waiters > 0 {
+		return
+	}
+	current.cancel()
+	delete(u.flights, tokenHash)
+	u.lookups.Forget(tokenHash)
+}
+
+// jitter picks a wait between half of backoff and backoff, so that the
+// retries of concurrent verifications spread out.
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func hashToken(authHeader string) string {
+	sum := sha256.Sum256([]byte(authHeader))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenExpiry reads the expiry of a token users-service accepted, which is
+// why its signature need not be checked again. Tokens without one are not
+// cached.
+func tokenExpiry(authHeader string) (time.Time, bool) {
+	var claims jwt.RegisteredClaims
+	_, _, err := jwt.NewParser().ParseUnverified(strings.TrimPrefix(authHeader, "Bearer "), &claims)
+	if err != nil || claims.ExpiresAt == nil {
+		return time.Time{}, false
+	}
+	return claims.ExpiresAt.Time, true
+}
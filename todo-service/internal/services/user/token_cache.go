@@ -0,0 +1,67 @@
+package user
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// tokenCache remembers the user of up to size verified tokens, until they
+// expire, evicting the least recently used first. Tokens are kept by their
+// hash, never as they are.
+type tokenCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// recency holds the most recently used entry at its front.
+	recency *list.List
+}
+
+type cachedVerification struct {
+	tokenHash string
+	userId    string
+	expiresAt time.Time
+}
+
+func newTokenCache(size int) *tokenCache {
+	return &tokenCache{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		recency: list.New(),
+	}
+}
+
+func (c *tokenCache) get(tokenHash string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[tokenHash]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(cachedVerification)
+	if !now.Before(entry.expiresAt) {
+		c.recency.Remove(element)
+		delete(c.entries, tokenHash)
+		return "", false
+	}
+	c.recency.MoveToFront(element)
+	return entry.userId, true
+}
+
+func (c *tokenCache) put(tokenHash, userId string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[tokenHash]; ok {
+		element.Value = cachedVerification{tokenHash, userId, expiresAt}
+		c.recency.MoveToFront(element)
+		return
+	}
+	c.entries[tokenHash] = c.recency.PushFront(cachedVerification{tokenHash, userId, expiresAt})
+	if c.recency.Len() > c.size {
+		oldest := c.recency.Back()
+		c.recency.Remove(oldest)
+		delete(c.entries, oldest.Value.(cachedVerification).tokenHash)
+	}
+}
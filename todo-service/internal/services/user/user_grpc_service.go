@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	usersv1 "github.com/olad5/productive-pulse/pkg/proto/users/v1"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrInvalidAuthHeader = appErrors.ErrUnauthorized.Wrap(errors.New("authorization header must be a bearer token"))
+
+// UserGrpcService verifies users by calling the users-service gRPC API
+// directly instead of going through the proxy.
+type UserGrpcService struct {
+	client usersv1.UserServiceClient
+}
+
+func NewUserGrpcService(conn grpc.ClientConnInterface) (*UserGrpcService, error) {
+	if conn == nil {
+		return nil, errors.New("conn cannot be nil")
+	}
+	return &UserGrpcService{usersv1.NewUserServiceClient(conn)}, nil
+}
+
+func (u *UserGrpcService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	ctx, span := tracer.Start(ctx, "user-service-grpc-adapter")
+	defer span.End()
+
+	const Bearer = "Bearer "
+	if !strings.HasPrefix(authHeader, Bearer) {
+		return "", ErrInvalidAuthHeader
+	}
+
+	res, err := u.client.VerifyToken(ctx, &usersv1.VerifyTokenRequest{
+		AccessToken: strings.TrimPrefix(authHeader, Bearer),
+	})
+	if code := status.Code(err); code == codes.Unauthenticated || code == codes.InvalidArgument {
+		return "", fmt.Errorf("%w: %v", ErrTokenRejected, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return res.GetUserId(), nil
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -18,6 +20,10 @@ type UserServiceResponse struct {
 	} `json:"data"`
 }
 
+// ErrTokenRejected is returned when users-service answered that a token is
+// not valid, as opposed to failing to answer.
+var ErrTokenRejected = appErrors.ErrUnauthorized.Wrap(errors.New("token rejected by UserService"))
+
 type UserServiceAdapter interface {
 	VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error)
 }
@@ -43,7 +49,7 @@ func (u *UserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authH
 	ctx, span := tracer.Start(ctx, "user-service-adapter")
 	defer span.End()
 
-	url := u.url + "/users/auth"
+	url := u.url + "/v1/users/auth"
 
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -57,6 +63,9 @@ func (u *UserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authH
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("%w: %d", ErrTokenRejected, res.StatusCode)
+	}
 	if res.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("bad request to UserService: %d", res.StatusCode)
 	}
@@ -0,0 +1,176 @@
+package projects
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ProjectService struct {
+	projectRepo infra.ProjectRepository
+}
+
+const maxProjectNameLength = 100
+
+var (
+	// NOTE: projects of other users are reported as missing, so that their
+	// ids cannot be probed.
+	ErrProjectNotFound     = appErrors.NotFound("project_not_found", "record not found")
+	ErrInvalidProjectId    = appErrors.BadRequest("invalid_project_id", "invalid projectId")
+	ErrInvalidUserId       = appErrors.Unauthorized("invalid_user_id", "failing to parse user uuid")
+	ErrProjectNameRequired = appErrors.BadRequest("name_required", "name required")
+	ErrProjectNameTooLong  = appErrors.BadRequest("project_name_too_long", "name must be at most 100 characters")
+	ErrInvalidMemberId     = appErrors.BadRequest("invalid_member_id", "invalid user_id")
+	// NOTE: the personal project is not stored, so it cannot have members.
+	ErrPersonalProjectNotShared = appErrors.BadRequest("personal_project_not_shared", "the personal project cannot have members")
+	ErrOwnerIsNotMember         = appErrors.BadRequest("owner_is_not_member", "the owner of a project cannot be added as a member")
+)
+
+func NewProjectService(projectRepo infra.ProjectRepository) (*ProjectService, error) {
+	if projectRepo == nil {
+		return &ProjectService{}, errors.New("ProjectService failed to initialize")
+	}
+	return &ProjectService{projectRepo}, nil
+}
+
+func (p *ProjectService) CreateProject(ctx context.Context, tracer trace.Tracer, userId, name string) (domain.Project, error) {
+	ctx, span := tracer.Start(ctx, "CreateProject-ProjectService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return domain.Project{}, ErrInvalidUserId
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return domain.Project{}, ErrProjectNameRequired
+	}
+	if len([]rune(name)) > maxProjectNameLength {
+		return domain.Project{}, ErrProjectNameTooLong
+	}
+
+	now := time.Now()
+	project := domain.Project{
+		ID:        uuid.New(),
+		UserId:    userIdInUUID,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.projectRepo.CreateProject(ctx, project); err != nil {
+		return domain.Project{}, err
+	}
+	return project, nil
+}
+
+// GetProjects lists the personal project of userId first, and then the
+// projects they created.
+func (p *ProjectService) GetProjects(ctx context.Context, tracer trace.Tracer, userId string) ([]domain.Project, error) {
+	ctx, span := tracer.Start(ctx, "GetProjects-ProjectService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return []domain.Project{}, ErrInvalidUserId
+	}
+	created, err := p.projectRepo.GetProjects(ctx, userIdInUUID)
+	if err != nil {
+		return []domain.Project{}, err
+	}
+	return append([]domain.Project{domain.PersonalProject(userIdInUUID)}, created...), nil
+}
+
+// GetProject returns a project userId may file todos under.
+func (p *ProjectService) GetProject(ctx context.Context, tracer trace.Tracer, userId, projectId string) (domain.Project, error) {
+	ctx, span := tracer.Start(ctx, "GetProject-ProjectService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return domain.Project{}, ErrInvalidUserId
+	}
+	projectIdInUUID, err := uuid.Parse(projectId)
+	if err != nil {
+		return domain.Project{}, ErrInvalidProjectId
+	}
+	if projectIdInUUID == userIdInUUID {
+		return domain.PersonalProject(userIdInUUID), nil
+	}
+
+	project, err := p.projectRepo.GetProject(ctx, projectIdInUUID)
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return domain.Project{}, ErrProjectNotFound
+	}
+	if err != nil {
+		return domain.Project{}, err
+	}
+	if project.UserId != userIdInUUID {
+		return domain.Project{}, ErrProjectNotFound
+	}
+	return project, nil
+}
+
+// AddProjectMember lets memberId collaborate on a project of userId.
+func (p *ProjectService) AddProjectMember(ctx context.Context, tracer trace.Tracer, userId, projectId, memberId string) (domain.ProjectMember, error) {
+	ctx, span := tracer.Start(ctx, "AddProjectMember-ProjectService")
+	defer span.End()
+
+	memberIdInUUID, err := uuid.Parse(memberId)
+	if err != nil {
+		return domain.ProjectMember{}, ErrInvalidMemberId
+	}
+	project, err := p.GetProject(ctx, tracer, userId, projectId)
+	if err != nil {
+		return domain.ProjectMember{}, err
+	}
+	if project.ID == project.UserId {
+		return domain.ProjectMember{}, ErrPersonalProjectNotShared
+	}
+	if memberIdInUUID == project.UserId {
+		return domain.ProjectMember{}, ErrOwnerIsNotMember
+	}
+
+	member := domain.ProjectMember{ProjectId: project.ID, UserId: memberIdInUUID, AddedAt: time.Now()}
+	if err := p.projectRepo.AddProjectMember(ctx, member); err != nil {
+		return domain.ProjectMember{}, err
+	}
+	return member, nil
+}
+
+// CanAccessProject reports whether userId owns or is a member of projectId.
+// Ids that do not parse are denied rather than failing.
+func (p *ProjectService) CanAccessProject(ctx context.Context, tracer trace.Tracer, userId, projectId string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "CanAccessProject-ProjectService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return false, nil
+	}
+	projectIdInUUID, err := uuid.Parse(projectId)
+	if err != nil {
+		return false, nil
+	}
+	if projectIdInUUID == userIdInUUID {
+		return true, nil
+	}
+
+	project, err := p.projectRepo.GetProject(ctx, projectIdInUUID)
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if project.UserId == userIdInUUID {
+		return true, nil
+	}
+	return p.projectRepo.IsProjectMember(ctx, projectIdInUUID, userIdInUUID)
+}
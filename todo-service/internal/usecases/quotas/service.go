@@ -0,0 +1,176 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultPlan is the plan of every user who was not moved to another one.
+const DefaultPlan = "free"
+
+var DefaultPlans = []domain.Plan{
+	{
+		Name: DefaultPlan,
+		Limits: map[domain.QuotaResource]int64{
+			domain.QuotaTodos:           500,
+			domain.QuotaAttachmentBytes: 100 << 20,
+			domain.QuotaWebhooks:        3,
+			domain.QuotaAPICalls:        10000,
+		},
+	},
+	{
+		Name: "pro",
+		Limits: map[domain.QuotaResource]int64{
+			domain.QuotaTodos:           50000,
+			domain.QuotaAttachmentBytes: 10 << 30,
+			domain.QuotaWebhooks:        50,
+			domain.QuotaAPICalls:        1000000,
+		},
+	},
+}
+
+var (
+	ErrInvalidUserId = errors.New("failing to parse user uuid")
+	ErrUnknownPlan   = errors.New("unknown plan")
+)
+
+// QuotaExceededError is returned when a write would take a user over the
+// limit of their plan.
+type QuotaExceededError struct {
+	Resource domain.QuotaResource
+	Plan     string
+	Limit    int64
+}
+
+func (q *QuotaExceededError) Error() string {
+	message := fmt.Sprintf("the %s plan allows at most %d %s", q.Plan, q.Limit, strings.ReplaceAll(string(q.Resource), "_", " "))
+	if q.Resource == domain.QuotaAPICalls {
+		message += " a month"
+	}
+	return message
+}
+
+// Code identifies the exceeded quota to clients, e.g. "todos_quota_exceeded".
+func (q *QuotaExceededError) Code() string {
+	return string(q.Resource) + "_quota_exceeded"
+}
+
+// AppError answers requests over a quota with a 403 naming it.
+func (q *QuotaExceededError) AppError() *appErrors.Error {
+	return appErrors.Forbidden(q.Code(), q.Error())
+}
+
+type QuotaService struct {
+	usageRepo infra.UsageRepository
+	plans     map[string]domain.Plan
+}
+
+func NewQuotaService(usageRepo infra.UsageRepository, plans []domain.Plan) (*QuotaService, error) {
+	if usageRepo == nil {
+		return &QuotaService{}, errors.New("QuotaService failed to initialize")
+	}
+	plansByName := make(map[string]domain.Plan, len(plans))
+	for _, plan := range plans {
+		plansByName[plan.Name] = plan
+	}
+	if _, ok := plansByName[DefaultPlan]; !ok {
+		return &QuotaService{}, fmt.Errorf("plans must include the %q plan", DefaultPlan)
+	}
+	return &QuotaService{usageRepo, plansByName}, nil
+}
+
+// CheckQuota returns a QuotaExceededError if adding additional of resource
+// would take userId over their limit. Run in the transaction of the write it
+// guards, the check holds until the write commits.
+func (q *QuotaService) CheckQuota(ctx context.Context, tracer trace.Tracer, userId uuid.UUID, resource domain.QuotaResource, additional int64) error {
+	ctx, span := tracer.Start(ctx, "CheckQuota-QuotaService")
+	defer span.End()
+
+	usage, err := q.usageRepo.GetUsage(ctx, userId, domain.UsagePeriod(time.Now()))
+	if err != nil {
+		return err
+	}
+	plan, err := q.plan(usage)
+	if err != nil {
+		return err
+	}
+	limit, ok := plan.Limit(resource)
+	if ok && usage.Used[resource]+additional > limit {
+		return &QuotaExceededError{Resource: resource, Plan: plan.Name, Limit: limit}
+	}
+	return nil
+}
+
+// RecordAPICall counts a call of userId, or returns a QuotaExceededError
+// without counting it once they used up the calls of their plan.
+func (q *QuotaService) RecordAPICall(ctx context.Context, tracer trace.Tracer, userId string) error {
+	ctx, span := tracer.Start(ctx, "RecordAPICall-QuotaService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return ErrInvalidUserId
+	}
+	period := domain.UsagePeriod(time.Now())
+	usage, err := q.usageRepo.GetUsage(ctx, userIdInUUID, period)
+	if err != nil {
+		return err
+	}
+	plan, err := q.plan(usage)
+	if err != nil {
+		return err
+	}
+	limit, ok := plan.Limit(domain.QuotaAPICalls)
+	if !ok {
+		limit = -1
+	}
+	recorded, err := q.usageRepo.RecordAPICall(ctx, userIdInUUID, period, limit)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		return &QuotaExceededError{Resource: domain.QuotaAPICalls, Plan: plan.Name, Limit: limit}
+	}
+	return nil
+}
+
+// GetUsage returns the usage of userId along with the plan it is limited by.
+func (q *QuotaService) GetUsage(ctx context.Context, tracer trace.Tracer, userId string) (domain.Usage, domain.Plan, error) {
+	ctx, span := tracer.Start(ctx, "GetUsage-QuotaService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return domain.Usage{}, domain.Plan{}, ErrInvalidUserId
+	}
+	usage, err := q.usageRepo.GetUsage(ctx, userIdInUUID, domain.UsagePeriod(time.Now()))
+	if err != nil {
+		return domain.Usage{}, domain.Plan{}, err
+	}
+	plan, err := q.plan(usage)
+	if err != nil {
+		return domain.Usage{}, domain.Plan{}, err
+	}
+	return usage, plan, nil
+}
+
+func (q *QuotaService) plan(usage domain.Usage) (domain.Plan, error) {
+	name := usage.Plan
+	if name == "" {
+		name = DefaultPlan
+	}
+	plan, ok := q.plans[name]
+	if !ok {
+		return domain.Plan{}, fmt.Errorf("%w %q for user %s", ErrUnknownPlan, name, usage.UserId)
+	}
+	return plan, nil
+}
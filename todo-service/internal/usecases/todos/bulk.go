@@ -0,0 +1,133 @@
+package todos
+
+import (
+	"context"
+	"fmt"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type BulkOperationType string
+
+const (
+	BulkCreate   BulkOperationType = "create"
+	BulkUpdate   BulkOperationType = "update"
+	BulkComplete BulkOperationType = "complete"
+	BulkDelete   BulkOperationType = "delete"
+	BulkMove     BulkOperationType = "move"
+	BulkLabel    BulkOperationType = "label"
+)
+
+const MaxBulkOperations = 100
+
+var (
+	ErrTooManyBulkOperations = appErrors.BadRequest("too_many_bulk_operations", fmt.Sprintf("a bulk request cannot have more than %d operations", MaxBulkOperations))
+	ErrEmptyBulkRequest      = appErrors.BadRequest("operations_required", "operations required")
+	ErrUnknownBulkOperation  = appErrors.BadRequest("unknown_bulk_operation", "unknown operation")
+)
+
+type BulkOperation struct {
+	Type      BulkOperationType
+	TodoId    string
+	Text      string
+	ProjectId string
+	// Labels replace the labels of the todo on a label operation.
+	Labels []string
+}
+
+type BulkResult struct {
+	Type BulkOperationType
+	Todo domain.Todo
+	Err  error
+}
+
+// BulkOperationError is returned by an atomic BulkTodos call and identifies
+// the operation that caused the transaction to roll back.
+type BulkOperationError struct {
+	Index int
+	Err   error
+}
+
+func (b *BulkOperationError) Error() string {
+	return fmt.Sprintf("operation %d failed: %v", b.Index, b.Err)
+}
+
+func (b *BulkOperationError) Unwrap() error {
+	return b.Err
+}
+
+// BulkTodos applies operations in order on behalf of userId. When atomic is
+// true the operations run in one transaction and the first failure rolls all
+// of them back; otherwise every operation is attempted and reports its own
+// outcome in the returned results.
+func (t *TodoService) BulkTodos(ctx context.Context, tracer trace.Tracer, userId string, operations []BulkOperation, atomic bool) ([]BulkResult, error) {
+	ctx, span := tracer.Start(ctx, "BulkTodos-TodoService")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("bulk.operations", len(operations)),
+		attribute.Bool("bulk.atomic", atomic),
+	)
+
+	if len(operations) == 0 {
+		return []BulkResult{}, ErrEmptyBulkRequest
+	}
+	if len(operations) > MaxBulkOperations {
+		return []BulkResult{}, ErrTooManyBulkOperations
+	}
+
+	if !atomic {
+		results := make([]BulkResult, 0, len(operations))
+		for _, operation := range operations {
+			results = append(results, t.applyBulkOperation(ctx, tracer, userId, operation))
+		}
+		return results, nil
+	}
+
+	var results []BulkResult
+	err := t.todoRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		// NOTE: the transaction may be retried, so results are rebuilt on
+		// every attempt.
+		results = make([]BulkResult, 0, len(operations))
+		for index, operation := range operations {
+			result := t.applyBulkOperation(ctx, tracer, userId, operation)
+			if result.Err != nil {
+				return &BulkOperationError{Index: index, Err: result.Err}
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return []BulkResult{}, err
+	}
+
+	return results, nil
+}
+
+func (t *TodoService) applyBulkOperation(ctx context.Context, tracer trace.Tracer, userId string, operation BulkOperation) BulkResult {
+	var todo domain.Todo
+	var err error
+	switch operation.Type {
+	case BulkCreate:
+		todo, err = t.CreateTodo(ctx, tracer, userId, operation.Text)
+	case BulkUpdate:
+		todo, err = t.UpdateTodo(ctx, tracer, userId, operation.TodoId, operation.Text)
+	case BulkComplete:
+		todo, err = t.CompleteTodo(ctx, tracer, userId, operation.TodoId)
+	case BulkDelete:
+		todo, err = t.DeleteTodo(ctx, tracer, userId, operation.TodoId)
+	case BulkMove:
+		todo, err = t.MoveTodo(ctx, tracer, userId, operation.TodoId, operation.ProjectId)
+	case BulkLabel:
+		todo, err = t.LabelTodo(ctx, tracer, userId, operation.TodoId, operation.Labels)
+	default:
+		err = ErrUnknownBulkOperation
+	}
+	if err != nil {
+		return BulkResult{Type: operation.Type, Err: err}
+	}
+	return BulkResult{Type: operation.Type, Todo: todo}
+}
@@ -0,0 +1,18 @@
+package todos
+
+import (
+	"context"
+
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+// commit runs write and appends events to the outbox in one transaction, so
+// an event is recorded if and only if the change it describes is.
+func (t *TodoService) commit(ctx context.Context, write func(ctx context.Context) error, events ...domain.TodoEvent) error {
+	return t.todoRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		return t.outbox.AppendEvents(ctx, events...)
+	})
+}
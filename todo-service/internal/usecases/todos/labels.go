@@ -0,0 +1,72 @@
+package todos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	MaxTodoLabels  = 20
+	maxLabelLength = 50
+)
+
+var (
+	ErrInvalidLabel  = appErrors.BadRequest("invalid_label", fmt.Sprintf("labels must be between 1 and %d characters", maxLabelLength))
+	ErrTooManyLabels = appErrors.BadRequest("too_many_labels", fmt.Sprintf("a todo can have at most %d labels", MaxTodoLabels))
+)
+
+// MoveTodo files a todo of userId under projectId, which must be one of
+// their projects.
+func (t *TodoService) MoveTodo(ctx context.Context, tracer trace.Tracer, userId, todoId, projectId string) (domain.Todo, error) {
+	projectIdInUUID, err := uuid.Parse(projectId)
+	if err != nil {
+		return domain.Todo{}, projects.ErrInvalidProjectId
+	}
+	return t.PatchTodo(ctx, tracer, userId, todoId, func(todo domain.Todo) (domain.Todo, error) {
+		todo.ProjectId = projectIdInUUID
+		return todo, nil
+	})
+}
+
+// LabelTodo replaces the labels of a todo of userId.
+func (t *TodoService) LabelTodo(ctx context.Context, tracer trace.Tracer, userId, todoId string, labels []string) (domain.Todo, error) {
+	return t.PatchTodo(ctx, tracer, userId, todoId, func(todo domain.Todo) (domain.Todo, error) {
+		todo.Labels = labels
+		return todo, nil
+	})
+}
+
+// checkProject fails unless the owner of todo may file it under its project.
+func (t *TodoService) checkProject(ctx context.Context, tracer trace.Tracer, todo domain.Todo) error {
+	_, err := t.projects.GetProject(ctx, tracer, todo.UserId.String(), todo.ProjectId.String())
+	return err
+}
+
+// normalizeLabels trims every label and drops repeated ones, keeping the
+// order they were given in.
+func normalizeLabels(labels []string) ([]string, error) {
+	normalized := make([]string, 0, len(labels))
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || len([]rune(label)) > maxLabelLength {
+			return []string{}, ErrInvalidLabel
+		}
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	if len(normalized) > MaxTodoLabels {
+		return []string{}, ErrTooManyLabels
+	}
+	return normalized, nil
+}
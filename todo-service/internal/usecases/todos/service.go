@@ -3,34 +3,57 @@ package todos
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/olad5/productive-pulse/config"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 	"github.com/olad5/productive-pulse/todo-service/internal/domain"
 	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type TodoService struct {
 	todoRepo infra.TodoRepository
+	outbox   infra.OutboxRepository
+	quotas   *quotas.QuotaService
+	projects *projects.ProjectService
+	// clock stamps every field write so offline edits synced later resolve
+	// against server-side writes by HLC.
+	clock *domain.Clock
 
 	configurations *config.Configurations
 }
 
 var (
-	ErrTodoNotFound   = errors.New("record not found")
-	ErrInvalidToken   = errors.New("invalid token")
-	ErrInvalidTodoId  = errors.New("failing to parse todo uuid")
-	ErrInvalidUserId  = errors.New("failing to parse user uuid")
-	ErrNotOwnerOfTodo = errors.New("current user is not owner of this todo")
+	ErrTodoNotFound  = appErrors.NotFound("todo_not_found", "record not found")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrInvalidTodoId = appErrors.BadRequest("invalid_todo_id", "invalid todoId")
+	ErrInvalidUserId = appErrors.Unauthorized("invalid_user_id", "failing to parse user uuid")
+	// NOTE: todos of other users answer with 401 rather than 403, as they
+	// always have.
+	ErrNotOwnerOfTodo = appErrors.Unauthorized("not_owner_of_todo", "current user is not owner of this todo")
+	ErrSnoozeInPast   = appErrors.BadRequest("snooze_in_past", "snooze time must be in the future")
+	ErrEmptyTodoText  = appErrors.BadRequest("text_required", "Text required")
 )
 
-func NewTodoService(todoRepo infra.TodoRepository, configurations *config.Configurations) (*TodoService, error) {
+func NewTodoService(todoRepo infra.TodoRepository, outbox infra.OutboxRepository, quotaService *quotas.QuotaService, projectService *projects.ProjectService, configurations *config.Configurations) (*TodoService, error) {
 	if todoRepo == nil {
 		return &TodoService{}, errors.New("TodoService failed to initialize")
 	}
-	return &TodoService{todoRepo, configurations}, nil
+	if outbox == nil {
+		return &TodoService{}, errors.New("TodoService failed to initialize")
+	}
+	if quotaService == nil {
+		return &TodoService{}, errors.New("TodoService failed to initialize")
+	}
+	if projectService == nil {
+		return &TodoService{}, errors.New("TodoService failed to initialize")
+	}
+	return &TodoService{todoRepo, outbox, quotaService, projectService, domain.NewClock(uuid.NewString()), configurations}, nil
 }
 
 func (t *TodoService) CreateTodo(ctx context.Context, tracer trace.Tracer, userId, text string) (domain.Todo, error) {
@@ -41,15 +64,26 @@ func (t *TodoService) CreateTodo(ctx context.Context, tracer trace.Tracer, userI
 	if err != nil {
 		return domain.Todo{}, ErrInvalidUserId
 	}
+	if err := checkTodoText(text); err != nil {
+		return domain.Todo{}, err
+	}
 	newTodo := domain.Todo{
 		ID:        uuid.New(),
 		UserId:    userIdInUUId,
 		Text:      text,
+		ProjectId: userIdInUUId,
+		Labels:    []string{},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	newTodo = newTodo.Stamp(t.clock.Now(), domain.TodoFields...)
 
-	err = t.todoRepo.CreateTodo(ctx, newTodo)
+	err = t.commit(ctx, func(ctx context.Context) error {
+		if err := t.quotas.CheckQuota(ctx, tracer, userIdInUUId, domain.QuotaTodos, 1); err != nil {
+			return err
+		}
+		return t.todoRepo.CreateTodo(ctx, newTodo)
+	}, domain.NewTodoEvent(domain.TodoCreated, newTodo))
 	if err != nil {
 		return domain.Todo{}, err
 	}
@@ -69,21 +103,22 @@ func (t *TodoService) UpdateTodo(ctx context.Context, tracer trace.Tracer, userI
 	if err != nil {
 		return domain.Todo{}, ErrInvalidUserId
 	}
-
-	existingTodo, err := t.todoRepo.GetTodo(ctx, userIdInUUID, todoIdInUUID)
-	if err != nil && err.Error() == ErrTodoNotFound.Error() {
-		return domain.Todo{}, ErrTodoNotFound
+	if err := checkTodoText(updatedText); err != nil {
+		return domain.Todo{}, err
 	}
 
-	updatedTodo := domain.Todo{
-		ID:        existingTodo.ID,
-		UserId:    existingTodo.UserId,
-		Text:      updatedText,
-		CreatedAt: existingTodo.CreatedAt,
-		UpdatedAt: existingTodo.UpdatedAt,
+	existingTodo, err := t.getTodo(ctx, userIdInUUID, todoIdInUUID)
+	if err != nil {
+		return domain.Todo{}, err
 	}
 
-	err = t.todoRepo.UpdateTodo(ctx, updatedTodo)
+	updatedTodo := existingTodo
+	updatedTodo.Text = updatedText
+	updatedTodo = updatedTodo.Stamp(t.clock.Now(), domain.TodoFieldText)
+
+	err = t.commit(ctx, func(ctx context.Context) error {
+		return t.todoRepo.UpdateTodo(ctx, updatedTodo, domain.TodoFieldText)
+	}, domain.NewTodoEvent(domain.TodoUpdated, updatedTodo))
 	if err != nil {
 		return domain.Todo{}, err
 	}
@@ -91,6 +126,63 @@ func (t *TodoService) UpdateTodo(ctx context.Context, tracer trace.Tracer, userI
 	return updatedTodo, nil
 }
 
+// PatchTodo loads the todo, lets patch compute its new state and persists
+// only the fields that changed.
+func (t *TodoService) PatchTodo(ctx context.Context, tracer trace.Tracer, userId, todoId string, patch func(domain.Todo) (domain.Todo, error)) (domain.Todo, error) {
+	ctx, span := tracer.Start(ctx, "PatchTodo-TodoService")
+	defer span.End()
+
+	existingTodo, err := t.GetTodo(ctx, tracer, userId, todoId)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	patchedTodo, err := patch(existingTodo)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+	patchedTodo.ID = existingTodo.ID
+	patchedTodo.UserId = existingTodo.UserId
+	patchedTodo.CreatedAt = existingTodo.CreatedAt
+	patchedTodo.Labels, err = normalizeLabels(patchedTodo.Labels)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	changedFields := existingTodo.ChangedFields(patchedTodo)
+	if len(changedFields) == 0 {
+		return existingTodo, nil
+	}
+	if err := checkTodoText(patchedTodo.Text); err != nil {
+		return domain.Todo{}, err
+	}
+	for _, field := range changedFields {
+		if field == domain.TodoFieldSnoozedUntil && patchedTodo.SnoozedUntil != nil && !patchedTodo.IsSnoozed(time.Now()) {
+			return domain.Todo{}, ErrSnoozeInPast
+		}
+		if field == domain.TodoFieldProjectId {
+			if err := t.checkProject(ctx, tracer, patchedTodo); err != nil {
+				return domain.Todo{}, err
+			}
+		}
+	}
+	patchedTodo.UpdatedAt = time.Now()
+	patchedTodo = patchedTodo.Stamp(t.clock.Now(), changedFields...)
+
+	todoEvents := []domain.TodoEvent{domain.NewTodoEvent(domain.TodoUpdated, patchedTodo)}
+	if existingTodo.CompletedAt == nil && patchedTodo.CompletedAt != nil {
+		todoEvents = append(todoEvents, domain.NewTodoEvent(domain.TodoCompleted, patchedTodo))
+	}
+	err = t.commit(ctx, func(ctx context.Context) error {
+		return t.todoRepo.UpdateTodo(ctx, patchedTodo, changedFields...)
+	}, todoEvents...)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	return patchedTodo, nil
+}
+
 func (t *TodoService) GetTodo(ctx context.Context, tracer trace.Tracer, userId, todoId string) (domain.Todo, error) {
 	ctx, span := tracer.Start(ctx, "GetTodo-TodoService")
 	defer span.End()
@@ -104,21 +196,35 @@ func (t *TodoService) GetTodo(ctx context.Context, tracer trace.Tracer, userId,
 		return domain.Todo{}, ErrInvalidUserId
 	}
 
-	todo, err := t.todoRepo.GetTodo(ctx, userIdInUUID, todoIdInUUID)
-	if err != nil && err.Error() == ErrTodoNotFound.Error() {
+	return t.getTodo(ctx, userIdInUUID, todoIdInUUID)
+}
+
+// getTodo reads a todo of userId, turning the repository's errors into the
+// service's own.
+// checkTodoText rejects a text that is empty or only whitespace, whichever
+// API it came through.
+func checkTodoText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return ErrEmptyTodoText
+	}
+	return nil
+}
+
+func (t *TodoService) getTodo(ctx context.Context, userId, todoId uuid.UUID) (domain.Todo, error) {
+	todo, err := t.todoRepo.GetTodo(ctx, userId, todoId)
+	if errors.Is(err, infra.ErrRecordNotFound) {
 		return domain.Todo{}, ErrTodoNotFound
 	}
-	if err != nil && err.Error() == ErrNotOwnerOfTodo.Error() {
+	if errors.Is(err, infra.ErrNotOwnerOfTodo) {
 		return domain.Todo{}, ErrNotOwnerOfTodo
 	}
 	if err != nil {
 		return domain.Todo{}, err
 	}
-
 	return todo, nil
 }
 
-func (t *TodoService) GetTodos(ctx context.Context, tracer trace.Tracer, userId string) ([]domain.Todo, error) {
+func (t *TodoService) GetTodos(ctx context.Context, tracer trace.Tracer, userId string, includeSnoozed bool) ([]domain.Todo, error) {
 	ctx, span := tracer.Start(ctx, "GetTodos-TodoService")
 	defer span.End()
 
@@ -127,10 +233,149 @@ func (t *TodoService) GetTodos(ctx context.Context, tracer trace.Tracer, userId
 		return []domain.Todo{}, ErrInvalidUserId
 	}
 
-	todos, err := t.todoRepo.GetTodos(ctx, userIdInUUID)
+	todos, err := t.todoRepo.GetTodos(ctx, userIdInUUID, includeSnoozed)
 	if err != nil {
 		return []domain.Todo{}, err
 	}
 
 	return todos, nil
 }
+
+// GetTodosByIds loads several of the user's todos in one repository read.
+// Ids that do not resolve to one of the user's todos are left out.
+func (t *TodoService) GetTodosByIds(ctx context.Context, tracer trace.Tracer, userId string, todoIds []string) ([]domain.Todo, error) {
+	ctx, span := tracer.Start(ctx, "GetTodosByIds-TodoService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return []domain.Todo{}, ErrInvalidUserId
+	}
+	todoIdsInUUID := make([]uuid.UUID, 0, len(todoIds))
+	for _, todoId := range todoIds {
+		todoIdInUUID, err := uuid.Parse(todoId)
+		if err != nil {
+			return []domain.Todo{}, ErrInvalidTodoId
+		}
+		todoIdsInUUID = append(todoIdsInUUID, todoIdInUUID)
+	}
+
+	return t.todoRepo.GetTodosByIds(ctx, userIdInUUID, todoIdsInUUID)
+}
+
+func (t *TodoService) SnoozeTodo(ctx context.Context, tracer trace.Tracer, userId, todoId string, until time.Time) (domain.Todo, error) {
+	ctx, span := tracer.Start(ctx, "SnoozeTodo-TodoService")
+	defer span.End()
+
+	if !until.After(time.Now()) {
+		return domain.Todo{}, ErrSnoozeInPast
+	}
+
+	existingTodo, err := t.GetTodo(ctx, tracer, userId, todoId)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	snoozedTodo := existingTodo
+	snoozedTodo.SnoozedUntil = &until
+	snoozedTodo.UpdatedAt = time.Now()
+	snoozedTodo = snoozedTodo.Stamp(t.clock.Now(), domain.TodoFieldSnoozedUntil)
+
+	err = t.commit(ctx, func(ctx context.Context) error {
+		return t.todoRepo.UpdateTodo(ctx, snoozedTodo, domain.TodoFieldSnoozedUntil)
+	}, domain.NewTodoEvent(domain.TodoSnoozed, snoozedTodo))
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	return snoozedTodo, nil
+}
+
+// WakeSnoozedTodos clears the snooze on every todo that is due by now and
+// records a TodoUnsnoozed event for each of them.
+func (t *TodoService) WakeSnoozedTodos(ctx context.Context, tracer trace.Tracer, now time.Time) ([]domain.Todo, error) {
+	ctx, span := tracer.Start(ctx, "WakeSnoozedTodos-TodoService")
+	defer span.End()
+
+	dueTodos, err := t.todoRepo.GetDueSnoozedTodos(ctx, now)
+	if err != nil {
+		return []domain.Todo{}, err
+	}
+
+	var wokenTodos []domain.Todo
+	for _, todo := range dueTodos {
+		wokenTodo := todo
+		wokenTodo.SnoozedUntil = nil
+		wokenTodo.UpdatedAt = now
+		at := t.clock.Now()
+		wokenTodo = wokenTodo.Stamp(at, domain.TodoFieldSnoozedUntil)
+
+		var unsnoozed bool
+		err = t.todoRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			unsnoozed, err = t.todoRepo.UnsnoozeTodo(ctx, todo, now, at)
+			if err != nil || !unsnoozed {
+				return err
+			}
+			return t.outbox.AppendEvents(ctx, domain.NewTodoEvent(domain.TodoUnsnoozed, wokenTodo))
+		})
+		if err != nil {
+			return wokenTodos, err
+		}
+		if unsnoozed {
+			wokenTodos = append(wokenTodos, wokenTodo)
+		}
+	}
+
+	return wokenTodos, nil
+}
+
+func (t *TodoService) CompleteTodo(ctx context.Context, tracer trace.Tracer, userId, todoId string) (domain.Todo, error) {
+	ctx, span := tracer.Start(ctx, "CompleteTodo-TodoService")
+	defer span.End()
+
+	existingTodo, err := t.GetTodo(ctx, tracer, userId, todoId)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+	if existingTodo.CompletedAt != nil {
+		return existingTodo, nil
+	}
+
+	now := time.Now()
+	completedTodo := existingTodo
+	completedTodo.CompletedAt = &now
+	completedTodo.UpdatedAt = now
+	completedTodo = completedTodo.Stamp(t.clock.Now(), domain.TodoFieldCompletedAt)
+
+	err = t.commit(ctx, func(ctx context.Context) error {
+		return t.todoRepo.UpdateTodo(ctx, completedTodo, domain.TodoFieldCompletedAt)
+	}, domain.NewTodoEvent(domain.TodoCompleted, completedTodo))
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	return completedTodo, nil
+}
+
+func (t *TodoService) DeleteTodo(ctx context.Context, tracer trace.Tracer, userId, todoId string) (domain.Todo, error) {
+	ctx, span := tracer.Start(ctx, "DeleteTodo-TodoService")
+	defer span.End()
+
+	existingTodo, err := t.GetTodo(ctx, tracer, userId, todoId)
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	err = t.commit(ctx, func(ctx context.Context) error {
+		return t.todoRepo.DeleteTodo(ctx, existingTodo, t.clock.Now())
+	}, domain.NewTodoEvent(domain.TodoDeleted, existingTodo))
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return domain.Todo{}, ErrTodoNotFound
+	}
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	return existingTodo, nil
+}
@@ -0,0 +1,268 @@
+package todos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	MaxSyncChanges = 500
+	syncPageSize   = 500
+)
+
+var (
+	ErrInvalidSyncToken   = appErrors.BadRequest("invalid_sync_token", "invalid sync token")
+	ErrTooManySyncChanges = appErrors.BadRequest("too_many_sync_changes", fmt.Sprintf("a sync can push at most %d changes", MaxSyncChanges))
+	ErrTodoDeleted        = appErrors.Conflict("todo_deleted", "todo has been deleted")
+	ErrMissingClock       = appErrors.BadRequest("missing_clock", "every change must carry an hlc")
+)
+
+// SyncChange is one local write made by a client. Todo holds the new value
+// of every field listed in Todo.Clocks, each stamped with the client's HLC
+// for that write. A delete instead sets Deleted and DeletedAt.
+type SyncChange struct {
+	TodoId    uuid.UUID
+	Todo      domain.Todo
+	Deleted   bool
+	DeletedAt domain.HLC
+}
+
+type SyncRejection struct {
+	TodoId uuid.UUID
+	Err    error
+}
+
+type SyncResult struct {
+	// Token is passed back on the next sync to receive only later changes.
+	Token    string
+	Changes  []domain.TodoChange
+	HasMore  bool
+	Rejected []SyncRejection
+}
+
+// Sync applies the client's changes and returns every change to the user's
+// todos after syncToken, including the outcome of those just applied.
+//
+// Conflicts resolve per field: the write with the later HLC wins, whichever
+// side made it. Deletes win over concurrent edits, so a deleted todo is
+// never resurrected by a client that had not yet seen its tombstone.
+func (t *TodoService) Sync(ctx context.Context, tracer trace.Tracer, userId, syncToken string, changes []SyncChange) (SyncResult, error) {
+	ctx, span := tracer.Start(ctx, "Sync-TodoService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return SyncResult{}, ErrInvalidUserId
+	}
+	sequence, err := parseSyncToken(syncToken)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if len(changes) > MaxSyncChanges {
+		return SyncResult{}, ErrTooManySyncChanges
+	}
+
+	result := SyncResult{Rejected: []SyncRejection{}}
+	for _, change := range changes {
+		err := t.applySyncChange(ctx, tracer, userIdInUUID, change)
+		if isSyncRejection(err) {
+			result.Rejected = append(result.Rejected, SyncRejection{TodoId: change.TodoId, Err: err})
+			continue
+		}
+		if err != nil {
+			return SyncResult{}, err
+		}
+	}
+
+	serverChanges, err := t.todoRepo.GetChangesSince(ctx, userIdInUUID, sequence, syncPageSize+1)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if len(serverChanges) > syncPageSize {
+		serverChanges = serverChanges[:syncPageSize]
+		result.HasMore = true
+	}
+	if len(serverChanges) > 0 {
+		sequence = serverChanges[len(serverChanges)-1].Sequence
+	}
+	result.Changes = serverChanges
+	result.Token = strconv.FormatInt(sequence, 10)
+	return result, nil
+}
+
+func (t *TodoService) applySyncChange(ctx context.Context, tracer trace.Tracer, userId uuid.UUID, change SyncChange) error {
+	if change.Deleted {
+		if change.DeletedAt.IsZero() {
+			return ErrMissingClock
+		}
+		if err := t.clock.Observe(change.DeletedAt); err != nil {
+			return err
+		}
+	}
+	for _, clock := range change.Todo.Clocks {
+		if err := t.clock.Observe(clock); err != nil {
+			return err
+		}
+	}
+
+	return t.todoRepo.RunInTransaction(ctx, func(ctx context.Context) error {
+		existingTodo, err := t.getTodo(ctx, userId, change.TodoId)
+		if err != nil && !errors.Is(err, ErrTodoNotFound) {
+			return err
+		}
+		if err == nil {
+			if change.Deleted {
+				return t.deleteSyncedTodo(ctx, existingTodo, change.DeletedAt)
+			}
+			return t.mergeSyncedTodo(ctx, tracer, existingTodo, change)
+		}
+
+		tombstone, err := t.todoRepo.GetChange(ctx, change.TodoId)
+		if err != nil && !errors.Is(err, infra.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if tombstone.UserId != userId {
+				return ErrNotOwnerOfTodo
+			}
+			if change.Deleted {
+				return nil
+			}
+			return ErrTodoDeleted
+		}
+		if change.Deleted {
+			// NOTE: created and deleted offline; the server never saw it.
+			return nil
+		}
+		return t.createSyncedTodo(ctx, tracer, userId, change)
+	})
+}
+
+func (t *TodoService) createSyncedTodo(ctx context.Context, tracer trace.Tracer, userId uuid.UUID, change SyncChange) error {
+	if _, ok := change.Todo.Clocks[domain.TodoFieldText]; !ok {
+		return ErrEmptyTodoText
+	}
+	now := time.Now()
+	newTodo := domain.Todo{
+		ID:        change.TodoId,
+		UserId:    userId,
+		Text:      change.Todo.Text,
+		ProjectId: userId,
+		Labels:    []string{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}.Stamp(domain.HLC{})
+	for field, clock := range change.Todo.Clocks {
+		setTodoField(&newTodo, change.Todo, field)
+		newTodo.Clocks[field] = clock
+	}
+	if err := t.checkSyncedTodo(ctx, tracer, &newTodo); err != nil {
+		return err
+	}
+
+	if err := t.quotas.CheckQuota(ctx, tracer, userId, domain.QuotaTodos, 1); err != nil {
+		return err
+	}
+	if err := t.todoRepo.CreateTodo(ctx, newTodo); err != nil {
+		return err
+	}
+	return t.outbox.AppendEvents(ctx, domain.NewTodoEvent(domain.TodoCreated, newTodo))
+}
+
+func (t *TodoService) mergeSyncedTodo(ctx context.Context, tracer trace.Tracer, existingTodo domain.Todo, change SyncChange) error {
+	mergedTodo := existingTodo.Stamp(domain.HLC{})
+	var mergedFields []domain.TodoField
+	for _, field := range domain.TodoFields {
+		clock, ok := change.Todo.Clocks[field]
+		if !ok || !clock.After(existingTodo.Clocks[field]) {
+			continue
+		}
+		setTodoField(&mergedTodo, change.Todo, field)
+		mergedTodo.Clocks[field] = clock
+		mergedFields = append(mergedFields, field)
+	}
+	if len(mergedFields) == 0 {
+		return nil
+	}
+	if err := t.checkSyncedTodo(ctx, tracer, &mergedTodo); err != nil {
+		return err
+	}
+	mergedTodo.UpdatedAt = time.Now()
+
+	todoEvents := []domain.TodoEvent{domain.NewTodoEvent(domain.TodoUpdated, mergedTodo)}
+	if existingTodo.CompletedAt == nil && mergedTodo.CompletedAt != nil {
+		todoEvents = append(todoEvents, domain.NewTodoEvent(domain.TodoCompleted, mergedTodo))
+	}
+	if err := t.todoRepo.UpdateTodo(ctx, mergedTodo, mergedFields...); err != nil {
+		return err
+	}
+	return t.outbox.AppendEvents(ctx, todoEvents...)
+}
+
+// checkSyncedTodo validates a todo built from a client's changes, and
+// normalises its labels the way PatchTodo does.
+func (t *TodoService) checkSyncedTodo(ctx context.Context, tracer trace.Tracer, todo *domain.Todo) error {
+	if err := checkTodoText(todo.Text); err != nil {
+		return err
+	}
+	labels, err := normalizeLabels(todo.Labels)
+	if err != nil {
+		return err
+	}
+	todo.Labels = labels
+	return t.checkProject(ctx, tracer, *todo)
+}
+
+func (t *TodoService) deleteSyncedTodo(ctx context.Context, existingTodo domain.Todo, deletedAt domain.HLC) error {
+	if err := t.todoRepo.DeleteTodo(ctx, existingTodo, deletedAt); err != nil {
+		return err
+	}
+	return t.outbox.AppendEvents(ctx, domain.NewTodoEvent(domain.TodoDeleted, existingTodo))
+}
+
+func setTodoField(todo *domain.Todo, from domain.Todo, field domain.TodoField) {
+	switch field {
+	case domain.TodoFieldText:
+		todo.Text = from.Text
+	case domain.TodoFieldProjectId:
+		todo.ProjectId = from.ProjectId
+	case domain.TodoFieldLabels:
+		todo.Labels = from.Labels
+	case domain.TodoFieldSnoozedUntil:
+		todo.SnoozedUntil = from.SnoozedUntil
+	case domain.TodoFieldCompletedAt:
+		todo.CompletedAt = from.CompletedAt
+	}
+}
+
+func parseSyncToken(syncToken string) (int64, error) {
+	if syncToken == "" {
+		return 0, nil
+	}
+	sequence, err := strconv.ParseInt(syncToken, 10, 64)
+	if err != nil || sequence < 0 {
+		return 0, ErrInvalidSyncToken
+	}
+	return sequence, nil
+}
+
+func isSyncRejection(err error) bool {
+	switch err {
+	case ErrNotOwnerOfTodo, ErrTodoDeleted, ErrEmptyTodoText, ErrMissingClock, domain.ErrClockDrift,
+		ErrInvalidLabel, ErrTooManyLabels, projects.ErrProjectNotFound:
+		return true
+	}
+	var quotaErr *quotas.QuotaExceededError
+	return errors.As(err, &quotaErr)
+}
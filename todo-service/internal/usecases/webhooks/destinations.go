@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// AddressPolicy reports whether webhooks may be delivered to an address.
+type AddressPolicy func(addr netip.Addr) bool
+
+// sharedAddressSpace is the carrier-grade NAT range, which is as internal as
+// the private ranges netip knows of.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicAddresses only lets webhooks reach the public internet, so they
+// cannot be pointed at the loopback, private or link-local addresses of the
+// network todo-service runs in.
+func PublicAddresses(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+var errAddressNotAllowed = errors.New("webhook address is not allowed")
+
+// NewDeliveryClient returns a client that only connects to the addresses
+// allow lets through. The check runs on the address being dialed, after
+// DNS resolution, so a host that resolves to another address than it did
+// when its webhook was created cannot get around it.
+func NewDeliveryClient(timeout time.Duration, allow AddressPolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allow(addrPort.Addr()) {
+				return errAddressNotAllowed
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// NOTE: through a proxy, the dialed address would be the proxy's rather
+	// than the receiver's.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// checkDestination resolves host and fails unless allow lets every address
+// it resolves to through.
+func checkDestination(ctx context.Context, host string, allow AddressPolicy) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !allow(addr) {
+			return ErrWebhookURLNotAllowed
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return ErrUnresolvableWebhookURL
+	}
+	for _, addr := range addrs {
+		if !allow(addr) {
+			return ErrWebhookURLNotAllowed
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	HeaderDeliveryId = "X-Webhook-Id"
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+// deliveryBatchSize is how many due deliveries DeliverDue attempts at once.
+const deliveryBatchSize = 100
+
+// HandleEvent is an events.Handler that logs a delivery of event to every
+// enabled webhook of the todo's owner that subscribes to it. The deliveries
+// are attempted by DeliverDue, so the relay that raised the event is not held
+// up, and the event is only dispatched once they are all persisted.
+func (w *WebhookService) HandleEvent(ctx context.Context, event domain.TodoEvent) error {
+	webhooks, err := w.webhookRepo.GetWebhooksForEvent(ctx, event.UserId, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks for %s: %w", event.Type, err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":          event.ID,
+		"type":        event.Type,
+		"occurred_at": event.OccurredAt,
+		"data":        utils.ToTodoDTO(event.Todo),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", event.Type, err)
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		// NOTE: the id is derived from the webhook and the event, so an event
+		// relayed again does not log its deliveries twice.
+		delivery := domain.WebhookDelivery{
+			ID:            uuid.NewSHA1(webhook.ID, event.ID[:]),
+			WebhookId:     webhook.ID,
+			EventId:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			CreatedAt:     now,
+			NextAttemptAt: &now,
+		}
+		err := w.webhookRepo.CreateDelivery(ctx, delivery)
+		if err != nil && !errors.Is(err, infra.ErrDuplicateDelivery) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverDue attempts the deliveries due at now, once each, and returns how
+// many it attempted. A failed delivery is due again after a backoff, until it
+// runs out of attempts.
+//
+// NOTE: a delivery is attempted again when DeliverDue is stopped before it
+// logged the attempt, so receivers see each delivery at least once.
+func (w *WebhookService) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := w.tracer.Start(ctx, "DeliverDue-WebhookService")
+	defer span.End()
+
+	deliveries, err := w.webhookRepo.GetDueDeliveries(ctx, now, deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery domain.WebhookDelivery) {
+			defer wg.Done()
+			w.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	span.SetAttributes(attribute.Int("webhook.deliveries", len(deliveries)))
+	return len(deliveries), nil
+}
+
+func (w *WebhookService) deliver(ctx context.Context, delivery domain.WebhookDelivery) {
+	ctx, span := w.tracer.Start(ctx, "DeliverWebhook-WebhookService")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("webhook.id", delivery.WebhookId.String()),
+		attribute.String("webhook.event", string(delivery.EventType)),
+	)
+
+	webhook, err := w.webhookRepo.GetWebhookForDelivery(ctx, delivery.WebhookId)
+	if err != nil {
+		log.Printf("WebhookService failed to load webhook %s: %v", delivery.WebhookId, err)
+		return
+	}
+	if webhook.Disabled {
+		delivery.Error = ErrWebhookDisabled.Error()
+		delivery.NextAttemptAt = nil
+		if err := w.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+			log.Printf("WebhookService failed to log delivery %s: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	delivery = w.attempt(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		// NOTE: stopped midway, the attempt is left unlogged and made again.
+		return
+	}
+	finished := delivery.Succeeded || delivery.Attempts >= w.policy.MaxAttempts
+	if finished {
+		delivery.NextAttemptAt = nil
+	} else {
+		next := time.Now().Add(w.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if err := w.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("WebhookService failed to log delivery %s: %v", delivery.ID, err)
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("webhook.attempts", delivery.Attempts),
+		attribute.Bool("webhook.succeeded", delivery.Succeeded),
+	)
+	if finished {
+		w.recordOutcome(ctx, webhook, delivery.Succeeded)
+	}
+}
+
+// backoff returns how long a delivery waits after its attempts-th attempt
+// failed.
+func (w *WebhookService) backoff(attempts int) time.Duration {
+	backoff := w.policy.InitialBackoff
+	for i := 1; i < attempts && backoff < w.policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > w.policy.MaxBackoff {
+		backoff = w.policy.MaxBackoff
+	}
+	return backoff
+}
+
+// recordOutcome tracks consecutive failed deliveries and disables the webhook
+// once they reach the policy's limit.
+func (w *WebhookService) recordOutcome(ctx context.Context, webhook domain.Webhook, succeeded bool) {
+	w.outcomeMu.Lock()
+	defer w.outcomeMu.Unlock()
+
+	current, err := w.webhookRepo.GetWebhook(ctx, webhook.UserId, webhook.ID)
+	if err != nil {
+		return
+	}
+	if succeeded {
+		if current.ConsecutiveFailures == 0 {
+			return
+		}
+		current.ConsecutiveFailures = 0
+	} else {
+		current.ConsecutiveFailures++
+		if w.policy.DisableAfterFailures > 0 && current.ConsecutiveFailures >= w.policy.DisableAfterFailures {
+			current.Disabled = true
+			log.Printf("WebhookService disabled webhook %s after %d failed deliveries", current.ID, current.ConsecutiveFailures)
+		}
+	}
+	current.UpdatedAt = time.Now()
+	if err := w.webhookRepo.UpdateWebhook(ctx, current); err != nil {
+		log.Printf("WebhookService failed to update webhook %s: %v", current.ID, err)
+	}
+}
+
+// attempt makes one signed POST of the delivery's payload and records the
+// result on the returned delivery.
+func (w *WebhookService) attempt(ctx context.Context, webhook domain.Webhook, delivery domain.WebhookDelivery) domain.WebhookDelivery {
+	delivery.Attempts++
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryId, delivery.ID.String())
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	delivery.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		delivery.Error = fmt.Sprintf("%s: receiver responded with %d", ErrDeliveryFailed, res.StatusCode)
+		return delivery
+	}
+	now := time.Now()
+	delivery.Succeeded = true
+	delivery.Error = ""
+	delivery.DeliveredAt = &now
+	return delivery
+}
+
+// Sign returns the X-Webhook-Signature value for payload: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<payload>" keyed with the webhook's secret.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,272 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebhookService struct {
+	webhookRepo infra.WebhookRepository
+	quotas      *quotas.QuotaService
+	client      *http.Client
+	// allowAddress decides which addresses webhooks may be created for.
+	allowAddress AddressPolicy
+	tracer       trace.Tracer
+	policy       RetryPolicy
+
+	// outcomeMu serialises the read-modify-write of a webhook's failure
+	// count between concurrent deliveries.
+	outcomeMu *sync.Mutex
+}
+
+// RetryPolicy controls how a delivery is retried and when a failing webhook
+// is switched off.
+type RetryPolicy struct {
+	MaxAttempts          int
+	InitialBackoff       time.Duration
+	MaxBackoff           time.Duration
+	DisableAfterFailures int
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          5,
+	InitialBackoff:       time.Second,
+	MaxBackoff:           time.Minute,
+	DisableAfterFailures: 10,
+}
+
+var (
+	ErrWebhookNotFound        = appErrors.NotFound("webhook_not_found", "record not found")
+	ErrDeliveryNotFound       = appErrors.NotFound("delivery_not_found", "record not found")
+	ErrNotOwnerOfWebhook      = appErrors.Unauthorized("not_owner_of_webhook", "current user is not owner of this webhook")
+	ErrInvalidUserId          = appErrors.Unauthorized("invalid_user_id", "failing to parse user uuid")
+	ErrInvalidWebhookId       = appErrors.BadRequest("invalid_webhook_id", "invalid webhookId")
+	ErrInvalidDeliveryId      = appErrors.BadRequest("invalid_delivery_id", "invalid deliveryId")
+	ErrInvalidWebhookURL      = appErrors.BadRequest("invalid_webhook_url", "url must be an absolute http or https url")
+	ErrWebhookURLNotAllowed   = appErrors.BadRequest("webhook_url_not_allowed", "url must not point at a loopback, private or link-local address")
+	ErrUnresolvableWebhookURL = appErrors.BadRequest("unresolvable_webhook_url", "the host of the url could not be resolved")
+	ErrMissingEventTypes      = appErrors.BadRequest("event_types_required", "event_types required")
+	ErrUnknownEventType       = appErrors.BadRequest("unknown_event_type", "unknown event type")
+	ErrWebhookDisabled        = appErrors.BadRequest("webhook_disabled", "webhook is disabled")
+	ErrDeliveryFailed         = errors.New("webhook delivery failed")
+	ErrSecretGeneration       = errors.New("failed to generate webhook secret")
+)
+
+func NewWebhookService(webhookRepo infra.WebhookRepository, quotaService *quotas.QuotaService, client *http.Client, allowAddress AddressPolicy, tracer trace.Tracer, policy RetryPolicy) (*WebhookService, error) {
+	if webhookRepo == nil {
+		return &WebhookService{}, errors.New("WebhookService failed to initialize")
+	}
+	if quotaService == nil {
+		return &WebhookService{}, errors.New("WebhookService failed to initialize")
+	}
+	if client == nil {
+		return &WebhookService{}, errors.New("client cannot be nil")
+	}
+	if allowAddress == nil {
+		return &WebhookService{}, errors.New("allowAddress cannot be nil")
+	}
+	if tracer == nil {
+		return &WebhookService{}, errors.New("tracer cannot be empty")
+	}
+	if policy.MaxAttempts < 1 {
+		return &WebhookService{}, errors.New("MaxAttempts must be at least 1")
+	}
+	return &WebhookService{webhookRepo, quotaService, client, allowAddress, tracer, policy, &sync.Mutex{}}, nil
+}
+
+func (w *WebhookService) CreateWebhook(ctx context.Context, tracer trace.Tracer, userId, webhookURL, secret string, eventTypes []string) (domain.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "CreateWebhook-WebhookService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return domain.Webhook{}, ErrInvalidUserId
+	}
+	parsedURL, err := url.Parse(webhookURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return domain.Webhook{}, ErrInvalidWebhookURL
+	}
+	if len(eventTypes) == 0 {
+		return domain.Webhook{}, ErrMissingEventTypes
+	}
+	subscribedTypes := make([]domain.TodoEventType, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !domain.IsTodoEventType(domain.TodoEventType(eventType)) {
+			return domain.Webhook{}, ErrUnknownEventType
+		}
+		subscribedTypes = append(subscribedTypes, domain.TodoEventType(eventType))
+	}
+	// NOTE: deliveries check the address they dial again, as the host may
+	// resolve elsewhere by then.
+	if err := checkDestination(ctx, parsedURL.Hostname(), w.allowAddress); err != nil {
+		return domain.Webhook{}, err
+	}
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			return domain.Webhook{}, err
+		}
+	}
+
+	newWebhook := domain.Webhook{
+		ID:         uuid.New(),
+		UserId:     userIdInUUID,
+		URL:        webhookURL,
+		Secret:     secret,
+		EventTypes: subscribedTypes,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	// NOTE: webhooks are written through their own client, outside the
+	// transaction the check runs in, so racing creates can overshoot the
+	// limit by the number of requests in the race.
+	err = w.quotas.CheckQuota(ctx, tracer, userIdInUUID, domain.QuotaWebhooks, 1)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	err = w.webhookRepo.CreateWebhook(ctx, newWebhook)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	return newWebhook, nil
+}
+
+func (w *WebhookService) GetWebhook(ctx context.Context, tracer trace.Tracer, userId, webhookId string) (domain.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "GetWebhook-WebhookService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return domain.Webhook{}, ErrInvalidUserId
+	}
+	webhookIdInUUID, err := uuid.Parse(webhookId)
+	if err != nil {
+		return domain.Webhook{}, ErrInvalidWebhookId
+	}
+
+	webhook, err := w.webhookRepo.GetWebhook(ctx, userIdInUUID, webhookIdInUUID)
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return domain.Webhook{}, ErrWebhookNotFound
+	}
+	if errors.Is(err, infra.ErrNotOwnerOfWebhook) {
+		return domain.Webhook{}, ErrNotOwnerOfWebhook
+	}
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (w *WebhookService) GetWebhooks(ctx context.Context, tracer trace.Tracer, userId string) ([]domain.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "GetWebhooks-WebhookService")
+	defer span.End()
+
+	userIdInUUID, err := uuid.Parse(userId)
+	if err != nil {
+		return []domain.Webhook{}, ErrInvalidUserId
+	}
+	return w.webhookRepo.GetWebhooks(ctx, userIdInUUID)
+}
+
+func (w *WebhookService) DeleteWebhook(ctx context.Context, tracer trace.Tracer, userId, webhookId string) error {
+	ctx, span := tracer.Start(ctx, "DeleteWebhook-WebhookService")
+	defer span.End()
+
+	webhook, err := w.GetWebhook(ctx, tracer, userId, webhookId)
+	if err != nil {
+		return err
+	}
+	return w.webhookRepo.DeleteWebhook(ctx, webhook.ID)
+}
+
+// EnableWebhook turns a webhook that was disabled after repeated failures
+// back on.
+func (w *WebhookService) EnableWebhook(ctx context.Context, tracer trace.Tracer, userId, webhookId string) (domain.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "EnableWebhook-WebhookService")
+	defer span.End()
+
+	webhook, err := w.GetWebhook(ctx, tracer, userId, webhookId)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	webhook.Disabled = false
+	webhook.ConsecutiveFailures = 0
+	webhook.UpdatedAt = time.Now()
+	err = w.webhookRepo.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (w *WebhookService) GetDeliveries(ctx context.Context, tracer trace.Tracer, userId, webhookId string) ([]domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "GetDeliveries-WebhookService")
+	defer span.End()
+
+	webhook, err := w.GetWebhook(ctx, tracer, userId, webhookId)
+	if err != nil {
+		return []domain.WebhookDelivery{}, err
+	}
+	return w.webhookRepo.GetDeliveries(ctx, webhook.ID)
+}
+
+// ReplayDelivery sends the payload of a logged delivery again, once, and logs
+// the outcome as a new delivery.
+func (w *WebhookService) ReplayDelivery(ctx context.Context, tracer trace.Tracer, userId, webhookId, deliveryId string) (domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "ReplayDelivery-WebhookService")
+	defer span.End()
+
+	webhook, err := w.GetWebhook(ctx, tracer, userId, webhookId)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	if webhook.Disabled {
+		return domain.WebhookDelivery{}, ErrWebhookDisabled
+	}
+	deliveryIdInUUID, err := uuid.Parse(deliveryId)
+	if err != nil {
+		return domain.WebhookDelivery{}, ErrInvalidDeliveryId
+	}
+	original, err := w.webhookRepo.GetDelivery(ctx, webhook.ID, deliveryIdInUUID)
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return domain.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	replay := domain.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookId: webhook.ID,
+		EventId:   original.EventId,
+		EventType: original.EventType,
+		Payload:   original.Payload,
+		CreatedAt: time.Now(),
+	}
+	replay = w.attempt(ctx, webhook, replay)
+	if err := w.webhookRepo.CreateDelivery(ctx, replay); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	return replay, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", ErrSecretGeneration
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
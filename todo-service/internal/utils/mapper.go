@@ -1,12 +1,125 @@
 package utils
 
-import "github.com/olad5/productive-pulse/todo-service/internal/domain"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
 
 func ToTodoDTO(todo domain.Todo) map[string]interface{} {
 	return map[string]interface{}{
-		"id":         todo.ID,
-		"text":       todo.Text,
-		"created_at": todo.CreatedAt,
-		"updated_at": todo.UpdatedAt,
+		"id":            todo.ID,
+		"text":          todo.Text,
+		"project_id":    todo.ProjectId,
+		"labels":        todoLabels(todo),
+		"snoozed_until": todo.SnoozedUntil,
+		"completed_at":  todo.CompletedAt,
+		"created_at":    todo.CreatedAt,
+		"updated_at":    todo.UpdatedAt,
+	}
+}
+
+// todoLabels sends todos without labels as an empty list rather than null.
+func todoLabels(todo domain.Todo) []string {
+	if todo.Labels == nil {
+		return []string{}
+	}
+	return todo.Labels
+}
+
+func ToProjectDTO(project domain.Project) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         project.ID,
+		"name":       project.Name,
+		"created_at": project.CreatedAt,
+		"updated_at": project.UpdatedAt,
+	}
+}
+
+func ToProjectMemberDTO(member domain.ProjectMember) map[string]interface{} {
+	return map[string]interface{}{
+		"project_id": member.ProjectId,
+		"user_id":    member.UserId,
+		"added_at":   member.AddedAt,
+	}
+}
+
+func ToWebhookDTO(webhook domain.Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                   webhook.ID,
+		"url":                  webhook.URL,
+		"event_types":          webhook.EventTypes,
+		"disabled":             webhook.Disabled,
+		"consecutive_failures": webhook.ConsecutiveFailures,
+		"created_at":           webhook.CreatedAt,
+		"updated_at":           webhook.UpdatedAt,
+	}
+}
+
+func ToWebhookDeliveryDTO(delivery domain.WebhookDelivery) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           delivery.ID,
+		"event_id":     delivery.EventId,
+		"event_type":   delivery.EventType,
+		"payload":      json.RawMessage(delivery.Payload),
+		"attempts":     delivery.Attempts,
+		"status_code":  delivery.StatusCode,
+		"error":        delivery.Error,
+		"succeeded":    delivery.Succeeded,
+		"created_at":   delivery.CreatedAt,
+		"delivered_at": delivery.DeliveredAt,
+	}
+}
+
+// TodoV2DTO is the /v2 representation of a todo. Unlike the /v1 map it is
+// typed, reports completion as a boolean and leaves unset times out instead
+// of sending null.
+type TodoV2DTO struct {
+	ID           uuid.UUID  `json:"id"`
+	Text         string     `json:"text"`
+	ProjectId    uuid.UUID  `json:"project_id"`
+	Labels       []string   `json:"labels"`
+	Completed    bool       `json:"completed"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func ToTodoV2DTO(todo domain.Todo) TodoV2DTO {
+	return TodoV2DTO{
+		ID:           todo.ID,
+		Text:         todo.Text,
+		ProjectId:    todo.ProjectId,
+		Labels:       todoLabels(todo),
+		Completed:    todo.CompletedAt != nil,
+		CompletedAt:  todo.CompletedAt,
+		SnoozedUntil: todo.SnoozedUntil,
+		CreatedAt:    todo.CreatedAt,
+		UpdatedAt:    todo.UpdatedAt,
+	}
+}
+
+// ToUsageDTO reports every quota of plan, with a nil limit for the ones it
+// leaves unlimited.
+func ToUsageDTO(usage domain.Usage, plan domain.Plan) map[string]interface{} {
+	quotas := map[string]interface{}{}
+	for _, resource := range domain.QuotaResources {
+		var limit *int64
+		if planLimit, ok := plan.Limit(resource); ok {
+			limit = &planLimit
+		}
+		quotas[string(resource)] = map[string]interface{}{
+			"used":  usage.Used[resource],
+			"limit": limit,
+		}
+	}
+	return map[string]interface{}{
+		"plan":         plan.Name,
+		"period_start": usage.Period,
+		"period_end":   usage.Period.AddDate(0, 1, 0),
+		"quotas":       quotas,
 	}
 }
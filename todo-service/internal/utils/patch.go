@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	ErrUnsupportedPatchType = appErrors.New("unsupported_patch_type", http.StatusUnsupportedMediaType, "unsupported patch content type")
+	ErrInvalidPatch         = appErrors.Unprocessable("invalid_patch", "invalid patch document")
+)
+
+// invalidPatch is ErrInvalidPatch saying what is wrong with the patch.
+func invalidPatch(format string, args ...interface{}) error {
+	return ErrInvalidPatch.WithMessage(ErrInvalidPatch.Message + ": " + fmt.Sprintf(format, args...))
+}
+
+var readOnlyTodoFields = []string{"id", "created_at", "updated_at"}
+
+// ApplyTodoPatch applies an RFC 7396 merge patch or an RFC 6902 JSON patch to
+// the DTO representation of todo and validates the result against that same
+// representation.
+func ApplyTodoPatch(todo domain.Todo, contentType string, patch []byte) (domain.Todo, error) {
+	original, err := json.Marshal(ToTodoDTO(todo))
+	if err != nil {
+		return domain.Todo{}, err
+	}
+
+	var patched []byte
+	switch mediaType(contentType) {
+	case MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return domain.Todo{}, invalidPatch("%v", err)
+		}
+	case JSONPatchContentType:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return domain.Todo{}, invalidPatch("%v", err)
+		}
+		patched, err = operations.Apply(original)
+		if err != nil {
+			return domain.Todo{}, invalidPatch("%v", err)
+		}
+	default:
+		return domain.Todo{}, ErrUnsupportedPatchType
+	}
+
+	return validateTodoDocument(todo, original, patched)
+}
+
+func validateTodoDocument(todo domain.Todo, original, patched []byte) (domain.Todo, error) {
+	var originalFields, patchedFields map[string]interface{}
+	if err := json.Unmarshal(original, &originalFields); err != nil {
+		return domain.Todo{}, err
+	}
+	if err := json.Unmarshal(patched, &patchedFields); err != nil {
+		return domain.Todo{}, invalidPatch("result is not an object")
+	}
+	for _, field := range readOnlyTodoFields {
+		if !reflect.DeepEqual(originalFields[field], patchedFields[field]) {
+			return domain.Todo{}, invalidPatch("%s is read-only", field)
+		}
+	}
+	for field := range patchedFields {
+		if _, known := originalFields[field]; !known {
+			return domain.Todo{}, invalidPatch("unknown field %s", field)
+		}
+	}
+	text, ok := patchedFields["text"].(string)
+	if !ok {
+		return domain.Todo{}, invalidPatch("text must be a string")
+	}
+
+	projectId, ok := patchedFields["project_id"].(string)
+	if !ok {
+		return domain.Todo{}, invalidPatch("project_id must be a uuid")
+	}
+	projectIdInUUID, err := uuid.Parse(projectId)
+	if err != nil {
+		return domain.Todo{}, invalidPatch("project_id must be a uuid")
+	}
+
+	type todoDocument struct {
+		SnoozedUntil *time.Time `json:"snoozed_until"`
+		CompletedAt  *time.Time `json:"completed_at"`
+	}
+	var document todoDocument
+	if err := json.Unmarshal(patched, &document); err != nil {
+		return domain.Todo{}, invalidPatch("snoozed_until and completed_at must be null or RFC 3339 timestamps")
+	}
+	// NOTE: removing labels, or setting them to null, clears them.
+	var labels struct {
+		Labels []string `json:"labels"`
+	}
+	if err := json.Unmarshal(patched, &labels); err != nil {
+		return domain.Todo{}, invalidPatch("labels must be a list of strings")
+	}
+
+	patchedTodo := todo
+	patchedTodo.Text = text
+	patchedTodo.ProjectId = projectIdInUUID
+	patchedTodo.Labels = labels.Labels
+	patchedTodo.SnoozedUntil = document.SnoozedUntil
+	patchedTodo.CompletedAt = document.CompletedAt
+	return patchedTodo, nil
+}
+
+func mediaType(contentType string) string {
+	return strings.TrimSpace(strings.Split(contentType, ";")[0])
+}
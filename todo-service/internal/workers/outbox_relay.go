@@ -0,0 +1,149 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const outboxRelayBatchSize = 100
+
+// OutboxRelay forwards events recorded in the outbox to its sinks. An event
+// is marked dispatched only once every sink accepted it, so sinks see each
+// event at least once and must tolerate duplicates by event id. The events of
+// one todo are sent in sequence order; a failure holds back that todo's later
+// events until the next run. An event that failed maxAttempts times is parked
+// instead, and the todo's later events go ahead without it.
+//
+// NOTE: ordering assumes a single relay per outbox. Events are not claimed,
+// so the relay must run on one instance only; see TodoServiceOutboxRelay.
+type OutboxRelay struct {
+	outbox      infra.OutboxRepository
+	sinks       []events.Publisher
+	tracer      trace.Tracer
+	interval    time.Duration
+	maxAttempts int
+}
+
+func NewOutboxRelay(outbox infra.OutboxRepository, sinks []events.Publisher, tracer trace.Tracer, interval time.Duration, maxAttempts int) (*OutboxRelay, error) {
+	if outbox == nil {
+		return nil, errors.New("outbox cannot be empty")
+	}
+	if len(sinks) == 0 {
+		return nil, errors.New("sinks cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	if maxAttempts < 1 {
+		return nil, errors.New("maxAttempts must be at least 1")
+	}
+	return &OutboxRelay{outbox, sinks, tracer, interval, maxAttempts}, nil
+}
+
+// Run relays pending events every interval until ctx is cancelled.
+func (o *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := o.RelayPending(ctx); err != nil {
+				log.Printf("OutboxRelay failed to relay events: %v", err)
+			}
+		}
+	}
+}
+
+// RelayPending makes one pass over the outbox and returns how many events it
+// dispatched.
+func (o *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	ctx, span := o.tracer.Start(ctx, "RelayPending-OutboxRelay")
+	defer span.End()
+
+	entries, err := o.outbox.GetPendingEvents(ctx, outboxRelayBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	for _, todoEntries := range groupByTodo(entries) {
+		for _, entry := range todoEntries {
+			if err := o.send(ctx, entry.Event); err != nil {
+				o.fail(ctx, entry, err)
+				break
+			}
+			if err := o.outbox.MarkEventDispatched(ctx, entry.Event.ID); err != nil {
+				return dispatched, err
+			}
+			dispatched++
+		}
+	}
+	span.SetAttributes(attribute.Int("outbox.dispatched", dispatched))
+
+	return dispatched, nil
+}
+
+// fail records that entry could not be sent, and parks it once it ran out of
+// attempts.
+func (o *OutboxRelay) fail(ctx context.Context, entry domain.OutboxEntry, cause error) {
+	if entry.Attempts+1 < o.maxAttempts {
+		if err := o.outbox.MarkEventFailed(ctx, entry.Event.ID, cause); err != nil {
+			log.Printf("OutboxRelay failed to record failure of event %s: %v", entry.Event.ID, err)
+		}
+		return
+	}
+	if err := o.outbox.MarkEventParked(ctx, entry.Event.ID, cause); err != nil {
+		log.Printf("OutboxRelay failed to park event %s: %v", entry.Event.ID, err)
+		return
+	}
+	log.Printf("OutboxRelay parked event %s after %d failed attempts: %v", entry.Event.ID, entry.Attempts+1, cause)
+}
+
+func (o *OutboxRelay) send(ctx context.Context, event domain.TodoEvent) error {
+	for _, sink := range o.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByTodo splits entries per todo, keeping todos in the order their
+// oldest event appears and each todo's events in sequence order.
+func groupByTodo(entries []domain.OutboxEntry) [][]domain.OutboxEntry {
+	var order []uuid.UUID
+	groups := map[uuid.UUID][]domain.OutboxEntry{}
+	for _, entry := range entries {
+		todoId := entry.Event.TodoId
+		if _, seen := groups[todoId]; !seen {
+			order = append(order, todoId)
+		}
+		groups[todoId] = append(groups[todoId], entry)
+	}
+
+	grouped := make([][]domain.OutboxEntry, 0, len(order))
+	for _, todoId := range order {
+		todoEntries := groups[todoId]
+		sort.SliceStable(todoEntries, func(i, j int) bool {
+			return todoEntries[i].Sequence < todoEntries[j].Sequence
+		})
+		grouped = append(grouped, todoEntries)
+	}
+	return grouped
+}
@@ -0,0 +1,51 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type UnsnoozeWorker struct {
+	todoService todos.TodoService
+	tracer      trace.Tracer
+	interval    time.Duration
+}
+
+func NewUnsnoozeWorker(todoService todos.TodoService, tracer trace.Tracer, interval time.Duration) (*UnsnoozeWorker, error) {
+	if todoService == (todos.TodoService{}) {
+		return nil, errors.New("TodoService cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	return &UnsnoozeWorker{todoService, tracer, interval}, nil
+}
+
+// Run wakes due todos every interval until ctx is cancelled.
+func (u *UnsnoozeWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			wokenTodos, err := u.todoService.WakeSnoozedTodos(ctx, u.tracer, now)
+			if err != nil {
+				log.Printf("UnsnoozeWorker failed to wake todos: %v", err)
+			}
+			if len(wokenTodos) > 0 {
+				log.Printf("UnsnoozeWorker woke %d todos", len(wokenTodos))
+			}
+		}
+	}
+}
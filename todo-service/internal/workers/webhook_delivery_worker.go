@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/webhooks"
+)
+
+// WebhookDeliveryWorker attempts the webhook deliveries that are due.
+//
+// NOTE: workers of several instances may attempt the same delivery at once,
+// which receivers already tolerate, as deliveries are made at least once.
+type WebhookDeliveryWorker struct {
+	webhookService *webhooks.WebhookService
+	interval       time.Duration
+}
+
+func NewWebhookDeliveryWorker(webhookService *webhooks.WebhookService, interval time.Duration) (*WebhookDeliveryWorker, error) {
+	if webhookService == nil {
+		return nil, errors.New("webhookService cannot be empty")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	return &WebhookDeliveryWorker{webhookService, interval}, nil
+}
+
+// Run attempts due deliveries every interval until ctx is cancelled.
+func (w *WebhookDeliveryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := w.webhookService.DeliverDue(ctx, now); err != nil {
+				log.Printf("WebhookDeliveryWorker failed to deliver webhooks: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package contract
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+// ProjectRepository runs the project contract against repo. Every case works
+// on projects of a user of its own, so repo may hold data already.
+func ProjectRepository(t *testing.T, repo infra.ProjectRepository) {
+	ctx := context.Background()
+
+	t.Run(`Given a user with two projects and a project of another user
+      When the user lists their projects and gets the other user's one
+      Then only their own projects should be listed, oldest first
+      And the other user's project should still be returned by id
+    `,
+		func(t *testing.T) {
+			userId := uuid.New()
+			older, newer, theirs := newProject(userId), newProject(userId), newProject(uuid.New())
+			older.CreatedAt = now().Add(-time.Hour)
+			for _, project := range []domain.Project{newer, older, theirs} {
+				if err := repo.CreateProject(ctx, project); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			projects, err := repo.GetProjects(ctx, userId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(projects) != 2 || projects[0].ID != older.ID || projects[1].ID != newer.ID {
+				t.Errorf("Expected projects %s and %s. Got %+v", older.ID, newer.ID, projects)
+			}
+
+			got, err := repo.GetProject(ctx, theirs.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != theirs.ID || got.UserId != theirs.UserId || got.Name != theirs.Name || !got.CreatedAt.Equal(theirs.CreatedAt) {
+				t.Errorf("Expected project %+v. Got %+v", theirs, got)
+			}
+
+			_, err = repo.GetProject(ctx, uuid.New())
+			assertError(t, err, infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given a project with a member added twice
+      When membership of the member and of another user is checked
+      Then only the member should be a member of it
+    `,
+		func(t *testing.T) {
+			project := newProject(uuid.New())
+			if err := repo.CreateProject(ctx, project); err != nil {
+				t.Fatal(err)
+			}
+			member := domain.ProjectMember{ProjectId: project.ID, UserId: uuid.New(), AddedAt: now()}
+			for i := 0; i < 2; i++ {
+				if err := repo.AddProjectMember(ctx, member); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			isMember, err := repo.IsProjectMember(ctx, project.ID, member.UserId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !isMember {
+				t.Error("Expected the added user to be a member")
+			}
+			isMember, err = repo.IsProjectMember(ctx, project.ID, uuid.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isMember {
+				t.Error("Expected another user not to be a member")
+			}
+		},
+	)
+}
+
+func newProject(userId uuid.UUID) domain.Project {
+	createdAt := now()
+	return domain.Project{
+		ID:        uuid.New(),
+		UserId:    userId,
+		Name:      "contract project " + uuid.NewString(),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
@@ -0,0 +1,401 @@
+// Package contract holds the behaviour every todo-service storage backend
+// must share, so that the services on top cannot tell them apart.
+package contract
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+// TodoRepository runs the contract against repo. Every case works on todos
+// of a user of its own, so repo may hold data already.
+func TodoRepository(t *testing.T, repo infra.TodoRepository) {
+	ctx := context.Background()
+
+	t.Run(`Given a created todo
+      When its owner and another user get it
+      Then the owner should get it back as written
+      And the other user should be told they do not own it
+    `,
+		func(t *testing.T) {
+			todo := newTodo(uuid.New())
+			mustCreate(t, repo, todo)
+
+			got, err := repo.GetTodo(ctx, todo.UserId, todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameTodo(t, got, todo)
+
+			_, err = repo.GetTodo(ctx, uuid.New(), todo.ID)
+			assertError(t, err, infra.ErrNotOwnerOfTodo)
+			_, err = repo.GetTodo(ctx, todo.UserId, uuid.New())
+			assertError(t, err, infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given a user with a snoozed todo, a todo whose snooze ran out and a plain todo
+      When they list their todos
+      Then the snoozed todo should only be listed along with snoozed todos
+    `,
+		func(t *testing.T) {
+			userId := uuid.New()
+			snoozed, wokenUp, plain := newTodo(userId), newTodo(userId), newTodo(userId)
+			snoozed.SnoozedUntil = timePtr(now().Add(time.Hour))
+			wokenUp.SnoozedUntil = timePtr(now().Add(-time.Hour))
+			for _, todo := range []domain.Todo{snoozed, wokenUp, plain} {
+				mustCreate(t, repo, todo)
+			}
+
+			awake, err := repo.GetTodos(ctx, userId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertIds(t, awake, wokenUp.ID, plain.ID)
+			all, err := repo.GetTodos(ctx, userId, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertIds(t, all, snoozed.ID, wokenUp.ID, plain.ID)
+		},
+	)
+
+	t.Run(`Given todos of two users
+      When one of them gets todos by ids that include the other's and a missing one
+      Then only their own todos should come back
+    `,
+		func(t *testing.T) {
+			userId := uuid.New()
+			own, theirs := newTodo(userId), newTodo(uuid.New())
+			mustCreate(t, repo, own)
+			mustCreate(t, repo, theirs)
+
+			todos, err := repo.GetTodosByIds(ctx, userId, []uuid.UUID{own.ID, theirs.ID, uuid.New()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertIds(t, todos, own.ID)
+		},
+	)
+
+	t.Run(`Given a created todo
+      When only its text is updated
+      Then its text and the clock of its text should change
+      And its other fields should be left as stored
+    `,
+		func(t *testing.T) {
+			todo := newTodo(uuid.New())
+			mustCreate(t, repo, todo)
+
+			at := clock(2)
+			updated := todo
+			updated.Text = "updated text"
+			updated.CompletedAt = timePtr(now())
+			updated.UpdatedAt = now().Add(time.Second)
+			updated = updated.Stamp(at, domain.TodoFieldText, domain.TodoFieldCompletedAt)
+			if err := repo.UpdateTodo(ctx, updated, domain.TodoFieldText); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetTodo(ctx, todo.UserId, todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := todo.Stamp(at, domain.TodoFieldText)
+			want.Text = updated.Text
+			want.UpdatedAt = updated.UpdatedAt
+			assertSameTodo(t, got, want)
+
+			missing := newTodo(todo.UserId)
+			assertError(t, repo.UpdateTodo(ctx, missing, domain.TodoFieldText), infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given a created todo
+      When its project and labels are updated
+      Then it should be filed under the new project with the labels in the order given
+    `,
+		func(t *testing.T) {
+			todo := newTodo(uuid.New())
+			mustCreate(t, repo, todo)
+
+			at := clock(2)
+			updated := todo
+			updated.ProjectId = uuid.New()
+			updated.Labels = []string{"work", "urgent"}
+			updated = updated.Stamp(at, domain.TodoFieldProjectId, domain.TodoFieldLabels)
+			if err := repo.UpdateTodo(ctx, updated, domain.TodoFieldProjectId, domain.TodoFieldLabels); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetTodo(ctx, todo.UserId, todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameTodo(t, got, updated)
+		},
+	)
+
+	t.Run(`Given a deleted todo
+      When it is read or deleted again
+      Then it should not be found
+      And its change should be a tombstone stamped with the time of deletion
+    `,
+		func(t *testing.T) {
+			todo := newTodo(uuid.New())
+			mustCreate(t, repo, todo)
+			deletedAt := clock(3)
+			if err := repo.DeleteTodo(ctx, todo, deletedAt); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := repo.GetTodo(ctx, todo.UserId, todo.ID)
+			assertError(t, err, infra.ErrRecordNotFound)
+			assertError(t, repo.DeleteTodo(ctx, todo, deletedAt), infra.ErrRecordNotFound)
+
+			change, err := repo.GetChange(ctx, todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !change.Deleted || change.DeletedAt != deletedAt || change.Todo.Text != todo.Text {
+				t.Errorf("Expected a tombstone of %q deleted at %v. Got %+v", todo.Text, deletedAt, change)
+			}
+			_, err = repo.GetChange(ctx, uuid.New())
+			assertError(t, err, infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given a user who wrote to their todos several times
+      When they get their changes since a sequence
+      Then they should get the latest change of each todo after it, in sequence order
+      And no more than the limit
+    `,
+		func(t *testing.T) {
+			userId := uuid.New()
+			first, second := newTodo(userId), newTodo(userId)
+			mustCreate(t, repo, first)
+			mustCreate(t, repo, second)
+			updated := first
+			updated.Text = "updated text"
+			if err := repo.UpdateTodo(ctx, updated, domain.TodoFieldText); err != nil {
+				t.Fatal(err)
+			}
+
+			changes, err := repo.GetChangesSince(ctx, userId, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != 2 || changes[0].TodoId != second.ID || changes[1].TodoId != first.ID {
+				t.Fatalf("Expected the changes of the second todo and then the first. Got %+v", changes)
+			}
+			if changes[0].Sequence >= changes[1].Sequence || changes[1].Todo.Text != updated.Text {
+				t.Errorf("Expected increasing sequences and the updated text. Got %+v", changes)
+			}
+
+			limited, err := repo.GetChangesSince(ctx, userId, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(limited) != 1 || limited[0].TodoId != second.ID {
+				t.Errorf("Expected only the change of the second todo. Got %+v", limited)
+			}
+			none, err := repo.GetChangesSince(ctx, userId, changes[1].Sequence, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(none) != 0 {
+				t.Errorf("Expected no changes after the last one. Got %+v", none)
+			}
+		},
+	)
+
+	t.Run(`Given a todo whose snooze ran out
+      When it is unsnoozed from a stale read and then from a current one
+      Then only the current read should unsnooze it
+      And it should no longer be due
+    `,
+		func(t *testing.T) {
+			todo := newTodo(uuid.New())
+			todo.SnoozedUntil = timePtr(now().Add(-time.Minute))
+			mustCreate(t, repo, todo)
+
+			due, err := repo.GetDueSnoozedTodos(ctx, now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !containsId(due, todo.ID) {
+				t.Fatalf("Expected todo %s to be due", todo.ID)
+			}
+
+			stale := todo
+			stale.SnoozedUntil = timePtr(now().Add(-time.Hour))
+			at := clock(4)
+			unsnoozed, err := repo.UnsnoozeTodo(ctx, stale, now(), at)
+			if err != nil || unsnoozed {
+				t.Fatalf("Expected a stale read not to unsnooze the todo. Got %v, %v", unsnoozed, err)
+			}
+			unsnoozed, err = repo.UnsnoozeTodo(ctx, todo, now(), at)
+			if err != nil || !unsnoozed {
+				t.Fatalf("Expected the todo to be unsnoozed. Got %v, %v", unsnoozed, err)
+			}
+
+			got, err := repo.GetTodo(ctx, todo.UserId, todo.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SnoozedUntil != nil || got.Clocks[domain.TodoFieldSnoozedUntil] != at {
+				t.Errorf("Expected no snooze stamped %v. Got %v stamped %v", at, got.SnoozedUntil, got.Clocks[domain.TodoFieldSnoozedUntil])
+			}
+			due, err = repo.GetDueSnoozedTodos(ctx, now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if containsId(due, todo.ID) {
+				t.Errorf("Expected todo %s not to be due anymore", todo.ID)
+			}
+		},
+	)
+
+	t.Run(`Given a transaction that creates todos, one of them in a nested transaction
+      When it fails after the nested transaction returned
+      Then none of its todos nor their changes should be kept
+      And the same transaction should keep both once it succeeds
+    `,
+		func(t *testing.T) {
+			userId := uuid.New()
+			outer, inner := newTodo(userId), newTodo(userId)
+			failure := errors.New("failure after the writes")
+			run := func(fail bool) error {
+				return repo.RunInTransaction(ctx, func(ctx context.Context) error {
+					if err := repo.CreateTodo(ctx, outer); err != nil {
+						return err
+					}
+					err := repo.RunInTransaction(ctx, func(ctx context.Context) error {
+						return repo.CreateTodo(ctx, inner)
+					})
+					if err != nil {
+						return err
+					}
+					if fail {
+						return failure
+					}
+					return nil
+				})
+			}
+
+			if err := run(true); !errors.Is(err, failure) {
+				t.Fatalf("Expected the transaction to fail with %v. Got %v", failure, err)
+			}
+			todos, err := repo.GetTodos(ctx, userId, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertIds(t, todos)
+			if _, err := repo.GetChange(ctx, inner.ID); err == nil {
+				t.Errorf("Expected the change of a rolled back todo not to be kept")
+			}
+
+			if err := run(false); err != nil {
+				t.Fatal(err)
+			}
+			todos, err = repo.GetTodos(ctx, userId, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertIds(t, todos, outer.ID, inner.ID)
+		},
+	)
+}
+
+// now is truncated to what every backend stores, milliseconds.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func clock(logical uint32) domain.HLC {
+	return domain.HLC{WallTime: now().UnixMilli(), Logical: logical, NodeId: "contract"}
+}
+
+func newTodo(userId uuid.UUID) domain.Todo {
+	createdAt := now()
+	return domain.Todo{
+		ID:        uuid.New(),
+		UserId:    userId,
+		Text:      "contract todo " + uuid.NewString(),
+		ProjectId: userId,
+		Labels:    []string{},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}.Stamp(clock(1), domain.TodoFieldText)
+}
+
+func mustCreate(t *testing.T, repo infra.TodoRepository, todo domain.Todo) {
+	t.Helper()
+	if err := repo.CreateTodo(context.Background(), todo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertError(t *testing.T, err, expected error) {
+	t.Helper()
+	if !errors.Is(err, expected) {
+		t.Errorf("Expected error %q. Got %v", expected, err)
+	}
+}
+
+func assertSameTodo(t *testing.T, got, want domain.Todo) {
+	t.Helper()
+	same := got.ID == want.ID && got.UserId == want.UserId && got.Text == want.Text &&
+		got.ProjectId == want.ProjectId && len(got.Labels) == len(want.Labels) &&
+		sameTime(got.SnoozedUntil, want.SnoozedUntil) && sameTime(got.CompletedAt, want.CompletedAt) &&
+		got.CreatedAt.Equal(want.CreatedAt) && got.UpdatedAt.Equal(want.UpdatedAt) &&
+		len(got.Clocks) == len(want.Clocks)
+	for i, label := range want.Labels {
+		same = same && got.Labels[i] == label
+	}
+	for field, clock := range want.Clocks {
+		same = same && got.Clocks[field] == clock
+	}
+	if !same {
+		t.Errorf("Expected todo %+v. Got %+v", want, got)
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func containsId(todos []domain.Todo, id uuid.UUID) bool {
+	for _, todo := range todos {
+		if todo.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func assertIds(t *testing.T, todos []domain.Todo, ids ...uuid.UUID) {
+	t.Helper()
+	if len(todos) != len(ids) {
+		t.Errorf("Expected %d todos. Got %d", len(ids), len(todos))
+		return
+	}
+	for _, id := range ids {
+		if !containsId(todos, id) {
+			t.Errorf("Expected todo %s to be listed", id)
+		}
+	}
+}
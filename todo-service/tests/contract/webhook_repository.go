@@ -0,0 +1,126 @@
+package contract
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+)
+
+// WebhookRepository runs the webhook contract against repo. Every case works
+// on a webhook of its own, so repo may hold data already.
+func WebhookRepository(t *testing.T, repo infra.WebhookRepository) {
+	ctx := context.Background()
+
+	t.Run(`Given a webhook with a delivery due in an hour, one due in two hours and a finished one
+      When the deliveries due in an hour and a half are listed
+      Then only the one due in an hour should be listed among them
+      And creating it again should fail as a duplicate
+    `,
+		func(t *testing.T) {
+			// NOTE: the deliveries are due in the future, and the webhook is
+			// disabled, so the workers of the suite leave them alone.
+			webhook := newWebhook(uuid.New())
+			if err := repo.CreateWebhook(ctx, webhook); err != nil {
+				t.Fatal(err)
+			}
+			dueSoon, dueLater, finished := newDelivery(webhook.ID), newDelivery(webhook.ID), newDelivery(webhook.ID)
+			dueSoon.NextAttemptAt = timePtr(now().Add(time.Hour))
+			dueLater.NextAttemptAt = timePtr(now().Add(2 * time.Hour))
+			finished.Attempts = 1
+			finished.Succeeded = true
+			for _, delivery := range []domain.WebhookDelivery{dueSoon, dueLater, finished} {
+				if err := repo.CreateDelivery(ctx, delivery); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			due, err := repo.GetDueDeliveries(ctx, now().Add(90*time.Minute), 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listed := map[uuid.UUID]bool{}
+			for i, delivery := range due {
+				listed[delivery.ID] = true
+				if i > 0 && delivery.NextAttemptAt.Before(*due[i-1].NextAttemptAt) {
+					t.Error("Expected due deliveries to be listed longest due first")
+				}
+			}
+			if !listed[dueSoon.ID] || listed[dueLater.ID] || listed[finished.ID] {
+				t.Errorf("Expected only the delivery due in an hour to be listed. Got %v", listed)
+			}
+
+			err = repo.CreateDelivery(ctx, dueSoon)
+			assertError(t, err, infra.ErrDuplicateDelivery)
+		},
+	)
+
+	t.Run(`Given a delivery due in an hour
+      When it is updated as finished
+      Then it should no longer be listed as due
+    `,
+		func(t *testing.T) {
+			webhook := newWebhook(uuid.New())
+			if err := repo.CreateWebhook(ctx, webhook); err != nil {
+				t.Fatal(err)
+			}
+			delivery := newDelivery(webhook.ID)
+			delivery.NextAttemptAt = timePtr(now().Add(time.Hour))
+			if err := repo.CreateDelivery(ctx, delivery); err != nil {
+				t.Fatal(err)
+			}
+
+			delivery.Attempts = 1
+			delivery.Succeeded = true
+			delivery.NextAttemptAt = nil
+			if err := repo.UpdateDelivery(ctx, delivery); err != nil {
+				t.Fatal(err)
+			}
+
+			due, err := repo.GetDueDeliveries(ctx, now().Add(90*time.Minute), 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, dueDelivery := range due {
+				if dueDelivery.ID == delivery.ID {
+					t.Error("Expected a finished delivery not to be listed as due")
+				}
+			}
+			got, err := repo.GetWebhookForDelivery(ctx, webhook.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != webhook.ID || got.UserId != webhook.UserId {
+				t.Errorf("Expected webhook %s of %s. Got %s of %s", webhook.ID, webhook.UserId, got.ID, got.UserId)
+			}
+		},
+	)
+}
+
+func newWebhook(userId uuid.UUID) domain.Webhook {
+	createdAt := now()
+	return domain.Webhook{
+		ID:         uuid.New(),
+		UserId:     userId,
+		URL:        "http://127.0.0.1:1/hooks",
+		Secret:     "contract-secret",
+		EventTypes: []domain.TodoEventType{domain.TodoCreated},
+		Disabled:   true,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+}
+
+func newDelivery(webhookId uuid.UUID) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:        uuid.New(),
+		WebhookId: webhookId,
+		EventId:   uuid.New(),
+		EventType: domain.TodoCreated,
+		Payload:   []byte(`{}`),
+		CreatedAt: now(),
+	}
+}
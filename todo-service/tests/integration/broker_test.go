@@ -0,0 +1,93 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/redis/go-redis/v9"
+)
+
+func newRedisBrokerSink(t *testing.T, address string) *events.BrokerSink {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: address})
+	t.Cleanup(func() { client.Close() })
+	broker, err := events.NewRedisStreamBroker(client, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := events.NewBrokerSink(broker, "todo-events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sink
+}
+
+func TestRedisBrokerSink(t *testing.T) {
+	t.Run(`Given a broker sink on a Redis stream
+      When it publishes a todo event
+      Then the stream should get an entry keyed by the todo id that carries the event
+    `,
+		func(t *testing.T) {
+			redisServer := miniredis.RunT(t)
+			sink := newRedisBrokerSink(t, redisServer.Addr())
+			event := domain.TodoEvent{
+				ID:         uuid.New(),
+				Type:       domain.TodoCreated,
+				TodoId:     uuid.New(),
+				UserId:     uuid.New(),
+				OccurredAt: time.Now(),
+			}
+
+			if err := sink.Publish(context.Background(), event); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := redisServer.Stream("todo-events")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 stream entry, got %d", len(entries))
+			}
+			values := map[string]string{}
+			for i := 0; i+1 < len(entries[0].Values); i += 2 {
+				values[entries[0].Values[i]] = entries[0].Values[i+1]
+			}
+			if values["key"] != event.TodoId.String() {
+				t.Errorf("expected key %s, got %q", event.TodoId, values["key"])
+			}
+			var payload map[string]interface{}
+			if err := json.Unmarshal([]byte(values["payload"]), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload["id"] != event.ID.String() || payload["type"] != string(domain.TodoCreated) {
+				t.Errorf("expected the payload of event %s, got %v", event.ID, payload)
+			}
+		},
+	)
+
+	t.Run(`Given a broker sink whose Redis server is down
+      When it publishes a todo event
+      Then it should fail, so the relay sends the event again
+    `,
+		func(t *testing.T) {
+			redisServer := miniredis.RunT(t)
+			sink := newRedisBrokerSink(t, redisServer.Addr())
+			redisServer.Close()
+
+			err := sink.Publish(context.Background(), domain.TodoEvent{ID: uuid.New(), TodoId: uuid.New()})
+			if err == nil {
+				t.Error("expected publishing to a stopped server to fail")
+			}
+		},
+	)
+}
@@ -0,0 +1,201 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/todo-service/internal/collab"
+)
+
+const (
+	user1Id = "8aa74031-393d-401e-a5d3-ba72089abe40"
+	user2Id = "9a98dc85-fe0a-4cbb-8bb7-f67fceae7751"
+)
+
+func dialCollaboration(t *testing.T, baseUrl, token string) *websocket.Conn {
+	t.Helper()
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(baseUrl, "http")+"/todos/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func awaitCollabMessage(t *testing.T, conn *websocket.Conn, match func(collab.Message) bool) collab.Message {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_ = conn.SetReadDeadline(deadline)
+		var message collab.Message
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("failed waiting for collaboration message: %v", err)
+		}
+		if match(message) {
+			return message
+		}
+	}
+}
+
+func TestCollaboration(t *testing.T) {
+	testServer := httptest.NewServer(svr.Router)
+	defer testServer.Close()
+
+	t.Run("test for missing authorization header", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/todos/ws", nil)
+		response := tests.ExecuteRequest(req, svr)
+		tests.AssertStatusCode(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run(`Given an authenticated user subscribed to their project
+      When they create a todo
+      Then a change event for that todo should be pushed over the socket
+    `,
+		func(t *testing.T) {
+			conn := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+			defer conn.Close()
+
+			_ = conn.WriteJSON(collab.Message{Type: collab.MessageSubscribe, ProjectId: user1Id})
+			awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageSubscribed
+			})
+
+			todoId := createTodoAs(t, ValidTokenForUser1)
+
+			message := awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageChange && message.Event.Todo["id"] == todoId
+			})
+			if message.Event.Type != "todo.created" {
+				t.Fatalf("expected todo.created, got %q", message.Event.Type)
+			}
+		},
+	)
+
+	t.Run(`Given two connections of the same user in one project
+      When one announces it is editing a todo
+      Then the other should see that presence
+      And should see it leave when it disconnects
+    `,
+		func(t *testing.T) {
+			viewer := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+			defer viewer.Close()
+			editor := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+
+			_ = viewer.WriteJSON(collab.Message{Type: collab.MessageSubscribe, ProjectId: user1Id})
+			awaitCollabMessage(t, viewer, func(message collab.Message) bool {
+				return message.Type == collab.MessageSubscribed
+			})
+			_ = editor.WriteJSON(collab.Message{Type: collab.MessageSubscribe, ProjectId: user1Id})
+			subscribed := awaitCollabMessage(t, editor, func(message collab.Message) bool {
+				return message.Type == collab.MessageSubscribed
+			})
+
+			_ = editor.WriteJSON(collab.Message{
+				Type:      collab.MessagePresence,
+				ProjectId: user1Id,
+				TodoId:    "some-todo",
+				State:     collab.PresenceEditing,
+			})
+			awaitCollabMessage(t, viewer, func(message collab.Message) bool {
+				return message.Type == collab.MessagePresence && message.ClientId == subscribed.ClientId &&
+					message.State == collab.PresenceEditing && message.TodoId == "some-todo"
+			})
+
+			editor.Close()
+			awaitCollabMessage(t, viewer, func(message collab.Message) bool {
+				return message.Type == collab.MessagePresence && message.ClientId == subscribed.ClientId &&
+					message.State == collab.PresenceLeft
+			})
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When they subscribe to another user's project
+      Then they should receive an error message
+    `,
+		func(t *testing.T) {
+			conn := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+			defer conn.Close()
+
+			_ = conn.WriteJSON(collab.Message{Type: collab.MessageSubscribe, ProjectId: user2Id})
+			message := awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageError
+			})
+			if message.Message != collab.ErrProjectAccessDenied.Error() {
+				t.Fatalf("expected %q, got %q", collab.ErrProjectAccessDenied.Error(), message.Message)
+			}
+		},
+	)
+
+	t.Run(`Given a project of another user they were added to as a member
+      When they subscribe to it and the owner moves a todo into it
+      Then a change event for that todo should be pushed over their socket
+    `,
+		func(t *testing.T) {
+			projectId := createProjectAs(t, ValidTokenForUser2)
+			req, _ := http.NewRequest(http.MethodPost, "/projects/"+projectId+"/members", bytes.NewBufferString(fmt.Sprintf(`{"user_id": "%s"}`, user1Id)))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser2)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+
+			conn := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+			defer conn.Close()
+			_ = conn.WriteJSON(collab.Message{Type: collab.MessageSubscribe, ProjectId: projectId})
+			awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageSubscribed && message.ProjectId == projectId
+			})
+
+			todoId := createTodoAs(t, ValidTokenForUser2)
+			bulkTodos(t, ValidTokenForUser2, fmt.Sprintf(`{"operations": [{"op": "move", "id": "%s", "project_id": "%s"}]}`, todoId, projectId))
+
+			awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageChange && message.ProjectId == projectId && message.Event.Todo["id"] == todoId
+			})
+		},
+	)
+
+	t.Run(`Given a project of another user they were not added to
+      When they subscribe to it
+      Then they should receive an error message
+    `,
+		func(t *testing.T) {
+			projectId := createProjectAs(t, ValidTokenForUser2)
+			conn := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+			defer conn.Close()
+
+			_ = conn.WriteJSON(collab.Message{Type: collab.MessageSubscribe, ProjectId: projectId})
+			message := awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageError
+			})
+			if message.Message != collab.ErrProjectAccessDenied.Error() {
+				t.Fatalf("expected %q, got %q", collab.ErrProjectAccessDenied.Error(), message.Message)
+			}
+		},
+	)
+
+	t.Run(`Given an open connection
+      When the client refreshes its token with one for a different user
+      Then it should receive an error message
+    `,
+		func(t *testing.T) {
+			conn := dialCollaboration(t, testServer.URL, ValidTokenForUser1)
+			defer conn.Close()
+
+			_ = conn.WriteJSON(collab.Message{Type: collab.MessageAuth, Token: ValidTokenForUser2})
+			awaitCollabMessage(t, conn, func(message collab.Message) bool {
+				return message.Type == collab.MessageError
+			})
+		},
+	)
+}
@@ -0,0 +1,201 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+)
+
+func graphQLAs(t *testing.T, token, query string) map[string]interface{} {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	return tests.ParseResponse(response)
+}
+
+func TestGraphQL(t *testing.T) {
+	t.Run("test for missing authorization header", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewBufferString(`{"query": "{ me { id } }"}`))
+		response := tests.ExecuteRequest(req, svr)
+		tests.AssertStatusCode(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run(`Given an authenticated user
+      When they create a todo with a mutation
+      Then querying it by id, alongside another todo, should return both
+    `,
+		func(t *testing.T) {
+			created := graphQLAs(t, ValidTokenForUser1, `mutation { createTodo(text: "from graphql") { id text } }`)
+			if created["errors"] != nil {
+				t.Fatalf("unexpected errors: %v", created["errors"])
+			}
+			firstId := created["data"].(map[string]interface{})["createTodo"].(map[string]interface{})["id"].(string)
+			secondId := createTodoAs(t, ValidTokenForUser1)
+
+			result := graphQLAs(t, ValidTokenForUser1, fmt.Sprintf(
+				`{ first: todo(id: "%s") { id text project { id } } second: todo(id: "%s") { id } me { id } }`, firstId, secondId))
+			if result["errors"] != nil {
+				t.Fatalf("unexpected errors: %v", result["errors"])
+			}
+			data := result["data"].(map[string]interface{})
+			first := data["first"].(map[string]interface{})
+			if first["text"] != "from graphql" {
+				t.Fatalf("expected the created todo, got %v", first)
+			}
+			me := data["me"].(map[string]interface{})["id"]
+			if first["project"].(map[string]interface{})["id"] != me {
+				t.Fatalf("expected the todo in the user's personal project, got %v", first["project"])
+			}
+			if data["second"].(map[string]interface{})["id"] != secondId {
+				t.Fatalf("expected the second todo, got %v", data["second"])
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When they query another user's todo
+      Then it should resolve to null with an error
+    `,
+		func(t *testing.T) {
+			todoId := createTodoAs(t, ValidTokenForUser2)
+			result := graphQLAs(t, ValidTokenForUser1, fmt.Sprintf(`{ todo(id: "%s") { id } }`, todoId))
+			if result["errors"] == nil || result["data"].(map[string]interface{})["todo"] != nil {
+				t.Fatalf("expected no todo and an error, got %v", result)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When they create a project, move a todo into it and label the todo with mutations
+      Then the todo should resolve to that project with its labels
+      And the project and the label should each list the todo
+    `,
+		func(t *testing.T) {
+			created := graphQLAs(t, ValidTokenForUser1, `mutation { createProject(name: "from graphql") { id name } }`)
+			if created["errors"] != nil {
+				t.Fatalf("unexpected errors: %v", created["errors"])
+			}
+			projectId := created["data"].(map[string]interface{})["createProject"].(map[string]interface{})["id"].(string)
+			todoId := createTodoAs(t, ValidTokenForUser1)
+			label := fmt.Sprint("label-", tests.GenerateUniqueId())
+
+			result := graphQLAs(t, ValidTokenForUser1, fmt.Sprintf(
+				`mutation { moveTodo(id: "%s", projectId: "%s") { id } labelTodo(id: "%s", labels: ["%s"]) { project { id name } labels { name } } }`,
+				todoId, projectId, todoId, label))
+			if result["errors"] != nil {
+				t.Fatalf("unexpected errors: %v", result["errors"])
+			}
+			labelled := result["data"].(map[string]interface{})["labelTodo"].(map[string]interface{})
+			project := labelled["project"].(map[string]interface{})
+			if project["id"] != projectId || project["name"] != "from graphql" {
+				t.Fatalf("expected the todo in project %s, got %v", projectId, project)
+			}
+			labels := labelled["labels"].([]interface{})
+			if len(labels) != 1 || labels[0].(map[string]interface{})["name"] != label {
+				t.Fatalf("expected the todo labelled %s, got %v", label, labels)
+			}
+
+			result = graphQLAs(t, ValidTokenForUser1, fmt.Sprintf(
+				`{ project(id: "%s") { todos { id } } labels { name todos { id } } }`, projectId))
+			if result["errors"] != nil {
+				t.Fatalf("unexpected errors: %v", result["errors"])
+			}
+			data := result["data"].(map[string]interface{})
+			projectTodos := data["project"].(map[string]interface{})["todos"].([]interface{})
+			if len(projectTodos) != 1 || projectTodos[0].(map[string]interface{})["id"] != todoId {
+				t.Fatalf("expected the project to list only todo %s, got %v", todoId, projectTodos)
+			}
+			listed := false
+			for _, item := range data["labels"].([]interface{}) {
+				if item.(map[string]interface{})["name"] != label {
+					continue
+				}
+				labelTodos := item.(map[string]interface{})["todos"].([]interface{})
+				listed = len(labelTodos) == 1 && labelTodos[0].(map[string]interface{})["id"] == todoId
+			}
+			if !listed {
+				t.Fatalf("expected label %s to list todo %s, got %v", label, todoId, data["labels"])
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When they query a project of another user
+      Then it should resolve to null
+    `,
+		func(t *testing.T) {
+			projectId := createProjectAs(t, ValidTokenForUser2)
+			result := graphQLAs(t, ValidTokenForUser1, fmt.Sprintf(`{ project(id: "%s") { id } }`, projectId))
+			if result["errors"] != nil || result["data"].(map[string]interface{})["project"] != nil {
+				t.Fatalf("expected no project, got %v", result)
+			}
+		},
+	)
+
+	t.Run(`Given a query nested deeper than the limit
+      When it is sent
+      Then it should be rejected without running
+    `,
+		func(t *testing.T) {
+			result := graphQLAs(t, ValidTokenForUser1,
+				`{ me { projects { todos { project { todos { project { todos { project { todos { id } } } } } } } } } }`)
+			errors, _ := result["errors"].([]interface{})
+			if len(errors) == 0 || !strings.Contains(errors[0].(map[string]interface{})["message"].(string), "depth") {
+				t.Fatalf("expected a depth error, got %v", result)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user subscribed to todoEvents
+      When they create a todo
+      Then the event should be streamed to them
+    `,
+		func(t *testing.T) {
+			testServer := httptest.NewServer(svr.Router)
+			defer testServer.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, testServer.URL+"/graphql",
+				bytes.NewBufferString(`{"query": "subscription { todoEvents { type todo { id } } }"}`))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "text/event-stream")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			tests.AssertStatusCode(t, http.StatusOK, res.StatusCode)
+
+			todoId := createTodoAs(t, ValidTokenForUser1)
+
+			scanner := bufio.NewScanner(res.Body)
+			for scanner.Scan() {
+				line := scanner.Text()
+				if strings.HasPrefix(line, "data: ") && strings.Contains(line, todoId) {
+					if !strings.Contains(line, `"type":"todo.created"`) {
+						t.Fatalf("expected a todo.created event, got %s", line)
+					}
+					return
+				}
+			}
+			t.Fatal("stream ended before the todo event arrived")
+		},
+	)
+}
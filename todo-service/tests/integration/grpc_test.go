@@ -0,0 +1,180 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	todosv1 "github.com/olad5/productive-pulse/pkg/proto/todos/v1"
+	usersv1 "github.com/olad5/productive-pulse/pkg/proto/users/v1"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func withBearer(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func assertGrpcCode(t *testing.T, expected codes.Code, err error) {
+	t.Helper()
+	if actual := status.Code(err); actual != expected {
+		t.Errorf("Expected gRPC code %s. Got %s (%v)\n", expected, actual, err)
+	}
+}
+
+func TestGrpcTodoService(t *testing.T) {
+	ctx := context.Background()
+	user1Ctx := withBearer(ctx, ValidTokenForUser1)
+	user2Ctx := withBearer(ctx, ValidTokenForUser2)
+
+	t.Run(`Given a request without an authorization token, when any todo RPC is
+    called, then it fails with Unauthenticated.`,
+		func(t *testing.T) {
+			_, err := todoClient.ListTodos(ctx, &todosv1.ListTodosRequest{})
+			assertGrpcCode(t, codes.Unauthenticated, err)
+
+			_, err = todoClient.ListTodos(withBearer(ctx, inValidToken), &todosv1.ListTodosRequest{})
+			assertGrpcCode(t, codes.Unauthenticated, err)
+		},
+	)
+
+	t.Run(`Given an authenticated user, when they create, get, list, update and
+    delete a todo over gRPC, then every call reflects the latest state.`,
+		func(t *testing.T) {
+			created, err := todoClient.CreateTodo(user1Ctx, &todosv1.CreateTodoRequest{Text: "grpc todo"})
+			if err != nil {
+				t.Fatalf("Expected no error creating todo. Got %v", err)
+			}
+			if created.GetText() != "grpc todo" || created.GetCreatedAt() == nil {
+				t.Errorf("unexpected created todo %v", created)
+			}
+
+			fetched, err := todoClient.GetTodo(user1Ctx, &todosv1.GetTodoRequest{Id: created.GetId()})
+			if err != nil {
+				t.Fatalf("Expected no error getting todo. Got %v", err)
+			}
+			if fetched.GetId() != created.GetId() {
+				t.Errorf("Expected todo %s. Got %s", created.GetId(), fetched.GetId())
+			}
+
+			listed, err := todoClient.ListTodos(user1Ctx, &todosv1.ListTodosRequest{})
+			if err != nil {
+				t.Fatalf("Expected no error listing todos. Got %v", err)
+			}
+			found := false
+			for _, todo := range listed.GetTodos() {
+				if todo.GetId() == created.GetId() {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected todo %s in listing", created.GetId())
+			}
+
+			updated, err := todoClient.UpdateTodo(user1Ctx, &todosv1.UpdateTodoRequest{Id: created.GetId(), Text: "updated grpc todo"})
+			if err != nil {
+				t.Fatalf("Expected no error updating todo. Got %v", err)
+			}
+			if updated.GetText() != "updated grpc todo" {
+				t.Errorf("Expected updated text. Got %q", updated.GetText())
+			}
+
+			_, err = todoClient.DeleteTodo(user1Ctx, &todosv1.DeleteTodoRequest{Id: created.GetId()})
+			if err != nil {
+				t.Fatalf("Expected no error deleting todo. Got %v", err)
+			}
+			_, err = todoClient.GetTodo(user1Ctx, &todosv1.GetTodoRequest{Id: created.GetId()})
+			assertGrpcCode(t, codes.NotFound, err)
+		},
+	)
+
+	t.Run(`Given a todo owned by another user, when it is fetched or updated over
+    gRPC, then the call fails with PermissionDenied.`,
+		func(t *testing.T) {
+			created, err := todoClient.CreateTodo(user1Ctx, &todosv1.CreateTodoRequest{Text: "private todo"})
+			if err != nil {
+				t.Fatalf("Expected no error creating todo. Got %v", err)
+			}
+
+			_, err = todoClient.GetTodo(user2Ctx, &todosv1.GetTodoRequest{Id: created.GetId()})
+			assertGrpcCode(t, codes.PermissionDenied, err)
+
+			_, err = todoClient.UpdateTodo(user2Ctx, &todosv1.UpdateTodoRequest{Id: created.GetId(), Text: "stolen"})
+			assertGrpcCode(t, codes.PermissionDenied, err)
+		},
+	)
+
+	t.Run(`Given an empty text or malformed id, when the todo RPCs are called, then
+    they fail with InvalidArgument.`,
+		func(t *testing.T) {
+			_, err := todoClient.CreateTodo(user1Ctx, &todosv1.CreateTodoRequest{})
+			assertGrpcCode(t, codes.InvalidArgument, err)
+
+			_, err = todoClient.CreateTodo(user1Ctx, &todosv1.CreateTodoRequest{Text: "   "})
+			assertGrpcCode(t, codes.InvalidArgument, err)
+
+			_, err = todoClient.GetTodo(user1Ctx, &todosv1.GetTodoRequest{Id: "not-a-uuid"})
+			assertGrpcCode(t, codes.InvalidArgument, err)
+		},
+	)
+}
+
+type stubUsersGrpcServer struct {
+	usersv1.UnimplementedUserServiceServer
+}
+
+func (stubUsersGrpcServer) VerifyToken(ctx context.Context, request *usersv1.VerifyTokenRequest) (*usersv1.VerifyTokenResponse, error) {
+	if request.GetAccessToken() == ValidTokenForUser1 {
+		return &usersv1.VerifyTokenResponse{UserId: user1Id}, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func TestUserGrpcService(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	usersv1.RegisterUserServiceServer(grpcServer, stubUsersGrpcServer{})
+	conn, err := tests.NewBufconnClientConn(grpcServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer grpcServer.Stop()
+
+	userService, err := user.NewUserGrpcService(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := otel.Tracer("test")
+
+	t.Run(`Given a valid bearer token, when VerifyUser is called on the gRPC adapter,
+    then it returns the user id from users-service.`,
+		func(t *testing.T) {
+			userId, err := userService.VerifyUser(context.Background(), tracer, "Bearer "+ValidTokenForUser1)
+			if err != nil {
+				t.Fatalf("Expected no error. Got %v", err)
+			}
+			if userId != user1Id {
+				t.Errorf("Expected user id %s. Got %s", user1Id, userId)
+			}
+		},
+	)
+
+	t.Run(`Given an invalid or non-bearer token, when VerifyUser is called on the
+    gRPC adapter, then it returns an error.`,
+		func(t *testing.T) {
+			if _, err := userService.VerifyUser(context.Background(), tracer, "Bearer "+inValidToken); err == nil {
+				t.Error("Expected an error for an invalid token")
+			}
+			if _, err := userService.VerifyUser(context.Background(), tracer, ValidTokenForUser1); err == nil {
+				t.Error("Expected an error for a missing Bearer prefix")
+			}
+		},
+	)
+}
@@ -0,0 +1,201 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/pkg/jwks"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// remoteUserService stands in for users-service, counting the tokens it is
+// asked to verify.
+type remoteUserService struct {
+	calls  int32
+	userId string
+}
+
+func (r *remoteUserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	atomic.AddInt32(&r.calls, 1)
+	return r.userId, nil
+}
+
+// jwksServer publishes the public keys of the keys it is given, until it
+// is made unavailable.
+type jwksServer struct {
+	mu          sync.Mutex
+	set         jwks.Set
+	unavailable bool
+}
+
+func (j *jwksServer) publish(t *testing.T, keys ...ed25519.PrivateKey) {
+	t.Helper()
+	set := jwks.Set{}
+	for _, key := range keys {
+		jwk, err := jwks.NewKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	j.mu.Lock()
+	j.set = set
+	j.mu.Unlock()
+}
+
+func (j *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.unavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(j.set)
+}
+
+func newSigningKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func signAccessToken(t *testing.T, key ed25519.PrivateKey, userId string, expiresAt time.Time) string {
+	t.Helper()
+	jwk, err := jwks.NewKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{
+		Subject:   userId,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	token.Header["kid"] = jwk.Kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+func TestLocalUserService(t *testing.T) {
+	ctx := context.Background()
+	tracer := otel.Tracer("local-user-service-test")
+	signingKey, rotatedKey := newSigningKey(t), newSigningKey(t)
+	keys := &jwksServer{}
+	keys.publish(t, signingKey)
+	server := httptest.NewServer(keys)
+	defer server.Close()
+
+	remote := &remoteUserService{userId: uuid.NewString()}
+	userService, err := user.NewLocalUserService(server.Client(), server.URL, remote, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run(`Given a token signed with a published key
+      When it is verified
+      Then its user should be returned
+      And users-service should not be asked`,
+		func(t *testing.T) {
+			userId := uuid.NewString()
+			verified, err := userService.VerifyUser(ctx, tracer, signAccessToken(t, signingKey, userId, time.Now().Add(time.Minute)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verified != userId {
+				t.Errorf("Expected user %s. Got %s", userId, verified)
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != 0 {
+				t.Errorf("Expected no remote verification. Got %d", calls)
+			}
+		},
+	)
+
+	t.Run(`Given an expired token, or one whose signature does not match its key
+      When they are verified
+      Then they should be rejected without asking users-service`,
+		func(t *testing.T) {
+			userId := uuid.NewString()
+			expired := signAccessToken(t, signingKey, userId, time.Now().Add(-time.Minute))
+			forged := signAccessToken(t, signingKey, userId, time.Now().Add(time.Minute))
+			forged = forged[:len(forged)-4] + "AAAA"
+			for name, token := range map[string]string{"expired": expired, "forged": forged, "no bearer": "Basic abc"} {
+				if _, err := userService.VerifyUser(ctx, tracer, token); err == nil {
+					t.Errorf("%s: Expected the token to be rejected", name)
+				}
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != 0 {
+				t.Errorf("Expected no remote verification. Got %d", calls)
+			}
+		},
+	)
+
+	t.Run(`Given a token signed with a key rotated in after the keys were read
+      When it is verified
+      Then users-service should verify it
+      And once the keys are read again it should be verified locally`,
+		func(t *testing.T) {
+			userId := uuid.NewString()
+			token := signAccessToken(t, rotatedKey, userId, time.Now().Add(time.Minute))
+			keys.publish(t, rotatedKey, signingKey)
+
+			verified, err := userService.VerifyUser(ctx, tracer, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verified != remote.userId || atomic.LoadInt32(&remote.calls) != 1 {
+				t.Errorf("Expected users-service to verify the token once. Got %s after %d calls", verified, remote.calls)
+			}
+
+			if err := userService.Refresh(ctx); err != nil {
+				t.Fatal(err)
+			}
+			verified, err = userService.VerifyUser(ctx, tracer, token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verified != userId || atomic.LoadInt32(&remote.calls) != 1 {
+				t.Errorf("Expected user %s to be verified locally. Got %s after %d calls", userId, verified, remote.calls)
+			}
+		},
+	)
+
+	t.Run(`Given keys that can no longer be read
+      When they are refreshed
+      Then the keys read before should still verify tokens`,
+		func(t *testing.T) {
+			keys.mu.Lock()
+			keys.unavailable = true
+			keys.mu.Unlock()
+			if err := userService.Refresh(ctx); err == nil {
+				t.Error("Expected the refresh to fail")
+			}
+			userId := uuid.NewString()
+			verified, err := userService.VerifyUser(ctx, tracer, signAccessToken(t, signingKey, userId, time.Now().Add(time.Minute)))
+			if err != nil || verified != userId {
+				t.Errorf("Expected user %s. Got %s, %v", userId, verified, err)
+			}
+		},
+	)
+}
@@ -0,0 +1,151 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/config"
+	infraMongo "github.com/olad5/productive-pulse/todo-service/internal/infra/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoMigrations(t *testing.T) {
+	if _, ok := todoRepository.(*infraMongo.MongoRepository); !ok {
+		t.Skip("only the mongo backend has indexes and data migrations")
+	}
+	ctx := context.Background()
+	mongoConfig, database := isolatedMongoConfig(t)
+	migrator, err := infraMongo.NewMigrator(ctx, nil, mongoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { migrator.Close(ctx) })
+	todoRepo, err := infraMongo.NewMongoRepo(ctx, nil, mongoConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { todoRepo.Close(ctx) })
+	if _, err := migrator.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	collection := func(name string) *mongo.Collection {
+		return database.Collection(mongoConfig.CollectionPrefix + name)
+	}
+
+	t.Run(`Given a migrated database
+      When it is migrated again
+      Then no data migration should be applied again
+      And the todos should be indexed by user and creation time, and by text
+    `,
+		func(t *testing.T) {
+			applied, err := migrator.Run(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != 0 {
+				t.Errorf("Expected no migrations to be applied. Got %v", applied)
+			}
+
+			specs, err := collection("todos").Indexes().ListSpecifications(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := map[string]bool{}
+			for _, spec := range specs {
+				names[spec.Name] = true
+			}
+			for _, name := range []string{"user_id_1_created_at_1", "text_text", "user_id_1_snoozed_until_1"} {
+				if !names[name] {
+					t.Errorf("Expected index %s on todos. Got %v", name, names)
+				}
+			}
+		},
+	)
+
+	t.Run(`Given a todo written before todos could be snoozed or completed
+      When the backfill of those fields is applied again
+      Then the todo should have both, unset
+      And it should still be listed as a todo that is not snoozed
+    `,
+		func(t *testing.T) {
+			userId, todoId := uuid.New(), uuid.New()
+			now := time.Now().UTC()
+			legacyTodo := bson.M{
+				"_id":        primitiveUUID(todoId),
+				"user_id":    primitiveUUID(userId),
+				"text":       "legacy todo",
+				"created_at": now,
+				"updated_at": now,
+			}
+			if _, err := collection("todos").InsertOne(ctx, legacyTodo); err != nil {
+				t.Fatal(err)
+			}
+			backfills := bson.M{"_id": bson.M{"$in": bson.A{"0001_backfill_todos_snoozed_until", "0002_backfill_todos_completed_at"}}}
+			if _, err := collection("schema_migrations").DeleteMany(ctx, backfills); err != nil {
+				t.Fatal(err)
+			}
+
+			applied, err := migrator.Migrate(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != 2 {
+				t.Errorf("Expected both backfills to be applied. Got %v", applied)
+			}
+
+			var stored bson.M
+			if err := collection("todos").FindOne(ctx, bson.M{"_id": primitiveUUID(todoId)}).Decode(&stored); err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range []string{"snoozed_until", "completed_at"} {
+				if value, ok := stored[field]; !ok || value != nil {
+					t.Errorf("Expected %s to be set to null. Got %v", field, stored)
+				}
+			}
+			todos, err := todoRepo.GetTodos(ctx, userId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(todos) != 1 || todos[0].ID != todoId {
+				t.Errorf("Expected the legacy todo to be listed. Got %v", todos)
+			}
+		},
+	)
+}
+
+// isolatedMongoConfig points at a database of t's own, with prefixed
+// collections, which is dropped once t is done. The database is also
+// returned, to look at what the repositories stored.
+func isolatedMongoConfig(t *testing.T) (infraMongo.MongoConfig, *mongo.Database) {
+	t.Helper()
+	ctx := context.Background()
+	mongoConfig := infraMongo.NewMongoConfig(config.GetConfig("../config/.test.env"))
+	mongoConfig.DatabaseName = "todo-service-test-" + uuid.NewString()[:8]
+	mongoConfig.CollectionPrefix = "test_"
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoConfig.ConnectionString))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database := client.Database(mongoConfig.DatabaseName)
+	t.Cleanup(func() {
+		if err := database.Drop(ctx); err != nil {
+			t.Error(err)
+		}
+		client.Disconnect(ctx)
+	})
+	return mongoConfig, database
+}
+
+// primitiveUUID encodes id the way the repositories store uuids.
+func primitiveUUID(id uuid.UUID) primitive.Binary {
+	return primitive.Binary{Subtype: 0x04, Data: id[:]}
+}
@@ -0,0 +1,56 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Run(`Given the todo-service router, when the OpenAPI spec is fetched from
+    /openapi.json, then it is an OpenAPI 3.1 document describing every route.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+
+			var document struct {
+				OpenAPI string `json:"openapi"`
+			}
+			if err := json.Unmarshal(response.Body.Bytes(), &document); err != nil {
+				t.Fatal(err)
+			}
+			if document.OpenAPI != "3.1.0" {
+				t.Errorf("Expected an OpenAPI 3.1.0 document. Got %q", document.OpenAPI)
+			}
+			tests.AssertRoutesDocumented(t, svr.Router, response.Body.Bytes())
+		},
+	)
+
+	t.Run(`Given requests that break the spec, when they are sent, then the request
+    validator rejects them with a 400 before authentication runs.`,
+		func(t *testing.T) {
+			for _, request := range []struct {
+				method, route, body string
+			}{
+				{http.MethodPost, "/todos", `{"text": ""}`},
+				{http.MethodPost, "/todos", `{"text": 42}`},
+				{http.MethodGet, "/todos?include_snoozed=sometimes", ``},
+				{http.MethodPost, "/todos/bulk", `{"atomic": true}`},
+				{http.MethodPost, "/webhooks", `{"url": "http://localhost", "event_types": []}`},
+			} {
+				req, _ := http.NewRequest(request.method, request.route, bytes.NewBufferString(request.body))
+				response := tests.ExecuteRequest(req, svr)
+				if response.Code != http.StatusBadRequest {
+					t.Errorf("%s %s: expected response code %d. Got %d", request.method, request.route, http.StatusBadRequest, response.Code)
+				}
+			}
+		},
+	)
+}
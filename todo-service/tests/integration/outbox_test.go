@@ -0,0 +1,194 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []domain.TodoEvent
+}
+
+func recordEvents() *eventRecorder {
+	recorder := &eventRecorder{}
+	eventBus.Subscribe(func(ctx context.Context, event domain.TodoEvent) error {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		recorder.events = append(recorder.events, event)
+		return nil
+	})
+	return recorder
+}
+
+func (e *eventRecorder) forTodo(todoId string) []domain.TodoEventType {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var eventTypes []domain.TodoEventType
+	for _, event := range e.events {
+		if event.TodoId.String() == todoId {
+			eventTypes = append(eventTypes, event.Type)
+		}
+	}
+	return eventTypes
+}
+
+func (e *eventRecorder) forText(text string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	count := 0
+	for _, event := range e.events {
+		if event.Todo.Text == text {
+			count++
+		}
+	}
+	return count
+}
+
+func TestOutbox(t *testing.T) {
+	recorder := recordEvents()
+
+	t.Run(`Given an authenticated user
+      When they create, update and complete a todo
+      Then the relayed events for that todo should arrive in the order they happened
+    `,
+		func(t *testing.T) {
+			todoId := createTodoAs(t, ValidTokenForUser1)
+
+			updateReq, _ := http.NewRequest(http.MethodPatch, "/todos/"+todoId, bytes.NewBufferString(`{"text": "updated"}`))
+			updateReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(updateReq, svr).Code)
+
+			completeReq, _ := http.NewRequest(http.MethodPost, "/todos/bulk",
+				bytes.NewBufferString(fmt.Sprintf(`{"operations": [{"op": "complete", "id": "%s"}]}`, todoId)))
+			completeReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(completeReq, svr).Code)
+
+			expected := []domain.TodoEventType{domain.TodoCreated, domain.TodoUpdated, domain.TodoCompleted}
+			var got []domain.TodoEventType
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+				got = recorder.forTodo(todoId)
+				if len(got) >= len(expected) {
+					break
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Errorf("got events %v, expected %v", got, expected)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When an atomic bulk request that creates a todo is rolled back
+      Then no event should be relayed for that todo
+    `,
+		func(t *testing.T) {
+			text := "rolled back text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			requestBody := []byte(fmt.Sprintf(`{
+			"atomic": true,
+			"operations": [
+				{"op": "create", "text": "%s"},
+				{"op": "delete", "id": "caf3d5c8-0db7-4b27-a02e-e5a664684568"}
+			]
+			}`, text))
+			req, _ := http.NewRequest(http.MethodPost, "/todos/bulk", bytes.NewBuffer(requestBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusBadRequest, tests.ExecuteRequest(req, svr).Code)
+
+			time.Sleep(500 * time.Millisecond)
+			if count := recorder.forText(text); count != 0 {
+				t.Errorf("expected no events for a rolled back todo, got %d", count)
+			}
+		},
+	)
+	t.Run(`Given a subscriber that fails the first time it sees an event
+      When the todo the event is about is created
+      Then the event should be relayed again until the subscriber accepts it
+    `,
+		func(t *testing.T) {
+			text := "flaky subscriber text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+			eventBus.Subscribe(func(ctx context.Context, event domain.TodoEvent) error {
+				if event.Todo.Text != text {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				if attempts == 1 {
+					return errors.New("subscriber is not ready yet")
+				}
+				return nil
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(fmt.Sprintf(`{"text": "%s"}`, text)))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(req, svr).Code)
+
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+				if recorder.forText(text) >= 2 {
+					break
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts < 2 {
+				t.Errorf("expected the event to be relayed again after it failed, got %d attempts", attempts)
+			}
+		},
+	)
+	t.Run(`Given a subscriber that fails every time it sees an event
+      When the todo the event is about is created
+      Then the event should be parked after the last attempt and not relayed again
+    `,
+		func(t *testing.T) {
+			text := "failing subscriber text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+			eventBus.Subscribe(func(ctx context.Context, event domain.TodoEvent) error {
+				if event.Todo.Text != text {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				attempts++
+				return errors.New("subscriber is down")
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(fmt.Sprintf(`{"text": "%s"}`, text)))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(req, svr).Code)
+
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+				if recorder.forText(text) >= outboxMaxAttempts {
+					break
+				}
+			}
+			// NOTE: a few more runs of the relay would send it again, had it
+			// not been parked.
+			time.Sleep(300 * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts != outboxMaxAttempts {
+				t.Errorf("expected the event to be sent %d times before it was parked, got %d", outboxMaxAttempts, attempts)
+			}
+		},
+	)
+}
@@ -0,0 +1,94 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+)
+
+func TestProblemDetails(t *testing.T) {
+	route := "/todos/caf3d5c8-0db7-4b27-a02e-e5a664684568"
+
+	t.Run(`Given an authenticated user who accepts application/problem+json
+      When they get a todo that does not exist
+      Then they should receive a 404 problem naming the error by its code`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, route, nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			req.Header.Set("Accept", response.ProblemContentType+", application/json;q=0.5")
+			res := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusNotFound, res.Code)
+			tests.AssertResponseMessage(t, res.Header().Get("Content-Type"), response.ProblemContentType)
+
+			problem := tests.ParseResponse(res)
+			tests.AssertResponseMessage(t, problem["title"].(string), http.StatusText(http.StatusNotFound))
+			tests.AssertResponseMessage(t, problem["detail"].(string), "record not found")
+			tests.AssertResponseMessage(t, problem["code"].(string), "todo_not_found")
+			tests.AssertResponseMessage(t, problem["instance"].(string), route)
+			if status := problem["status"].(float64); status != http.StatusNotFound {
+				t.Errorf("Expected status %d in the problem. Got %v", http.StatusNotFound, status)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user who does not ask for problems
+      When they get a todo that does not exist
+      Then they should receive the usual error response, with the same code`,
+		func(t *testing.T) {
+			for _, accept := range []string{"", "application/json", response.ProblemContentType + ";q=0"} {
+				req, _ := http.NewRequest(http.MethodGet, route, nil)
+				req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+				req.Header.Set("Accept", accept)
+				res := tests.ExecuteRequest(req, svr)
+				tests.AssertStatusCode(t, http.StatusNotFound, res.Code)
+				tests.AssertResponseMessage(t, res.Header().Get("Content-Type"), "application/json")
+
+				body := tests.ParseResponse(res)
+				tests.AssertResponseMessage(t, body["status"].(string), "error")
+				tests.AssertResponseMessage(t, body["message"].(string), "record not found")
+				tests.AssertResponseMessage(t, body["code"].(string), "todo_not_found")
+			}
+		},
+	)
+
+	t.Run(`Given a user without a token who accepts application/problem+json
+      When they list their todos
+      Then they should receive a 401 problem`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/todos", nil)
+			req.Header.Set("Accept", response.ProblemContentType)
+			res := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusUnauthorized, res.Code)
+			tests.AssertResponseMessage(t, tests.ParseResponse(res)["code"].(string), "unauthorized")
+		},
+	)
+
+	t.Run(`Given users-service is down
+      When a user lists their todos
+      Then they should receive a 503 naming users-service, rather than a 401`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/todos", nil)
+			req.Header.Set("Authorization", "Bearer "+TokenWhileUserServiceIsDown)
+			res := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusServiceUnavailable, res.Code)
+			tests.AssertResponseMessage(t, tests.ParseResponse(res)["code"].(string), "user_service_unavailable")
+		},
+	)
+
+	t.Run(`Given a token users-service rejects
+      When a user lists their todos with it
+      Then they should receive a 401`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/todos", nil)
+			req.Header.Set("Authorization", "Bearer "+inValidToken)
+			res := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusUnauthorized, res.Code)
+			tests.AssertResponseMessage(t, tests.ParseResponse(res)["code"].(string), "unauthorized")
+		},
+	)
+}
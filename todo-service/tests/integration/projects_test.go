@@ -0,0 +1,131 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+)
+
+func createProjectAs(t *testing.T, token string) string {
+	t.Helper()
+	name := "project with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+	req, _ := http.NewRequest(http.MethodPost, "/projects", bytes.NewBufferString(fmt.Sprintf(`{"name": "%s"}`, name)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	return tests.ParseResponse(response)["data"].(map[string]interface{})["id"].(string)
+}
+
+func bulkTodos(t *testing.T, token, requestBody string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, "/todos/bulk", bytes.NewBufferString(requestBody))
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	return response
+}
+
+func TestProjects(t *testing.T) {
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they create a project and list their projects
+      Then their personal project should be listed first
+      And the project they created should be listed after it
+    `,
+		func(t *testing.T) {
+			projectId := createProjectAs(t, ValidTokenForUser1)
+
+			req, _ := http.NewRequest(http.MethodGet, "/projects", nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+
+			projectsData := tests.ParseResponse(response)["data"].([]interface{})
+			tests.AssertResponseMessage(t, projectsData[0].(map[string]interface{})["id"].(string), user1Id)
+			listed := false
+			for _, project := range projectsData[1:] {
+				listed = listed || project.(map[string]interface{})["id"].(string) == projectId
+			}
+			if !listed {
+				t.Errorf("expected project %s to be listed, got %v", projectId, projectsData)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they create a project without a name
+      Then they should receive a 400 Bad Request response
+    `,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/projects", bytes.NewBufferString(`{"name": "   "}`))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+		},
+	)
+}
+
+func TestBulkMoveAndLabel(t *testing.T) {
+	t.Run(`Given an authenticated user with a todo and a project of their own
+      When they move the todo into the project and label it in one bulk request
+      Then both operations should succeed
+      And the todo should be filed under the project with the labels trimmed and deduplicated
+    `,
+		func(t *testing.T) {
+			todoId := createTodoAs(t, ValidTokenForUser1)
+			projectId := createProjectAs(t, ValidTokenForUser1)
+
+			body := bulkTodos(t, ValidTokenForUser1, fmt.Sprintf(`{
+			"atomic": true,
+			"operations": [
+				{"op": "move", "id": "%s", "project_id": "%s"},
+				{"op": "label", "id": "%s", "labels": ["work", " urgent ", "work"]}
+			]
+			}`, todoId, projectId, todoId))
+
+			results := tests.ParseResponse(body)["data"].([]interface{})
+			labelled := results[1].(map[string]interface{})["data"].(map[string]interface{})
+			tests.AssertResponseMessage(t, labelled["project_id"].(string), projectId)
+			labels := labelled["labels"].([]interface{})
+			if len(labels) != 2 || labels[0] != "work" || labels[1] != "urgent" {
+				t.Errorf("expected labels [work urgent], got %v", labels)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user with a todo
+      When they move it into a project of another user and into a project that does not exist
+      Then both moves should fail as not found
+      And the todo should stay in their personal project
+    `,
+		func(t *testing.T) {
+			todoId := createTodoAs(t, ValidTokenForUser1)
+			theirProjectId := createProjectAs(t, ValidTokenForUser2)
+
+			body := bulkTodos(t, ValidTokenForUser1, fmt.Sprintf(`{
+			"atomic": false,
+			"operations": [
+				{"op": "move", "id": "%s", "project_id": "%s"},
+				{"op": "move", "id": "%s", "project_id": "caf3d5c8-0db7-4b27-a02e-e5a664684568"}
+			]
+			}`, todoId, theirProjectId, todoId))
+
+			for _, result := range tests.ParseResponse(body)["data"].([]interface{}) {
+				tests.AssertResponseMessage(t, result.(map[string]interface{})["status"].(string), "error")
+				tests.AssertResponseMessage(t, result.(map[string]interface{})["message"].(string), "record not found")
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, "/todos/"+todoId, nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			data := tests.ParseResponse(response)["data"].(map[string]interface{})
+			tests.AssertResponseMessage(t, data["project_id"].(string), user1Id)
+		},
+	)
+}
@@ -0,0 +1,161 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
+)
+
+// routerWithLimits serves the suite with a default plan allowing limits, on
+// top of what user 1 already used.
+func routerWithLimits(t *testing.T, limits map[domain.QuotaResource]int64) http.Handler {
+	t.Helper()
+	usage, err := usageRepo.GetUsage(context.Background(), uuid.MustParse(user1Id), domain.UsagePeriod(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	planLimits := map[domain.QuotaResource]int64{}
+	for resource, limit := range limits {
+		planLimits[resource] = usage.Used[resource] + limit
+	}
+	quotaService, err := quotas.NewQuotaService(usageRepo, []domain.Plan{{Name: quotas.DefaultPlan, Limits: planLimits}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil, rateLimitedUsers, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newQuotaRouter(rateLimiter, quotaService)
+}
+
+func requestAsUser1(router http.Handler, method, route, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, route, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+	req.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, req)
+	return response
+}
+
+func assertQuotaExceeded(t *testing.T, response *httptest.ResponseRecorder, code string) {
+	t.Helper()
+	tests.AssertStatusCode(t, http.StatusForbidden, response.Code)
+	if got, _ := tests.ParseResponse(response)["code"].(string); got != code {
+		t.Errorf("Expected code %q. Got %q", code, got)
+	}
+}
+
+func usedQuota(t *testing.T, response *httptest.ResponseRecorder, resource domain.QuotaResource) float64 {
+	t.Helper()
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	// NOTE: the body is parsed from a copy, as tests read several quotas of
+	// one response.
+	data := tests.ParseResponse(&httptest.ResponseRecorder{Body: bytes.NewBuffer(response.Body.Bytes())})["data"].(map[string]interface{})
+	return data["quotas"].(map[string]interface{})[string(resource)].(map[string]interface{})["used"].(float64)
+}
+
+func TestQuotas(t *testing.T) {
+	t.Run(`Given an authenticated user
+      When they create a todo and then delete it
+      Then GET /usage should report one more todo and then one less
+      And it should not count itself as an API call
+    `,
+		func(t *testing.T) {
+			before := requestAsUser1(svr.Router, http.MethodGet, "/v1/usage", "")
+			todos, apiCalls := usedQuota(t, before, domain.QuotaTodos), usedQuota(t, before, domain.QuotaAPICalls)
+			if plan := tests.ParseResponse(before)["data"].(map[string]interface{})["plan"]; plan != quotas.DefaultPlan {
+				t.Errorf("Expected plan %q. Got %v", quotas.DefaultPlan, plan)
+			}
+
+			created := requestAsUser1(svr.Router, http.MethodPost, "/v1/todos", `{"text": "counted todo"}`)
+			tests.AssertStatusCode(t, http.StatusOK, created.Code)
+			afterCreate := requestAsUser1(svr.Router, http.MethodGet, "/v1/usage", "")
+			if used := usedQuota(t, afterCreate, domain.QuotaTodos); used != todos+1 {
+				t.Errorf("Expected %v todos. Got %v", todos+1, used)
+			}
+			if used := usedQuota(t, afterCreate, domain.QuotaAPICalls); used != apiCalls+1 {
+				t.Errorf("Expected %v api calls. Got %v", apiCalls+1, used)
+			}
+
+			todoId := tests.ParseResponse(created)["data"].(map[string]interface{})["id"].(string)
+			deleted := requestAsUser1(svr.Router, http.MethodPost, "/v1/todos/bulk",
+				`{"operations": [{"op": "delete", "id": "`+todoId+`"}]}`)
+			tests.AssertStatusCode(t, http.StatusOK, deleted.Code)
+			if used := usedQuota(t, requestAsUser1(svr.Router, http.MethodGet, "/v1/usage", ""), domain.QuotaTodos); used != todos {
+				t.Errorf("Expected %v todos. Got %v", todos, used)
+			}
+		},
+	)
+
+	t.Run(`Given a user whose plan limits attachment storage
+      When they request GET /usage
+      Then it should report the reserved attachment storage quota with its limit and nothing used
+    `,
+		func(t *testing.T) {
+			router := routerWithLimits(t, map[domain.QuotaResource]int64{domain.QuotaAttachmentBytes: 1024})
+
+			response := requestAsUser1(router, http.MethodGet, "/v1/usage", "")
+			if used := usedQuota(t, response, domain.QuotaAttachmentBytes); used != 0 {
+				t.Errorf("Expected no attachment bytes used. Got %v", used)
+			}
+			quota := tests.ParseResponse(response)["data"].(map[string]interface{})["quotas"].(map[string]interface{})[string(domain.QuotaAttachmentBytes)].(map[string]interface{})
+			if limit := quota["limit"]; limit != float64(1024) {
+				t.Errorf("Expected a limit of 1024 attachment bytes. Got %v", limit)
+			}
+		},
+	)
+
+	t.Run(`Given a user one todo away from the limit of their plan
+      When they create two todos
+      Then the second should be refused with a 403 and the todos_quota_exceeded code
+    `,
+		func(t *testing.T) {
+			router := routerWithLimits(t, map[domain.QuotaResource]int64{domain.QuotaTodos: 1})
+
+			tests.AssertStatusCode(t, http.StatusOK,
+				requestAsUser1(router, http.MethodPost, "/v1/todos", `{"text": "last todo allowed"}`).Code)
+			assertQuotaExceeded(t, requestAsUser1(router, http.MethodPost, "/v1/todos", `{"text": "one todo too many"}`),
+				"todos_quota_exceeded")
+		},
+	)
+
+	t.Run(`Given a user at the webhook limit of their plan
+      When they create a webhook
+      Then they should get a 403 with the webhooks_quota_exceeded code
+    `,
+		func(t *testing.T) {
+			router := routerWithLimits(t, map[domain.QuotaResource]int64{domain.QuotaWebhooks: 0})
+
+			assertQuotaExceeded(t, requestAsUser1(router, http.MethodPost, "/v1/webhooks",
+				`{"url": "https://203.0.113.10/hooks", "event_types": ["todo.created"]}`),
+				"webhooks_quota_exceeded")
+		},
+	)
+
+	t.Run(`Given a user with one API call left this month
+      When they make two calls
+      Then the second should be refused with the api_calls_quota_exceeded code
+      And GET /usage should still answer
+    `,
+		func(t *testing.T) {
+			router := routerWithLimits(t, map[domain.QuotaResource]int64{domain.QuotaAPICalls: 1})
+
+			tests.AssertStatusCode(t, http.StatusOK, requestAsUser1(router, http.MethodGet, "/v1/todos", "").Code)
+			assertQuotaExceeded(t, requestAsUser1(router, http.MethodGet, "/v1/todos", ""), "api_calls_quota_exceeded")
+			tests.AssertStatusCode(t, http.StatusOK, requestAsUser1(router, http.MethodGet, "/v1/usage", "").Code)
+		},
+	)
+}
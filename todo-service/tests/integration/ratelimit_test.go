@@ -0,0 +1,91 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/redis/go-redis/v9"
+)
+
+func createRateLimitedTodoAs(router http.Handler, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/v1/todos", bytes.NewBufferString(`{"text": "rate limited todo"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, req)
+	return response
+}
+
+func TestRateLimit(t *testing.T) {
+	policy, err := ratelimit.ParsePolicy("POST /todos user=2/1m;* ip=100/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redisServer := miniredis.RunT(t)
+	redisStore, err := ratelimit.NewRedisStore(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}), "todo-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range map[string]ratelimit.Store{"memory": ratelimit.NewMemoryStore(), "redis": redisStore} {
+		rateLimiter, err := ratelimit.NewLimiter(store, policy, rateLimitedUsers, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := newRouter(rateLimiter)
+
+		t.Run(name+`: Given a user that used up their limit for creating todos
+      When they create another one
+      Then they should get a 429 in the error format with Retry-After
+      And another user from the same IP should not be limited
+    `,
+			func(t *testing.T) {
+				for i := 0; i < 2; i++ {
+					tests.AssertStatusCode(t, http.StatusOK, createRateLimitedTodoAs(router, ValidTokenForUser1).Code)
+				}
+
+				response := createRateLimitedTodoAs(router, ValidTokenForUser1)
+				tests.AssertStatusCode(t, http.StatusTooManyRequests, response.Code)
+				tests.AssertResponseMessage(t, tests.ParseResponse(response)["message"].(string), ratelimit.ErrTooManyRequests.Message)
+				if response.Header().Get(ratelimit.RetryAfterHeader) == "" {
+					t.Error("Expected a Retry-After header")
+				}
+				if policy := response.Header().Get(ratelimit.PolicyHeader); policy != "2;w=60;burst=2" {
+					t.Errorf("Expected RateLimit-Policy %q. Got %q", "2;w=60;burst=2", policy)
+				}
+
+				tests.AssertStatusCode(t, http.StatusOK, createRateLimitedTodoAs(router, ValidTokenForUser2).Code)
+			},
+		)
+
+		t.Run(name+`: Given a route without a rule of its own
+      When it is called
+      Then the default IP limit should apply
+    `,
+			func(t *testing.T) {
+				req, _ := http.NewRequest(http.MethodGet, "/v1/todos", nil)
+				req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+				req.RemoteAddr = "192.0.2.3:1234"
+				response := httptest.NewRecorder()
+				router.ServeHTTP(response, req)
+
+				tests.AssertStatusCode(t, http.StatusOK, response.Code)
+				if limit := response.Header().Get(ratelimit.LimitHeader); limit != "100" {
+					t.Errorf("Expected RateLimit-Limit 100. Got %q", limit)
+				}
+				if remaining := response.Header().Get(ratelimit.RemainingHeader); remaining != "99" {
+					t.Errorf("Expected RateLimit-Remaining 99. Got %q", remaining)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/tests/contract"
+)
+
+func TestTodoRepositoryContract(t *testing.T) {
+	t.Run(`Given the storage backend the suite runs against
+      When it goes through the repository contract
+      Then it should behave like every other backend
+    `,
+		func(t *testing.T) {
+			contract.TodoRepository(t, todoRepository)
+		},
+	)
+}
+
+func TestWebhookRepositoryContract(t *testing.T) {
+	t.Run(`Given the storage backend the suite runs against
+      When it goes through the webhook repository contract
+      Then it should behave like every other backend
+    `,
+		func(t *testing.T) {
+			contract.WebhookRepository(t, webhookRepository)
+		},
+	)
+}
+
+func TestProjectRepositoryContract(t *testing.T) {
+	t.Run(`Given the storage backend the suite runs against
+      When it goes through the project repository contract
+      Then it should behave like every other backend
+    `,
+		func(t *testing.T) {
+			contract.ProjectRepository(t, todoRepository.(infra.ProjectRepository))
+		},
+	)
+}
@@ -0,0 +1,277 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flakyUserService answers with verify, counting the calls it gets.
+type flakyUserService struct {
+	calls  int32
+	verify func(call int32) (string, error)
+}
+
+func (f *flakyUserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
+	return f.verify(atomic.AddInt32(&f.calls, 1))
+}
+
+// unsignedToken is an access token of userId that expires in a minute. Its
+// signature does not matter, as only users-service checks it.
+func unsignedToken(t *testing.T, userId string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userId,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	signed, err := token.SignedString([]byte("users-service only"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+func newResilientUserService(t *testing.T, next user.UserServiceAdapter, policy user.ResiliencePolicy) *user.ResilientUserService {
+	t.Helper()
+	userService, err := user.NewResilientUserService(next, noop.NewMeterProvider().Meter(""), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userService
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestResilientUserService(t *testing.T) {
+	ctx := context.Background()
+	policy := user.ResiliencePolicy{
+		CacheSize:        2,
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+		FailureThreshold: 2,
+		OpenDuration:     50 * time.Millisecond,
+	}
+
+	t.Run(`Given a token users-service verified
+      When it is verified again
+      Then its user should come from the cache
+      And the span should record the cache hit`,
+		func(t *testing.T) {
+			userId := uuid.NewString()
+			remote := &flakyUserService{verify: func(int32) (string, error) { return userId, nil }}
+			userService := newResilientUserService(t, remote, policy)
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			token := unsignedToken(t, userId)
+
+			for i := 0; i < 2; i++ {
+				if verified, err := userService.VerifyUser(ctx, tracer, token); err != nil || verified != userId {
+					t.Fatalf("Expected user %s. Got %s, %v", userId, verified, err)
+				}
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != 1 {
+				t.Errorf("Expected users-service to be called once. Got %d", calls)
+			}
+			spans := recorder.Ended()
+			if hit, ok := spanAttribute(spans[len(spans)-1], "user.cache_hit"); !ok || !hit.AsBool() {
+				t.Errorf("Expected the last verification to record a cache hit. Got %v", hit.Emit())
+			}
+		},
+	)
+
+	t.Run(`Given more verified tokens than the cache holds
+      When the least recently used one is verified again
+      Then users-service should be asked again`,
+		func(t *testing.T) {
+			remote := &flakyUserService{verify: func(int32) (string, error) { return "user", nil }}
+			userService := newResilientUserService(t, remote, policy)
+			tracer := trace.NewNoopTracerProvider().Tracer("test")
+			first, second, third := unsignedToken(t, "first"), unsignedToken(t, "second"), unsignedToken(t, "third")
+
+			for _, token := range []string{first, second, first, third, first, second} {
+				if _, err := userService.VerifyUser(ctx, tracer, token); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != 4 {
+				t.Errorf("Expected second to be evicted by third and fetched again. Got %d calls", calls)
+			}
+		},
+	)
+
+	t.Run(`Given a token users-service rejects
+      When it is verified twice
+      Then it should be rejected each time without retries or caching`,
+		func(t *testing.T) {
+			remote := &flakyUserService{verify: func(int32) (string, error) { return "", user.ErrTokenRejected }}
+			userService := newResilientUserService(t, remote, policy)
+			tracer := trace.NewNoopTracerProvider().Tracer("test")
+			token := unsignedToken(t, uuid.NewString())
+
+			for i := 0; i < 2; i++ {
+				if _, err := userService.VerifyUser(ctx, tracer, token); !errors.Is(err, user.ErrTokenRejected) {
+					t.Fatalf("Expected the token to be rejected. Got %v", err)
+				}
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != 2 {
+				t.Errorf("Expected one call per verification. Got %d", calls)
+			}
+		},
+	)
+
+	t.Run(`Given many concurrent verifications of the same token
+      When users-service is slow to answer
+      Then they should share a single call`,
+		func(t *testing.T) {
+			userId := uuid.NewString()
+			release := make(chan struct{})
+			remote := &flakyUserService{verify: func(int32) (string, error) {
+				<-release
+				return userId, nil
+			}}
+			userService := newResilientUserService(t, remote, policy)
+			tracer := trace.NewNoopTracerProvider().Tracer("test")
+			token := unsignedToken(t, userId)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if verified, err := userService.VerifyUser(ctx, tracer, token); err != nil || verified != userId {
+						t.Errorf("Expected user %s. Got %s, %v", userId, verified, err)
+					}
+				}()
+			}
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			if calls := atomic.LoadInt32(&remote.calls); calls != 1 {
+				t.Errorf("Expected a single call. Got %d", calls)
+			}
+		},
+	)
+
+	t.Run(`Given users-service fails twice before it answers
+      When a token is verified
+      Then the verification should be retried until it succeeds`,
+		func(t *testing.T) {
+			userId := uuid.NewString()
+			remote := &flakyUserService{verify: func(call int32) (string, error) {
+				if call < 3 {
+					return "", errors.New("connection refused")
+				}
+				return userId, nil
+			}}
+			userService := newResilientUserService(t, remote, policy)
+			tracer := trace.NewNoopTracerProvider().Tracer("test")
+
+			if verified, err := userService.VerifyUser(ctx, tracer, unsignedToken(t, userId)); err != nil || verified != userId {
+				t.Fatalf("Expected user %s. Got %s, %v", userId, verified, err)
+			}
+			if calls := atomic.LoadInt32(&remote.calls); calls != 3 {
+				t.Errorf("Expected 3 attempts. Got %d", calls)
+			}
+		},
+	)
+
+	t.Run(`Given users-service is down
+      When verifications keep failing
+      Then the breaker should open and fail them fast
+      And once users-service is back, a trial call should close it again`,
+		func(t *testing.T) {
+			var down int32 = 1
+			remote := &flakyUserService{verify: func(int32) (string, error) {
+				if atomic.LoadInt32(&down) == 1 {
+					return "", errors.New("connection refused")
+				}
+				return "user", nil
+			}}
+			userService := newResilientUserService(t, remote, policy)
+			tracer := trace.NewNoopTracerProvider().Tracer("test")
+
+			for i := 0; i < policy.FailureThreshold; i++ {
+				if _, err := userService.VerifyUser(ctx, tracer, unsignedToken(t, uuid.NewString())); err == nil || errors.Is(err, user.ErrUserServiceUnavailable) {
+					t.Fatalf("Expected users-service to fail. Got %v", err)
+				}
+			}
+			calls := atomic.LoadInt32(&remote.calls)
+			if _, err := userService.VerifyUser(ctx, tracer, unsignedToken(t, uuid.NewString())); !errors.Is(err, user.ErrUserServiceUnavailable) {
+				t.Fatalf("Expected the open breaker to fail fast. Got %v", err)
+			}
+			if after := atomic.LoadInt32(&remote.calls); after != calls {
+				t.Errorf("Expected no call while the breaker is open. Got %d more", after-calls)
+			}
+
+			atomic.StoreInt32(&down, 0)
+			time.Sleep(policy.OpenDuration)
+			for i := 0; i < 2; i++ {
+				if _, err := userService.VerifyUser(ctx, tracer, unsignedToken(t, uuid.NewString())); err != nil {
+					t.Fatalf("Expected the breaker to let verifications through again. Got %v", err)
+				}
+			}
+		},
+	)
+
+	t.Run(`Given users-service keeps failing and retries back off for long
+      When the request verifying a token is cancelled
+      Then the verification should stop right away
+      And the next verification of the token should not wait for it`,
+		func(t *testing.T) {
+			var down int32 = 1
+			remote := &flakyUserService{verify: func(int32) (string, error) {
+				if atomic.LoadInt32(&down) == 1 {
+					return "", errors.New("connection refused")
+				}
+				return "user", nil
+			}}
+			slowRetries := policy
+			slowRetries.InitialBackoff = time.Hour
+			slowRetries.MaxBackoff = time.Hour
+			userService := newResilientUserService(t, remote, slowRetries)
+			tracer := trace.NewNoopTracerProvider().Tracer("test")
+			token := unsignedToken(t, uuid.NewString())
+
+			requestCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			started := time.Now()
+			if _, err := userService.VerifyUser(requestCtx, tracer, token); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected the verification to be cancelled. Got %v", err)
+			}
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Fatalf("Expected the verification to stop with its request. It took %v", elapsed)
+			}
+
+			atomic.StoreInt32(&down, 0)
+			nextCtx, cancelNext := context.WithTimeout(ctx, time.Second)
+			defer cancelNext()
+			if _, err := userService.VerifyUser(nextCtx, tracer, token); err != nil {
+				t.Fatalf("Expected the next verification to start afresh. Got %v", err)
+			}
+		},
+	)
+}
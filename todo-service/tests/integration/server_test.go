@@ -9,59 +9,314 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/exaring/otelpgx"
+	"github.com/google/uuid"
 	"github.com/olad5/productive-pulse/config"
 	"github.com/olad5/productive-pulse/pkg/app/server"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	todosv1 "github.com/olad5/productive-pulse/pkg/proto/todos/v1"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
 	tests "github.com/olad5/productive-pulse/pkg/tests"
 	"github.com/olad5/productive-pulse/pkg/utils"
 	"github.com/olad5/productive-pulse/todo-service/internal/app/router"
+	"github.com/olad5/productive-pulse/todo-service/internal/app/rpc"
+	"github.com/olad5/productive-pulse/todo-service/internal/collab"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+	"github.com/olad5/productive-pulse/todo-service/internal/graph"
 	"github.com/olad5/productive-pulse/todo-service/internal/handlers"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra/memory"
 	"github.com/olad5/productive-pulse/todo-service/internal/infra/mongo"
+	"github.com/olad5/productive-pulse/todo-service/internal/infra/postgres"
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
 
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/projects"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/quotas"
 	"github.com/olad5/productive-pulse/todo-service/internal/usecases/todos"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/webhooks"
+	"github.com/olad5/productive-pulse/todo-service/internal/workers"
+	"go.mongodb.org/mongo-driver/event"
 	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 	"go.opentelemetry.io/otel"
 )
 
-var svr *server.Server
+var (
+	svr        *server.Server
+	eventBus   *events.Bus
+	todoClient todosv1.TodoServiceClient
+	// newRouter builds the router of the suite around another rate limiter.
+	newRouter func(rateLimiter *ratelimit.Limiter) http.Handler
+	// newQuotaRouter also swaps the quota service, to test other plans.
+	newQuotaRouter func(rateLimiter *ratelimit.Limiter, quotaService *quotas.QuotaService) http.Handler
+	// usageRepo backs the quota services of the suite.
+	usageRepo infra.UsageRepository
+	// rateLimitedUsers identifies the users of the suite for rate limiters.
+	rateLimitedUsers ratelimit.IdentifyUser
+	// todoRepository is the backend the suite runs against.
+	todoRepository    infra.TodoRepository
+	webhookRepository infra.WebhookRepository
+)
+
+// outboxMaxAttempts is how many times the relay of the suite sends an event
+// before it parks it.
+const outboxMaxAttempts = 3
+
+// backends are the storage backends the suite runs against in turn, unless
+// TODO_SERVICE_STORAGE picks one.
+var backends = []string{"mongo", "postgres", "memory"}
 
 func TestMain(m *testing.M) {
 	configurations := config.GetConfig("../config/.test.env")
-	ctx := context.Background()
 
 	tracerProvider, err := utils.NewTracerProvider(configurations.TodoServiceName, "")
 	if err != nil {
 		log.Fatal("JaegerTraceProvider failed to Initialize", err)
 	}
+	mongoMonitor := otelmongo.NewMonitor(otelmongo.WithTracerProvider(tracerProvider))
+
+	suiteBackends := backends
+	if configurations.TodoServiceStorage != "" {
+		suiteBackends = []string{configurations.TodoServiceStorage}
+	}
+	exitVal := 0
+	for _, backend := range suiteBackends {
+		log.Printf("running the suite against the %s backend", backend)
+		ctx, cancel := context.WithCancel(context.Background())
+		setUpSuite(ctx, configurations, mongoMonitor, backend)
+		if code := m.Run(); code != 0 {
+			exitVal = code
+		}
+		cancel()
+		if err := webhookRepository.Close(context.Background()); err != nil {
+			log.Print("failed to close the webhook repo: ", err)
+		}
+		if err := todoRepository.Close(context.Background()); err != nil {
+			log.Print("failed to close the todo repo: ", err)
+		}
+	}
+	os.Exit(exitVal)
+}
+
+func newRepositories(ctx context.Context, configurations *config.Configurations, mongoMonitor *event.CommandMonitor, backend string) (todoStore, infra.WebhookRepository) {
+	switch backend {
+	case "mongo":
+		mongoConfig := mongo.NewMongoConfig(configurations)
+		migrator, err := mongo.NewMigrator(ctx, mongoMonitor, mongoConfig)
+		if err != nil {
+			log.Fatal("Error Initializing Migrator", err)
+		}
+		defer migrator.Close(ctx)
+		if _, err := migrator.Run(ctx); err != nil {
+			log.Fatal("Error Migrating Todo Repo", err)
+		}
+		todoRepo, err := mongo.NewMongoRepo(ctx, mongoMonitor, mongoConfig)
+		if err != nil {
+			log.Fatal("Error Initializing Todo Repo")
+		}
+		webhookRepo, err := mongo.NewMongoWebhookRepo(ctx, mongoMonitor, mongoConfig)
+		if err != nil {
+			log.Fatal("Error Initializing Webhook Repo")
+		}
+		return todoRepo, webhookRepo
+	case "postgres":
+		todoRepo, err := postgres.NewPostgresRepo(ctx, otelpgx.NewTracer(), configurations.TodoServiceDBUrl)
+		if err != nil {
+			log.Fatal("Error Initializing Todo Repo", err)
+		}
+		if _, err := todoRepo.Migrator().Up(ctx); err != nil {
+			log.Fatal("Failed to migrate Todo Repo", err)
+		}
+		webhookRepo, err := postgres.NewPostgresWebhookRepo(ctx, otelpgx.NewTracer(), configurations.TodoServiceDBUrl)
+		if err != nil {
+			log.Fatal("Error Initializing Webhook Repo", err)
+		}
+		return todoRepo, webhookRepo
+	case "memory":
+		todoRepo := memory.NewMemoryRepo()
+		webhookRepo, err := memory.NewMemoryWebhookRepo(todoRepo)
+		if err != nil {
+			log.Fatal("Error Initializing Webhook Repo", err)
+		}
+		return todoRepo, webhookRepo
+	default:
+		log.Fatalf("unknown backend %q", backend)
+		return nil, nil
+	}
+}
+
+type todoStore interface {
+	infra.TodoRepository
+	infra.OutboxRepository
+	infra.UsageRepository
+	infra.ProjectRepository
+}
+
+// webhookReceiverAddresses lets deliveries reach the receivers the suite
+// listens with on loopback, which PublicAddresses keeps them off.
+func webhookReceiverAddresses(addr netip.Addr) bool {
+	return addr.IsLoopback() || webhooks.PublicAddresses(addr)
+}
 
+// setUpSuite points the suite variables at a fresh set of services on top of
+// backend. Its workers stop with ctx.
+func setUpSuite(ctx context.Context, configurations *config.Configurations, mongoMonitor *event.CommandMonitor, backend string) {
 	tracer := otel.Tracer(configurations.TodoServiceName)
 
-	mongoMonitor := otelmongo.NewMonitor(otelmongo.WithTracerProvider(tracerProvider))
-	todoRepo, err := mongo.NewMongoRepo(ctx, mongoMonitor, configurations.TodoServiceDBConnectionString)
+	todoRepo, webhookRepo := newRepositories(ctx, configurations, mongoMonitor, backend)
+	webhookRetryPolicy := webhooks.RetryPolicy{
+		MaxAttempts:          2,
+		InitialBackoff:       10 * time.Millisecond,
+		MaxBackoff:           10 * time.Millisecond,
+		DisableAfterFailures: 2,
+	}
+	// NOTE: the suite runs on a plan without limits, so the todos and API
+	// calls it piles up in the test database never get in its way.
+	quotaService, err := quotas.NewQuotaService(todoRepo, []domain.Plan{{Name: quotas.DefaultPlan}})
 	if err != nil {
-		log.Fatal("Error Initializing Todo Repo")
+		log.Fatal("Error Initializing QuotaService", err)
 	}
 
-	todoService, err := todos.NewTodoService(todoRepo, configurations)
+	projectService, err := projects.NewProjectService(todoRepo)
+	if err != nil {
+		log.Fatal("Error Initializing ProjectService")
+	}
+	usageRepo = todoRepo
+	todoRepository = todoRepo
+	webhookRepository = webhookRepo
+	webhookService, err := webhooks.NewWebhookService(webhookRepo, quotaService, webhooks.NewDeliveryClient(time.Second, webhookReceiverAddresses), webhookReceiverAddresses, tracer, webhookRetryPolicy)
+	if err != nil {
+		log.Fatal("Error Initializing WebhookService")
+	}
+
+	eventBus = events.NewBus()
+	eventBus.Subscribe(webhookService.HandleEvent)
+
+	streamHub := events.NewStreamHub(100)
+	eventBus.Subscribe(streamHub.HandleEvent)
+
+	collabHub, err := collab.NewHub(collab.NewInMemoryPubSub(), func(ctx context.Context, userId uuid.UUID, projectId string) (bool, error) {
+		return projectService.CanAccessProject(ctx, tracer, userId.String(), projectId)
+	})
+	if err != nil {
+		log.Fatal("Error Initializing Collaboration Hub")
+	}
+	eventBus.Subscribe(collabHub.HandleEvent)
+
+	outboxRelay, err := workers.NewOutboxRelay(todoRepo, []events.Publisher{eventBus}, tracer, 50*time.Millisecond, outboxMaxAttempts)
+	if err != nil {
+		log.Fatal("Error Initializing OutboxRelay")
+	}
+	go outboxRelay.Run(ctx)
+
+	webhookDeliveryWorker, err := workers.NewWebhookDeliveryWorker(webhookService, 20*time.Millisecond)
+	if err != nil {
+		log.Fatal("Error Initializing WebhookDeliveryWorker")
+	}
+	go webhookDeliveryWorker.Run(ctx)
+
+	todoService, err := todos.NewTodoService(todoRepo, todoRepo, quotaService, projectService, configurations)
 	if err != nil {
 		log.Fatal("Error Initializing TodoService")
 	}
-	userService := &StubUserService{
+	userService, err := user.NewRequestScopedUserService(&StubUserService{
 		client: &http.Client{},
 		url:    "",
+	})
+	if err != nil {
+		log.Fatal("Error Initializing UserService")
+	}
+	var idempotencyStore idempotency.Store
+	switch backend {
+	case "memory":
+		idempotencyStore = idempotency.NewMemoryStore(time.Hour, time.Minute)
+	case "postgres":
+		idempotencyStore, err = idempotency.NewPostgresStore(ctx, otelpgx.NewTracer(), configurations.TodoServiceDBUrl, time.Hour, time.Minute)
+	default:
+		idempotencyStore, err = idempotency.NewMongoStore(ctx, mongoMonitor, configurations.TodoServiceDBConnectionString, mongo.NewMongoConfig(configurations).Database(), time.Hour, time.Minute)
+	}
+	if err != nil {
+		log.Fatal("Error Initializing Idempotency Store", err)
 	}
 
-	todoHandler, err := handlers.NewTodoHandler(*todoService, userService, tracer)
+	newQuotaRouter = func(rateLimiter *ratelimit.Limiter, quotaService *quotas.QuotaService) http.Handler {
+		todoService, err := todos.NewTodoService(todoRepo, todoRepo, quotaService, projectService, configurations)
+		if err != nil {
+			log.Fatal("Error Initializing TodoService")
+		}
+		webhookService, err := webhooks.NewWebhookService(webhookRepo, quotaService, webhooks.NewDeliveryClient(time.Second, webhookReceiverAddresses), webhookReceiverAddresses, tracer, webhookRetryPolicy)
+		if err != nil {
+			log.Fatal("Error Initializing WebhookService")
+		}
+		todoHandler, err := handlers.NewTodoHandler(*todoService, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the Todo handler: ", err)
+		}
+		webhookHandler, err := handlers.NewWebhookHandler(webhookService, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the Webhook handler: ", err)
+		}
+		projectHandler, err := handlers.NewProjectHandler(projectService, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the Project handler: ", err)
+		}
+		usageHandler, err := handlers.NewUsageHandler(quotaService, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the Usage handler: ", err)
+		}
+		todoEventsHandler, err := handlers.NewTodoEventsHandler(streamHub, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the TodoEvents handler: ", err)
+		}
+		collaborationHandler, err := handlers.NewCollaborationHandler(collabHub, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the Collaboration handler: ", err)
+		}
+		graphQLServer, err := graph.NewServer(todoService, projectService, streamHub, tracer, graph.DefaultLimits)
+		if err != nil {
+			log.Fatal("Error Initializing GraphQL Server", err)
+		}
+		graphQLHandler, err := handlers.NewGraphQLHandler(graphQLServer, userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the GraphQL handler: ", err)
+		}
+		countAPICalls := router.CountAPICalls(quotaService, userService, tracer)
+		return router.NewHttpRouter(*todoHandler, *todoEventsHandler, *collaborationHandler, *graphQLHandler, *webhookHandler, *projectHandler, *usageHandler, idempotencyStore, rateLimiter, countAPICalls, configurations)
+	}
+	newRouter = func(rateLimiter *ratelimit.Limiter) http.Handler {
+		return newQuotaRouter(rateLimiter, quotaService)
+	}
+	rateLimitedUsers = router.IdentifyUser(userService, tracer)
+	rateLimitPolicy, err := ratelimit.ParsePolicy(configurations.TodoServiceRateLimits)
+	if err != nil {
+		log.Fatal("Invalid rate limit policy: ", err)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rateLimitPolicy, rateLimitedUsers, false)
 	if err != nil {
-		log.Fatal("failed to create the Todo handler: ", err)
+		log.Fatal("failed to create the rate limiter: ", err)
 	}
-	appRouter := router.NewHttpRouter(*todoHandler, configurations)
-	svr = server.CreateNewServer(appRouter)
+	svr = server.CreateNewServer(newRouter(rateLimiter))
+
+	todoServer, err := rpc.NewTodoServer(*todoService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the Todo gRPC server: ", err)
+	}
+	grpcServer, err := rpc.NewGrpcServer(todoServer, userService, quotaService)
+	if err != nil {
+		log.Fatal("failed to create the gRPC server: ", err)
+	}
+	grpcConn, err := tests.NewBufconnClientConn(grpcServer)
+	if err != nil {
+		log.Fatal("failed to connect to the Todo gRPC server: ", err)
+	}
+	todoClient = todosv1.NewTodoServiceClient(grpcConn)
 
-	exitVal := m.Run()
-	os.Exit(exitVal)
 }
 
 func TestCreateTodo(t *testing.T) {
@@ -92,6 +347,18 @@ func TestCreateTodo(t *testing.T) {
 			tests.AssertResponseMessage(t, data["text"].(string), text)
 		},
 	)
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+    When they make a POST request to the create todo endpoint with a text of only whitespace
+    Then they should receive a 400 Bad Request response naming the missing text
+    `,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(`{"text": "   "}`))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+			tests.AssertResponseMessage(t, tests.ParseResponse(response)["code"].(string), "text_required")
+		},
+	)
 	t.Run(`Given an unauthenticated user or a user with an invalid JWT token
         When they make a POST request to the create todo endpoint
         Then they should receive a 401 Unauthorized response
@@ -248,4 +515,407 @@ func TestUpdateTodo(t *testing.T) {
 			tests.AssertResponseMessage(t, data["text"].(string), updatedText)
 		},
 	)
+
+	t.Run(` Given an authenticated user with valid credentials and a valid JWT token
+      When they update a todo that does not exist, or one another user created
+      Then they should receive a 404 Not Found and a 401 Unauthorized response
+      rather than a 500
+    `,
+		func(t *testing.T) {
+			createRequestBody := []byte(fmt.Sprintf(`{
+			"text": "some random text with an id :%d"
+			}`, tests.GenerateUniqueId()))
+			createReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(createRequestBody))
+			createReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			createData := tests.ParseResponse(tests.ExecuteRequest(createReq, svr))["data"].(map[string]interface{})
+			id := createData["id"].(string)
+
+			for expected, request := range map[int]struct{ id, token string }{
+				http.StatusNotFound:     {"caf3d5c8-0db7-4b27-a02e-e5a664684568", ValidTokenForUser1},
+				http.StatusUnauthorized: {id, ValidTokenForUser2},
+			} {
+				req, _ := http.NewRequest(http.MethodPatch, route+"/"+request.id, bytes.NewBufferString(`{"text": "updated text"}`))
+				req.Header.Set("Authorization", "Bearer "+request.token)
+				tests.AssertStatusCode(t, expected, tests.ExecuteRequest(req, svr).Code)
+			}
+		},
+	)
+}
+
+func TestSnoozeTodo(t *testing.T) {
+	route := "/todos"
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      And there exists a todo owned by the user
+      When they snooze the todo until a time in the future
+      Then they should receive a 200 OK response
+      And the todo should be hidden from the default todos listing
+      And the todo should be listed when include_snoozed is true
+    `,
+		func(t *testing.T) {
+			text := "some random text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			createRequestBody := []byte(fmt.Sprintf(`{
+			"text": "%s"
+			}`, text))
+			createReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(createRequestBody))
+			createReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			createResponse := tests.ExecuteRequest(createReq, svr)
+			createData := tests.ParseResponse(createResponse)["data"].(map[string]interface{})
+			id := createData["id"].(string)
+
+			until := time.Now().Add(time.Hour).Format(time.RFC3339)
+			snoozeRequestBody := []byte(fmt.Sprintf(`{
+			"until": "%s"
+			}`, until))
+			req, _ := http.NewRequest(http.MethodPost, route+"/"+id+"/snooze", bytes.NewBuffer(snoozeRequestBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+
+			if containsTodo(t, route, id) {
+				t.Errorf("snoozed todo %s should not be in the default listing", id)
+			}
+			if !containsTodo(t, route+"?include_snoozed=true", id) {
+				t.Errorf("snoozed todo %s should be listed when include_snoozed is true", id)
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they snooze a todo until a time in the past
+      Then they should receive a 400 Bad Request response
+    `,
+		func(t *testing.T) {
+			id := "caf3d5c8-0db7-4b27-a02e-e5a664684568"
+			until := time.Now().Add(-time.Hour).Format(time.RFC3339)
+			snoozeRequestBody := []byte(fmt.Sprintf(`{
+			"until": "%s"
+			}`, until))
+			req, _ := http.NewRequest(http.MethodPost, route+"/"+id+"/snooze", bytes.NewBuffer(snoozeRequestBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+		},
+	)
+}
+
+func containsTodo(t *testing.T, route, id string) bool {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, route, nil)
+	req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	data, _ := tests.ParseResponse(response)["data"].([]interface{})
+	for _, item := range data {
+		if item.(map[string]interface{})["id"].(string) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBulkTodos(t *testing.T) {
+	route := "/todos/bulk"
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they make a non-atomic bulk request where one operation fails
+      Then they should receive a 200 OK response
+      And every operation should report its own result in order
+    `,
+		func(t *testing.T) {
+			text := "bulk text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			requestBody := []byte(fmt.Sprintf(`{
+			"atomic": false,
+			"operations": [
+				{"op": "create", "text": "%s"},
+				{"op": "delete", "id": "caf3d5c8-0db7-4b27-a02e-e5a664684568"}
+			]
+			}`, text))
+			req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(requestBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+
+			results := tests.ParseResponse(response)["data"].([]interface{})
+			if len(results) != 2 {
+				t.Fatalf("expected 2 results, got %d", len(results))
+			}
+			created := results[0].(map[string]interface{})
+			tests.AssertResponseMessage(t, created["status"].(string), "ok")
+			tests.AssertResponseMessage(t, created["data"].(map[string]interface{})["text"].(string), text)
+			deleted := results[1].(map[string]interface{})
+			tests.AssertResponseMessage(t, deleted["status"].(string), "error")
+			tests.AssertResponseMessage(t, deleted["message"].(string), "record not found")
+		},
+	)
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they make an atomic bulk request where one operation fails
+      Then they should receive a 400 Bad Request response
+      And none of the operations should be applied
+    `,
+		func(t *testing.T) {
+			text := "bulk text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			requestBody := []byte(fmt.Sprintf(`{
+			"atomic": true,
+			"operations": [
+				{"op": "create", "text": "%s"},
+				{"op": "complete", "id": "caf3d5c8-0db7-4b27-a02e-e5a664684568"}
+			]
+			}`, text))
+			req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(requestBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+
+			listReq, _ := http.NewRequest(http.MethodGet, "/todos", nil)
+			listReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			listResponse := tests.ExecuteRequest(listReq, svr)
+			todosData := tests.ParseResponse(listResponse)["data"].([]interface{})
+			for _, item := range todosData {
+				if item.(map[string]interface{})["text"].(string) == text {
+					t.Errorf("todo created in a rolled back bulk request should not exist")
+				}
+			}
+		},
+	)
+}
+
+func TestPatchTodo(t *testing.T) {
+	route := "/todos"
+	createTodo := func(t *testing.T) string {
+		text := "some random text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+		createRequestBody := []byte(fmt.Sprintf(`{
+			"text": "%s"
+			}`, text))
+		createReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(createRequestBody))
+		createReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+		createResponse := tests.ExecuteRequest(createReq, svr)
+		createData := tests.ParseResponse(createResponse)["data"].(map[string]interface{})
+		return createData["id"].(string)
+	}
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      And there exists a todo owned by the user
+      When they PATCH the todo with an application/merge-patch+json document
+      Then they should receive a 200 OK response
+      And only the fields in the patch should change
+    `,
+		func(t *testing.T) {
+			id := createTodo(t)
+			completedAt := "2023-08-01T10:00:00Z"
+			patchBody := []byte(fmt.Sprintf(`{"completed_at": "%s"}`, completedAt))
+			req, _ := http.NewRequest(http.MethodPatch, route+"/"+id, bytes.NewBuffer(patchBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			data := tests.ParseResponse(response)["data"].(map[string]interface{})
+			tests.AssertResponseMessage(t, data["completed_at"].(string), completedAt)
+		},
+	)
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      And there exists a todo owned by the user
+      When they PATCH the todo with an application/json-patch+json document
+      Then they should receive a 200 OK response
+      And the operations should be applied to the todo
+    `,
+		func(t *testing.T) {
+			id := createTodo(t)
+			updatedText := "patched text with an id: " + fmt.Sprint(tests.GenerateUniqueId())
+			patchBody := []byte(fmt.Sprintf(`[{"op": "replace", "path": "/text", "value": "%s"}]`, updatedText))
+			req, _ := http.NewRequest(http.MethodPatch, route+"/"+id, bytes.NewBuffer(patchBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			req.Header.Set("Content-Type", "application/json-patch+json")
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			data := tests.ParseResponse(response)["data"].(map[string]interface{})
+			tests.AssertResponseMessage(t, data["text"].(string), updatedText)
+		},
+	)
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      And there exists a todo owned by the user
+      When they PATCH a read-only field of the todo
+      Then they should receive a 422 Unprocessable Entity response
+    `,
+		func(t *testing.T) {
+			id := createTodo(t)
+			patchBody := []byte(`{"created_at": "2020-01-01T00:00:00Z"}`)
+			req, _ := http.NewRequest(http.MethodPatch, route+"/"+id, bytes.NewBuffer(patchBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusUnprocessableEntity, response.Code)
+		},
+	)
+}
+
+func TestIdempotentCreateTodo(t *testing.T) {
+	route := "/todos"
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they retry a POST request to the create todo endpoint with the same Idempotency-Key and body
+      Then they should receive the original response
+      And only one todo should be created
+    `,
+		func(t *testing.T) {
+			key := "create-todo-" + fmt.Sprint(tests.GenerateUniqueId())
+			text := "some random text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+			requestBody := fmt.Sprintf(`{"text": "%s"}`, text)
+
+			firstReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(requestBody))
+			firstReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			firstReq.Header.Set(idempotency.HeaderKey, key)
+			firstResponse := tests.ExecuteRequest(firstReq, svr)
+			tests.AssertStatusCode(t, http.StatusOK, firstResponse.Code)
+			firstId := tests.ParseResponse(firstResponse)["data"].(map[string]interface{})["id"].(string)
+
+			retryReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(requestBody))
+			retryReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			retryReq.Header.Set(idempotency.HeaderKey, key)
+			retryResponse := tests.ExecuteRequest(retryReq, svr)
+			tests.AssertStatusCode(t, http.StatusOK, retryResponse.Code)
+			tests.AssertResponseMessage(t, retryResponse.Header().Get(idempotency.HeaderReplayed), "true")
+			retryId := tests.ParseResponse(retryResponse)["data"].(map[string]interface{})["id"].(string)
+			tests.AssertResponseMessage(t, retryId, firstId)
+		},
+	)
+
+	t.Run(`Given an authenticated user with valid credentials and a valid JWT token
+      When they reuse an Idempotency-Key with a different request body
+      Then they should receive a 422 Unprocessable Entity response
+    `,
+		func(t *testing.T) {
+			key := "create-todo-" + fmt.Sprint(tests.GenerateUniqueId())
+
+			firstReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(`{"text": "first"}`))
+			firstReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			firstReq.Header.Set(idempotency.HeaderKey, key)
+			firstResponse := tests.ExecuteRequest(firstReq, svr)
+			tests.AssertStatusCode(t, http.StatusOK, firstResponse.Code)
+
+			secondReq, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(`{"text": "second"}`))
+			secondReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			secondReq.Header.Set(idempotency.HeaderKey, key)
+			secondResponse := tests.ExecuteRequest(secondReq, svr)
+			tests.AssertStatusCode(t, http.StatusUnprocessableEntity, secondResponse.Code)
+		},
+	)
+}
+
+// cancellableStore fails every call made on a cancelled context, as the
+// stores backed by a database do.
+type cancellableStore struct {
+	*idempotency.MemoryStore
+}
+
+func (c cancellableStore) Complete(ctx context.Context, record idempotency.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.MemoryStore.Complete(ctx, record)
+}
+
+func (c cancellableStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.MemoryStore.Release(ctx, key)
+}
+
+func TestIdempotencyAfterClientDisconnect(t *testing.T) {
+	t.Run(`Given a POST request with an Idempotency-Key whose handler committed its write
+      When the client disconnects before the response is stored
+      Then its retry with the key should get the stored response back
+      And the handler should not run again
+    `,
+		func(t *testing.T) {
+			store := cancellableStore{idempotency.NewMemoryStore(time.Hour, time.Millisecond)}
+			key := "disconnect-" + fmt.Sprint(tests.GenerateUniqueId())
+			writes := 0
+			var disconnect context.CancelFunc
+			handler := idempotency.Middleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writes++
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte(`{"status":"ok"}`))
+				if disconnect != nil {
+					disconnect()
+				}
+			}))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			disconnect = cancel
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/todos", bytes.NewBufferString(`{"text": "once"}`))
+			req.Header.Set(idempotency.HeaderKey, key)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			disconnect = nil
+			time.Sleep(10 * time.Millisecond)
+			retryReq, _ := http.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(`{"text": "once"}`))
+			retryReq.Header.Set(idempotency.HeaderKey, key)
+			retryResponse := httptest.NewRecorder()
+			handler.ServeHTTP(retryResponse, retryReq)
+
+			tests.AssertStatusCode(t, http.StatusCreated, retryResponse.Code)
+			tests.AssertResponseMessage(t, retryResponse.Header().Get(idempotency.HeaderReplayed), "true")
+			if writes != 1 {
+				t.Errorf("expected the handler to run once, ran %d times", writes)
+			}
+		},
+	)
+}
+
+func TestIdempotencyReservationLease(t *testing.T) {
+	t.Run(`Given a request that reserved an Idempotency-Key and never completed
+      When another request with the key arrives within the lease
+      Then it should find the key in flight
+      And once the lease ran out, it should reserve the key itself
+    `,
+		func(t *testing.T) {
+			ctx := context.Background()
+			lease := 20 * time.Millisecond
+			store := idempotency.NewMemoryStore(time.Hour, lease)
+			key := "abandoned-" + fmt.Sprint(tests.GenerateUniqueId())
+
+			if _, reserved, err := store.Reserve(ctx, key, "first"); err != nil || !reserved {
+				t.Fatalf("expected the first request to reserve the key, got %v, %v", reserved, err)
+			}
+			if record, reserved, err := store.Reserve(ctx, key, "first"); err != nil || reserved || record.Completed {
+				t.Fatalf("expected the key to be in flight, got %v, %v, %v", record, reserved, err)
+			}
+
+			time.Sleep(2 * lease)
+			record, reserved, err := store.Reserve(ctx, key, "first")
+			if err != nil || !reserved {
+				t.Fatalf("expected the abandoned reservation to be taken over, got %v, %v", reserved, err)
+			}
+			tests.AssertResponseMessage(t, record.RequestHash, "first")
+		},
+	)
+
+	t.Run(`Given a request that completed with an Idempotency-Key
+      When the lease ran out
+      Then a retry with the key should still find its response
+    `,
+		func(t *testing.T) {
+			ctx := context.Background()
+			lease := 20 * time.Millisecond
+			store := idempotency.NewMemoryStore(time.Hour, lease)
+			key := "completed-" + fmt.Sprint(tests.GenerateUniqueId())
+
+			record, _, err := store.Reserve(ctx, key, "first")
+			if err != nil {
+				t.Fatal(err)
+			}
+			record.StatusCode = http.StatusOK
+			if err := store.Complete(ctx, record); err != nil {
+				t.Fatal(err)
+			}
+
+			time.Sleep(2 * lease)
+			record, reserved, err := store.Reserve(ctx, key, "first")
+			if err != nil || reserved || !record.Completed {
+				t.Fatalf("expected the completed record, got %v, %v, %v", record, reserved, err)
+			}
+		},
+	)
 }
@@ -0,0 +1,56 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+	"github.com/olad5/productive-pulse/todo-service/internal/events"
+)
+
+func TestStreamHubRedelivery(t *testing.T) {
+	t.Run(`Given a subscriber to the todo events of a user
+      When the relay delivers the same event twice
+      Then the subscriber should receive it once
+      And a client resuming before it should find it once in the backlog
+    `,
+		func(t *testing.T) {
+			ctx := context.Background()
+			hub := events.NewStreamHub(10)
+			userId := uuid.New()
+			first := domain.TodoEvent{ID: uuid.New(), Type: domain.TodoCreated, TodoId: uuid.New(), UserId: userId, OccurredAt: time.Now()}
+			redelivered := domain.TodoEvent{ID: uuid.New(), Type: domain.TodoUpdated, TodoId: first.TodoId, UserId: userId, OccurredAt: time.Now()}
+			if err := hub.HandleEvent(ctx, first); err != nil {
+				t.Fatal(err)
+			}
+			subscription, _, _ := hub.Subscribe(userId, "")
+			defer hub.Unsubscribe(subscription)
+
+			for i := 0; i < 2; i++ {
+				if err := hub.HandleEvent(ctx, redelivered); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if received := <-subscription.Events; received.ID != redelivered.ID {
+				t.Fatalf("expected event %s, got %s", redelivered.ID, received.ID)
+			}
+			select {
+			case received := <-subscription.Events:
+				t.Errorf("expected the redelivered event to be dropped, got %s again", received.ID)
+			default:
+			}
+
+			resumed, backlog, ok := hub.Subscribe(userId, first.ID.String())
+			defer hub.Unsubscribe(resumed)
+			if !ok || len(backlog) != 1 || backlog[0].ID != redelivered.ID {
+				t.Errorf("expected a backlog of event %s alone, got %v, %v", redelivered.ID, backlog, ok)
+			}
+		},
+	)
+}
@@ -2,9 +2,10 @@ package integration
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/olad5/productive-pulse/todo-service/internal/services/user"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -20,5 +21,8 @@ func (s *StubUserService) VerifyUser(ctx context.Context, tracer trace.Tracer, a
 	if authHeader == "Bearer "+ValidTokenForUser2 {
 		return "9a98dc85-fe0a-4cbb-8bb7-f67fceae7751", nil
 	}
-	return "", errors.New("error decoding jwt")
+	if authHeader == "Bearer "+TokenWhileUserServiceIsDown {
+		return "", user.ErrUserServiceUnavailable
+	}
+	return "", fmt.Errorf("%w: error decoding jwt", user.ErrTokenRejected)
 }
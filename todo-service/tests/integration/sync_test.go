@@ -0,0 +1,179 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/todo-service/internal/domain"
+)
+
+func syncAs(t *testing.T, token string, request map[string]interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(request)
+	req, _ := http.NewRequest(http.MethodPost, "/sync", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := tests.ExecuteRequest(req, svr)
+	parsed := tests.ParseResponse(response)
+	data, _ := parsed["data"].(map[string]interface{})
+	return response.Code, data
+}
+
+func syncedChange(data map[string]interface{}, todoId string) map[string]interface{} {
+	for _, change := range data["changes"].([]interface{}) {
+		change := change.(map[string]interface{})
+		if change["id"] == todoId {
+			return change
+		}
+	}
+	return nil
+}
+
+func deviceClock(wallTime time.Time) string {
+	return domain.HLC{WallTime: wallTime.UnixMilli(), NodeId: "device-a"}.String()
+}
+
+func TestSync(t *testing.T) {
+	t.Run("test for invalid sync token", func(t *testing.T) {
+		code, _ := syncAs(t, ValidTokenForUser1, map[string]interface{}{"sync_token": "not-a-token"})
+		tests.AssertStatusCode(t, http.StatusBadRequest, code)
+	})
+
+	t.Run(`Given a client with a todo created offline
+      When it syncs
+      Then the todo should be created with the client's id
+      And a later sync with the returned token should not return it again
+    `,
+		func(t *testing.T) {
+			_, initial := syncAs(t, ValidTokenForUser1, map[string]interface{}{})
+			todoId := uuid.NewString()
+
+			code, data := syncAs(t, ValidTokenForUser1, map[string]interface{}{
+				"sync_token": initial["sync_token"],
+				"changes": []map[string]interface{}{{
+					"id": todoId,
+					"fields": map[string]interface{}{
+						"text": map[string]interface{}{"value": "written offline", "hlc": deviceClock(time.Now())},
+					},
+				}},
+			})
+			tests.AssertStatusCode(t, http.StatusOK, code)
+			change := syncedChange(data, todoId)
+			if change == nil {
+				t.Fatal("expected the created todo among the synced changes")
+			}
+			if text := change["todo"].(map[string]interface{})["text"]; text != "written offline" {
+				t.Fatalf("expected synced text, got %v", text)
+			}
+
+			_, next := syncAs(t, ValidTokenForUser1, map[string]interface{}{"sync_token": data["sync_token"]})
+			if syncedChange(next, todoId) != nil {
+				t.Fatal("expected no changes after the latest sync token")
+			}
+		},
+	)
+
+	t.Run(`Given a todo edited on the server after an offline edit was made
+      When the older offline edit syncs
+      Then the server's text should win
+      And a newer offline edit should win over the server's
+    `,
+		func(t *testing.T) {
+			offlineEditAt := time.Now()
+			todoId := createTodoAs(t, ValidTokenForUser1)
+
+			updateReq, _ := http.NewRequest(http.MethodPatch, "/todos/"+todoId, bytes.NewBufferString(`{"text": "edited on server"}`))
+			updateReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(updateReq, svr).Code)
+
+			_, data := syncAs(t, ValidTokenForUser1, map[string]interface{}{
+				"changes": []map[string]interface{}{{
+					"id": todoId,
+					"fields": map[string]interface{}{
+						"text": map[string]interface{}{"value": "stale offline edit", "hlc": deviceClock(offlineEditAt.Add(-time.Second))},
+					},
+				}},
+			})
+			if text := syncedChange(data, todoId)["todo"].(map[string]interface{})["text"]; text != "edited on server" {
+				t.Fatalf("expected the server edit to win, got %v", text)
+			}
+
+			_, data = syncAs(t, ValidTokenForUser1, map[string]interface{}{
+				"changes": []map[string]interface{}{{
+					"id": todoId,
+					"fields": map[string]interface{}{
+						"text": map[string]interface{}{"value": "newer offline edit", "hlc": deviceClock(time.Now().Add(time.Second))},
+					},
+				}},
+			})
+			if text := syncedChange(data, todoId)["todo"].(map[string]interface{})["text"]; text != "newer offline edit" {
+				t.Fatalf("expected the newer offline edit to win, got %v", text)
+			}
+		},
+	)
+
+	t.Run(`Given a todo deleted through sync
+      When another client syncs
+      Then it should receive a tombstone
+      And a later edit to that todo should be rejected
+    `,
+		func(t *testing.T) {
+			todoId := createTodoAs(t, ValidTokenForUser1)
+
+			_, data := syncAs(t, ValidTokenForUser1, map[string]interface{}{
+				"changes": []map[string]interface{}{{
+					"id": todoId, "deleted": true, "deleted_at": deviceClock(time.Now()),
+				}},
+			})
+			change := syncedChange(data, todoId)
+			if change == nil || change["deleted"] != true {
+				t.Fatalf("expected a tombstone, got %v", change)
+			}
+
+			_, data = syncAs(t, ValidTokenForUser1, map[string]interface{}{
+				"changes": []map[string]interface{}{{
+					"id": todoId,
+					"fields": map[string]interface{}{
+						"text": map[string]interface{}{"value": "too late", "hlc": deviceClock(time.Now().Add(time.Second))},
+					},
+				}},
+			})
+			rejected := data["rejected"].([]interface{})
+			if len(rejected) != 1 || rejected[0].(map[string]interface{})["message"] != "todo has been deleted" {
+				t.Fatalf("expected the edit to be rejected, got %v", rejected)
+			}
+
+			getReq, _ := http.NewRequest(http.MethodGet, "/todos/"+todoId, nil)
+			getReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusNotFound, tests.ExecuteRequest(getReq, svr).Code)
+		},
+	)
+
+	t.Run(`Given a todo owned by another user
+      When a client syncs an edit to it
+      Then the edit should be rejected
+    `,
+		func(t *testing.T) {
+			todoId := createTodoAs(t, ValidTokenForUser2)
+
+			_, data := syncAs(t, ValidTokenForUser1, map[string]interface{}{
+				"changes": []map[string]interface{}{{
+					"id": todoId,
+					"fields": map[string]interface{}{
+						"text": map[string]interface{}{"value": "not mine", "hlc": deviceClock(time.Now())},
+					},
+				}},
+			})
+			if len(data["rejected"].([]interface{})) != 1 {
+				t.Fatalf("expected the edit to be rejected, got %v", data["rejected"])
+			}
+		},
+	)
+}
@@ -0,0 +1,162 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+)
+
+type streamedEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+func openTodoEventStream(t *testing.T, baseUrl, token, lastEventId string) (chan streamedEvent, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl+"/todos/events", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	tests.AssertStatusCode(t, http.StatusOK, res.StatusCode)
+	if contentType := res.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("expected text/event-stream content type, got %q", contentType)
+	}
+
+	received := make(chan streamedEvent, 16)
+	go func() {
+		defer res.Body.Close()
+		defer close(received)
+		scanner := bufio.NewScanner(res.Body)
+		var event streamedEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event.eventType != "" {
+					received <- event
+				}
+				event = streamedEvent{}
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.eventType = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return received, cancel
+}
+
+func awaitStreamedEvent(t *testing.T, received chan streamedEvent, match func(streamedEvent) bool) streamedEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, open := <-received:
+			if !open {
+				t.Fatal("event stream closed before the expected event arrived")
+			}
+			if match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for streamed event")
+		}
+	}
+}
+
+func TestTodoEvents(t *testing.T) {
+	testServer := httptest.NewServer(svr.Router)
+	defer testServer.Close()
+
+	t.Run("test for missing authorization header", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/todos/events", nil)
+		response := tests.ExecuteRequest(req, svr)
+		tests.AssertStatusCode(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run(`Given an authenticated user subscribed to the event stream
+      When they create a todo
+      Then a todo.created event for that todo should be streamed to them
+      And another user's stream should not receive it
+    `,
+		func(t *testing.T) {
+			received, cancel := openTodoEventStream(t, testServer.URL, ValidTokenForUser1, "")
+			defer cancel()
+			otherReceived, otherCancel := openTodoEventStream(t, testServer.URL, ValidTokenForUser2, "")
+			defer otherCancel()
+
+			todoId := createTodoAs(t, ValidTokenForUser1)
+
+			event := awaitStreamedEvent(t, received, func(event streamedEvent) bool {
+				return strings.Contains(event.data, todoId)
+			})
+			if event.eventType != "todo.created" {
+				t.Fatalf("expected todo.created, got %q", event.eventType)
+			}
+
+			select {
+			case event := <-otherReceived:
+				if strings.Contains(event.data, todoId) {
+					t.Fatal("another user received the todo event")
+				}
+			case <-time.After(300 * time.Millisecond):
+			}
+		},
+	)
+
+	t.Run(`Given a client that disconnected after receiving an event
+      When it reconnects with that event id as Last-Event-ID
+      Then the events it missed should be replayed
+    `,
+		func(t *testing.T) {
+			received, cancel := openTodoEventStream(t, testServer.URL, ValidTokenForUser1, "")
+			firstTodoId := createTodoAs(t, ValidTokenForUser1)
+			lastSeen := awaitStreamedEvent(t, received, func(event streamedEvent) bool {
+				return strings.Contains(event.data, firstTodoId)
+			})
+			cancel()
+
+			missedTodoId := createTodoAs(t, ValidTokenForUser1)
+			// NOTE: give the relay time to publish the missed event to the log.
+			time.Sleep(500 * time.Millisecond)
+
+			resumed, resumedCancel := openTodoEventStream(t, testServer.URL, ValidTokenForUser1, lastSeen.id)
+			defer resumedCancel()
+			awaitStreamedEvent(t, resumed, func(event streamedEvent) bool {
+				return strings.Contains(event.data, missedTodoId)
+			})
+		},
+	)
+
+	t.Run(`Given a client whose Last-Event-ID is no longer in the event log
+      When it reconnects
+      Then it should be told to reset its state
+    `,
+		func(t *testing.T) {
+			received, cancel := openTodoEventStream(t, testServer.URL, ValidTokenForUser1, "unknown-event-id")
+			defer cancel()
+			awaitStreamedEvent(t, received, func(event streamedEvent) bool {
+				return event.eventType == "reset"
+			})
+		},
+	)
+}
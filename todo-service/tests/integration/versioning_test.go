@@ -0,0 +1,104 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/pkg/versioning"
+)
+
+func TestVersioning(t *testing.T) {
+	t.Run(`Given a todo created through /v1
+      When it is listed through /v1
+      Then the response should not carry deprecation headers
+    `,
+		func(t *testing.T) {
+			createReq, _ := http.NewRequest(http.MethodPost, "/v1/todos", bytes.NewBufferString(`{"text": "versioned todo"}`))
+			createReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(createReq, svr).Code)
+
+			req, _ := http.NewRequest(http.MethodGet, "/v1/todos", nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			for _, header := range []string{versioning.DeprecationHeader, versioning.SunsetHeader} {
+				if value := response.Header().Get(header); value != "" {
+					t.Errorf("Expected no %s header on /v1. Got %q", header, value)
+				}
+			}
+		},
+	)
+
+	t.Run(`Given the unversioned alias of a /v1 route
+      When it is called
+      Then it should answer like /v1 with Deprecation, Sunset and successor Link headers
+    `,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/todos", nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			if response.Header().Get(versioning.DeprecationHeader) == "" {
+				t.Error("Expected a Deprecation header on the unversioned route")
+			}
+			if response.Header().Get(versioning.SunsetHeader) == "" {
+				t.Error("Expected a Sunset header on the unversioned route")
+			}
+			expectedLink := `</v1/todos>; rel="successor-version"`
+			if link := response.Header().Get(versioning.LinkHeader); link != expectedLink {
+				t.Errorf("Expected Link header %q. Got %q", expectedLink, link)
+			}
+		},
+	)
+
+	t.Run(`Given a todo created through /v2
+      When it is fetched through /v2
+      Then it should report completion as a boolean and leave unset times out
+    `,
+		func(t *testing.T) {
+			createReq, _ := http.NewRequest(http.MethodPost, "/v2/todos", bytes.NewBufferString(`{"text": "typed todo"}`))
+			createReq.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			createResponse := tests.ExecuteRequest(createReq, svr)
+			tests.AssertStatusCode(t, http.StatusOK, createResponse.Code)
+			created := tests.ParseResponse(createResponse)["data"].(map[string]interface{})
+
+			req, _ := http.NewRequest(http.MethodGet, "/v2/todos/"+created["id"].(string), nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			todo := tests.ParseResponse(response)["data"].(map[string]interface{})
+			if todo["completed"] != false {
+				t.Errorf("Expected completed to be false. Got %v", todo["completed"])
+			}
+			for _, field := range []string{"completed_at", "snoozed_until"} {
+				if _, ok := todo[field]; ok {
+					t.Errorf("Expected %s to be left out. Got %v", field, todo[field])
+				}
+			}
+		},
+	)
+
+	t.Run(`Given a user
+      When their todos are listed through /v2
+      Then the data should always be an array
+    `,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/v2/todos", nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser2)
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			if _, ok := tests.ParseResponse(response)["data"].([]interface{}); !ok {
+				t.Errorf("Expected data to be an array. Got %s", response.Body.String())
+			}
+		},
+	)
+}
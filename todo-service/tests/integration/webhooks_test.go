@@ -0,0 +1,200 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/todo-service/internal/usecases/webhooks"
+)
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, statusCode int) (*httptest.Server, chan receivedWebhook) {
+	t.Helper()
+	received := make(chan receivedWebhook, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver, received
+}
+
+func createWebhook(t *testing.T, url, secret, token string) string {
+	t.Helper()
+	requestBody := []byte(fmt.Sprintf(`{
+			"url": "%s",
+			"secret": "%s",
+			"event_types": ["todo.created"]
+			}`, url, secret))
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBuffer(requestBody))
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	data := tests.ParseResponse(response)["data"].(map[string]interface{})
+	return data["id"].(string)
+}
+
+func createTodoAs(t *testing.T, token string) string {
+	t.Helper()
+	text := "some random text with an id :" + fmt.Sprint(tests.GenerateUniqueId())
+	req, _ := http.NewRequest(http.MethodPost, "/todos", bytes.NewBufferString(fmt.Sprintf(`{"text": "%s"}`, text)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	return tests.ParseResponse(response)["data"].(map[string]interface{})["id"].(string)
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Run(`Given an authenticated user with a webhook subscribed to todo.created
+      When they create a todo
+      Then the receiver should get a signed todo.created delivery for that todo
+      And the delivery should be logged and replayable
+    `,
+		func(t *testing.T) {
+			secret := "test-secret"
+			receiver, received := newWebhookReceiver(t, http.StatusOK)
+			webhookId := createWebhook(t, receiver.URL, secret, ValidTokenForUser2)
+			todoId := createTodoAs(t, ValidTokenForUser2)
+
+			var delivery receivedWebhook
+			select {
+			case delivery = <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("webhook was not delivered")
+			}
+
+			tests.AssertResponseMessage(t, delivery.header.Get(webhooks.HeaderEvent), "todo.created")
+			expectedSignature := webhooks.Sign(secret, delivery.header.Get(webhooks.HeaderTimestamp), delivery.body)
+			tests.AssertResponseMessage(t, delivery.header.Get(webhooks.HeaderSignature), expectedSignature)
+			var payload map[string]interface{}
+			if err := json.Unmarshal(delivery.body, &payload); err != nil {
+				t.Fatalf("webhook payload is not json: %v", err)
+			}
+			tests.AssertResponseMessage(t, payload["data"].(map[string]interface{})["id"].(string), todoId)
+
+			var deliveryId string
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+				req, _ := http.NewRequest(http.MethodGet, "/webhooks/"+webhookId+"/deliveries", nil)
+				req.Header.Set("Authorization", "Bearer "+ValidTokenForUser2)
+				response := tests.ExecuteRequest(req, svr)
+				deliveries := tests.ParseResponse(response)["data"].([]interface{})
+				if len(deliveries) > 0 && deliveries[0].(map[string]interface{})["succeeded"].(bool) {
+					deliveryId = deliveries[0].(map[string]interface{})["id"].(string)
+					break
+				}
+			}
+			if deliveryId == "" {
+				t.Fatal("successful delivery was not logged")
+			}
+
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks/"+webhookId+"/deliveries/"+deliveryId+"/replay", nil)
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser2)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			select {
+			case replayed := <-received:
+				tests.AssertResponseMessage(t, string(replayed.body), string(delivery.body))
+			case <-time.After(5 * time.Second):
+				t.Fatal("webhook replay was not delivered")
+			}
+		},
+	)
+
+	t.Run(`Given an authenticated user with a webhook whose receiver keeps failing
+      When enough deliveries to it fail
+      Then the webhook should be disabled
+    `,
+		func(t *testing.T) {
+			receiver, _ := newWebhookReceiver(t, http.StatusInternalServerError)
+			webhookId := createWebhook(t, receiver.URL, "test-secret", ValidTokenForUser2)
+			createTodoAs(t, ValidTokenForUser2)
+			createTodoAs(t, ValidTokenForUser2)
+
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+				req, _ := http.NewRequest(http.MethodGet, "/webhooks", nil)
+				req.Header.Set("Authorization", "Bearer "+ValidTokenForUser2)
+				response := tests.ExecuteRequest(req, svr)
+				for _, item := range tests.ParseResponse(response)["data"].([]interface{}) {
+					webhook := item.(map[string]interface{})
+					if webhook["id"].(string) == webhookId && webhook["disabled"].(bool) {
+						return
+					}
+				}
+			}
+			t.Error("webhook should be disabled after repeated failed deliveries")
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When they create a webhook with an unknown event type
+      Then they should receive a 400 Bad Request response
+    `,
+		func(t *testing.T) {
+			requestBody := []byte(`{"url": "http://localhost:1234", "event_types": ["todo.exploded"]}`)
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBuffer(requestBody))
+			req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+		},
+	)
+
+	t.Run(`Given an authenticated user
+      When they create a webhook for a private, link-local or unspecified address
+      Then they should receive a 400 Bad Request response naming the url as not allowed
+    `,
+		func(t *testing.T) {
+			for _, url := range []string{
+				"http://10.0.0.1/hooks",
+				"http://192.168.1.10:8080/hooks",
+				"http://169.254.169.254/latest/meta-data",
+				"http://[fe80::1]/hooks",
+				"http://0.0.0.0:4000/hooks",
+			} {
+				requestBody := []byte(fmt.Sprintf(`{"url": "%s", "event_types": ["todo.created"]}`, url))
+				req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBuffer(requestBody))
+				req.Header.Set("Authorization", "Bearer "+ValidTokenForUser1)
+				response := tests.ExecuteRequest(req, svr)
+				tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+				if code := tests.ParseResponse(response)["code"]; code != "webhook_url_not_allowed" {
+					t.Errorf("%s: expected code webhook_url_not_allowed, got %v", url, code)
+				}
+			}
+		},
+	)
+
+	t.Run(`Given a delivery client that only lets public addresses through
+      When it sends a request to a receiver listening on loopback
+      Then the request should fail without reaching the receiver
+    `,
+		func(t *testing.T) {
+			receiver, received := newWebhookReceiver(t, http.StatusOK)
+			client := webhooks.NewDeliveryClient(time.Second, webhooks.PublicAddresses)
+
+			response, err := client.Post(receiver.URL, "application/json", bytes.NewBufferString(`{}`))
+			if err == nil {
+				response.Body.Close()
+				t.Fatal("expected the request to loopback to fail")
+			}
+			select {
+			case <-received:
+				t.Error("receiver should not have been reached")
+			default:
+			}
+		},
+	)
+}
@@ -0,0 +1,15 @@
+// Package api holds the OpenAPI description of the users-service HTTP API.
+package api
+
+import (
+	_ "embed"
+
+	"github.com/olad5/productive-pulse/pkg/openapi"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Spec is hand-maintained alongside the router; integration tests run with
+// response validation on so that it cannot drift from the handlers.
+var Spec = openapi.MustLoad(document)
@@ -2,34 +2,93 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/exaring/otelpgx"
 	"github.com/olad5/productive-pulse/config"
 	"github.com/olad5/productive-pulse/pkg/app/server"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
 	"github.com/olad5/productive-pulse/pkg/utils"
 	"github.com/olad5/productive-pulse/users-service/internal/app/router"
+	"github.com/olad5/productive-pulse/users-service/internal/app/rpc"
 	"github.com/olad5/productive-pulse/users-service/internal/handlers"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+	"github.com/olad5/productive-pulse/users-service/internal/infra/memory"
 	"github.com/olad5/productive-pulse/users-service/internal/infra/postgres"
+	"github.com/olad5/productive-pulse/users-service/internal/infra/sqlite"
 	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
 )
 
+const (
+	idempotencyKeyTTL   = 24 * time.Hour
+	idempotencyKeyLease = time.Minute
+)
+
+// repository is what every storage backend keeps: users and their refresh
+// tokens.
+type repository interface {
+	infra.UserRepository
+	infra.RefreshTokenRepository
+}
+
+func newUserRepository(ctx context.Context, configurations *config.Configurations) (repository, error) {
+	switch configurations.UserServiceStorage {
+	case "", "postgres":
+		return postgres.NewPostgresRepo(ctx, otelpgx.NewTracer(), configurations.UserServiceDBUrl)
+	case "sqlite":
+		return sqlite.NewSQLiteRepo(ctx, configurations.UserServiceDBUrl)
+	case "memory":
+		return memory.NewMemoryRepo(), nil
+	default:
+		return nil, fmt.Errorf("unknown user service storage %q", configurations.UserServiceStorage)
+	}
+}
+
+func newIdempotencyStore(ctx context.Context, configurations *config.Configurations) (idempotency.Store, error) {
+	switch configurations.UserServiceStorage {
+	case "", "postgres":
+		return idempotency.NewPostgresStore(ctx, otelpgx.NewTracer(), configurations.UserServiceDBUrl, idempotencyKeyTTL, idempotencyKeyLease)
+	case "sqlite", "memory":
+		// NOTE: a SQLite database serves a single node, whose memory is
+		// enough to recognise retries; they are forgotten on restart.
+		return idempotency.NewMemoryStore(idempotencyKeyTTL, idempotencyKeyLease), nil
+	default:
+		return nil, fmt.Errorf("unknown user service storage %q", configurations.UserServiceStorage)
+	}
+}
+
 func main() {
 	configurations := config.GetConfig(".env")
+	flag.StringVar(&configurations.UserServiceStorage, "storage", configurations.UserServiceStorage,
+		`where to keep users: "postgres", "sqlite" or "memory", which needs no database`)
+	migrateOnStart := flag.Bool("migrate", true,
+		"apply pending schema migrations at startup; without it, run the migrate subcommand before deploying")
+	flag.Parse()
 	ctx := context.Background()
 
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(ctx, configurations, flag.Args()[1:]); err != nil {
+			log.Fatal("Failed to migrate: ", err)
+		}
+		return
+	}
+
 	tracerProvider, err := utils.NewTracerProvider(configurations.UserServiceName, configurations.TracingCollectorEndpoint)
 	if err != nil {
 		log.Fatal("JaegerTraceProvider failed to Initialize", err)
 	}
 	tracer := tracerProvider.Tracer(configurations.UserServiceName)
-	postgresTracer := otelpgx.NewTracer()
 
 	port := configurations.UserServicePort
-	userRepo, err := postgres.NewPostgresRepo(ctx, postgresTracer, configurations.UserServiceDBUrl)
+	userRepo, err := newUserRepository(ctx, configurations)
 	if err != nil {
-		log.Fatal("Error Initializing User Repo")
+		log.Fatal("Error Initializing User Repo", err)
 	}
 
 	err = userRepo.Ping(ctx)
@@ -37,7 +96,23 @@ func main() {
 		log.Fatal("Failed to ping UserRepo", err)
 	}
 
-	userService, err := users.NewUserService(userRepo, configurations)
+	if repo, ok := userRepo.(migratable); ok && *migrateOnStart {
+		applied, err := repo.Migrator().Up(ctx)
+		logMigrations("applied", applied)
+		if err != nil {
+			log.Fatal("Failed to migrate: ", err)
+		}
+	}
+
+	keys, err := users.LoadKeySet(configurations)
+	if err != nil {
+		log.Fatal("Error Loading Signing Keys: ", err)
+	}
+
+	userService, err := users.NewUserService(userRepo, userRepo, keys, users.TokenLifetimes{
+		Access:  configurations.UserServiceAccessTokenLifetime,
+		Refresh: configurations.UserServiceRefreshTokenLifetime,
+	})
 	if err != nil {
 		log.Fatal("Error Initializing UserService")
 	}
@@ -47,10 +122,44 @@ func main() {
 		log.Fatal("failed to create the User handler: ", err)
 	}
 
-	appRouter := router.NewHttpRouter(*userHandler, configurations)
+	rateLimitPolicy, err := ratelimit.ParsePolicy(configurations.UserServiceRateLimits)
+	if err != nil {
+		log.Fatal("Invalid rate limit policy: ", err)
+	}
+	rateLimitStore, err := ratelimit.NewStore(configurations.RateLimitRedisAddress, configurations.UserServiceName)
+	if err != nil {
+		log.Fatal("Error Initializing Rate Limit Store", err)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(rateLimitStore, rateLimitPolicy, router.IdentifyUser(*userService, tracer), configurations.RateLimitTrustProxy)
+	if err != nil {
+		log.Fatal("failed to create the rate limiter: ", err)
+	}
+
+	idempotencyStore, err := newIdempotencyStore(ctx, configurations)
+	if err != nil {
+		log.Fatal("Error Initializing Idempotency Store", err)
+	}
+
+	appRouter := router.NewHttpRouter(*userHandler, idempotencyStore, rateLimiter, configurations)
 
 	svr := server.CreateNewServer(appRouter)
 
+	if grpcPort := configurations.UserServiceGrpcPort; grpcPort != "" {
+		userServer, err := rpc.NewUserServer(*userService, tracer)
+		if err != nil {
+			log.Fatal("failed to create the User gRPC server: ", err)
+		}
+		listener, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Fatal("failed to listen for gRPC: ", err)
+		}
+		grpcServer := rpc.NewGrpcServer(userServer)
+		go func() {
+			log.Printf("gRPC Server Listening on port %s", grpcPort)
+			log.Fatal(grpcServer.Serve(listener))
+		}()
+	}
+
 	log.Printf("Server Listening on port %s", port)
 	log.Fatal(http.ListenAndServe(":"+port, svr.Router))
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/olad5/productive-pulse/config"
+	"github.com/olad5/productive-pulse/pkg/migrations"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+const migrateUsage = `usage: users-service migrate [up | down [steps] | version]`
+
+// migratable is a repository with a schema to migrate.
+type migratable interface {
+	infra.UserRepository
+	Migrator() *migrations.Migrator
+}
+
+func migrator(ctx context.Context, configurations *config.Configurations) (*migrations.Migrator, error) {
+	userRepo, err := newUserRepository(ctx, configurations)
+	if err != nil {
+		return nil, err
+	}
+	repo, ok := userRepo.(migratable)
+	if !ok {
+		return nil, fmt.Errorf("%q storage has no schema to migrate", configurations.UserServiceStorage)
+	}
+	return repo.Migrator(), nil
+}
+
+// runMigrate runs the migrate subcommand, with the arguments that follow it.
+func runMigrate(ctx context.Context, configurations *config.Configurations, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	if len(args) > 2 || (len(args) == 2 && command != "down") {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := migrator(ctx, configurations)
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		logMigrations("applied", applied)
+		if err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive number: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		logMigrations("reverted", reverted)
+		if err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("schema is at version %d", version)
+	return nil
+}
+
+func logMigrations(verb string, applied []migrations.Migration) {
+	for _, migration := range applied {
+		log.Printf("%s migration %d_%s", verb, migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdentifyUser identifies the callers of the rate limited routes by the user
+// id of their access token.
+func IdentifyUser(userService users.UserService, tracer trace.Tracer) ratelimit.IdentifyUser {
+	return func(r *http.Request) string {
+		userId, err := userService.VerifyUser(r.Context(), tracer, r.Header.Get("Authorization"))
+		if err != nil {
+			return ""
+		}
+		return userId
+	}
+}
@@ -8,19 +8,45 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/olad5/productive-pulse/config"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	"github.com/olad5/productive-pulse/pkg/openapi"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	"github.com/olad5/productive-pulse/pkg/versioning"
+	"github.com/olad5/productive-pulse/users-service/api"
 	"github.com/olad5/productive-pulse/users-service/internal/handlers"
 )
 
-func NewHttpRouter(userHandler handlers.UserHandler, configurations *config.Configurations) http.Handler {
+func NewHttpRouter(userHandler handlers.UserHandler, idempotencyStore idempotency.Store, rateLimiter *ratelimit.Limiter, configurations *config.Configurations) http.Handler {
 	router := chi.NewRouter()
 	router.Use(
 		middleware.AllowContentType("application/json"),
 		middleware.SetHeader("Content-Type", "application/json"),
 	)
 	router.Use(otelchi.Middleware(configurations.UserServiceName, otelchi.WithChiRoutes(router)))
+	if configurations.OpenAPIValidateResponses {
+		router.Use(openapi.ResponseValidator(api.Spec))
+	}
+	router.Use(rateLimiter.Middleware(router))
+	router.Use(openapi.RequestValidator(api.Spec))
 
+	router.Get("/openapi.json", openapi.Handler(api.Spec))
+	router.Get("/.well-known/jwks.json", userHandler.JWKS)
+	router.Route("/v1", func(router chi.Router) {
+		registerV1Routes(router, userHandler, idempotencyStore)
+	})
+	router.Group(func(router chi.Router) {
+		router.Use(versioning.Deprecated(versioning.Unversioned))
+		registerV1Routes(router, userHandler, idempotencyStore)
+	})
+	return router
+}
+
+func registerV1Routes(router chi.Router, userHandler handlers.UserHandler, idempotencyStore idempotency.Store) {
 	router.Get("/users/auth", userHandler.Auth)
+	// NOTE: login and refresh are left out of idempotency keys: a replay
+	// would hand out a rotated token pair again without reuse detection,
+	// and the stored responses would keep raw tokens.
 	router.Post("/users/login", userHandler.Login)
-	router.Post("/users", userHandler.Register)
-	return router
+	router.Post("/users/token/refresh", userHandler.RefreshToken)
+	router.With(idempotency.Middleware(idempotencyStore)).Post("/users", userHandler.Register)
 }
@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	usersv1 "github.com/olad5/productive-pulse/pkg/proto/users/v1"
+	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const bearer = "Bearer "
+
+// UserServer serves users.v1.UserService on top of the same UserService
+// used by the HTTP handlers.
+type UserServer struct {
+	usersv1.UnimplementedUserServiceServer
+	service users.UserService
+	tracer  trace.Tracer
+}
+
+func NewUserServer(service users.UserService, tracer trace.Tracer) (*UserServer, error) {
+	if service == (users.UserService{}) {
+		return nil, errors.New("user service cannot be empty")
+	}
+	if tracer == nil {
+		return nil, errors.New("tracer cannot be empty")
+	}
+	return &UserServer{service: service, tracer: tracer}, nil
+}
+
+// NewGrpcServer returns a gRPC server with the UserServer registered and otel
+// interceptors installed, so spans continue the trace of the calling service.
+func NewGrpcServer(userServer *UserServer) *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+	usersv1.RegisterUserServiceServer(grpcServer, userServer)
+	return grpcServer
+}
+
+func (u *UserServer) Register(ctx context.Context, request *usersv1.RegisterRequest) (*usersv1.RegisterResponse, error) {
+	ctx, span := u.tracer.Start(ctx, "Register-rpc")
+	defer span.End()
+
+	if request.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email required")
+	}
+	if request.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "password required")
+	}
+	if request.GetFirstName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "first_name required")
+	}
+	if request.GetLastName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "last_name required")
+	}
+
+	newUser, err := u.service.CreateUser(ctx, u.tracer, request.GetFirstName(), request.GetLastName(), request.GetEmail(), request.GetPassword())
+	if errors.Is(err, users.ErrUserAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, appErrors.ErrSomethingWentWrong.Message)
+	}
+
+	return &usersv1.RegisterResponse{
+		User: &usersv1.User{
+			Id:        newUser.ID.String(),
+			Email:     newUser.Email,
+			FirstName: newUser.FirstName,
+			LastName:  newUser.LastName,
+		},
+	}, nil
+}
+
+func (u *UserServer) Login(ctx context.Context, request *usersv1.LoginRequest) (*usersv1.LoginResponse, error) {
+	ctx, span := u.tracer.Start(ctx, "Login-rpc")
+	defer span.End()
+
+	if request.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email required")
+	}
+	if request.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "password required")
+	}
+
+	tokens, err := u.service.LogUserIn(ctx, u.tracer, request.GetEmail(), request.GetPassword())
+	if errors.Is(err, users.ErrPasswordIncorrect) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, appErrors.ErrSomethingWentWrong.Message)
+	}
+
+	return &usersv1.LoginResponse{AccessToken: tokens.AccessToken}, nil
+}
+
+func (u *UserServer) VerifyToken(ctx context.Context, request *usersv1.VerifyTokenRequest) (*usersv1.VerifyTokenResponse, error) {
+	ctx, span := u.tracer.Start(ctx, "VerifyToken-rpc")
+	defer span.End()
+
+	if request.GetAccessToken() == "" {
+		return nil, status.Error(codes.Unauthenticated, appErrors.ErrUnauthorized.Message)
+	}
+
+	userId, err := u.service.VerifyUser(ctx, u.tracer, bearer+request.GetAccessToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, appErrors.ErrUnauthorized.Message)
+	}
+
+	return &usersv1.VerifyTokenResponse{UserId: userId}, nil
+}
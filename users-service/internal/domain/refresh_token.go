@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken trades, once, for a new access token and the refresh token
+// that succeeds it. Only the hash of the token is stored.
+type RefreshToken struct {
+	ID     uuid.UUID
+	UserId uuid.UUID
+	// FamilyId is shared by every token rotated from the same login.
+	FamilyId  uuid.UUID
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// UsedAt is set once the token was traded for its successor.
+	UsedAt *time.Time
+	// RevokedAt is set once the family of the token was revoked.
+	RevokedAt *time.Time
+}
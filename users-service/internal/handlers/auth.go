@@ -16,7 +16,7 @@ func (u UserHandler) Auth(w http.ResponseWriter, r *http.Request) {
 
 	userId, err := u.service.VerifyUser(ctx, u.tracer, authHeader)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrUnauthorized, http.StatusUnauthorized)
+		response.Error(w, r, appErrors.ErrUnauthorized)
 		return
 	}
 
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// jwksMaxAge is how long clients may cache the keys. Those that meet a kid
+// they have not cached, that of a key just rotated in, read them again.
+const jwksMaxAge = "max-age=300"
+
+// JWKS serves the public keys access tokens are verified with, as a JSON
+// Web Key Set.
+func (u UserHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	_, span := u.tracer.Start(r.Context(), "jwks-handler")
+	defer span.End()
+
+	w.Header().Set("Cache-Control", jwksMaxAge)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(u.service.JWKS()); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 	response "github.com/olad5/productive-pulse/pkg/utils"
-	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
 )
 
 func (u UserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -14,7 +13,7 @@ func (u UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	ctx, span := u.tracer.Start(ctx, "login-handler")
 	defer span.End()
 	if r.Body == nil {
-		response.ErrorResponse(w, appErrors.ErrMissingBody, http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrMissingBody)
 		return
 	}
 	type requestDTO struct {
@@ -24,34 +23,19 @@ func (u UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var request requestDTO
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrInvalidJson, http.StatusBadRequest)
-		return
-	}
-	if request.Email == "" {
-		response.ErrorResponse(w, "email required", http.StatusBadRequest)
-		return
-	}
-	if request.Password == "" {
-		response.ErrorResponse(w, "password required", http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrInvalidJson)
 		return
 	}
 
-	accessToken, err := u.service.LogUserIn(ctx, u.tracer, request.Email, request.Password)
-	if err != nil && err.Error() == users.ErrUserNotFound {
-		response.ErrorResponse(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	if err != nil && err.Error() == users.ErrPasswordIncorrect {
-		response.ErrorResponse(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	tokens, err := u.service.LogUserIn(ctx, u.tracer, request.Email, request.Password)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
 	response.SuccessResponse(w, "user logged in successfully",
 		map[string]interface{}{
-			"access_token": accessToken,
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
 		})
 }
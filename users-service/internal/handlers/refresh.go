@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	response "github.com/olad5/productive-pulse/pkg/utils"
+)
+
+func (u UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := u.tracer.Start(ctx, "refresh-token-handler")
+	defer span.End()
+	if r.Body == nil {
+		response.Error(w, r, appErrors.ErrMissingBody)
+		return
+	}
+	type requestDTO struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	var request requestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		response.Error(w, r, appErrors.ErrInvalidJson)
+		return
+	}
+
+	tokens, err := u.service.RefreshTokens(ctx, u.tracer, request.RefreshToken)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.SuccessResponse(w, "tokens refreshed successfully",
+		map[string]interface{}{
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+		})
+}
@@ -7,7 +7,6 @@ import (
 	appErrors "github.com/olad5/productive-pulse/pkg/errors"
 
 	response "github.com/olad5/productive-pulse/pkg/utils"
-	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
 )
 
 func (u UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -15,7 +14,7 @@ func (u UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	ctx, span := u.tracer.Start(ctx, "Register-handler")
 	defer span.End()
 	if r.Body == nil {
-		response.ErrorResponse(w, appErrors.ErrMissingBody, http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrMissingBody)
 		return
 	}
 	type requestDTO struct {
@@ -27,34 +26,12 @@ func (u UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var request requestDTO
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrInvalidJson, http.StatusBadRequest)
+		response.Error(w, r, appErrors.ErrInvalidJson)
 		return
 	}
-	if request.Email == "" {
-		response.ErrorResponse(w, "email required", http.StatusBadRequest)
-		return
-	}
-	if request.Password == "" {
-		response.ErrorResponse(w, "password required", http.StatusBadRequest)
-		return
-	}
-
-	if request.FirstName == "" {
-		response.ErrorResponse(w, "first_name required", http.StatusBadRequest)
-		return
-	}
-	if request.LastName == "" {
-		response.ErrorResponse(w, "last_name required", http.StatusBadRequest)
-		return
-	}
-
 	newUser, err := u.service.CreateUser(ctx, u.tracer, request.FirstName, request.LastName, request.Email, request.Password)
-	if err != nil && err.Error() == users.ErrUserAlreadyExists {
-		response.ErrorResponse(w, err.Error(), http.StatusBadRequest)
-		return
-	}
 	if err != nil {
-		response.ErrorResponse(w, appErrors.ErrSomethingWentWrong, http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
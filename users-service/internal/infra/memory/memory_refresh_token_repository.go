@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+func (m *MemoryRepository) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createRefreshToken(token)
+}
+
+func (m *MemoryRepository) GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	token, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return domain.RefreshToken{}, infra.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+func (m *MemoryRepository) RotateRefreshToken(ctx context.Context, usedId uuid.UUID, usedAt time.Time, next domain.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, token := range m.refreshTokens {
+		if token.ID != usedId {
+			continue
+		}
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			return infra.ErrRefreshTokenUsed
+		}
+		if err := m.createRefreshToken(next); err != nil {
+			return err
+		}
+		token.UsedAt = &usedAt
+		m.refreshTokens[hash] = token
+		return nil
+	}
+	return infra.ErrRefreshTokenUsed
+}
+
+func (m *MemoryRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, token := range m.refreshTokens {
+		if token.FamilyId == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			m.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) createRefreshToken(token domain.RefreshToken) error {
+	if _, ok := m.refreshTokens[token.TokenHash]; ok {
+		return errors.New("refresh token hash already exists")
+	}
+	for _, existing := range m.refreshTokens {
+		if existing.ID == token.ID {
+			return errors.New("refresh token id already exists")
+		}
+	}
+	m.refreshTokens[token.TokenHash] = token
+	return nil
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+// MemoryRepository keeps users in the memory of a single instance, for tests
+// and local development. Nothing survives a restart.
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	usersByEmail map[string]domain.User
+	// refreshTokens are keyed by their hash.
+	refreshTokens map[string]domain.RefreshToken
+}
+
+func NewMemoryRepo() *MemoryRepository {
+	return &MemoryRepository{
+		usersByEmail:  map[string]domain.User{},
+		refreshTokens: map[string]domain.RefreshToken{},
+	}
+}
+
+func (m *MemoryRepository) CreateUser(ctx context.Context, user domain.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.usersByEmail[user.Email]; ok {
+		return infra.ErrDuplicateEmail
+	}
+	for _, existing := range m.usersByEmail {
+		if existing.ID == user.ID {
+			return errors.New("user id already exists")
+		}
+	}
+	m.usersByEmail[user.Email] = user
+	return nil
+}
+
+func (m *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.usersByEmail[email]
+	if !ok {
+		return domain.User{}, infra.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
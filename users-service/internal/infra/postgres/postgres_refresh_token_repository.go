@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+// NOTE: refresh_tokens keeps times without a time zone; they are written,
+// and read back, in UTC.
+
+const insertRefreshToken = "INSERT INTO refresh_tokens(id, user_id, family_id, token_hash, created_at, expires_at) values($1, $2, $3, $4, $5, $6)"
+
+func (p *PostgresRepository) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	_, err := p.connection.Exec(ctx, insertRefreshToken,
+		token.ID, token.UserId, token.FamilyId, token.TokenHash, token.CreatedAt.UTC(), token.ExpiresAt.UTC())
+	return err
+}
+
+func (p *PostgresRepository) GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	row := p.connection.QueryRow(ctx, "SELECT id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash = $1", tokenHash)
+
+	var token domain.RefreshToken
+	if err := row.Scan(&token.ID, &token.UserId, &token.FamilyId, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.RefreshToken{}, infra.ErrRecordNotFound
+		}
+		return domain.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (p *PostgresRepository) RotateRefreshToken(ctx context.Context, usedId uuid.UUID, usedAt time.Time, next domain.RefreshToken) error {
+	return pgx.BeginFunc(ctx, p.connection, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "UPDATE refresh_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL", usedId, usedAt.UTC())
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return infra.ErrRefreshTokenUsed
+		}
+		_, err = tx.Exec(ctx, insertRefreshToken,
+			next.ID, next.UserId, next.FamilyId, next.TokenHash, next.CreatedAt.UTC(), next.ExpiresAt.UTC())
+		return err
+	})
+}
+
+func (p *PostgresRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error {
+	_, err := p.connection.Exec(ctx, "UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL", familyId, revokedAt.UTC())
+	return err
+}
@@ -2,19 +2,23 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/olad5/productive-pulse/pkg/migrations"
 	"github.com/olad5/productive-pulse/users-service/internal/domain"
 	"github.com/olad5/productive-pulse/users-service/internal/infra"
+	"github.com/olad5/productive-pulse/users-service/internal/infra/schema"
 )
 
 type PostgresRepository struct {
 	connection *pgxpool.Pool
+	migrator   *migrations.Migrator
 }
 
 func NewPostgresRepo(ctx context.Context, tracer pgx.QueryTracer, DatabaseUrl string) (*PostgresRepository, error) {
@@ -23,48 +27,44 @@ func NewPostgresRepo(ctx context.Context, tracer pgx.QueryTracer, DatabaseUrl st
 	}
 
 	dbConfig, err := pgxpool.ParseConfig(DatabaseUrl)
+	if err != nil {
+		return &PostgresRepository{}, fmt.Errorf("Failed to create PostgresRepository:  %w", err)
+	}
 	dbConfig.ConnConfig.Tracer = tracer
 	connectionPool, err := pgxpool.NewWithConfig(ctx, dbConfig)
 	if err != nil {
 		return &PostgresRepository{}, fmt.Errorf("Failed to create PostgresRepository:  %w", err)
 	}
 
-	return &PostgresRepository{connection: connectionPool}, nil
-}
-
-func (p *PostgresRepository) Migrate(ctx context.Context) error {
-	query := `
-  CREATE TABLE IF NOT EXISTS users(
-        id UUID PRIMARY KEY,
-        email TEXT NOT NULL UNIQUE,
-        first_name TEXT NOT NULL,
-        last_name TEXT NOT NULL,
-        password TEXT NOT NULL
-    );
-  `
-	_, err := p.connection.Exec(ctx, query)
+	// NOTE: migrations go through database/sql, on connections of their own
+	// that are closed once a run is over.
+	migrationDB := stdlib.OpenDB(*dbConfig.ConnConfig.Copy())
+	migrationDB.SetMaxIdleConns(0)
+	migrator, err := migrations.NewMigrator(migrationDB, migrations.Postgres, schema.Files)
 	if err != nil {
-		return fmt.Errorf("Failed to Migrate Postgres DB:  %w", err)
+		return &PostgresRepository{}, err
 	}
 
-	return nil
+	return &PostgresRepository{connection: connectionPool, migrator: migrator}, nil
+}
+
+// Migrator migrates the schema of the database. The repository expects it
+// to be up to date.
+func (p *PostgresRepository) Migrator() *migrations.Migrator {
+	return p.migrator
 }
 
 func (p *PostgresRepository) CreateUser(ctx context.Context, user domain.User) error {
-	err := p.Migrate(ctx)
-	if err != nil {
-		return err
+	err := p.connection.QueryRow(ctx, "INSERT INTO users(id, first_name, last_name, email, password) values($1, $2, $3, $4, $5) RETURNING id", user.ID, user.FirstName, user.LastName, user.Email, user.Password).Scan(nil)
+	var pgErr *pgconn.PgError
+	// 23505 is unique_violation
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "users_email_key" {
+		return infra.ErrDuplicateEmail
 	}
-
-	err = p.connection.QueryRow(ctx, "INSERT INTO users(id, first_name, last_name, email, password) values($1, $2, $3, $4, $5) RETURNING id", user.ID, user.FirstName, user.LastName, user.Email, user.Password).Scan(nil)
 	return err
 }
 
 func (p *PostgresRepository) GetUserByEmail(ctx context.Context, userEmail string) (domain.User, error) {
-	err := p.Migrate(ctx)
-	if err != nil {
-		return domain.User{}, err
-	}
 	row := p.connection.QueryRow(ctx, "SELECT * FROM users WHERE email = $1", userEmail)
 
 	var id uuid.UUID
@@ -74,9 +74,10 @@ func (p *PostgresRepository) GetUserByEmail(ctx context.Context, userEmail strin
 	var password string
 
 	if err := row.Scan(&id, &email, &first_name, &last_name, &password); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return domain.User{}, errors.New(infra.ErrRecordNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.User{}, infra.ErrRecordNotFound
 		}
+		return domain.User{}, err
 	}
 
 	return domain.User{
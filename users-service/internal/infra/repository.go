@@ -2,14 +2,40 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/olad5/productive-pulse/users-service/internal/domain"
 )
 
-var ErrRecordNotFound = "No Record found"
+var (
+	ErrRecordNotFound = errors.New("No Record found")
+	ErrDuplicateEmail = errors.New("a user with this email already exists")
+	// ErrRefreshTokenUsed is returned when a refresh token is rotated after
+	// it was used or revoked.
+	ErrRefreshTokenUsed = errors.New("refresh token was used or revoked")
+)
 
 type UserRepository interface {
 	Ping(ctx context.Context) error
+	// CreateUser fails with ErrDuplicateEmail when the email is taken.
 	CreateUser(ctx context.Context, user domain.User) error
+	// GetUserByEmail fails with ErrRecordNotFound when no user has email.
 	GetUserByEmail(ctx context.Context, email string) (domain.User, error)
 }
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error
+	// GetRefreshToken fails with ErrRecordNotFound when no token hashes to
+	// tokenHash.
+	GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error)
+	// RotateRefreshToken marks the token usedId used at usedAt and creates
+	// next, together. It fails with ErrRefreshTokenUsed, creating nothing,
+	// unless usedId is neither used nor revoked, so that a token is only ever
+	// rotated once.
+	RotateRefreshToken(ctx context.Context, usedId uuid.UUID, usedAt time.Time, next domain.RefreshToken) error
+	// RevokeRefreshTokenFamily revokes every token of familyId that is not
+	// revoked yet.
+	RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error
+}
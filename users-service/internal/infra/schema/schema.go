@@ -0,0 +1,8 @@
+// Package schema holds the migrations of the users-service SQL backends,
+// which both apply with pkg/migrations.
+package schema
+
+import "embed"
+
+//go:embed sql/*.sql
+var Files embed.FS
@@ -0,0 +1,9 @@
+-- IF NOT EXISTS adopts databases whose users table was created before
+-- migrations were versioned.
+CREATE TABLE IF NOT EXISTS users(
+    id UUID PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    password TEXT NOT NULL
+);
@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys(
+    key TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code INT NOT NULL DEFAULT 0,
+    header JSONB,
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys(created_at);
@@ -0,0 +1,5 @@
+package sqlite
+
+// modernc.org/sqlite is a pure Go driver, so binaries built with it still
+// cross-compile and run without cgo.
+import _ "modernc.org/sqlite"
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+const insertRefreshToken = "INSERT INTO refresh_tokens(id, user_id, family_id, token_hash, created_at, expires_at) values(?, ?, ?, ?, ?, ?)"
+
+func (s *SQLiteRepository) CreateRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	_, err := s.connection.ExecContext(ctx, insertRefreshToken, refreshTokenArgs(token)...)
+	return err
+}
+
+func (s *SQLiteRepository) GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	row := s.connection.QueryRowContext(ctx, "SELECT id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash = ?", tokenHash)
+
+	var id, userId, familyId string
+	var usedAt, revokedAt sql.NullTime
+	var token domain.RefreshToken
+	if err := row.Scan(&id, &userId, &familyId, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &usedAt, &revokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.RefreshToken{}, infra.ErrRecordNotFound
+		}
+		return domain.RefreshToken{}, err
+	}
+	var err error
+	if token.ID, err = uuid.Parse(id); err != nil {
+		return domain.RefreshToken{}, fmt.Errorf("invalid id of refresh token %s: %w", id, err)
+	}
+	if token.UserId, err = uuid.Parse(userId); err != nil {
+		return domain.RefreshToken{}, fmt.Errorf("invalid user id of refresh token %s: %w", id, err)
+	}
+	if token.FamilyId, err = uuid.Parse(familyId); err != nil {
+		return domain.RefreshToken{}, fmt.Errorf("invalid family id of refresh token %s: %w", id, err)
+	}
+	token.CreatedAt, token.ExpiresAt = token.CreatedAt.UTC(), token.ExpiresAt.UTC()
+	if usedAt.Valid {
+		used := usedAt.Time.UTC()
+		token.UsedAt = &used
+	}
+	if revokedAt.Valid {
+		revoked := revokedAt.Time.UTC()
+		token.RevokedAt = &revoked
+	}
+	return token, nil
+}
+
+func (s *SQLiteRepository) RotateRefreshToken(ctx context.Context, usedId uuid.UUID, usedAt time.Time, next domain.RefreshToken) error {
+	tx, err := s.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL", usedAt.UTC(), usedId.String())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return infra.ErrRefreshTokenUsed
+	}
+	if _, err := tx.ExecContext(ctx, insertRefreshToken, refreshTokenArgs(next)...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId uuid.UUID, revokedAt time.Time) error {
+	_, err := s.connection.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL", revokedAt.UTC(), familyId.String())
+	return err
+}
+
+// refreshTokenArgs are the arguments of insertRefreshToken. Ids are stored
+// as text, like the ids of users they reference.
+func refreshTokenArgs(token domain.RefreshToken) []interface{} {
+	return []interface{}{
+		token.ID.String(), token.UserId.String(), token.FamilyId.String(), token.TokenHash,
+		token.CreatedAt.UTC(), token.ExpiresAt.UTC(),
+	}
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/pkg/migrations"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+	"github.com/olad5/productive-pulse/users-service/internal/infra/schema"
+)
+
+// driverName is the database/sql driver the repository opens databases
+// with, registered by sqlite_driver.go.
+const driverName = "sqlite"
+
+// SQLiteRepository keeps users in a single SQLite file, for single-node
+// deployments that would rather not run Postgres.
+type SQLiteRepository struct {
+	connection *sql.DB
+	migrator   *migrations.Migrator
+}
+
+func NewSQLiteRepo(ctx context.Context, databaseUrl string) (*SQLiteRepository, error) {
+	if databaseUrl == "" {
+		return &SQLiteRepository{}, errors.New("databaseUrl cannot be empty")
+	}
+
+	dataSourceName, err := DataSourceName(databaseUrl)
+	if err != nil {
+		return &SQLiteRepository{}, err
+	}
+	connection, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return &SQLiteRepository{}, fmt.Errorf("Failed to create SQLiteRepository:  %w", err)
+	}
+
+	migrator, err := migrations.NewMigrator(connection, migrations.SQLite, schema.Files)
+	if err != nil {
+		connection.Close()
+		return &SQLiteRepository{}, err
+	}
+
+	return &SQLiteRepository{connection: connection, migrator: migrator}, nil
+}
+
+// DataSourceName turns a sqlite:// or file: URL into what the driver opens.
+// Every connection runs in WAL mode, so reads do not wait for writes, and
+// waits for locks rather than failing with SQLITE_BUSY.
+func DataSourceName(databaseUrl string) (string, error) {
+	parsed, err := url.Parse(databaseUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid sqlite database url: %w", err)
+	}
+	var path string
+	switch parsed.Scheme {
+	case "sqlite":
+		// NOTE: sqlite://users.db is relative to the working directory and
+		// sqlite:///var/lib/users.db absolute.
+		path = parsed.Host + parsed.Path
+	case "file":
+		path = parsed.Opaque + parsed.Path
+	default:
+		return "", fmt.Errorf("unsupported sqlite database url scheme %q", parsed.Scheme)
+	}
+	if path == "" {
+		return "", errors.New("sqlite database url has no path")
+	}
+
+	query := parsed.Query()
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Add("_pragma", "foreign_keys(1)")
+	return "file:" + path + "?" + query.Encode(), nil
+}
+
+// Migrator migrates the schema of the database, with the same migrations as
+// Postgres. The repository expects it to be up to date.
+func (s *SQLiteRepository) Migrator() *migrations.Migrator {
+	return s.migrator
+}
+
+func (s *SQLiteRepository) CreateUser(ctx context.Context, user domain.User) error {
+	_, err := s.connection.ExecContext(ctx, "INSERT INTO users(id, first_name, last_name, email, password) values(?, ?, ?, ?, ?)", user.ID.String(), user.FirstName, user.LastName, user.Email, user.Password)
+	// NOTE: drivers only agree on the message of a unique violation.
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
+		return infra.ErrDuplicateEmail
+	}
+	return err
+}
+
+func (s *SQLiteRepository) GetUserByEmail(ctx context.Context, userEmail string) (domain.User, error) {
+	row := s.connection.QueryRowContext(ctx, "SELECT id, email, first_name, last_name, password FROM users WHERE email = ?", userEmail)
+
+	var id string
+	var user domain.User
+	if err := row.Scan(&id, &user.Email, &user.FirstName, &user.LastName, &user.Password); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, infra.ErrRecordNotFound
+		}
+		return domain.User{}, err
+	}
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("invalid id of user %s: %w", userEmail, err)
+	}
+	user.ID = userId
+
+	return user, nil
+}
+
+func (s *SQLiteRepository) Ping(ctx context.Context) error {
+	err := s.connection.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to Ping SQLite DB:  %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package users
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/olad5/productive-pulse/config"
+	"github.com/olad5/productive-pulse/pkg/jwks"
+)
+
+// KeySet signs access tokens with its signing key and verifies them with any
+// of its keys, so the tokens signed before a key rotation stay valid until
+// they expire. Tokens name the key that signed them in the kid of their
+// header.
+type KeySet struct {
+	signingKey crypto.Signer
+	signingKid string
+	method     jwt.SigningMethod
+	publicKeys map[string]crypto.PublicKey
+	set        jwks.Set
+}
+
+// NewKeySet signs with signingKey, an *rsa.PrivateKey (RS256) or an
+// ed25519.PrivateKey (EdDSA), and also verifies with previousKeys.
+func NewKeySet(signingKey crypto.Signer, previousKeys ...crypto.PublicKey) (*KeySet, error) {
+	if signingKey == nil {
+		return nil, errors.New("signingKey cannot be empty")
+	}
+	var method jwt.SigningMethod
+	switch signingKey.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", signingKey)
+	}
+
+	keys := &KeySet{
+		signingKey: signingKey,
+		method:     method,
+		publicKeys: map[string]crypto.PublicKey{},
+		set:        jwks.Set{Keys: []jwks.Key{}},
+	}
+	for _, publicKey := range append([]crypto.PublicKey{signingKey.Public()}, previousKeys...) {
+		key, err := jwks.NewKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keys.publicKeys[key.Kid]; ok {
+			continue
+		}
+		keys.publicKeys[key.Kid] = publicKey
+		keys.set.Keys = append(keys.set.Keys, key)
+	}
+	keys.signingKid = keys.set.Keys[0].Kid
+	return keys, nil
+}
+
+// LoadKeySet reads the signing keys of configurations. Without a signing key
+// file, it signs with an Ed25519 key of its own, which lasts as long as the
+// process.
+func LoadKeySet(configurations *config.Configurations) (*KeySet, error) {
+	var signingKey crypto.Signer
+	if configurations.UserServiceSigningKeyFile == "" {
+		log.Print("USER_SERVICE_SIGNING_KEY_FILE is not set; signing access tokens with a key that is lost on restart")
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate a signing key: %w", err)
+		}
+		signingKey = privateKey
+	} else {
+		key, err := readPEMKey(configurations.UserServiceSigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if signingKey, ok = key.(crypto.Signer); !ok {
+			return nil, fmt.Errorf("%s does not hold a private key", configurations.UserServiceSigningKeyFile)
+		}
+	}
+
+	var previousKeys []crypto.PublicKey
+	for _, file := range configurations.UserServicePreviousSigningKeyFiles {
+		key, err := readPEMKey(file)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE: the previous keys may be kept as private keys, the way they
+		// were used, or as their public keys.
+		if privateKey, ok := key.(crypto.Signer); ok {
+			key = privateKey.Public()
+		}
+		previousKeys = append(previousKeys, key)
+	}
+	return NewKeySet(signingKey, previousKeys...)
+}
+
+// readPEMKey reads the first key of a PEM file: a PKCS #8 or PKCS #1 private
+// key, or a PKIX or PKCS #1 public key.
+func readPEMKey(file string) (interface{}, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM block", file)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s holds an unsupported %s PEM block", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	return key, nil
+}
+
+// JWKS returns the public keys of k, the signing key first.
+func (k *KeySet) JWKS() jwks.Set {
+	return k.set
+}
+
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.signingKid
+	return token.SignedString(k.signingKey)
+}
+
+// keyFunc picks the key that verifies a token by the kid of its header.
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	publicKey, ok := k.publicKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,122 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrInvalidRefreshToken = appErrors.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	ErrExpiredRefreshToken = appErrors.Unauthorized("expired_refresh_token", "expired refresh token")
+	ErrRefreshTokenReused  = appErrors.Unauthorized("refresh_token_reused", "refresh token was already used, log in again")
+)
+
+// refreshTokenBytes is the entropy of a refresh token, which is what keeps
+// it from being guessed: it is opaque and only its hash is stored.
+const refreshTokenBytes = 32
+
+// RefreshTokens trades refreshToken for new tokens of its user. A refresh
+// token trades once: the one it is traded for succeeds it in its family.
+//
+// A token that is traded again was copied, and either copy may be the
+// attacker's, so the whole family is revoked and its user has to log in
+// again.
+func (u *UserService) RefreshTokens(ctx context.Context, tracer trace.Tracer, refreshToken string) (Tokens, error) {
+	ctx, span := tracer.Start(ctx, "RefreshTokens")
+	defer span.End()
+	if refreshToken == "" {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	stored, err := u.refreshTokenRepo.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+	span.SetAttributes(attribute.String("refresh_token.family_id", stored.FamilyId.String()))
+
+	now := time.Now()
+	if stored.UsedAt != nil {
+		return Tokens{}, u.revokeFamily(ctx, span, stored.FamilyId, now)
+	}
+	if stored.RevokedAt != nil {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if !now.Before(stored.ExpiresAt) {
+		return Tokens{}, ErrExpiredRefreshToken
+	}
+
+	accessToken, err := generateJWT(stored.UserId, u.keys, u.lifetimes.Access)
+	if err != nil {
+		return Tokens{}, err
+	}
+	next, plainRefreshToken, err := newRefreshToken(stored.UserId, stored.FamilyId, now, u.lifetimes.Refresh)
+	if err != nil {
+		return Tokens{}, err
+	}
+	err = u.refreshTokenRepo.RotateRefreshToken(ctx, stored.ID, now, next)
+	// NOTE: the token was traded, or its family revoked, since it was read:
+	// a concurrent trade of the same token is a replay all the same.
+	if errors.Is(err, infra.ErrRefreshTokenUsed) {
+		return Tokens{}, u.revokeFamily(ctx, span, stored.FamilyId, now)
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{AccessToken: accessToken, RefreshToken: plainRefreshToken}, nil
+}
+
+// revokeFamily revokes every token of familyId after one of them was
+// replayed, and returns the error the replay is answered with.
+func (u *UserService) revokeFamily(ctx context.Context, span trace.Span, familyId uuid.UUID, now time.Time) error {
+	span.SetAttributes(attribute.Bool("refresh_token.reused", true))
+	if err := u.refreshTokenRepo.RevokeRefreshTokenFamily(ctx, familyId, now); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family %s: %w", familyId, err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// newRefreshToken returns a refresh token of userId in familyId, valid for
+// lifetime from now, along with the token to hand out, which is not stored.
+func newRefreshToken(userId, familyId uuid.UUID, now time.Time, lifetime time.Duration) (domain.RefreshToken, string, error) {
+	secret := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.RefreshToken{}, "", fmt.Errorf("failed to generate a refresh token: %w", err)
+	}
+	plain := base64.RawURLEncoding.EncodeToString(secret)
+
+	// NOTE: times are kept in UTC, which SQL backends store them in, and
+	// without the monotonic reading that would not survive a round trip.
+	now = now.UTC().Round(0)
+	return domain.RefreshToken{
+		ID:        uuid.New(),
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: hashRefreshToken(plain),
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}, plain, nil
+}
+
+// hashRefreshToken is what a refresh token is stored, and looked up, by.
+// Unlike passwords, refresh tokens are random, so an unsalted SHA-256 does
+// not make them any easier to guess.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
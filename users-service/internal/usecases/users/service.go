@@ -3,13 +3,13 @@ package users
 import (
 	"context"
 	"errors"
-	"fmt"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/olad5/productive-pulse/config"
+	appErrors "github.com/olad5/productive-pulse/pkg/errors"
+	"github.com/olad5/productive-pulse/pkg/jwks"
 	"github.com/olad5/productive-pulse/users-service/internal/domain"
 	"github.com/olad5/productive-pulse/users-service/internal/infra"
 	"go.opentelemetry.io/otel/trace"
@@ -17,22 +17,60 @@ import (
 )
 
 type UserService struct {
-	userRepo       infra.UserRepository
-	configurations *config.Configurations
+	userRepo         infra.UserRepository
+	refreshTokenRepo infra.RefreshTokenRepository
+	keys             *KeySet
+	lifetimes        TokenLifetimes
+}
+
+// TokenLifetimes are how long the tokens a login issues stay valid.
+type TokenLifetimes struct {
+	Access  time.Duration
+	Refresh time.Duration
+}
+
+// DefaultTokenLifetimes stand in for the lifetimes left zero.
+var DefaultTokenLifetimes = TokenLifetimes{
+	Access:  time.Hour,
+	Refresh: 30 * 24 * time.Hour,
+}
+
+// Tokens are what a login, or a refresh, issues: an access token and the
+// refresh token that trades for the next ones.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
 }
 
 var (
-	ErrUserAlreadyExists = "email already exist"
-	ErrPasswordIncorrect = "incorrect credentials"
-	ErrUserNotFound      = "user not found"
-	ErrInvalidToken      = errors.New("invalid token")
+	ErrUserAlreadyExists = appErrors.BadRequest("user_already_exists", "email already exist")
+	ErrPasswordIncorrect = appErrors.BadRequest("incorrect_credentials", "incorrect credentials")
+	ErrUserNotFound      = appErrors.NotFound("user_not_found", "user not found")
+	ErrInvalidToken      = appErrors.Unauthorized("invalid_token", "invalid token")
+	ErrExpiredToken      = appErrors.Unauthorized("expired_token", "expired token")
+	ErrMalformedToken    = appErrors.Unauthorized("malformed_token", "error decoding jwt")
 )
 
-func NewUserService(userRepo infra.UserRepository, configurations *config.Configurations) (*UserService, error) {
+func NewUserService(userRepo infra.UserRepository, refreshTokenRepo infra.RefreshTokenRepository, keys *KeySet, lifetimes TokenLifetimes) (*UserService, error) {
 	if userRepo == nil {
 		return &UserService{}, errors.New("UserService failed to initialize")
 	}
-	return &UserService{userRepo, configurations}, nil
+	if refreshTokenRepo == nil {
+		return &UserService{}, errors.New("refreshTokenRepo cannot be empty")
+	}
+	if keys == nil {
+		return &UserService{}, errors.New("keys cannot be empty")
+	}
+	if lifetimes.Access < 0 || lifetimes.Refresh < 0 {
+		return &UserService{}, errors.New("token lifetimes cannot be negative")
+	}
+	if lifetimes.Access == 0 {
+		lifetimes.Access = DefaultTokenLifetimes.Access
+	}
+	if lifetimes.Refresh == 0 {
+		lifetimes.Refresh = DefaultTokenLifetimes.Refresh
+	}
+	return &UserService{userRepo, refreshTokenRepo, keys, lifetimes}, nil
 }
 
 func (u *UserService) CreateUser(ctx context.Context, tracer trace.Tracer, firstName, lastName, email, password string) (domain.User, error) {
@@ -40,9 +78,9 @@ func (u *UserService) CreateUser(ctx context.Context, tracer trace.Tracer, first
 	defer span.End()
 	existingUser, err := u.userRepo.GetUserByEmail(ctx, email)
 	if err == nil && existingUser.Email == email {
-		return domain.User{}, errors.New(ErrUserAlreadyExists)
+		return domain.User{}, ErrUserAlreadyExists
 	}
-	if err != nil {
+	if err != nil && !errors.Is(err, infra.ErrRecordNotFound) {
 		return domain.User{}, err
 	}
 
@@ -59,28 +97,45 @@ func (u *UserService) CreateUser(ctx context.Context, tracer trace.Tracer, first
 	}
 
 	err = u.userRepo.CreateUser(ctx, newUser)
+	if errors.Is(err, infra.ErrDuplicateEmail) {
+		return domain.User{}, ErrUserAlreadyExists
+	}
 	if err != nil {
 		return domain.User{}, err
 	}
 	return newUser, nil
 }
 
-func (u *UserService) LogUserIn(ctx context.Context, tracer trace.Tracer, email, password string) (string, error) {
+// LogUserIn issues the tokens of the user, whose refresh token starts a
+// family of its own.
+func (u *UserService) LogUserIn(ctx context.Context, tracer trace.Tracer, email, password string) (Tokens, error) {
 	ctx, span := tracer.Start(ctx, "LogUserIn")
 	defer span.End()
 	existingUser, err := u.userRepo.GetUserByEmail(ctx, email)
-	if err != nil && err.Error() == infra.ErrRecordNotFound {
-		return "", errors.New(ErrUserNotFound)
+	// NOTE: an unknown email gets the same answer as a wrong password, so
+	// logins do not reveal who has an account.
+	if errors.Is(err, infra.ErrRecordNotFound) {
+		return Tokens{}, ErrPasswordIncorrect
+	}
+	if err != nil {
+		return Tokens{}, err
 	}
 	if isPasswordCorrect := comparePasswords(existingUser.Password, []byte(password)); isPasswordCorrect == false {
-		return "", errors.New(ErrPasswordIncorrect)
+		return Tokens{}, ErrPasswordIncorrect
 	}
 
-	accessToken, err := generateJWT(existingUser, u.configurations.UserServiceSecretKey)
+	accessToken, err := generateJWT(existingUser.ID, u.keys, u.lifetimes.Access)
 	if err != nil {
-		return "", err
+		return Tokens{}, err
+	}
+	refreshToken, plainRefreshToken, err := newRefreshToken(existingUser.ID, uuid.New(), time.Now(), u.lifetimes.Refresh)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if err := u.refreshTokenRepo.CreateRefreshToken(ctx, refreshToken); err != nil {
+		return Tokens{}, err
 	}
-	return accessToken, nil
+	return Tokens{AccessToken: accessToken, RefreshToken: plainRefreshToken}, nil
 }
 
 func (u *UserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authHeader string) (string, error) {
@@ -89,49 +144,48 @@ func (u *UserService) VerifyUser(ctx context.Context, tracer trace.Tracer, authH
 	const Bearer = "Bearer "
 	if authHeader != "" && strings.HasPrefix(authHeader, Bearer) {
 		token := strings.TrimPrefix(authHeader, Bearer)
-		return verifyJWT(token, u.configurations.UserServiceSecretKey)
+		return verifyJWT(token, u.keys)
 	}
 
 	return "", ErrInvalidToken
 }
 
-func generateJWT(user domain.User, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(time.Hour * 1).Unix(),
+// JWKS returns the public keys access tokens are verified with.
+func (u *UserService) JWKS() jwks.Set {
+	return u.keys.JWKS()
+}
+
+func generateJWT(userId uuid.UUID, keys *KeySet, lifetime time.Duration) (string, error) {
+	now := time.Now()
+	tokenString, err := keys.sign(jwt.RegisteredClaims{
+		Subject:   userId.String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
 	})
-	tokenString, err := token.SignedString([]byte(secret))
 	if err != nil {
 		return "", errors.New("Error generating JWT token")
 	}
 	return tokenString, nil
 }
 
-func verifyJWT(tokenString, secret string) (string, error) {
+func verifyJWT(tokenString string, keys *KeySet) (string, error) {
 	if tokenString == "" {
 		return "", ErrInvalidToken
 	}
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["sub"])
-		}
-		return []byte(secret), nil
-	})
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, keys.keyFunc,
+		jwt.WithValidMethods([]string{jwks.RS256, jwks.EdDSA}),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return "", ErrExpiredToken
+	}
 	if err != nil {
-		return "", errors.New("error decoding jwt")
-	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			return "", errors.New("expired token")
-		}
-		userId, ok := claims["sub"]
-		if ok == true && userId != nil {
-			return userId.(string), nil
-		}
-		return "", ErrInvalidToken
-	} else {
+		return "", ErrMalformedToken.Wrap(err)
+	}
+	if claims.ExpiresAt == nil || claims.Subject == "" {
 		return "", ErrInvalidToken
 	}
+	return claims.Subject, nil
 }
 
 func hashAndSalt(plainPassword []byte) (string, error) {
@@ -0,0 +1,157 @@
+package contract
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+// RefreshTokenRepository runs the contract against repo, whose tokens
+// belong to users it registers in users.
+func RefreshTokenRepository(t *testing.T, users infra.UserRepository, repo infra.RefreshTokenRepository) {
+	ctx := context.Background()
+	// NOTE: Postgres keeps microseconds.
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	newToken := func(t *testing.T, familyId uuid.UUID) domain.RefreshToken {
+		t.Helper()
+		user := newUser()
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		return newRefreshToken(user.ID, familyId, now)
+	}
+
+	t.Run(`Given a created refresh token
+      When it is fetched by its hash
+      Then it should come back as written, neither used nor revoked
+    `,
+		func(t *testing.T) {
+			token := newToken(t, uuid.New())
+			if err := repo.CreateRefreshToken(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetRefreshToken(ctx, token.TokenHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRefreshToken(t, got, token)
+		},
+	)
+
+	t.Run(`Given a hash no refresh token has
+      When a token is fetched by it
+      Then it should fail with ErrRecordNotFound
+    `,
+		func(t *testing.T) {
+			_, err := repo.GetRefreshToken(ctx, uuid.NewString())
+			assertError(t, err, infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given a refresh token
+      When it is rotated
+      Then it should be used and its successor created
+      And rotating it again should fail with ErrRefreshTokenUsed, creating nothing
+    `,
+		func(t *testing.T) {
+			token := newToken(t, uuid.New())
+			if err := repo.CreateRefreshToken(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+			next := newRefreshToken(token.UserId, token.FamilyId, now)
+			if err := repo.RotateRefreshToken(ctx, token.ID, now, next); err != nil {
+				t.Fatal(err)
+			}
+
+			used, err := repo.GetRefreshToken(ctx, token.TokenHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if used.UsedAt == nil || !used.UsedAt.Equal(now) {
+				t.Errorf("Expected the token to be used at %v. Got %v", now, used.UsedAt)
+			}
+			got, err := repo.GetRefreshToken(ctx, next.TokenHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRefreshToken(t, got, next)
+
+			replay := newRefreshToken(token.UserId, token.FamilyId, now)
+			assertError(t, repo.RotateRefreshToken(ctx, token.ID, now, replay), infra.ErrRefreshTokenUsed)
+			_, err = repo.GetRefreshToken(ctx, replay.TokenHash)
+			assertError(t, err, infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given two refresh tokens of a family and one of another
+      When the family is revoked
+      Then both of its tokens should be revoked, and the other one kept
+      And rotating a revoked token should fail with ErrRefreshTokenUsed
+    `,
+		func(t *testing.T) {
+			familyId := uuid.New()
+			first := newToken(t, familyId)
+			second := newRefreshToken(first.UserId, familyId, now)
+			other := newRefreshToken(first.UserId, uuid.New(), now)
+			for _, token := range []domain.RefreshToken{first, second, other} {
+				if err := repo.CreateRefreshToken(ctx, token); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.RevokeRefreshTokenFamily(ctx, familyId, now); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, token := range []domain.RefreshToken{first, second} {
+				got, err := repo.GetRefreshToken(ctx, token.TokenHash)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.RevokedAt == nil || !got.RevokedAt.Equal(now) {
+					t.Errorf("Expected token %s to be revoked at %v. Got %v", token.ID, now, got.RevokedAt)
+				}
+			}
+			got, err := repo.GetRefreshToken(ctx, other.TokenHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertRefreshToken(t, got, other)
+
+			next := newRefreshToken(first.UserId, familyId, now)
+			assertError(t, repo.RotateRefreshToken(ctx, second.ID, now, next), infra.ErrRefreshTokenUsed)
+		},
+	)
+}
+
+func newRefreshToken(userId, familyId uuid.UUID, now time.Time) domain.RefreshToken {
+	return domain.RefreshToken{
+		ID:        uuid.New(),
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: "contract" + uuid.NewString(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+}
+
+// assertRefreshToken compares got with a token that was neither used nor
+// revoked.
+func assertRefreshToken(t *testing.T, got, expected domain.RefreshToken) {
+	t.Helper()
+	if got.ID != expected.ID || got.UserId != expected.UserId || got.FamilyId != expected.FamilyId || got.TokenHash != expected.TokenHash {
+		t.Errorf("Expected refresh token %+v. Got %+v", expected, got)
+	}
+	if !got.CreatedAt.Equal(expected.CreatedAt) || !got.ExpiresAt.Equal(expected.ExpiresAt) {
+		t.Errorf("Expected refresh token created at %v and expiring at %v. Got %v and %v",
+			expected.CreatedAt, expected.ExpiresAt, got.CreatedAt, got.ExpiresAt)
+	}
+	if got.UsedAt != nil || got.RevokedAt != nil {
+		t.Errorf("Expected refresh token %s to be neither used nor revoked. Got %v and %v", got.ID, got.UsedAt, got.RevokedAt)
+	}
+}
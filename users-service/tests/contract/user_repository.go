@@ -0,0 +1,101 @@
+// Package contract holds the behaviour every users-service storage backend
+// must share, so that the services on top cannot tell them apart.
+package contract
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/olad5/productive-pulse/users-service/internal/domain"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+)
+
+// UserRepository runs the contract against repo. Every case registers users
+// of its own, so repo may hold data already.
+func UserRepository(t *testing.T, repo infra.UserRepository) {
+	ctx := context.Background()
+
+	t.Run(`Given a reachable repository
+      When it is pinged
+      Then it should not fail
+    `,
+		func(t *testing.T) {
+			if err := repo.Ping(ctx); err != nil {
+				t.Fatal(err)
+			}
+		},
+	)
+
+	t.Run(`Given a created user
+      When they are fetched by their email
+      Then they should come back as written
+    `,
+		func(t *testing.T) {
+			user := newUser()
+			if err := repo.CreateUser(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetUserByEmail(ctx, user.Email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != user {
+				t.Errorf("Expected user %+v. Got %+v", user, got)
+			}
+		},
+	)
+
+	t.Run(`Given an email nobody registered with
+      When a user is fetched by it
+      Then it should fail with ErrRecordNotFound
+    `,
+		func(t *testing.T) {
+			_, err := repo.GetUserByEmail(ctx, newUser().Email)
+			assertError(t, err, infra.ErrRecordNotFound)
+		},
+	)
+
+	t.Run(`Given a created user
+      When another user is created with their email
+      Then it should fail with ErrDuplicateEmail
+      And the first user should be kept
+    `,
+		func(t *testing.T) {
+			user := newUser()
+			if err := repo.CreateUser(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+			duplicate := newUser()
+			duplicate.Email = user.Email
+
+			assertError(t, repo.CreateUser(ctx, duplicate), infra.ErrDuplicateEmail)
+			got, err := repo.GetUserByEmail(ctx, user.Email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != user.ID {
+				t.Errorf("Expected user %s to be kept. Got %s", user.ID, got.ID)
+			}
+		},
+	)
+}
+
+func newUser() domain.User {
+	return domain.User{
+		ID:        uuid.New(),
+		Email:     "contract" + uuid.NewString() + "@gmail.com",
+		FirstName: "will",
+		LastName:  "hansen",
+		Password:  "some-hashed-password",
+	}
+}
+
+func assertError(t *testing.T, err, expected error) {
+	t.Helper()
+	if !errors.Is(err, expected) {
+		t.Errorf("Expected error %q. Got %v", expected, err)
+	}
+}
@@ -0,0 +1,104 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	usersv1 "github.com/olad5/productive-pulse/pkg/proto/users/v1"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func assertGrpcCode(t *testing.T, expected codes.Code, err error) {
+	t.Helper()
+	if actual := status.Code(err); actual != expected {
+		t.Errorf("Expected gRPC code %s. Got %s (%v)\n", expected, actual, err)
+	}
+}
+
+func TestGrpcUserService(t *testing.T) {
+	ctx := context.Background()
+	email := "grpc" + fmt.Sprint(tests.GenerateUniqueId()) + "@gmail.com"
+	password := "some-random-password"
+
+	t.Run(`Given a valid registration request, when it is sent over gRPC,
+    then the user is created and returned without the password.`,
+		func(t *testing.T) {
+			res, err := userClient.Register(ctx, &usersv1.RegisterRequest{
+				Email:     email,
+				FirstName: "will",
+				LastName:  "hansen",
+				Password:  password,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error. Got %v", err)
+			}
+			if res.GetUser().GetEmail() != email || res.GetUser().GetId() == "" {
+				t.Errorf("Expected registered user with email %s. Got %v", email, res.GetUser())
+			}
+		},
+	)
+
+	t.Run(`Given an email that is already registered, when it is registered again
+    over gRPC, then the call fails with AlreadyExists.`,
+		func(t *testing.T) {
+			_, err := userClient.Register(ctx, &usersv1.RegisterRequest{
+				Email:     email,
+				FirstName: "will",
+				LastName:  "hansen",
+				Password:  password,
+			})
+			assertGrpcCode(t, codes.AlreadyExists, err)
+		},
+	)
+
+	t.Run(`Given a registration request without an email, when it is sent over
+    gRPC, then the call fails with InvalidArgument.`,
+		func(t *testing.T) {
+			_, err := userClient.Register(ctx, &usersv1.RegisterRequest{
+				FirstName: "will",
+				LastName:  "hansen",
+				Password:  password,
+			})
+			assertGrpcCode(t, codes.InvalidArgument, err)
+		},
+	)
+
+	t.Run(`Given a registered user, when they log in over gRPC and the access token
+    is verified, then VerifyToken returns their user id.`,
+		func(t *testing.T) {
+			loginRes, err := userClient.Login(ctx, &usersv1.LoginRequest{Email: email, Password: password})
+			if err != nil {
+				t.Fatalf("Expected no error logging in. Got %v", err)
+			}
+			verifyRes, err := userClient.VerifyToken(ctx, &usersv1.VerifyTokenRequest{AccessToken: loginRes.GetAccessToken()})
+			if err != nil {
+				t.Fatalf("Expected no error verifying token. Got %v", err)
+			}
+			if verifyRes.GetUserId() == "" {
+				t.Error("Expected a user id, got an empty one")
+			}
+		},
+	)
+
+	t.Run(`Given a wrong password, when the user logs in over gRPC, then the call
+    fails with Unauthenticated.`,
+		func(t *testing.T) {
+			_, err := userClient.Login(ctx, &usersv1.LoginRequest{Email: email, Password: "wrong-password"})
+			assertGrpcCode(t, codes.Unauthenticated, err)
+		},
+	)
+
+	t.Run(`Given an invalid access token, when it is verified over gRPC, then the
+    call fails with Unauthenticated.`,
+		func(t *testing.T) {
+			_, err := userClient.VerifyToken(ctx, &usersv1.VerifyTokenRequest{AccessToken: "not-a-jwt"})
+			assertGrpcCode(t, codes.Unauthenticated, err)
+		},
+	)
+}
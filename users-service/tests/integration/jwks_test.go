@@ -0,0 +1,138 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/olad5/productive-pulse/pkg/jwks"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+)
+
+// registerAndLogIn registers a user of its own and returns their id and
+// access token.
+func registerAndLogIn(t *testing.T) (string, string) {
+	t.Helper()
+	userId, email := registerUser(t)
+	return userId, logIn(t, email)["access_token"].(string)
+}
+
+// registerUser registers a user of its own and returns their id and email.
+func registerUser(t *testing.T) (string, string) {
+	t.Helper()
+	email := "jess" + fmt.Sprint(tests.GenerateUniqueId()) + "@gmail.com"
+	registerReq, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(fmt.Sprintf(`{
+      "email": "%s",
+      "first_name": "jess",
+      "last_name": "moss",
+      "password": "some-random-password"
+      }`, email)))
+	registerResponse := tests.ExecuteRequest(registerReq, svr)
+	tests.AssertStatusCode(t, http.StatusOK, registerResponse.Code)
+	return tests.ParseResponse(registerResponse)["data"].(map[string]interface{})["id"].(string), email
+}
+
+// logIn logs the user of email in and returns the data of the response.
+func logIn(t *testing.T, email string) map[string]interface{} {
+	t.Helper()
+	loginReq, _ := http.NewRequest(http.MethodPost, "/users/login", bytes.NewBufferString(fmt.Sprintf(`{
+      "email": "%s",
+      "password": "some-random-password"
+      }`, email)))
+	loginResponse := tests.ExecuteRequest(loginReq, svr)
+	tests.AssertStatusCode(t, http.StatusOK, loginResponse.Code)
+	return tests.ParseResponse(loginResponse)["data"].(map[string]interface{})
+}
+
+func fetchJWKS(t *testing.T) jwks.Set {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	response := tests.ExecuteRequest(req, svr)
+	tests.AssertStatusCode(t, http.StatusOK, response.Code)
+	var set jwks.Set
+	if err := json.Unmarshal(response.Body.Bytes(), &set); err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func signToken(t *testing.T, key ed25519.PrivateKey, userId string) string {
+	t.Helper()
+	jwk, err := jwks.NewKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{
+		Subject:   userId,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	token.Header["kid"] = jwk.Kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWKS(t *testing.T) {
+	t.Run(`Given a user who logged in
+      When the key set is fetched
+      Then it should list the RS256 signing key first and the previous key after it
+      And the access token should name the signing key and verify with it`,
+		func(t *testing.T) {
+			userId, accessToken := registerAndLogIn(t)
+			set := fetchJWKS(t)
+			if len(set.Keys) != 2 || set.Keys[0].Alg != jwks.RS256 || set.Keys[1].Alg != jwks.EdDSA {
+				t.Fatalf("Expected an RS256 and an EdDSA key. Got %+v", set.Keys)
+			}
+
+			var claims jwt.RegisteredClaims
+			_, err := jwt.ParseWithClaims(accessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+				if kid := token.Header["kid"]; kid != set.Keys[0].Kid {
+					t.Errorf("Expected the token to be signed by %s. Got %v", set.Keys[0].Kid, kid)
+				}
+				return set.Keys[0].PublicKey()
+			}, jwt.WithValidMethods([]string{jwks.RS256}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != userId {
+				t.Errorf("Expected the token of %s. Got %s", userId, claims.Subject)
+			}
+		},
+	)
+
+	t.Run(`Given a token signed by the key that was rotated out
+      When it is verified
+      Then it should be accepted
+      But a token signed by a key that was never published should not`,
+		func(t *testing.T) {
+			userId, _ := registerAndLogIn(t)
+			req, _ := http.NewRequest(http.MethodGet, "/users/auth", nil)
+			req.Header.Set("Authorization", "Bearer "+signToken(t, previousSigningKey, userId))
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			if verified := tests.ParseResponse(response)["data"].(map[string]interface{})["user_id"]; verified != userId {
+				t.Errorf("Expected user %s. Got %v", userId, verified)
+			}
+
+			_, unknownKey, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ = http.NewRequest(http.MethodGet, "/users/auth", nil)
+			req.Header.Set("Authorization", "Bearer "+signToken(t, unknownKey, userId))
+			response = tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusUnauthorized, response.Code)
+		},
+	)
+}
@@ -0,0 +1,114 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/olad5/productive-pulse/pkg/migrations"
+	"github.com/olad5/productive-pulse/users-service/tests/contract"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run(`Given migration files with a missing down file or an invalid name
+      When they are loaded
+      Then loading should fail
+    `,
+		func(t *testing.T) {
+			for name, files := range map[string]fstest.MapFS{
+				"missing down": {"sql/0001_create_users.up.sql": {Data: []byte("SELECT 1;")}},
+				"invalid name": {"sql/create_users.up.sql": {Data: []byte("SELECT 1;")}},
+			} {
+				if _, err := migrations.Load(files); err == nil {
+					t.Errorf("%s: Expected loading to fail", name)
+				}
+			}
+		},
+	)
+
+	repo, ok := userRepository.(interface{ Migrator() *migrations.Migrator })
+	if !ok {
+		t.Skip("the backend has no schema to migrate")
+	}
+	migrator := repo.Migrator()
+
+	t.Run(`Given a migrated database
+      When several runners migrate it up at once
+      Then none of them should apply anything
+      And its version should stay the same
+    `,
+		func(t *testing.T) {
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 5)
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					applied, err := migrator.Up(ctx)
+					if err == nil && len(applied) != 0 {
+						t.Errorf("Expected no migrations to be applied. Got %v", applied)
+					}
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if after, err := migrator.Version(ctx); err != nil || after != version {
+				t.Errorf("Expected version %d. Got %d, %v", version, after, err)
+			}
+		},
+	)
+
+	t.Run(`Given a migrated database
+      When its latest migration is reverted and migrated up again
+      Then its version should go back and forth
+      And the repository should still pass its contract
+    `,
+		func(t *testing.T) {
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reverted, err := migrator.Down(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reverted) != 1 || reverted[0].Version != version {
+				t.Fatalf("Expected migration %d to be reverted. Got %v", version, reverted)
+			}
+			if before, err := migrator.Version(ctx); err != nil || before >= version {
+				t.Errorf("Expected a version below %d. Got %d, %v", version, before, err)
+			}
+
+			applied, err := migrator.Up(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != 1 || applied[0].Version != version {
+				t.Fatalf("Expected migration %d to be applied. Got %v", version, applied)
+			}
+			if after, err := migrator.Version(ctx); err != nil || after != version {
+				t.Errorf("Expected version %d. Got %d, %v", version, after, err)
+			}
+			contract.UserRepository(t, userRepository)
+		},
+	)
+}
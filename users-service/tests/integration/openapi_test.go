@@ -0,0 +1,45 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Run(`Given the users-service router, when the OpenAPI spec is fetched from
+    /openapi.json, then it is an OpenAPI 3.1 document describing every route.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+
+			var document struct {
+				OpenAPI string `json:"openapi"`
+			}
+			if err := json.Unmarshal(response.Body.Bytes(), &document); err != nil {
+				t.Fatal(err)
+			}
+			if document.OpenAPI != "3.1.0" {
+				t.Errorf("Expected an OpenAPI 3.1.0 document. Got %q", document.OpenAPI)
+			}
+			tests.AssertRoutesDocumented(t, svr.Router, response.Body.Bytes())
+		},
+	)
+
+	t.Run(`Given a registration request whose password is empty, when it is sent,
+    then the request validator rejects it with a 400 before it reaches the handler.`,
+		func(t *testing.T) {
+			requestBody := []byte(`{"email": "nobody@gmail.com", "first_name": "no", "last_name": "body", "password": ""}`)
+			req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(requestBody))
+			response := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+		},
+	)
+}
@@ -0,0 +1,65 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/pkg/utils"
+)
+
+func TestProblemDetails(t *testing.T) {
+	route := "/users/login"
+	requestBody := []byte(`{
+      "email": "problemnoexist@gmail.com",
+      "password": "some-random-password"
+      }`)
+
+	t.Run(`Given a user who accepts application/problem+json
+    when they log in with an account that does not exist,
+    then they should receive a 400 problem naming the error by its code.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(requestBody))
+			req.Header.Set("Accept", utils.ProblemContentType)
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+			tests.AssertResponseMessage(t, response.Header().Get("Content-Type"), utils.ProblemContentType)
+			problem := tests.ParseResponse(response)
+			tests.AssertResponseMessage(t, problem["detail"].(string), "incorrect credentials")
+			tests.AssertResponseMessage(t, problem["code"].(string), "incorrect_credentials")
+		},
+	)
+
+	t.Run(`Given a user who does not ask for problems
+    when they log in with an account that does not exist,
+    then they should receive the usual error response, with the same code.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBuffer(requestBody))
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+			body := tests.ParseResponse(response)
+			tests.AssertResponseMessage(t, body["message"].(string), "incorrect credentials")
+			tests.AssertResponseMessage(t, body["code"].(string), "incorrect_credentials")
+		},
+	)
+
+	t.Run(`Given a user who accepts application/problem+json
+    when their request does not match the API document,
+    then the validation error should be a problem as well.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(`{"email": "problem@gmail.com"}`))
+			req.Header.Set("Accept", utils.ProblemContentType)
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusBadRequest, response.Code)
+			tests.AssertResponseMessage(t, response.Header().Get("Content-Type"), utils.ProblemContentType)
+			tests.AssertResponseMessage(t, tests.ParseResponse(response)["code"].(string), "invalid_request")
+		},
+	)
+}
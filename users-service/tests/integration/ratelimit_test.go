@@ -0,0 +1,102 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/redis/go-redis/v9"
+)
+
+func rateLimitStores(t *testing.T) map[string]ratelimit.Store {
+	t.Helper()
+	redisServer := miniredis.RunT(t)
+	redisStore, err := ratelimit.NewRedisStore(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}), "users-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]ratelimit.Store{
+		"memory": ratelimit.NewMemoryStore(),
+		"redis":  redisStore,
+	}
+}
+
+func login(router http.Handler, route, remoteAddr string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, route, bytes.NewBufferString(`{
+      "email": "nobody@gmail.com",
+      "password": "some-random-password"
+      }`))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, req)
+	return response
+}
+
+func TestRateLimit(t *testing.T) {
+	policy, err := ratelimit.ParsePolicy("POST /users/login ip=2/1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range rateLimitStores(t) {
+		rateLimiter, err := ratelimit.NewLimiter(store, policy, func(r *http.Request) string { return "" }, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := newRouter(rateLimiter)
+
+		t.Run(name+`: Given a client that used up its login limit across /v1 and the unversioned
+    alias, when it logs in again, then the server should respond with a 429 in the error
+    format, telling it when to retry.`,
+			func(t *testing.T) {
+				first := login(router, "/v1/users/login", "192.0.2.1:1234")
+				if first.Code == http.StatusTooManyRequests {
+					t.Fatal("Expected the first login to be let through")
+				}
+				if first.Header().Get(ratelimit.LimitHeader) != "2" || first.Header().Get(ratelimit.RemainingHeader) != "1" {
+					t.Errorf("Expected RateLimit-Limit 2 and RateLimit-Remaining 1. Got %q and %q",
+						first.Header().Get(ratelimit.LimitHeader), first.Header().Get(ratelimit.RemainingHeader))
+				}
+				login(router, "/users/login", "192.0.2.1:1234")
+
+				response := login(router, "/v1/users/login", "192.0.2.1:1234")
+				tests.AssertStatusCode(t, http.StatusTooManyRequests, response.Code)
+				tests.AssertResponseMessage(t, tests.ParseResponse(response)["message"].(string), ratelimit.ErrTooManyRequests.Message)
+				if response.Header().Get(ratelimit.RetryAfterHeader) == "" {
+					t.Error("Expected a Retry-After header")
+				}
+				if remaining := response.Header().Get(ratelimit.RemainingHeader); remaining != "0" {
+					t.Errorf("Expected RateLimit-Remaining 0. Got %q", remaining)
+				}
+			},
+		)
+
+		t.Run(name+`: Given a client that used up its login limit, when another client logs in
+    or the first one registers, then neither should be limited.`,
+			func(t *testing.T) {
+				response := login(router, "/v1/users/login", "192.0.2.2:1234")
+				if response.Code == http.StatusTooManyRequests {
+					t.Error("Expected another client to have its own bucket")
+				}
+
+				req, _ := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewBuffer(registerBody()))
+				req.RemoteAddr = "192.0.2.1:1234"
+				req.Header.Set("Content-Type", "application/json")
+				registerResponse := httptest.NewRecorder()
+				router.ServeHTTP(registerResponse, req)
+				tests.AssertStatusCode(t, http.StatusOK, registerResponse.Code)
+				if limit := registerResponse.Header().Get(ratelimit.LimitHeader); limit != "" {
+					t.Errorf("Expected a route without a rule not to be limited. Got RateLimit-Limit %q", limit)
+				}
+			},
+		)
+	}
+}
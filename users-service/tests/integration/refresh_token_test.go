@@ -0,0 +1,176 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func refreshTokens(route, refreshToken string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, route, strings.NewReader(fmt.Sprintf(`{"refresh_token": %q}`, refreshToken)))
+	return tests.ExecuteRequest(req, svr)
+}
+
+// assertRefreshRejected asserts that response is a 401 naming the error by
+// code.
+func assertRefreshRejected(t *testing.T, response *httptest.ResponseRecorder, code string) {
+	t.Helper()
+	tests.AssertStatusCode(t, http.StatusUnauthorized, response.Code)
+	tests.AssertResponseMessage(t, tests.ParseResponse(response)["code"].(string), code)
+}
+
+func TestRefreshToken(t *testing.T) {
+	route := "/v1/users/token/refresh"
+
+	t.Run(`Given a user who logged in
+      When they trade their refresh token
+      Then they should receive a new access token, which is valid
+      And a new refresh token, which trades in turn`,
+		func(t *testing.T) {
+			_, email := registerUser(t)
+			refreshToken := logIn(t, email)["refresh_token"].(string)
+
+			response := refreshTokens(route, refreshToken)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			data := tests.ParseResponse(response)["data"].(map[string]interface{})
+			accessToken, nextRefreshToken := data["access_token"].(string), data["refresh_token"].(string)
+			if nextRefreshToken == "" || nextRefreshToken == refreshToken {
+				t.Fatalf("Expected a new refresh token. Got %q", nextRefreshToken)
+			}
+
+			authReq, _ := http.NewRequest(http.MethodGet, "/users/auth", nil)
+			authReq.Header.Set("Authorization", "Bearer "+accessToken)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(authReq, svr).Code)
+
+			tests.AssertStatusCode(t, http.StatusOK, refreshTokens("/users/token/refresh", nextRefreshToken).Code)
+		},
+	)
+
+	t.Run(`Given a refresh token that was traded already
+      When it is traded again
+      Then it should be rejected as reused
+      And every token rotated from the same login should be revoked
+      But the tokens of another login should still trade`,
+		func(t *testing.T) {
+			_, email := registerUser(t)
+			stolen := logIn(t, email)["refresh_token"].(string)
+			otherLogin := logIn(t, email)["refresh_token"].(string)
+
+			response := refreshTokens(route, stolen)
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			latest := tests.ParseResponse(response)["data"].(map[string]interface{})["refresh_token"].(string)
+
+			assertRefreshRejected(t, refreshTokens(route, stolen), "refresh_token_reused")
+			assertRefreshRejected(t, refreshTokens(route, latest), "invalid_refresh_token")
+			tests.AssertStatusCode(t, http.StatusOK, refreshTokens(route, otherLogin).Code)
+		},
+	)
+
+	t.Run(`Given a refresh token that was traded with an Idempotency-Key
+      When it is traded again with the same key
+      Then it should be rejected as reused rather than replayed`,
+		func(t *testing.T) {
+			_, email := registerUser(t)
+			refreshToken := logIn(t, email)["refresh_token"].(string)
+			key := "refresh-" + fmt.Sprint(tests.GenerateUniqueId())
+			body := fmt.Sprintf(`{"refresh_token": %q}`, refreshToken)
+
+			req, _ := http.NewRequest(http.MethodPost, route, strings.NewReader(body))
+			req.Header.Set(idempotency.HeaderKey, key)
+			tests.AssertStatusCode(t, http.StatusOK, tests.ExecuteRequest(req, svr).Code)
+
+			req, _ = http.NewRequest(http.MethodPost, route, strings.NewReader(body))
+			req.Header.Set(idempotency.HeaderKey, key)
+			assertRefreshRejected(t, tests.ExecuteRequest(req, svr), "refresh_token_reused")
+		},
+	)
+
+	t.Run(`Given a refresh token that was never issued
+      When it is traded
+      Then it should be rejected as invalid`,
+		func(t *testing.T) {
+			assertRefreshRejected(t, refreshTokens(route, "not-a-refresh-token"), "invalid_refresh_token")
+		},
+	)
+
+	t.Run(`Given a request without a refresh token
+      When it is made
+      Then it should be rejected as a bad request`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, route, strings.NewReader(`{}`))
+			tests.AssertStatusCode(t, http.StatusBadRequest, tests.ExecuteRequest(req, svr).Code)
+		},
+	)
+}
+
+func TestTokenLifetimes(t *testing.T) {
+	ctx := context.Background()
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := users.NewKeySet(signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lifetimes := users.TokenLifetimes{Access: 2 * time.Minute, Refresh: 50 * time.Millisecond}
+	userService, err := users.NewUserService(userRepository, userRepository, keys, lifetimes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run(`Given a service configured with token lifetimes
+      When a user logs in
+      Then their access token should expire after the access token lifetime`,
+		func(t *testing.T) {
+			_, email := registerUser(t)
+			tokens, err := userService.LogUserIn(ctx, tracer, email, "some-random-password")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var claims jwt.RegisteredClaims
+			if _, _, err := jwt.NewParser().ParseUnverified(tokens.AccessToken, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != lifetimes.Access {
+				t.Errorf("Expected the access token to live %v. Got %v", lifetimes.Access, lifetime)
+			}
+		},
+	)
+
+	t.Run(`Given a refresh token older than the refresh token lifetime
+      When it is traded
+      Then it should be rejected as expired`,
+		func(t *testing.T) {
+			_, email := registerUser(t)
+			tokens, err := userService.LogUserIn(ctx, tracer, email, "some-random-password")
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(lifetimes.Refresh)
+
+			_, err = userService.RefreshTokens(ctx, tracer, tokens.RefreshToken)
+			if !errors.Is(err, users.ErrExpiredRefreshToken) {
+				t.Errorf("Expected the refresh token to be expired. Got %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,32 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/olad5/productive-pulse/users-service/tests/contract"
+)
+
+func TestUserRepositoryContract(t *testing.T) {
+	t.Run(`Given the storage backend the suite runs against
+      When it goes through the repository contract
+      Then it should behave like every other backend
+    `,
+		func(t *testing.T) {
+			contract.UserRepository(t, userRepository)
+		},
+	)
+}
+
+func TestRefreshTokenRepositoryContract(t *testing.T) {
+	t.Run(`Given the storage backend the suite runs against
+      When it goes through the refresh token contract
+      Then it should behave like every other backend
+    `,
+		func(t *testing.T) {
+			contract.RefreshTokenRepository(t, userRepository, userRepository)
+		},
+	)
+}
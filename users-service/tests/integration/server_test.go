@@ -6,48 +6,148 @@ package integration
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/exaring/otelpgx"
 	"github.com/olad5/productive-pulse/config"
 	"github.com/olad5/productive-pulse/pkg/app/server"
+	"github.com/olad5/productive-pulse/pkg/idempotency"
+	"github.com/olad5/productive-pulse/pkg/migrations"
+	usersv1 "github.com/olad5/productive-pulse/pkg/proto/users/v1"
+	"github.com/olad5/productive-pulse/pkg/ratelimit"
 	tests "github.com/olad5/productive-pulse/pkg/tests"
 	"github.com/olad5/productive-pulse/pkg/utils"
 	"github.com/olad5/productive-pulse/users-service/internal/app/router"
+	"github.com/olad5/productive-pulse/users-service/internal/app/rpc"
 	"github.com/olad5/productive-pulse/users-service/internal/handlers"
+	"github.com/olad5/productive-pulse/users-service/internal/infra"
+	"github.com/olad5/productive-pulse/users-service/internal/infra/memory"
 	"github.com/olad5/productive-pulse/users-service/internal/infra/postgres"
+	"github.com/olad5/productive-pulse/users-service/internal/infra/sqlite"
 	"github.com/olad5/productive-pulse/users-service/internal/usecases/users"
 )
 
-var svr *server.Server
+var (
+	svr        *server.Server
+	userClient usersv1.UserServiceClient
+	// newRouter builds the router of the suite around another rate limiter.
+	newRouter func(rateLimiter *ratelimit.Limiter) http.Handler
+	// userRepository is the backend the suite runs against.
+	userRepository repository
+	// previousSigningKey signed tokens before the RSA key of the suite was
+	// rotated in, and still verifies them.
+	previousSigningKey ed25519.PrivateKey
+)
+
+// backends are the storage backends the suite runs against in turn, unless
+// USER_SERVICE_STORAGE picks one.
+var backends = []string{"postgres", "sqlite", "memory"}
 
 func TestMain(m *testing.M) {
 	configurations := config.GetConfig("../config/.test.env")
-	ctx := context.Background()
 
+	suiteBackends := backends
+	// NOTE: configurations.UserServiceStorage follows the scheme of the test
+	// database URL, so only an explicit USER_SERVICE_STORAGE narrows the suite.
+	if storage := os.Getenv("USER_SERVICE_STORAGE"); storage != "" {
+		suiteBackends = []string{storage}
+	}
+	exitVal := 0
+	for _, backend := range suiteBackends {
+		log.Printf("running the suite against the %s backend", backend)
+		setUpSuite(context.Background(), configurations, backend)
+		if code := m.Run(); code != 0 {
+			exitVal = code
+		}
+	}
+	os.Exit(exitVal)
+}
+
+// repository is what every storage backend keeps.
+type repository interface {
+	infra.UserRepository
+	infra.RefreshTokenRepository
+}
+
+func newUserRepository(ctx context.Context, configurations *config.Configurations, backend string) repository {
+	switch backend {
+	case "postgres":
+		userRepo, err := postgres.NewPostgresRepo(ctx, otelpgx.NewTracer(), configurations.UserServiceDBUrl)
+		if err != nil {
+			log.Fatal("Error Initializing User Repo")
+		}
+		return userRepo
+	case "sqlite":
+		databaseDir, err := os.MkdirTemp("", "users-service-test")
+		if err != nil {
+			log.Fatal(err)
+		}
+		userRepo, err := sqlite.NewSQLiteRepo(ctx, "sqlite://"+filepath.Join(databaseDir, "users.db"))
+		if err != nil {
+			log.Fatal("Error Initializing User Repo", err)
+		}
+		return userRepo
+	case "memory":
+		return memory.NewMemoryRepo()
+	default:
+		log.Fatalf("unknown backend %q", backend)
+		return nil
+	}
+}
+
+// setUpSuite points the suite variables at a fresh set of services on top of
+// backend.
+func setUpSuite(ctx context.Context, configurations *config.Configurations, backend string) {
 	tracerProvider, err := utils.NewTracerProvider(configurations.UserServiceName, "")
 	if err != nil {
 		log.Fatal("JaegerTraceProvider failed to Initialize", err)
 	}
 
 	tracer := tracerProvider.Tracer(configurations.UserServiceName)
-	postgresTracer := otelpgx.NewTracer()
 
-	userRepo, err := postgres.NewPostgresRepo(ctx, postgresTracer, configurations.UserServiceDBUrl)
+	userRepo := newUserRepository(ctx, configurations, backend)
+	err = userRepo.Ping(ctx)
 	if err != nil {
-		log.Fatal("Error Initializing User Repo")
+		log.Fatal(err)
 	}
+	if repo, ok := userRepo.(interface{ Migrator() *migrations.Migrator }); ok {
+		if _, err := repo.Migrator().Up(ctx); err != nil {
+			log.Fatal("Error Migrating User Repo", err)
+		}
+	}
+	userRepository = userRepo
 
-	err = userRepo.Ping(ctx)
+	var idempotencyStore idempotency.Store = idempotency.NewMemoryStore(time.Hour, time.Minute)
+	if backend == "postgres" {
+		idempotencyStore, err = idempotency.NewPostgresStore(ctx, otelpgx.NewTracer(), configurations.UserServiceDBUrl, time.Hour, time.Minute)
+		if err != nil {
+			log.Fatal("Error Initializing Idempotency Store", err)
+		}
+	}
+
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		log.Fatal(err)
 	}
+	_, previousSigningKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys, err := users.NewKeySet(signingKey, previousSigningKey.Public())
+	if err != nil {
+		log.Fatal("Error Initializing KeySet", err)
+	}
 
-	userService, err := users.NewUserService(userRepo, configurations)
+	userService, err := users.NewUserService(userRepo, userRepo, keys, users.DefaultTokenLifetimes)
 	if err != nil {
 		log.Fatal("Error Initializing UserService")
 	}
@@ -56,11 +156,28 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatal("failed to create the User handler: ", err)
 	}
-	appRouter := router.NewHttpRouter(*userHandler, configurations)
-	svr = server.CreateNewServer(appRouter)
+	newRouter = func(rateLimiter *ratelimit.Limiter) http.Handler {
+		return router.NewHttpRouter(*userHandler, idempotencyStore, rateLimiter, configurations)
+	}
+	rateLimitPolicy, err := ratelimit.ParsePolicy(configurations.UserServiceRateLimits)
+	if err != nil {
+		log.Fatal("Invalid rate limit policy: ", err)
+	}
+	rateLimiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rateLimitPolicy, router.IdentifyUser(*userService, tracer), false)
+	if err != nil {
+		log.Fatal("failed to create the rate limiter: ", err)
+	}
+	svr = server.CreateNewServer(newRouter(rateLimiter))
 
-	exitVal := m.Run()
-	os.Exit(exitVal)
+	userServer, err := rpc.NewUserServer(*userService, tracer)
+	if err != nil {
+		log.Fatal("failed to create the User gRPC server: ", err)
+	}
+	grpcConn, err := tests.NewBufconnClientConn(rpc.NewGrpcServer(userServer))
+	if err != nil {
+		log.Fatal("failed to connect to the User gRPC server: ", err)
+	}
+	userClient = usersv1.NewUserServiceClient(grpcConn)
 }
 
 func TestRegister(t *testing.T) {
@@ -120,6 +237,36 @@ func TestRegister(t *testing.T) {
 			tests.AssertResponseMessage(t, message, "email already exist")
 		},
 	)
+
+	t.Run(`Given a user registration request that was answered,
+    when the client retries it with the same Idempotency-Key and body,
+    then the server should replay the original response instead of answering
+    that the email address is already taken.`,
+		func(t *testing.T) {
+			key := "register-" + fmt.Sprint(tests.GenerateUniqueId())
+			email := "will" + fmt.Sprint(tests.GenerateUniqueId()) + "@gmail.com"
+			requestBody := fmt.Sprintf(`{
+      "email": "%s",
+      "first_name": "will",
+      "last_name": "hansen",
+      "password": "some-random-password"
+      }`, email)
+
+			req, _ := http.NewRequest("POST", route, bytes.NewBufferString(requestBody))
+			req.Header.Set(idempotency.HeaderKey, key)
+			firstResponse := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, firstResponse.Code)
+			firstId := tests.ParseResponse(firstResponse)["data"].(map[string]interface{})["id"].(string)
+
+			req, _ = http.NewRequest("POST", route, bytes.NewBufferString(requestBody))
+			req.Header.Set(idempotency.HeaderKey, key)
+			retryResponse := tests.ExecuteRequest(req, svr)
+			tests.AssertStatusCode(t, http.StatusOK, retryResponse.Code)
+			tests.AssertResponseMessage(t, retryResponse.Header().Get(idempotency.HeaderReplayed), "true")
+			retryId := tests.ParseResponse(retryResponse)["data"].(map[string]interface{})["id"].(string)
+			tests.AssertResponseMessage(t, retryId, firstId)
+		},
+	)
 }
 
 func TestLogin(t *testing.T) {
@@ -0,0 +1,62 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+
+	tests "github.com/olad5/productive-pulse/pkg/tests"
+	"github.com/olad5/productive-pulse/pkg/versioning"
+)
+
+func registerBody() []byte {
+	email := "vera" + fmt.Sprint(tests.GenerateUniqueId()) + "@gmail.com"
+	return []byte(fmt.Sprintf(`{
+      "email": "%s",
+      "first_name": "vera",
+      "last_name": "hansen",
+      "password": "some-random-password"
+      }`, email))
+}
+
+func TestVersioning(t *testing.T) {
+	t.Run(`Given a registration request sent to /v1/users, when it is handled, then the
+    user should be created and the response should not carry deprecation headers.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/v1/users", bytes.NewBuffer(registerBody()))
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			for _, header := range []string{versioning.DeprecationHeader, versioning.SunsetHeader} {
+				if value := response.Header().Get(header); value != "" {
+					t.Errorf("Expected no %s header on /v1. Got %q", header, value)
+				}
+			}
+		},
+	)
+
+	t.Run(`Given a registration request sent to the unversioned /users alias, when it is
+    handled, then the user should be created and the response should carry Deprecation,
+    Sunset and successor Link headers.`,
+		func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(registerBody()))
+			response := tests.ExecuteRequest(req, svr)
+
+			tests.AssertStatusCode(t, http.StatusOK, response.Code)
+			if response.Header().Get(versioning.DeprecationHeader) == "" {
+				t.Error("Expected a Deprecation header on the unversioned route")
+			}
+			if response.Header().Get(versioning.SunsetHeader) == "" {
+				t.Error("Expected a Sunset header on the unversioned route")
+			}
+			expectedLink := `</v1/users>; rel="successor-version"`
+			if link := response.Header().Get(versioning.LinkHeader); link != expectedLink {
+				t.Errorf("Expected Link header %q. Got %q", expectedLink, link)
+			}
+		},
+	)
+}